@@ -0,0 +1,138 @@
+package apiPageHandlers
+
+import (
+	f "GoForum/functions"
+	"fmt"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// feedEntriesLimit is the number of messages in the user and global feeds
+const feedEntriesLimit = 20
+
+// ThreadFeedHandler serves the Atom feed of the latest messages of a thread
+// Its path is /t/{threadName}/feed.atom?tags={tags}&token={token}
+// The "tags" are an optional comma separated list of tag names, only the messages having all of them are in the feed
+// The "token" is the feed token of a user, it is needed for the threads that are not open to everyone
+func ThreadFeedHandler(w http.ResponseWriter, r *http.Request) {
+	threadName := mux.Vars(r)["threadName"]
+	query := r.URL.Query()
+
+	// Check if the thread name is empty or does not exist
+	if threadName == "" || !f.CheckIfThreadNameExists(threadName) {
+		f.DebugPrintf("Thread \"%s\" does not exist\n", threadName)
+		http.Error(w, "Thread does not exist or was not specified !", http.StatusNotFound)
+		return
+	}
+
+	thread := f.GetThreadFromName(threadName)
+	threadConfig := f.GetThreadConfigFromThread(thread)
+
+	// The threads not open to everyone need the feed token of a user allowed to see their content
+	if !threadConfig.IsOpenToNonConnectedUsers || !threadConfig.IsOpenToNonMembers {
+		token := query.Get("token")
+		if token == "" {
+			f.DebugPrintf("Feed of the private thread \"%s\" requested without token\n", threadName)
+			http.Error(w, "A feed token is required for this thread", http.StatusUnauthorized)
+			return
+		}
+		user, err := f.GetUserFromFeedToken(token)
+		if err != nil {
+			f.DebugPrintf("Feed of the private thread \"%s\" requested with an invalid token\n", threadName)
+			http.Error(w, "Feed token is not valid", http.StatusUnauthorized)
+			return
+		}
+		if f.GetUserRankInThread(thread, user) < 0 {
+			f.DebugPrintf("User %s is banned from the thread %s\n", user.Username, threadName)
+			http.Error(w, "User is banned from the thread", http.StatusForbidden)
+			return
+		}
+		if !threadConfig.IsOpenToNonMembers && !f.IsUserInThread(thread, user) {
+			f.DebugPrintf("User %s is not a member of the private thread %s\n", user.Username, threadName)
+			http.Error(w, "User is not in the thread", http.StatusForbidden)
+			return
+		}
+	}
+
+	// Get the tags to filter the messages with
+	var tags []f.ThreadTag
+	if strTags := query.Get("tags"); strTags != "" {
+		var err error
+		tags, err = stringTagsToThreadTags(strings.Split(strTags, ","), thread)
+		if err != nil {
+			http.Error(w, "Error getting the thread tags", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// The messages are seen from an anonymous point of view
+	messages, err := f.GetMessagesFromThreadWithPOV(thread, 0, "desc", f.User{}, tags)
+	if err != nil {
+		f.ErrorPrintf("Error getting messages from thread: %s\n", err)
+		http.Error(w, "Error getting messages from thread", http.StatusInternalServerError)
+		return
+	}
+	var feedMessages []f.FeedMessage
+	for _, message := range messages {
+		// The tombstones of the deleted messages are not published
+		if message.DeletionState != f.NotDeleted {
+			continue
+		}
+		feedMessages = append(feedMessages, f.FeedMessage{ThreadName: thread.ThreadName, Message: message})
+	}
+
+	selfPath := "/t/" + url.PathEscape(thread.ThreadName) + "/feed.atom"
+	if r.URL.RawQuery != "" {
+		selfPath += "?" + r.URL.RawQuery
+	}
+	title := thread.ThreadName + " | GoForum"
+	if len(tags) > 0 {
+		var tagNames []string
+		for _, tag := range tags {
+			tagNames = append(tagNames, tag.TagName)
+		}
+		title = fmt.Sprintf("%s [%s] | GoForum", thread.ThreadName, strings.Join(tagNames, ", "))
+	}
+	f.WriteAtomFeed(w, f.NewAtomFeed(r, title, threadConfig.ThreadDescription, selfPath, "/t/"+url.PathEscape(thread.ThreadName), feedMessages))
+}
+
+// UserFeedHandler serves the Atom feed of the latest messages of a user
+// Its path is /profile/{user}/feed.atom
+// Only the messages posted in the threads open to everyone are in the feed
+func UserFeedHandler(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["user"]
+
+	// Check if the user exists
+	if !f.CheckIfUsernameExists(username) {
+		f.DebugPrintf("User \"%s\" does not exist\n", username)
+		http.Error(w, "User does not exist", http.StatusNotFound)
+		return
+	}
+	user, err := f.GetUserFromUsername(username)
+	if err != nil {
+		http.Error(w, "Error getting the user", http.StatusInternalServerError)
+		return
+	}
+
+	messages, err := f.GetLatestPublicMessages(user, feedEntriesLimit)
+	if err != nil {
+		http.Error(w, "Error getting the messages of the user", http.StatusInternalServerError)
+		return
+	}
+	profilePath := "/profile/" + url.PathEscape(user.Username)
+	f.WriteAtomFeed(w, f.NewAtomFeed(r, user.Username+" | GoForum", "", profilePath+"/feed.atom", profilePath, messages))
+}
+
+// GlobalFeedHandler serves the Atom feed of the latest messages of the forum
+// Its path is /feed.atom
+// Only the messages posted in the threads open to everyone are in the feed
+func GlobalFeedHandler(w http.ResponseWriter, r *http.Request) {
+	messages, err := f.GetLatestPublicMessages(f.User{}, feedEntriesLimit)
+	if err != nil {
+		http.Error(w, "Error getting the latest messages", http.StatusInternalServerError)
+		return
+	}
+	f.WriteAtomFeed(w, f.NewAtomFeed(r, "GoForum", "", "/feed.atom", "/", messages))
+}
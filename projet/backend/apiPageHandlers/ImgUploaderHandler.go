@@ -59,10 +59,10 @@ func ImgUploader(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		user = f.GetUser(r)
-		// Check if the user is the owner of the thread
-		if thread.OwnerID != user.UserID {
-			f.DebugPrintf("User is not the owner of the thread\n")
-			http.Error(w, "User is not the owner of the thread", http.StatusForbidden)
+		// Check if the user is allowed to manage the thread
+		if !f.HasThreadPermission(thread, user, f.PermissionManageThread) {
+			f.DebugPrintf("User is not allowed to manage the thread\n")
+			http.Error(w, "User is not allowed to manage the thread", http.StatusForbidden)
 			return
 		}
 		threadConfigs = f.GetThreadConfigFromThread(thread)
@@ -3,15 +3,20 @@ package apiPageHandlers
 import (
 	f "GoForum/functions"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 )
 
+// MessageCommentGetter returns a page of the comments of a message as a JSON array
+// The pages are loaded with the 'cursor' query parameter, the cursor of the next page is given in the 'X-Next-Cursor' header (empty when there is nothing left to load)
+// The 'offset' query parameter is still accepted in place of the cursor
 func MessageCommentGetter(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 	threadName := query.Get("thread")
 	messageId := query.Get("message")
 	offset := query.Get("offset")
+	cursor := query.Get("cursor")
 
 	// Check if the thread name is empty or does not exist
 	if threadName == "" || !f.CheckIfThreadNameExists(threadName) {
@@ -42,19 +47,15 @@ func MessageCommentGetter(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if the offset is empty or not a number
-	if offset == "" {
-		f.DebugPrintf("Offset is empty\n")
-		http.Error(w, "Offset is empty", http.StatusBadRequest)
-		return
-	}
-
-	// Convert the offset to an int
-	offsetInt, err := strconv.Atoi(offset)
-	if err != nil {
-		f.ErrorPrintf("Error parsing offset: %s\n", err)
-		http.Error(w, "Offset is not a number", http.StatusBadRequest)
-		return
+	// Convert the offset to an int if the comments are loaded with it
+	offsetInt := -1
+	if offset != "" {
+		offsetInt, err = strconv.Atoi(offset)
+		if err != nil || offsetInt < 0 {
+			f.DebugPrintf("Offset is not a valid number\n")
+			http.Error(w, "Offset is not a number", http.StatusBadRequest)
+			return
+		}
 	}
 	user := f.GetUser(r)
 
@@ -73,11 +74,31 @@ func MessageCommentGetter(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Check if the message is visible to the user (not hidden nor waiting for approval)
+	message, err := f.GetMessageByIDWithPOV(messageIdInt, user)
+	if err != nil || !f.CanUserSeeMessage(thread, user, message) {
+		f.DebugPrintf("Message \"%s\" is not visible to the user\n", messageId)
+		http.Error(w, "Message MessageID does not exist", http.StatusNotFound)
+		return
+	}
+
 	var comments []f.FormattedMessageComment
-	if user != (f.User{}) {
+	if offsetInt >= 0 {
 		comments, err = f.GetCommentsFromMessageWithPOV(messageIdInt, offsetInt, user)
 	} else {
-		comments, err = f.GetCommentsFromMessage(messageIdInt, offsetInt)
+		var nextCursor string
+		comments, nextCursor, err = f.GetCommentsFromMessageAfterCursor(messageIdInt, cursor, user)
+		w.Header().Set("X-Next-Cursor", nextCursor)
+	}
+	if errors.Is(err, f.ErrInvalidCursor) {
+		f.DebugPrintf("Cursor is not valid\n")
+		http.Error(w, "Cursor is not valid", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		f.ErrorPrintf("Error getting comments from message: %s\n", err)
+		http.Error(w, "Error getting comments from message", http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,111 @@
+package apiPageHandlers
+
+import (
+	f "GoForum/functions"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+)
+
+// MessageRevisionsGetter returns every version of a message with the changes between them
+// Only the author of the message and the moderation team of the thread can see them
+func MessageRevisionsGetter(w http.ResponseWriter, r *http.Request) {
+	messageID, err := strconv.Atoi(mux.Vars(r)["messageId"])
+	if err != nil {
+		f.DebugPrintf("Message ID is not a number\n")
+		http.Error(w, "Message ID is not a number", http.StatusBadRequest)
+		return
+	}
+	thread := f.GetThreadFromMessageID(messageID)
+	if thread.ThreadID == 0 {
+		f.DebugPrintf("Message %d does not exist\n", messageID)
+		http.Error(w, "Message does not exist", http.StatusNotFound)
+		return
+	}
+	user := f.GetUser(r)
+
+	// Check if the user is allowed to see the revisions of the message
+	message, err := f.GetMessageByIDWithPOV(messageID, user)
+	if err != nil || !f.CanUserSeeMessage(thread, user, message) {
+		f.DebugPrintf("Message %d is not visible to the user\n", messageID)
+		http.Error(w, "Message does not exist", http.StatusNotFound)
+		return
+	}
+	if message.DeletionState != f.NotDeleted {
+		f.DebugPrintf("Message %d was deleted\n", messageID)
+		http.Error(w, "Message does not exist", http.StatusNotFound)
+		return
+	}
+	authorID, err := f.GetMessageAuthorID(messageID)
+	if err != nil || !f.CanUserSeeRevisions(thread, user, authorID) {
+		f.DebugPrintf("User is not allowed to see the revisions of the message %d\n", messageID)
+		http.Error(w, "User is not allowed to see the revisions of this message", http.StatusForbidden)
+		return
+	}
+
+	previous, err := f.GetMessageRevisions(messageID)
+	if err != nil {
+		http.Error(w, "Error while getting the revisions", http.StatusInternalServerError)
+		return
+	}
+	current := f.ContentRevision{Title: message.MessageTitle, Content: message.MessageContent}
+	writeRevisions(w, f.BuildRevisionHistory(previous, current, message.CreationDate))
+}
+
+// CommentRevisionsGetter returns every version of a comment with the changes between them
+// Only the author of the comment and the moderation team of the thread can see them
+func CommentRevisionsGetter(w http.ResponseWriter, r *http.Request) {
+	commentID, err := strconv.Atoi(mux.Vars(r)["commentId"])
+	if err != nil {
+		f.DebugPrintf("Comment ID is not a number\n")
+		http.Error(w, "Comment ID is not a number", http.StatusBadRequest)
+		return
+	}
+	messageID := f.GetMessageIDFromCommentID(commentID)
+	thread := f.GetThreadFromMessageID(messageID)
+	if thread.ThreadID == 0 {
+		f.DebugPrintf("Comment %d does not exist\n", commentID)
+		http.Error(w, "Comment does not exist", http.StatusNotFound)
+		return
+	}
+	user := f.GetUser(r)
+
+	// Check if the user is allowed to see the revisions of the comment
+	message, err := f.GetMessageByIDWithPOV(messageID, user)
+	if err != nil || !f.CanUserSeeMessage(thread, user, message) {
+		f.DebugPrintf("Message %d is not visible to the user\n", messageID)
+		http.Error(w, "Comment does not exist", http.StatusNotFound)
+		return
+	}
+	authorID, err := f.GetCommentAuthorID(commentID)
+	if err != nil || !f.CanUserSeeRevisions(thread, user, authorID) {
+		f.DebugPrintf("User is not allowed to see the revisions of the comment %d\n", commentID)
+		http.Error(w, "User is not allowed to see the revisions of this comment", http.StatusForbidden)
+		return
+	}
+
+	comment, err := f.GetCommentByIDWithPOV(commentID, user)
+	if err != nil || comment.DeletionState != f.NotDeleted {
+		http.Error(w, "Comment does not exist", http.StatusNotFound)
+		return
+	}
+	previous, err := f.GetCommentRevisions(commentID)
+	if err != nil {
+		http.Error(w, "Error while getting the revisions", http.StatusInternalServerError)
+		return
+	}
+	current := f.ContentRevision{Content: comment.CommentContent}
+	writeRevisions(w, f.BuildRevisionHistory(previous, current, comment.CreationDate))
+}
+
+// writeRevisions writes the revisions as the JSON response
+func writeRevisions(w http.ResponseWriter, revisions []f.ContentRevision) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(revisions)
+	if err != nil {
+		f.ErrorPrintf("Error encoding revisions to JSON: %s\n", err)
+		http.Error(w, "Error encoding revisions to JSON", http.StatusInternalServerError)
+		return
+	}
+}
@@ -2,18 +2,39 @@ package apiPageHandlers
 
 import (
 	f "GoForum/functions"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gorilla/mux"
+	"io"
 	"net/http"
+	"slices"
 	"strconv"
+	"time"
 )
 
 type jsonMessage struct {
-	Title   string   `json:"title"`
-	Content string   `json:"content"`
-	Medias  IntSlice `json:"medias"`
-	Tags    IntSlice `json:"tags"`
+	Title   string    `json:"title"`
+	Content string    `json:"content"`
+	Medias  IntSlice  `json:"medias"`
+	Tags    IntSlice  `json:"tags"`
+	Poll    *jsonPoll `json:"poll"`
+}
+
+// jsonPoll is a custom type used to attach a poll to a new message
+// The closing date is in the RFC 3339 format, an empty one means that the poll never closes
+type jsonPoll struct {
+	Options           []string `json:"options"`
+	MultipleChoice    bool     `json:"multipleChoice"`
+	ResultsVisibility string   `json:"resultsVisibility"`
+	ClosingDate       string   `json:"closingDate"`
+}
+
+// jsonPollVote is a custom type used to handle ajax calls that vote in the poll of a message
+type jsonPollVote struct {
+	MessageID int   `json:"messageId"`
+	OptionIDs []int `json:"optionIds"`
 }
 
 type jsonUpdateMessage struct {
@@ -46,6 +67,19 @@ type jsonCommentDesignator struct {
 	MessageID int `json:"messageId,string"`
 }
 
+// jsonSaveMessage is a custom type used to handle ajax calls that save a message, the folder is optional
+type jsonSaveMessage struct {
+	MessageID int    `json:"messageId"`
+	Folder    string `json:"folder"`
+}
+
+// jsonSaveComment is a custom type used to handle ajax calls that save a comment, the folder is optional
+type jsonSaveComment struct {
+	CommentID int    `json:"commentId"`
+	MessageID int    `json:"messageId,string"`
+	Folder    string `json:"folder"`
+}
+
 // IntSlice is a custom type for handling string-to-int conversion
 type IntSlice []int
 
@@ -61,11 +95,27 @@ type jsonReportDesignator struct {
 	ReportID int `json:"reportId,string"`
 }
 
+type jsonReportAction struct {
+	ReportID int    `json:"reportId,string"`
+	Note     string `json:"note"`
+	Reason   string `json:"reason"`
+	Duration int    `json:"duration"`
+}
+
 // jsonUserDesignator is a custom type used to handle ajax calls that target a user
 type jsonUserDesignator struct {
 	Username string `json:"username"`
 }
 
+// jsonBan is a custom type used to handle ajax calls that ban a user
+// The duration is in hours, 0 means a permanent ban
+type jsonBan struct {
+	Username string `json:"username"`
+	Reason   string `json:"reason"`
+	Note     string `json:"note"`
+	Duration int    `json:"duration"`
+}
+
 // jsonThreadTagDesignator is a custom type used to handle ajax calls that target a tag
 type jsonThreadTagDesignator struct {
 	TagID int `json:"tagId"`
@@ -90,6 +140,97 @@ type jsonThreadTagUpdate struct {
 	TagColor string `json:"tagColor"`
 }
 
+// jsonWebhook is a custom type used to handle ajax calls that create a thread webhook
+type jsonWebhook struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+// jsonWebhookDesignator is a custom type used to handle ajax calls that target a thread webhook
+type jsonWebhookDesignator struct {
+	WebhookID int `json:"webhookId"`
+}
+
+// jsonAutoHideThreshold is a custom type used to handle ajax calls that set the auto hide threshold of a thread
+type jsonAutoHideThreshold struct {
+	Threshold int `json:"threshold"`
+}
+
+// jsonWordFilter is a custom type used to handle ajax calls that add a filtered word to a thread
+type jsonWordFilter struct {
+	Word   string `json:"word"`
+	Action string `json:"action"`
+}
+
+// jsonWordFilterDesignator is a custom type used to handle ajax calls that target a filtered word of a thread
+type jsonWordFilterDesignator struct {
+	FilterID int `json:"filterId"`
+}
+
+// jsonApprovalSettings is a custom type used to handle ajax calls that set the approval settings of a thread
+type jsonApprovalSettings struct {
+	MinPosts int `json:"minPosts"`
+	MinDays  int `json:"minDays"`
+}
+
+// jsonReputationSettings is a custom type used to handle ajax calls that set the reputation thresholds of a thread
+type jsonReputationSettings struct {
+	MinImages  int `json:"minImages"`
+	MinTags    int `json:"minTags"`
+	MinReports int `json:"minReports"`
+}
+
+// jsonThreadCategory is a custom type used to handle ajax calls that set the category of a thread
+type jsonThreadCategory struct {
+	Category string `json:"category"`
+}
+
+// jsonJoinThread is a custom type used to handle ajax calls that join a thread, the invite code is optional
+type jsonJoinThread struct {
+	InviteCode string `json:"inviteCode"`
+}
+
+// jsonAccessSettings is a custom type used to handle ajax calls that set who can see and join a thread
+type jsonAccessSettings struct {
+	JoinPolicy f.ThreadJoinPolicy `json:"joinPolicy"`
+	IsPrivate  bool               `json:"isPrivate"`
+}
+
+// jsonJoinRequestDesignator is a custom type used to handle ajax calls that target a join request of a thread
+type jsonJoinRequestDesignator struct {
+	RequestID int `json:"requestId"`
+}
+
+// jsonInvite is a custom type used to handle ajax calls that create an invite link of a thread
+// MaxUses and ExpiresInHours are 0 for an invite without limit
+type jsonInvite struct {
+	MaxUses        int `json:"maxUses"`
+	ExpiresInHours int `json:"expiresInHours"`
+}
+
+// jsonInviteDesignator is a custom type used to handle ajax calls that target an invite link of a thread
+type jsonInviteDesignator struct {
+	InviteID int `json:"inviteId"`
+}
+
+// jsonRole is a custom type used to handle ajax calls that create a custom role of a thread
+type jsonRole struct {
+	RoleName    string   `json:"roleName"`
+	Permissions []string `json:"permissions"`
+}
+
+// jsonRoleDesignator is a custom type used to handle ajax calls that target a custom role of a thread
+type jsonRoleDesignator struct {
+	RoleID int `json:"roleId"`
+}
+
+// jsonMemberRole is a custom type used to handle ajax calls that give a custom role to a member of a thread
+// A RoleID of 0 removes the role of the member
+type jsonMemberRole struct {
+	Username string `json:"username"`
+	RoleID   int    `json:"roleId"`
+}
+
 // ThreadContentHandler handles the thread message requests from ajax calls
 // Its path is /api/thread/{thread}/{action}?id={id}
 // The "thread" is the name of the thread
@@ -127,17 +268,60 @@ func ThreadContentHandler(w http.ResponseWriter, r *http.Request) {
 		action == "leaveThread" ||
 		action == "sendComment" ||
 		action == "deleteComment" ||
+		action == "restoreMessage" ||
+		action == "restoreComment" ||
 		action == "editComment" ||
 		action == "reportComment" ||
 		action == "upvoteComment" ||
 		action == "downvoteComment" ||
 		action == "banUser" ||
+		action == "unbanUser" ||
+		action == "listBans" ||
 		action == "setReportToResolved" ||
+		action == "claimReport" ||
+		action == "dismissReport" ||
+		action == "deleteReportedContent" ||
+		action == "banReportedAuthor" ||
 		action == "createThreadTag" ||
 		action == "editThreadTag" ||
 		action == "deleteThreadTag" ||
 		action == "promoteUser" ||
-		action == "demoteUser") {
+		action == "demoteUser" ||
+		action == "createWebhook" ||
+		action == "deleteWebhook" ||
+		action == "setAutoHideThreshold" ||
+		action == "addWordFilter" ||
+		action == "removeWordFilter" ||
+		action == "setApprovalSettings" ||
+		action == "setReputationSettings" ||
+		action == "setThreadCategory" ||
+		action == "setAccessSettings" ||
+		action == "approveJoinRequest" ||
+		action == "denyJoinRequest" ||
+		action == "createInvite" ||
+		action == "deleteInvite" ||
+		action == "createRole" ||
+		action == "deleteRole" ||
+		action == "setMemberRole" ||
+		action == "approveMessage" ||
+		action == "rejectMessage" ||
+		action == "votePoll" ||
+		action == "pinMessage" ||
+		action == "unpinMessage" ||
+		action == "lockMessage" ||
+		action == "unlockMessage" ||
+		action == "subscribeThread" ||
+		action == "unsubscribeThread" ||
+		action == "muteThread" ||
+		action == "unmuteThread" ||
+		action == "subscribeMessage" ||
+		action == "unsubscribeMessage" ||
+		action == "muteMessage" ||
+		action == "unmuteMessage" ||
+		action == "saveMessage" ||
+		action == "unsaveMessage" ||
+		action == "saveComment" ||
+		action == "unsaveComment") {
 
 		f.DebugPrintf("Action \"%s\" does not exist\n", action)
 		http.Error(w, "Action is empty or does not exist !", http.StatusNotFound)
@@ -204,6 +388,12 @@ func ThreadContentHandler(w http.ResponseWriter, r *http.Request) {
 	case "deleteComment":
 		deleteComment(w, r, thread, user)
 		return
+	case "restoreMessage":
+		restoreMessage(w, r, thread, user)
+		return
+	case "restoreComment":
+		restoreComment(w, r, thread, user)
+		return
 	case "editComment":
 		editComment(w, r, thread, user)
 		return
@@ -219,9 +409,27 @@ func ThreadContentHandler(w http.ResponseWriter, r *http.Request) {
 	case "banUser":
 		banUser(w, r, thread, user)
 		return
+	case "unbanUser":
+		unbanUser(w, r, thread, user)
+		return
+	case "listBans":
+		listBans(w, r, thread, user)
+		return
 	case "setReportToResolved":
 		setReportToResolved(w, r, thread, user)
 		return
+	case "claimReport":
+		claimReport(w, r, thread, user)
+		return
+	case "dismissReport":
+		dismissReport(w, r, thread, user)
+		return
+	case "deleteReportedContent":
+		deleteReportedContent(w, r, thread, user)
+		return
+	case "banReportedAuthor":
+		banReportedAuthor(w, r, thread, user)
+		return
 	case "createThreadTag":
 		createThreadTag(w, r, thread, user)
 		return
@@ -237,6 +445,111 @@ func ThreadContentHandler(w http.ResponseWriter, r *http.Request) {
 	case "demoteUser":
 		demoteUser(w, r, thread, user)
 		return
+	case "createWebhook":
+		createWebhook(w, r, thread, user)
+		return
+	case "deleteWebhook":
+		deleteWebhook(w, r, thread, user)
+		return
+	case "setAutoHideThreshold":
+		setAutoHideThreshold(w, r, thread, user)
+		return
+	case "addWordFilter":
+		addWordFilter(w, r, thread, user)
+		return
+	case "removeWordFilter":
+		removeWordFilter(w, r, thread, user)
+		return
+	case "setApprovalSettings":
+		setApprovalSettings(w, r, thread, user)
+		return
+	case "setReputationSettings":
+		setReputationSettings(w, r, thread, user)
+		return
+	case "setThreadCategory":
+		setThreadCategory(w, r, thread, user)
+		return
+	case "setAccessSettings":
+		setAccessSettings(w, r, thread, user)
+		return
+	case "approveJoinRequest":
+		reviewJoinRequest(w, r, thread, user, true)
+		return
+	case "denyJoinRequest":
+		reviewJoinRequest(w, r, thread, user, false)
+		return
+	case "createInvite":
+		createInvite(w, r, thread, user)
+		return
+	case "deleteInvite":
+		deleteInvite(w, r, thread, user)
+		return
+	case "createRole":
+		createRole(w, r, thread, user)
+		return
+	case "deleteRole":
+		deleteRole(w, r, thread, user)
+		return
+	case "setMemberRole":
+		setMemberRole(w, r, thread, user)
+		return
+	case "approveMessage":
+		reviewPendingMessage(w, r, thread, user, true)
+		return
+	case "rejectMessage":
+		reviewPendingMessage(w, r, thread, user, false)
+		return
+	case "votePoll":
+		votePoll(w, r, thread, user)
+		return
+	case "pinMessage":
+		pinOrLockMessage(w, r, thread, user, f.ModerationMessagePinned)
+		return
+	case "unpinMessage":
+		pinOrLockMessage(w, r, thread, user, f.ModerationMessageUnpinned)
+		return
+	case "lockMessage":
+		pinOrLockMessage(w, r, thread, user, f.ModerationMessageLocked)
+		return
+	case "unlockMessage":
+		pinOrLockMessage(w, r, thread, user, f.ModerationMessageUnlocked)
+		return
+	case "subscribeThread":
+		subscribeThread(w, r, thread, user)
+		return
+	case "unsubscribeThread":
+		unsubscribeThread(w, r, thread, user)
+		return
+	case "muteThread":
+		muteThread(w, r, thread, user, true)
+		return
+	case "unmuteThread":
+		muteThread(w, r, thread, user, false)
+		return
+	case "subscribeMessage":
+		subscribeMessage(w, r, thread, user)
+		return
+	case "unsubscribeMessage":
+		unsubscribeMessage(w, r, thread, user)
+		return
+	case "muteMessage":
+		muteMessage(w, r, thread, user, true)
+		return
+	case "unmuteMessage":
+		muteMessage(w, r, thread, user, false)
+		return
+	case "saveMessage":
+		saveMessage(w, r, thread, user)
+		return
+	case "unsaveMessage":
+		unsaveMessage(w, r, thread, user)
+		return
+	case "saveComment":
+		saveComment(w, r, thread, user)
+		return
+	case "unsaveComment":
+		unsaveComment(w, r, thread, user)
+		return
 	default:
 		f.DebugPrintf("Action \"%s\" does not exist\n", action)
 		http.Error(w, "Action does not exist !", http.StatusNotFound)
@@ -315,22 +628,69 @@ func sendMessage(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum,
 			}
 		}
 		f.DebugPrintf("All media IDs are valid\n")
+
+		// Check if the reputation of the user allows him to post images
+		if !f.HasReputationFor(thread, user, f.ReputationPostImages) {
+			f.DebugPrintf("User does not have enough reputation to post images in this thread\n")
+			http.Error(w, "User does not have enough reputation to post images in this thread", http.StatusForbidden)
+			return
+		}
 	} else {
 		f.DebugPrintf("No media IDs provided\n")
 	}
 
-	// Send the message
-	messageID, err := f.AddMessageInThread(thread, msg.Title, msg.Content, user, msg.Medias, msg.Tags)
+	// Check if the poll is valid
+	var poll *f.NewPoll
+	if msg.Poll != nil {
+		poll = &f.NewPoll{
+			Options:           msg.Poll.Options,
+			IsMultipleChoice:  msg.Poll.MultipleChoice,
+			ResultsVisibility: f.PollResultsVisibility(msg.Poll.ResultsVisibility),
+		}
+		if poll.ResultsVisibility == "" {
+			poll.ResultsVisibility = f.PollResultsAlways
+		}
+		if msg.Poll.ClosingDate != "" {
+			closingDate, err := time.Parse(time.RFC3339, msg.Poll.ClosingDate)
+			if err != nil {
+				f.DebugPrintf("Poll closing date is not valid: %v\n", err)
+				http.Error(w, "Poll closing date is not valid", http.StatusBadRequest)
+				return
+			}
+			poll.ClosingDate = &closingDate
+		}
+		if reason := f.IsNewPollValid(*poll); reason != "" {
+			f.DebugPrintf("Poll is not valid: %s\n", reason)
+			http.Error(w, "Poll is not valid: "+reason, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Run the word filters and the spam heuristics
+	verdict := f.FilterContent(thread, user, msg.Title, msg.Content, true)
+	if verdict.IsBlocked {
+		f.DebugPrintf("Message of %s blocked by the content filters: %s\n", user.Username, verdict.BlockReason)
+		http.Error(w, "Message was blocked by the content filters: "+verdict.BlockReason, http.StatusBadRequest)
+		return
+	}
+
+	// Send the message with its poll
+	messageID, err := f.AddMessageInThread(thread, verdict.Title, verdict.Content, user, msg.Medias, msg.Tags, poll)
 	if err != nil {
 		f.ErrorPrintf("Error while sending the message: %v\n", err)
 		http.Error(w, "Error while sending the message", http.StatusInternalServerError)
 		return
 	}
 	f.DebugPrintf("Message sent with MessageID: %d\n", messageID)
+	f.ReportFlaggedMessage(thread, messageID, verdict)
+	isPending := false
+	if message, err := f.GetMessageByID(messageID); err == nil {
+		isPending = message.ApprovalState == f.MessagePending
+	}
 
 	// Return the response with the message MessageID
 	w.WriteHeader(http.StatusOK)
-	_, err = w.Write([]byte(`{"status":"success", "messageId":` + strconv.Itoa(messageID) + `}`))
+	_, err = w.Write([]byte(`{"status":"success", "messageId":` + strconv.Itoa(messageID) + `, "isPending":` + strconv.FormatBool(isPending) + `}`))
 	if err != nil {
 		f.ErrorPrintf("Error while writing the response: %v\n", err)
 		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
@@ -353,8 +713,17 @@ func deleteMessage(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForu
 		return
 	}
 
+	// Keep the author and the title of the message for the moderation log
+	authorID, _ := f.GetMessageAuthorID(msgID)
+	message, _ := f.GetMessageByID(msgID)
+	if message.DeletionState != f.NotDeleted {
+		f.DebugPrintf("Message %d was already deleted\n", msgID)
+		http.Error(w, "Message was already deleted", http.StatusBadRequest)
+		return
+	}
+
 	// Delete the message
-	err := f.RemoveMessageFromThread(thread, msgID)
+	err := f.RemoveMessageFromThread(thread, msgID, user)
 	if err != nil {
 		f.ErrorPrintf("Error while deleting the message: %v\n", err)
 		http.Error(w, "Error while deleting the message", http.StatusInternalServerError)
@@ -362,6 +731,9 @@ func deleteMessage(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForu
 	}
 
 	f.DebugPrintf("Message with MessageID \"%d\" deleted by %s\n", msgID, user.Username)
+	if authorID != user.UserID {
+		f.LogModerationAction(thread, user, f.ModerationMessageDeleted, authorID, msgID, message.MessageTitle)
+	}
 
 	// Return the response
 	// Return the message MessageID
@@ -529,14 +901,23 @@ func editMessage(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum,
 	}
 	f.DebugPrintf("new msg data: %v", msg)
 
+	// Run the word filters and the spam heuristics
+	verdict := f.FilterContent(thread, user, msg.Title, msg.Content, false)
+	if verdict.IsBlocked {
+		f.DebugPrintf("Edit of the message %d blocked by the content filters: %s\n", msg.ID, verdict.BlockReason)
+		http.Error(w, "Message was blocked by the content filters: "+verdict.BlockReason, http.StatusBadRequest)
+		return
+	}
+
 	// Send the message
-	err = f.EditMessageFromThread(thread, msg.ID, msg.Title, msg.Content)
+	err = f.EditMessageFromThread(thread, msg.ID, verdict.Title, verdict.Content)
 	if err != nil {
 		f.ErrorPrintf("Error while sending the message: %v\n", err)
 		http.Error(w, "Error while sending the message", http.StatusInternalServerError)
 		return
 	}
 	f.DebugPrintf("Message with MessageID \"%d\" was edited by %s\n", msg.ID, user.Username)
+	f.ReportFlaggedMessage(thread, msg.ID, verdict)
 	// Return the response with the message MessageID
 	w.WriteHeader(http.StatusOK)
 	_, err = w.Write([]byte(`{"status":"success"}`))
@@ -557,6 +938,13 @@ func reportMessage(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForu
 		return
 	}
 
+	// Check if the reputation of the user allows him to report content
+	if !f.HasReputationFor(thread, user, f.ReputationReport) {
+		f.DebugPrintf("User does not have enough reputation to report content in this thread\n")
+		http.Error(w, "User does not have enough reputation to report content in this thread", http.StatusForbidden)
+		return
+	}
+
 	if r.Method != "POST" {
 		f.DebugPrintf("Method is not POST\n")
 		http.Error(w, "Method is not POST", http.StatusMethodNotAllowed)
@@ -644,6 +1032,102 @@ func reportMessage(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForu
 	}
 }
 
+// votePoll handles the vote poll action
+// This action is used to vote in the poll of a message, each user can only vote once
+// Take a jsonPollVote as input and returns the poll with its results
+func votePoll(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
+	if r.Method != "POST" {
+		f.DebugPrintf("Method is not POST\n")
+		http.Error(w, "Method is not POST", http.StatusMethodNotAllowed)
+		return
+	}
+	if !_checkThreadContentVisibility(w, thread, user) {
+		return
+	}
+
+	// Getting the form values
+	var vote jsonPollVote
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&vote); err != nil {
+		f.ErrorPrintf("Error while decoding the JSON: %v\n", err)
+		http.Error(w, "Error while decoding the JSON", http.StatusBadRequest)
+		return
+	}
+
+	// Check if the message is visible to the user and can still be voted
+	if !f.MessageExistsInThread(thread, vote.MessageID) {
+		f.DebugPrintf("Message %d does not exist in the thread\n", vote.MessageID)
+		http.Error(w, "Message MessageID is not valid", http.StatusBadRequest)
+		return
+	}
+	message, err := f.GetMessageByIDWithPOV(vote.MessageID, user)
+	if err != nil || !f.CanUserSeeMessage(thread, user, message) || message.DeletionState != f.NotDeleted {
+		f.DebugPrintf("Message %d is not visible to the user\n", vote.MessageID)
+		http.Error(w, "Message MessageID is not valid", http.StatusBadRequest)
+		return
+	}
+	if !_checkMessageNotLocked(w, vote.MessageID) {
+		return
+	}
+	if message.Poll == nil {
+		f.DebugPrintf("Message %d has no poll\n", vote.MessageID)
+		http.Error(w, "Message has no poll", http.StatusNotFound)
+		return
+	}
+	if message.Poll.IsClosed {
+		f.DebugPrintf("Poll of the message %d is closed\n", vote.MessageID)
+		http.Error(w, "Poll is closed", http.StatusForbidden)
+		return
+	}
+	if message.Poll.HasVoted {
+		f.DebugPrintf("User %s already voted in the poll of the message %d\n", user.Username, vote.MessageID)
+		http.Error(w, "User already voted in this poll", http.StatusConflict)
+		return
+	}
+
+	// Check if the chosen options are valid
+	if len(vote.OptionIDs) == 0 || (!message.Poll.IsMultipleChoice && len(vote.OptionIDs) > 1) {
+		f.DebugPrintf("Wrong number of options chosen in the poll\n")
+		http.Error(w, "Wrong number of options chosen", http.StatusBadRequest)
+		return
+	}
+	var chosen []int
+	for _, optionID := range vote.OptionIDs {
+		isPollOption := false
+		for _, option := range message.Poll.Options {
+			isPollOption = isPollOption || option.OptionID == optionID
+		}
+		if !isPollOption || slices.Contains(chosen, optionID) {
+			f.DebugPrintf("Option %d is not valid\n", optionID)
+			http.Error(w, "Option is not valid", http.StatusBadRequest)
+			return
+		}
+		chosen = append(chosen, optionID)
+	}
+
+	err = f.VoteInPoll(message.Poll.PollID, user, chosen)
+	if err != nil {
+		f.ErrorPrintf("Error while voting in the poll: %v\n", err)
+		http.Error(w, "Error while voting in the poll", http.StatusInternalServerError)
+		return
+	}
+	f.DebugPrintf("User %s voted in the poll of the message %d\n", user.Username, vote.MessageID)
+
+	// Return the poll with its results
+	poll, err := f.GetMessagePollWithPOV(vote.MessageID, user)
+	if err != nil {
+		http.Error(w, "Error while getting the poll", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(poll)
+	if err != nil {
+		f.ErrorPrintf("Error encoding the poll to JSON: %s\n", err)
+		http.Error(w, "Error encoding the poll to JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
 // upVoteMessage handles the upvote message action
 // This action is used to upvote a message
 func upVoteMessage(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
@@ -651,6 +1135,9 @@ func upVoteMessage(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForu
 	if id < 0 {
 		return
 	}
+	if !_checkMessageNotLocked(w, id) || !_checkMessageNotDeleted(w, id) {
+		return
+	}
 
 	// Check if the user has already up/downvoted the message
 	vote := f.HasUserAlreadyVotedOnMessage(user, id)
@@ -702,6 +1189,9 @@ func downVoteMessage(w http.ResponseWriter, r *http.Request, thread f.ThreadGoFo
 	if id < 0 {
 		return
 	}
+	if !_checkMessageNotLocked(w, id) || !_checkMessageNotDeleted(w, id) {
+		return
+	}
 
 	// Check if the user has already up/downvoted the message
 	vote := f.HasUserAlreadyVotedOnMessage(user, id)
@@ -747,7 +1237,9 @@ func downVoteMessage(w http.ResponseWriter, r *http.Request, thread f.ThreadGoFo
 }
 
 // joinThread handles the join thread action
-// This action is used to join a thread
+// This action is used to join a thread, following its join policy
+// Take an optional jsonJoinThread as input, a valid invite code makes the user join the thread whatever its policy
+// The status of the response is "requested" when a join request was sent instead
 func joinThread(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
 	if r.Method != "POST" {
 		f.DebugPrintf("Method is not POST\n")
@@ -760,15 +1252,41 @@ func joinThread(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum,
 		return
 	}
 
-	err := f.JoinThread(thread, user)
-	if err != nil {
+	// The body is optional, the join button of the thread page does not send any
+	var join jsonJoinThread
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&join); err != nil && !errors.Is(err, io.EOF) {
+		f.ErrorPrintf("Error while decoding the JSON: %v\n", err)
+		http.Error(w, "Error while decoding the JSON", http.StatusBadRequest)
+		return
+	}
+
+	joined, err := f.JoinThreadWithPolicy(thread, user, join.InviteCode)
+	switch {
+	case errors.Is(err, f.ErrInviteOnlyThread):
+		f.DebugPrintf("User tried to join an invite only thread without an invite\n")
+		http.Error(w, "This thread can only be joined with an invite", http.StatusForbidden)
+		return
+	case errors.Is(err, f.ErrInvalidInvite):
+		f.DebugPrintf("User tried to join the thread with an invalid invite\n")
+		http.Error(w, "The invite is not valid anymore", http.StatusBadRequest)
+		return
+	case errors.Is(err, f.ErrJoinAlreadyRequested):
+		f.DebugPrintf("User already requested to join the thread\n")
+		http.Error(w, "User already requested to join the thread", http.StatusBadRequest)
+		return
+	case err != nil:
 		f.ErrorPrintf("Error while joining the thread: %v\n", err)
 		http.Error(w, "Error while joining the thread", http.StatusInternalServerError)
 		return
 	}
+	status := "success"
+	if !joined {
+		status = "requested"
+	}
 	// Return the response
 	w.WriteHeader(http.StatusOK)
-	_, err = w.Write([]byte(`{"status":"success"}`))
+	_, err = w.Write([]byte(fmt.Sprintf(`{"status":"%s"}`, status)))
 	if err != nil {
 		f.ErrorPrintf("Error while writing the response: %v\n", err)
 		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
@@ -873,8 +1391,34 @@ func sendComment(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum,
 		return
 	}
 
+	// Check if the message is visible to the user (not hidden nor waiting for approval)
+	message, err := f.GetMessageByIDWithPOV(comment.MessageID, user)
+	if err != nil || !f.CanUserSeeMessage(thread, user, message) {
+		f.DebugPrintf("Message %d is not visible to the user\n", comment.MessageID)
+		http.Error(w, "Content MessageID is not valid", http.StatusBadRequest)
+		return
+	}
+
+	// Deleted messages cannot be commented
+	if message.DeletionState != f.NotDeleted {
+		f.DebugPrintf("Message %d was deleted\n", comment.MessageID)
+		http.Error(w, "Content MessageID is not valid", http.StatusBadRequest)
+		return
+	}
+	if !_checkMessageNotLocked(w, comment.MessageID) {
+		return
+	}
+
+	// Run the word filters and the spam heuristics
+	verdict := f.FilterContent(thread, user, "", comment.Content, true)
+	if verdict.IsBlocked {
+		f.DebugPrintf("Comment of %s blocked by the content filters: %s\n", user.Username, verdict.BlockReason)
+		http.Error(w, "Comment was blocked by the content filters: "+verdict.BlockReason, http.StatusBadRequest)
+		return
+	}
+
 	// Send the comment
-	commentID, err := f.AddCommentToPost(user, comment.MessageID, comment.Content)
+	commentID, err := f.AddCommentToPost(user, comment.MessageID, verdict.Content)
 	if err != nil {
 		f.ErrorPrintf("Error while sending the comment: %v\n", err)
 		http.Error(w, "Error while sending the comment", http.StatusInternalServerError)
@@ -882,6 +1426,7 @@ func sendComment(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum,
 	}
 
 	f.DebugPrintf("Content sent with CommentID '%d' on message '%d'\n", commentID, comment.MessageID)
+	f.ReportFlaggedComment(thread, commentID, comment.MessageID, verdict)
 	// Return the response with the comment CommentID
 	w.WriteHeader(http.StatusOK)
 	_, err = w.Write([]byte(`{"status":"success", "commentId":` + strconv.Itoa(commentID) + `}`))
@@ -954,8 +1499,16 @@ func editComment(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum,
 		return
 	}
 
+	// Run the word filters and the spam heuristics
+	verdict := f.FilterContent(thread, user, "", comment.Content, false)
+	if verdict.IsBlocked {
+		f.DebugPrintf("Edit of the comment %d blocked by the content filters: %s\n", comment.CommentID, verdict.BlockReason)
+		http.Error(w, "Comment was blocked by the content filters: "+verdict.BlockReason, http.StatusBadRequest)
+		return
+	}
+
 	// Update the comment
-	err = f.EditCommentFromPost(comment.CommentID, comment.Content)
+	err = f.EditCommentFromPost(comment.CommentID, verdict.Content)
 	if err != nil {
 		f.ErrorPrintf("Error while updating the comment: %v\n", err)
 		http.Error(w, "Error while updating the comment", http.StatusInternalServerError)
@@ -963,6 +1516,7 @@ func editComment(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum,
 	}
 
 	f.DebugPrintf("Content with CommentID \"%d\" was edited by %s\n", comment.CommentID, user.Username)
+	f.ReportFlaggedComment(thread, comment.CommentID, comment.MessageID, verdict)
 
 	// Return the response
 	w.WriteHeader(http.StatusOK)
@@ -988,13 +1542,105 @@ func deleteComment(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForu
 		return
 	}
 
+	// Keep the author and the content of the comment for the moderation log
+	authorID, _ := f.GetCommentAuthorID(commentID)
+	comment, _ := f.GetCommentByIDWithPOV(commentID, f.User{})
+	if comment.DeletionState != f.NotDeleted {
+		f.DebugPrintf("Comment %d was already deleted\n", commentID)
+		http.Error(w, "Comment was already deleted", http.StatusBadRequest)
+		return
+	}
+
 	// Delete the comment
-	err := f.RemoveCommentFromPost(commentID)
+	err := f.RemoveCommentFromPost(commentID, user)
 	if err != nil {
 		f.ErrorPrintf("Error while deleting the comment: %v\n", err)
 		http.Error(w, "Error while deleting the comment", http.StatusInternalServerError)
 		return
 	}
+
+	f.DebugPrintf("Comment %d deleted by %s\n", commentID, user.Username)
+	if authorID != user.UserID {
+		f.LogModerationAction(thread, user, f.ModerationCommentDeleted, authorID, commentID, comment.CommentContent)
+	}
+}
+
+// restoreMessage handles the restore message action
+// The author can restore the messages he deleted and the moderation team every deleted message, until the end of the restore window
+func restoreMessage(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
+	msgID := _checkMessageApiCallValidity(w, r, thread)
+	if msgID < 0 {
+		return
+	}
+
+	// Check if the user is allowed to restore the message
+	if !f.CanUserRestoreMessage(thread, user, msgID) {
+		f.DebugPrintf("User is not allowed to restore this message\n")
+		http.Error(w, "User is not allowed to restore this message", http.StatusForbidden)
+		return
+	}
+
+	err := f.RestoreMessage(thread, msgID)
+	if err != nil {
+		f.ErrorPrintf("Error while restoring the message: %v\n", err)
+		http.Error(w, "Error while restoring the message", http.StatusInternalServerError)
+		return
+	}
+
+	f.DebugPrintf("Message %d restored by %s\n", msgID, user.Username)
+	authorID, _ := f.GetMessageAuthorID(msgID)
+	if authorID != user.UserID {
+		message, _ := f.GetMessageByID(msgID)
+		f.LogModerationAction(thread, user, f.ModerationMessageRestored, authorID, msgID, message.MessageTitle)
+	}
+
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(`{"status":"success"}`))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// restoreComment handles the restore comment action
+// The author can restore the comments he deleted and the moderation team every deleted comment, until the end of the restore window
+func restoreComment(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
+	commentID := _checkCommentApiCallValidity(w, r, thread)
+	if commentID <= 0 {
+		return
+	}
+
+	// Check if the user is allowed to restore the comment
+	if !f.CanUserRestoreComment(thread, user, commentID) {
+		f.DebugPrintf("User is not allowed to restore this comment\n")
+		http.Error(w, "User is not allowed to restore this comment", http.StatusForbidden)
+		return
+	}
+
+	err := f.RestoreComment(commentID)
+	if err != nil {
+		f.ErrorPrintf("Error while restoring the comment: %v\n", err)
+		http.Error(w, "Error while restoring the comment", http.StatusInternalServerError)
+		return
+	}
+
+	f.DebugPrintf("Comment %d restored by %s\n", commentID, user.Username)
+	authorID, _ := f.GetCommentAuthorID(commentID)
+	if authorID != user.UserID {
+		comment, _ := f.GetCommentByIDWithPOV(commentID, f.User{})
+		f.LogModerationAction(thread, user, f.ModerationCommentRestored, authorID, commentID, comment.CommentContent)
+	}
+
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(`{"status":"success"}`))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
 }
 
 // reportComment handles the report comment action
@@ -1007,6 +1653,13 @@ func reportComment(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForu
 		return
 	}
 
+	// Check if the reputation of the user allows him to report content
+	if !f.HasReputationFor(thread, user, f.ReputationReport) {
+		f.DebugPrintf("User does not have enough reputation to report content in this thread\n")
+		http.Error(w, "User does not have enough reputation to report content in this thread", http.StatusForbidden)
+		return
+	}
+
 	if r.Method != "POST" {
 		f.DebugPrintf("Method is not POST\n")
 		http.Error(w, "Method is not POST", http.StatusMethodNotAllowed)
@@ -1101,7 +1754,11 @@ func upvoteComment(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForu
 	if id <= 0 {
 		return
 	}
-
+	messageID := f.GetMessageIDFromCommentID(id)
+	if !_checkMessageNotLocked(w, messageID) || !_checkMessageNotDeleted(w, messageID) || !_checkCommentNotDeleted(w, id) {
+		return
+	}
+
 	// Check if the user has already up/downvoted the comment
 	vote := f.HasUserAlreadyVotedOnComment(user, id)
 
@@ -1152,6 +1809,10 @@ func downvoteComment(w http.ResponseWriter, r *http.Request, thread f.ThreadGoFo
 	if id <= 0 {
 		return
 	}
+	messageID := f.GetMessageIDFromCommentID(id)
+	if !_checkMessageNotLocked(w, messageID) || !_checkMessageNotDeleted(w, messageID) || !_checkCommentNotDeleted(w, id) {
+		return
+	}
 
 	// Check if the user has already up/downvoted the comment
 	vote := f.HasUserAlreadyVotedOnComment(user, id)
@@ -1220,7 +1881,7 @@ func banUser(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, use
 	}
 
 	// Getting the form values
-	var msg jsonUserDesignator
+	var msg jsonBan
 	decoder := json.NewDecoder(r.Body)
 	if err := decoder.Decode(&msg); err != nil {
 		f.ErrorPrintf("Error while decoding the JSON: %v\n", err)
@@ -1248,6 +1909,30 @@ func banUser(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, use
 		return
 	}
 
+	// Check the reason, the note and the duration of the ban
+	if !f.IsBanReasonValid(msg.Reason) {
+		f.DebugPrintf("Ban reason is not valid\n")
+		http.Error(w, "Ban reason is not valid", http.StatusBadRequest)
+		return
+	}
+	if len(msg.Note) > 500 {
+		f.DebugPrintf("Ban note is too long\n")
+		http.Error(w, "Ban note is too long", http.StatusBadRequest)
+		return
+	}
+	if msg.Duration < 0 {
+		f.DebugPrintf("Ban duration is not valid\n")
+		http.Error(w, "Ban duration is not valid", http.StatusBadRequest)
+		return
+	}
+
+	// A user can't ban a member of the moderation team with the same or a higher rank
+	if f.GetUserRankInThread(thread, userToBan) >= f.GetUserRankInThread(thread, user) {
+		f.DebugPrintf("User is not allowed to ban a user with the same or a higher rank\n")
+		http.Error(w, "User is not allowed to ban a user with the same or a higher rank", http.StatusForbidden)
+		return
+	}
+
 	// Check if the user is already banned from the thread
 	if f.IsUserBannedFromThread(thread, userToBan) {
 		f.DebugPrintf("User is already banned from the thread\n")
@@ -1256,14 +1941,88 @@ func banUser(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, use
 	}
 
 	// Ban the user
-	err = f.BanUserFromThread(thread, userToBan)
+	err = f.BanUserFromThread(thread, userToBan, user, msg.Reason, msg.Note, msg.Duration)
 	if err != nil {
 		f.ErrorPrintf("Error while banning the user: %v\n", err)
 		http.Error(w, "Error while banning the user", http.StatusInternalServerError)
 		return
 	}
 
-	f.DebugPrintf("User %s was banned from thread %s by %s\n", userToBan.Username, thread.ThreadID, user.Username)
+	f.DebugPrintf("User %s was banned from thread %s by %s\n", userToBan.Username, thread.ThreadName, user.Username)
+	banDetails := msg.Reason + " (permanent)"
+	if msg.Duration > 0 {
+		banDetails = fmt.Sprintf("%s (%d hours)", msg.Reason, msg.Duration)
+	}
+	f.LogModerationAction(thread, user, f.ModerationUserBanned, userToBan.UserID, 0, banDetails)
+
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(`{"status":"success"}`))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// unbanUser handles the unban user action
+// Only the users allowed to ban can lift a ban
+// Take a jsonUserDesignator as input
+func unbanUser(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
+	// Check if the user is allowed to unban a user
+	if !f.IsUserAllowedToBanUserInThread(thread, user) { // Same check as banUser
+		f.DebugPrintf("User is not allowed to unban a user in this thread\n")
+		http.Error(w, "User is not allowed to unban a user in this thread", http.StatusForbidden)
+		return
+	}
+	if r.Method != "POST" {
+		f.DebugPrintf("Method is not POST\n")
+		http.Error(w, "Method is not POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Parse the form
+	err := r.ParseForm()
+	if err != nil {
+		f.ErrorPrintf("Error while parsing the form: %v\n", err)
+		http.Error(w, "Error while parsing the form", http.StatusBadRequest)
+		return
+	}
+
+	// Getting the form values
+	var msg jsonUserDesignator
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&msg); err != nil {
+		f.ErrorPrintf("Error while decoding the JSON: %v\n", err)
+		http.Error(w, "Error while decoding the JSON", http.StatusBadRequest)
+		return
+	}
+
+	// Check if the user username is valid
+	userToUnban, err := f.GetUserFromUsername(msg.Username)
+	if err != nil || (userToUnban == f.User{}) {
+		f.DebugPrintf("Username is not valid\n")
+		http.Error(w, "Username is not valid", http.StatusBadRequest)
+		return
+	}
+
+	// Check if the user is banned from the thread
+	if f.GetUserRankInThread(thread, userToUnban) != f.ThreadRankBanned {
+		f.DebugPrintf("User is not banned from the thread\n")
+		http.Error(w, "User is not banned from the thread", http.StatusBadRequest)
+		return
+	}
+
+	// Unban the user
+	err = f.UnbanUserFromThread(thread, userToUnban, user)
+	if err != nil {
+		f.ErrorPrintf("Error while unbanning the user: %v\n", err)
+		http.Error(w, "Error while unbanning the user", http.StatusInternalServerError)
+		return
+	}
+
+	f.DebugPrintf("User %s was unbanned from thread %s by %s\n", userToUnban.Username, thread.ThreadName, user.Username)
+	f.LogModerationAction(thread, user, f.ModerationUserUnbanned, userToUnban.UserID, 0, "")
 
 	// Return the response
 	w.WriteHeader(http.StatusOK)
@@ -1275,9 +2034,44 @@ func banUser(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, use
 	}
 }
 
+// listBans handles the list bans action
+// Only the users allowed to ban can see the bans (with their internal note)
+// Returns the current bans of the thread as a JSON array
+func listBans(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
+	// Check if the user is allowed to see the bans
+	if !f.IsUserAllowedToBanUserInThread(thread, user) { // Same check as banUser
+		f.DebugPrintf("User is not allowed to see the bans of this thread\n")
+		http.Error(w, "User is not allowed to see the bans of this thread", http.StatusForbidden)
+		return
+	}
+	if r.Method != "POST" {
+		f.DebugPrintf("Method is not POST\n")
+		http.Error(w, "Method is not POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	bans, err := f.GetThreadBans(thread)
+	if err != nil {
+		f.ErrorPrintf("Error while getting the bans: %v\n", err)
+		http.Error(w, "Error while getting the bans", http.StatusInternalServerError)
+		return
+	}
+	if bans == nil {
+		bans = []f.ThreadBan{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(bans)
+	if err != nil {
+		f.ErrorPrintf("Error encoding the bans to JSON: %v\n", err)
+		http.Error(w, "Error encoding the bans to JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
 func setReportToResolved(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
 	// Check if the user is allowed to set the report to resolved
-	if !f.IsUserAllowedToBanUserInThread(thread, user) { // Same check as banUser
+	if !f.HasThreadPermission(thread, user, f.PermissionManageReports) {
 		f.DebugPrintf("User is not allowed to set the report to resolved in this thread\n")
 		http.Error(w, "User is not allowed to set the report to resolved in this thread", http.StatusForbidden)
 		return
@@ -1328,6 +2122,122 @@ func setReportToResolved(w http.ResponseWriter, r *http.Request, thread f.Thread
 	}
 
 	f.DebugPrintf("Report %d was set to resolved by %s\n", report.ReportID, user.Username)
+	f.LogModerationAction(thread, user, f.ModerationReportResolved, 0, report.ReportID, "")
+
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(`{"status":"success"}`))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// _checkReportApiCallValidity checks if the report API call is valid
+// It checks if the user is allowed to manage the reports, if the method is POST, if the JSON is valid,
+// if the report exists in the thread and is still active and if the resolution note is valid
+// It returns the report and the decoded JSON, and false if the call is not valid
+func _checkReportApiCallValidity(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) (f.ReportedContent, jsonReportAction, bool) {
+	if !f.HasThreadPermission(thread, user, f.PermissionManageReports) {
+		f.DebugPrintf("User is not allowed to handle the reports of this thread\n")
+		http.Error(w, "User is not allowed to handle the reports of this thread", http.StatusForbidden)
+		return f.ReportedContent{}, jsonReportAction{}, false
+	}
+	if r.Method != "POST" {
+		f.DebugPrintf("Method is not POST\n")
+		http.Error(w, "Method is not POST", http.StatusMethodNotAllowed)
+		return f.ReportedContent{}, jsonReportAction{}, false
+	}
+
+	// Parse the form
+	err := r.ParseForm()
+	if err != nil {
+		f.ErrorPrintf("Error while parsing the form: %v\n", err)
+		http.Error(w, "Error while parsing the form", http.StatusBadRequest)
+		return f.ReportedContent{}, jsonReportAction{}, false
+	}
+
+	// Getting the form values
+	var msg jsonReportAction
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&msg); err != nil {
+		f.ErrorPrintf("Error while decoding the JSON: %v\n", err)
+		http.Error(w, "Error while decoding the JSON", http.StatusBadRequest)
+		return f.ReportedContent{}, jsonReportAction{}, false
+	}
+
+	// Check if the report exists in the thread
+	report, err := f.GetReportInThread(thread, msg.ReportID)
+	if err != nil {
+		f.DebugPrintf("Report ReportID is not valid\n")
+		http.Error(w, "Report ReportID is not valid", http.StatusBadRequest)
+		return f.ReportedContent{}, jsonReportAction{}, false
+	}
+
+	// Check if the report is still waiting for the moderation team
+	if report.State != f.ReportOpen && report.State != f.ReportClaimed {
+		f.DebugPrintf("Report is already closed\n")
+		http.Error(w, "Report is already closed", http.StatusBadRequest)
+		return f.ReportedContent{}, jsonReportAction{}, false
+	}
+
+	// Check if the note is valid
+	if !f.IsResolutionNoteValid(msg.Note) {
+		f.DebugPrintf("Resolution note is not valid\n")
+		http.Error(w, "Resolution note is not valid", http.StatusBadRequest)
+		return f.ReportedContent{}, jsonReportAction{}, false
+	}
+	return report, msg, true
+}
+
+// _closeReportGroup closes the reports made about the same content as the report and writes the response
+// The action is added to the moderation log
+func _closeReportGroup(w http.ResponseWriter, thread f.ThreadGoForum, user f.User, report f.ReportedContent, state f.ReportState, note string) {
+	err := f.CloseReportGroup(thread, report, user, state, note)
+	if err != nil {
+		f.ErrorPrintf("Error while closing the reports: %v\n", err)
+		http.Error(w, "Error while closing the reports", http.StatusInternalServerError)
+		return
+	}
+
+	f.DebugPrintf("Reports about the content of the report %d were %s by %s\n", report.ReportID, state, user.Username)
+	logAction := f.ModerationReportResolved
+	if state == f.ReportDismissed {
+		logAction = f.ModerationReportDismissed
+	}
+	reportedUser, _ := f.GetUserFromUsername(report.ReportedUserName)
+	f.LogModerationAction(thread, user, logAction, reportedUser.UserID, report.ReportID, note)
+
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(`{"status":"success"}`))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// claimReport handles the claim report action
+// The user becomes the assignee of every active report made about the same content
+// Take a jsonReportAction as input (the note is ignored)
+func claimReport(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
+	report, _, ok := _checkReportApiCallValidity(w, r, thread, user)
+	if !ok {
+		return
+	}
+
+	err := f.ClaimReportGroup(thread, report, user)
+	if err != nil {
+		f.ErrorPrintf("Error while claiming the reports: %v\n", err)
+		http.Error(w, "Error while claiming the reports", http.StatusInternalServerError)
+		return
+	}
+
+	f.DebugPrintf("Reports about the content of the report %d were claimed by %s\n", report.ReportID, user.Username)
+	reportedUser, _ := f.GetUserFromUsername(report.ReportedUserName)
+	f.LogModerationAction(thread, user, f.ModerationReportClaimed, reportedUser.UserID, report.ReportID, "")
 
 	// Return the response
 	w.WriteHeader(http.StatusOK)
@@ -1339,14 +2249,161 @@ func setReportToResolved(w http.ResponseWriter, r *http.Request, thread f.Thread
 	}
 }
 
+// dismissReport handles the dismiss report action
+// Every active report made about the same content is closed without any action
+// Take a jsonReportAction as input
+func dismissReport(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
+	report, msg, ok := _checkReportApiCallValidity(w, r, thread, user)
+	if !ok {
+		return
+	}
+	_closeReportGroup(w, thread, user, report, f.ReportDismissed, msg.Note)
+}
+
+// deleteReportedContent handles the delete reported content action
+// The reported message or comment is deleted and every active report made about it is closed as actioned
+// Take a jsonReportAction as input
+func deleteReportedContent(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
+	report, msg, ok := _checkReportApiCallValidity(w, r, thread, user)
+	if !ok {
+		return
+	}
+	reportedUser, _ := f.GetUserFromUsername(report.ReportedUserName)
+
+	if report.IsAPostAndNotAComment {
+		message, err := f.GetMessageByID(report.ReportedContentID)
+		if err != nil || message.DeletionState != f.NotDeleted {
+			f.DebugPrintf("Reported message was already deleted\n")
+			http.Error(w, "Reported message was already deleted", http.StatusBadRequest)
+			return
+		}
+		if !f.IsUserAllowedToDeleteMessage(thread, user, report.ReportedContentID) {
+			f.DebugPrintf("User is not allowed to delete this message\n")
+			http.Error(w, "User is not allowed to delete this message", http.StatusForbidden)
+			return
+		}
+		err = f.RemoveMessageFromThread(thread, report.ReportedContentID, user)
+		if err != nil {
+			f.ErrorPrintf("Error while deleting the message: %v\n", err)
+			http.Error(w, "Error while deleting the message", http.StatusInternalServerError)
+			return
+		}
+		f.DebugPrintf("Reported message %d deleted by %s\n", report.ReportedContentID, user.Username)
+		if reportedUser.UserID != user.UserID {
+			f.LogModerationAction(thread, user, f.ModerationMessageDeleted, reportedUser.UserID, report.ReportedContentID, message.MessageTitle)
+		}
+	} else {
+		comment, err := f.GetCommentByIDWithPOV(report.ReportedContentID, f.User{})
+		if err != nil || comment.DeletionState != f.NotDeleted {
+			f.DebugPrintf("Reported comment was already deleted\n")
+			http.Error(w, "Reported comment was already deleted", http.StatusBadRequest)
+			return
+		}
+		if !f.IsUserAllowedToDeleteComment(thread, user, report.ReportedContentID) {
+			f.DebugPrintf("User is not allowed to delete this comment\n")
+			http.Error(w, "User is not allowed to delete this comment", http.StatusForbidden)
+			return
+		}
+		err = f.RemoveCommentFromPost(report.ReportedContentID, user)
+		if err != nil {
+			f.ErrorPrintf("Error while deleting the comment: %v\n", err)
+			http.Error(w, "Error while deleting the comment", http.StatusInternalServerError)
+			return
+		}
+		f.DebugPrintf("Reported comment %d deleted by %s\n", report.ReportedContentID, user.Username)
+		if reportedUser.UserID != user.UserID {
+			f.LogModerationAction(thread, user, f.ModerationCommentDeleted, reportedUser.UserID, report.ReportedContentID, comment.CommentContent)
+		}
+	}
+
+	_closeReportGroup(w, thread, user, report, f.ReportActioned, msg.Note)
+}
+
+// banReportedAuthor handles the ban reported author action
+// The author of the reported content is banned and every active report made about the content is closed as actioned
+// Take a jsonReportAction as input, the reason of the ban is the type of the report when none is given
+func banReportedAuthor(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
+	if !f.IsUserAllowedToBanUserInThread(thread, user) {
+		f.DebugPrintf("User is not allowed to ban a user in this thread\n")
+		http.Error(w, "User is not allowed to ban a user in this thread", http.StatusForbidden)
+		return
+	}
+	report, msg, ok := _checkReportApiCallValidity(w, r, thread, user)
+	if !ok {
+		return
+	}
+
+	// Check if the author of the reported content is known
+	userToBan, err := f.GetUserFromUsername(report.ReportedUserName)
+	if err != nil || (userToBan == f.User{}) {
+		f.DebugPrintf("Author of the reported content is unknown\n")
+		http.Error(w, "Author of the reported content is unknown", http.StatusBadRequest)
+		return
+	}
+
+	// Check the ban parameters
+	if msg.Reason == "" {
+		msg.Reason = "Reported content (" + string(report.ReportType) + ")"
+	}
+	if !f.IsBanReasonValid(msg.Reason) {
+		f.DebugPrintf("Ban reason is not valid\n")
+		http.Error(w, "Ban reason is not valid", http.StatusBadRequest)
+		return
+	}
+	if msg.Duration < 0 {
+		f.DebugPrintf("Ban duration is not valid\n")
+		http.Error(w, "Ban duration is not valid", http.StatusBadRequest)
+		return
+	}
+
+	// Check if the author can be banned by the user
+	if f.GetUserRankInThread(thread, userToBan) >= f.GetUserRankInThread(thread, user) {
+		f.DebugPrintf("User cannot ban an other user with a higher or equal rank\n")
+		http.Error(w, "User cannot ban an other user with a higher or equal rank", http.StatusForbidden)
+		return
+	}
+
+	// Only the members of the thread can be banned
+	if !f.IsUserInThread(thread, userToBan) {
+		f.DebugPrintf("Author of the reported content is not in the thread\n")
+		http.Error(w, "Author of the reported content is not in the thread", http.StatusBadRequest)
+		return
+	}
+
+	// The author may already be banned because of an other report
+	if f.GetUserRankInThread(thread, userToBan) != f.ThreadRankBanned {
+		err = f.BanUserFromThread(thread, userToBan, user, msg.Reason, msg.Note, msg.Duration)
+		if err != nil {
+			f.ErrorPrintf("Error while banning the user: %v\n", err)
+			http.Error(w, "Error while banning the user", http.StatusInternalServerError)
+			return
+		}
+		f.DebugPrintf("User %s was banned from thread %s by %s\n", userToBan.Username, thread.ThreadName, user.Username)
+		banDetails := msg.Reason + " (permanent)"
+		if msg.Duration > 0 {
+			banDetails = fmt.Sprintf("%s (%d hours)", msg.Reason, msg.Duration)
+		}
+		f.LogModerationAction(thread, user, f.ModerationUserBanned, userToBan.UserID, 0, banDetails)
+	}
+
+	_closeReportGroup(w, thread, user, report, f.ReportActioned, msg.Note)
+}
+
 func createThreadTag(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
 	f.DebugPrintf("Creating thread tag\n")
-	if !(f.GetUserRankInThread(thread, user) >= f.ThreadRankOwner) {
+	if !f.HasThreadPermission(thread, user, f.PermissionManageTags) {
 		f.DebugPrintf("User is not allowed to create a tag in this thread\n")
 		http.Error(w, "User is not allowed to create a tag in this thread", http.StatusForbidden)
 		return
 	}
 
+	// Check if the reputation of the user allows him to create tags
+	if !f.HasReputationFor(thread, user, f.ReputationCreateTags) {
+		f.DebugPrintf("User does not have enough reputation to create a tag in this thread\n")
+		http.Error(w, "User does not have enough reputation to create a tag in this thread", http.StatusForbidden)
+		return
+	}
+
 	if r.Method != "POST" {
 		f.DebugPrintf("Method is not POST\n")
 		http.Error(w, "Method is not POST", http.StatusMethodNotAllowed)
@@ -1420,6 +2477,7 @@ func createThreadTag(w http.ResponseWriter, r *http.Request, thread f.ThreadGoFo
 	}
 
 	f.DebugPrintf("Tag %s was created in thread %s by %s\n", tag.TagName, thread.ThreadName, user.Username)
+	f.LogModerationAction(thread, user, f.ModerationTagCreated, 0, 0, tag.TagName+" "+tag.TagColor)
 	// Return the response
 	w.WriteHeader(http.StatusOK)
 	_, err = w.Write([]byte(`{"status":"success"}`))
@@ -1432,7 +2490,7 @@ func createThreadTag(w http.ResponseWriter, r *http.Request, thread f.ThreadGoFo
 
 func deleteThreadTag(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
 	f.DebugPrintf("Deleting thread tag\n")
-	if !(f.GetUserRankInThread(thread, user) >= f.ThreadRankOwner) {
+	if !f.HasThreadPermission(thread, user, f.PermissionManageTags) {
 		f.DebugPrintf("User is not allowed to delete a tag in this thread\n")
 		http.Error(w, "User is not allowed to delete a tag in this thread", http.StatusForbidden)
 		return
@@ -1479,6 +2537,7 @@ func deleteThreadTag(w http.ResponseWriter, r *http.Request, thread f.ThreadGoFo
 	}
 
 	f.DebugPrintf("Tag %s was deleted in thread %s by %s\n", tagFull.TagName, thread.ThreadName, user.Username)
+	f.LogModerationAction(thread, user, f.ModerationTagDeleted, 0, tag.TagID, tagFull.TagName)
 
 	w.WriteHeader(http.StatusOK)
 	_, err = w.Write([]byte(`{"status":"success"}`))
@@ -1491,7 +2550,7 @@ func deleteThreadTag(w http.ResponseWriter, r *http.Request, thread f.ThreadGoFo
 
 func editThreadTag(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
 	f.DebugPrintf("Editing thread tag\n")
-	if !(f.GetUserRankInThread(thread, user) >= f.ThreadRankOwner) {
+	if !f.HasThreadPermission(thread, user, f.PermissionManageTags) {
 		f.DebugPrintf("User is not allowed to edit a tag in this thread\n")
 		http.Error(w, "User is not allowed to edit a tag in this thread", http.StatusForbidden)
 		return
@@ -1528,6 +2587,7 @@ func editThreadTag(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForu
 	}
 
 	f.DebugPrintf("Tag %s was edited in thread %s by %s\n", tag.TagName, thread.ThreadName, user.Username)
+	f.LogModerationAction(thread, user, f.ModerationTagEdited, 0, tag.TagID, tag.TagName+" "+tag.TagColor)
 
 	w.WriteHeader(http.StatusOK)
 	_, err = w.Write([]byte(`{"status":"success"}`))
@@ -1546,7 +2606,7 @@ func editThreadTag(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForu
 // The user can only promote a user to a rank lower than his own
 // Send a JSON response with the status of the action and the promoted user and his new rank
 func promoteUser(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
-	if !(f.GetUserRankInThread(thread, user) >= f.ThreadRankModerator) {
+	if !f.HasThreadPermission(thread, user, f.PermissionPromoteMembers) {
 		f.DebugPrintf("User is not allowed to promote a user in this thread\n")
 		http.Error(w, "User is not allowed to promote a user in this thread", http.StatusForbidden)
 		return
@@ -1608,6 +2668,7 @@ func promoteUser(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum,
 	}
 
 	f.DebugPrintf("User %s was promoted in thread %s by %s\n", userToPromote.Username, thread.ThreadName, user.Username)
+	f.LogModerationAction(thread, user, f.ModerationUserPromoted, userToPromote.UserID, 0, fmt.Sprintf("rank %d", f.GetUserRankInThread(thread, userToPromote)))
 
 	// Return the response
 	w.WriteHeader(http.StatusOK)
@@ -1622,7 +2683,7 @@ func promoteUser(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum,
 // The user can only demote a user with a lower rank than his own
 // Send a JSON response with the status of the action and the promoted user and his new rank
 func demoteUser(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
-	if !(f.GetUserRankInThread(thread, user) >= f.ThreadRankModerator) {
+	if !f.HasThreadPermission(thread, user, f.PermissionPromoteMembers) {
 		f.DebugPrintf("User is not allowed to demote a user in this thread\n")
 		http.Error(w, "User is not allowed to demote a user in this thread", http.StatusForbidden)
 		return
@@ -1685,6 +2746,7 @@ func demoteUser(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum,
 	}
 
 	f.DebugPrintf("User %s was demoted in thread %s by %s\n", userToDemote.Username, thread.ThreadName, user.Username)
+	f.LogModerationAction(thread, user, f.ModerationUserDemoted, userToDemote.UserID, 0, fmt.Sprintf("rank %d", f.GetUserRankInThread(thread, userToDemote)))
 
 	// Return the response
 	w.WriteHeader(http.StatusOK)
@@ -1808,3 +2870,1217 @@ func (s *IntSlice) UnmarshalJSON(data []byte) error {
 	*s = result
 	return nil
 }
+
+// createWebhook handles the create webhook action
+// Only the users allowed to manage the thread can register a webhook
+// Take a jsonWebhook as input
+// Returns the id of the webhook and its secret (the secret is only shown once)
+func createWebhook(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
+	if !f.HasThreadPermission(thread, user, f.PermissionManageThread) {
+		f.DebugPrintf("User is not allowed to create a webhook in this thread\n")
+		http.Error(w, "User is not allowed to create a webhook in this thread", http.StatusForbidden)
+		return
+	}
+
+	if r.Method != "POST" {
+		f.DebugPrintf("Method is not POST\n")
+		http.Error(w, "Method is not POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Parse the form
+	err := r.ParseForm()
+	if err != nil {
+		f.ErrorPrintf("Error while parsing the form: %v\n", err)
+		http.Error(w, "Error while parsing the form", http.StatusBadRequest)
+		return
+	}
+
+	// Getting the form values
+	var webhook jsonWebhook
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&webhook); err != nil {
+		f.ErrorPrintf("Error while decoding the JSON: %v\n", err)
+		http.Error(w, "Error while decoding the JSON", http.StatusBadRequest)
+		return
+	}
+
+	// Check if the url is valid
+	if !f.IsWebhookURLValid(webhook.URL) {
+		f.DebugPrintf("Webhook url is not valid\n")
+		http.Error(w, "Webhook url is not valid", http.StatusBadRequest)
+		return
+	}
+
+	// Check if the events are valid
+	if len(webhook.Events) == 0 {
+		f.DebugPrintf("Webhook has no event\n")
+		http.Error(w, "Webhook has no event", http.StatusBadRequest)
+		return
+	}
+	var events []f.WebhookEvent
+	for _, strEvent := range webhook.Events {
+		event, err := f.GetWebhookEventFromString(strEvent)
+		if err != nil {
+			f.DebugPrintf("Webhook event is not valid\n")
+			http.Error(w, "Webhook event is not valid", http.StatusBadRequest)
+			return
+		}
+		events = append(events, event)
+	}
+
+	// Create the webhook
+	webhookID, secret, err := f.AddThreadWebhook(thread, webhook.URL, events)
+	if err != nil {
+		f.ErrorPrintf("Error while creating the webhook: %v\n", err)
+		http.Error(w, "Error while creating the webhook", http.StatusInternalServerError)
+		return
+	}
+
+	f.DebugPrintf("Webhook %d was created in thread %s by %s\n", webhookID, thread.ThreadName, user.Username)
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(fmt.Sprintf(`{"status":"success","webhook_id":%d,"secret":"%s"}`, webhookID, secret)))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// deleteWebhook handles the delete webhook action
+// Only the users allowed to manage the thread can remove a webhook
+// Take a jsonWebhookDesignator as input
+func deleteWebhook(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
+	if !f.HasThreadPermission(thread, user, f.PermissionManageThread) {
+		f.DebugPrintf("User is not allowed to delete a webhook in this thread\n")
+		http.Error(w, "User is not allowed to delete a webhook in this thread", http.StatusForbidden)
+		return
+	}
+
+	if r.Method != "POST" {
+		f.DebugPrintf("Method is not POST\n")
+		http.Error(w, "Method is not POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Parse the form
+	err := r.ParseForm()
+	if err != nil {
+		f.ErrorPrintf("Error while parsing the form: %v\n", err)
+		http.Error(w, "Error while parsing the form", http.StatusBadRequest)
+		return
+	}
+
+	// Getting the form values
+	var webhook jsonWebhookDesignator
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&webhook); err != nil {
+		f.ErrorPrintf("Error while decoding the JSON: %v\n", err)
+		http.Error(w, "Error while decoding the JSON", http.StatusBadRequest)
+		return
+	}
+
+	// Check if the webhook exists in the thread
+	if !f.WebhookExistsInThread(thread, webhook.WebhookID) {
+		f.DebugPrintf("Webhook does not exist in the thread\n")
+		http.Error(w, "Webhook does not exist in the thread", http.StatusNotFound)
+		return
+	}
+
+	// Remove the webhook
+	err = f.RemoveThreadWebhook(thread, webhook.WebhookID)
+	if err != nil {
+		f.ErrorPrintf("Error while deleting the webhook: %v\n", err)
+		http.Error(w, "Error while deleting the webhook", http.StatusInternalServerError)
+		return
+	}
+
+	f.DebugPrintf("Webhook %d was deleted from thread %s by %s\n", webhook.WebhookID, thread.ThreadName, user.Username)
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(`{"status":"success"}`))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// setAutoHideThreshold handles the set auto hide threshold action
+// Only the users allowed to manage the thread can change it
+// Take a jsonAutoHideThreshold as input
+func setAutoHideThreshold(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
+	if !f.HasThreadPermission(thread, user, f.PermissionManageThread) {
+		f.DebugPrintf("User is not allowed to change the auto hide threshold of this thread\n")
+		http.Error(w, "User is not allowed to change the auto hide threshold of this thread", http.StatusForbidden)
+		return
+	}
+
+	// Getting the form values
+	var threshold jsonAutoHideThreshold
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&threshold); err != nil {
+		f.ErrorPrintf("Error while decoding the JSON: %v\n", err)
+		http.Error(w, "Error while decoding the JSON", http.StatusBadRequest)
+		return
+	}
+
+	// Check if the threshold is valid
+	if !f.IsAutoHideReportThresholdValid(threshold.Threshold) {
+		f.DebugPrintf("Auto hide threshold is not valid\n")
+		http.Error(w, "Auto hide threshold is not valid", http.StatusBadRequest)
+		return
+	}
+
+	threadConfigs := f.GetThreadConfigFromThread(thread)
+	threadConfigs.AutoHideReportThreshold = threshold.Threshold
+	err := f.UpdateThreadConfigs(threadConfigs)
+	if err != nil {
+		f.ErrorPrintf("Error while updating the auto hide threshold: %v\n", err)
+		http.Error(w, "Error while updating the auto hide threshold", http.StatusInternalServerError)
+		return
+	}
+
+	f.DebugPrintf("Auto hide threshold of thread %s set to %d by %s\n", thread.ThreadName, threshold.Threshold, user.Username)
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(`{"status":"success"}`))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// addWordFilter handles the add word filter action
+// Only the users allowed to manage the thread can change its word filters
+// Take a jsonWordFilter as input
+// Returns the id of the filter
+func addWordFilter(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
+	if !f.HasThreadPermission(thread, user, f.PermissionManageThread) {
+		f.DebugPrintf("User is not allowed to add a word filter in this thread\n")
+		http.Error(w, "User is not allowed to add a word filter in this thread", http.StatusForbidden)
+		return
+	}
+
+	// Getting the form values
+	var filter jsonWordFilter
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&filter); err != nil {
+		f.ErrorPrintf("Error while decoding the JSON: %v\n", err)
+		http.Error(w, "Error while decoding the JSON", http.StatusBadRequest)
+		return
+	}
+
+	// Check if the word is valid
+	if !f.IsWordFilterValid(filter.Word) {
+		f.DebugPrintf("Filtered word is not valid\n")
+		http.Error(w, "Filtered word is not valid", http.StatusBadRequest)
+		return
+	}
+
+	// Check if the action is valid
+	action, err := f.GetWordFilterActionFromString(filter.Action)
+	if err != nil {
+		f.DebugPrintf("Word filter action is not valid\n")
+		http.Error(w, "Word filter action is not valid", http.StatusBadRequest)
+		return
+	}
+
+	filterID, err := f.AddThreadWordFilter(thread, filter.Word, action)
+	if err != nil {
+		f.ErrorPrintf("Error while adding the word filter: %v\n", err)
+		http.Error(w, "Error while adding the word filter, it may already exist", http.StatusInternalServerError)
+		return
+	}
+
+	f.DebugPrintf("Word filter %d was added in thread %s by %s\n", filterID, thread.ThreadName, user.Username)
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(fmt.Sprintf(`{"status":"success","filter_id":%d}`, filterID)))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// removeWordFilter handles the remove word filter action
+// Only the users allowed to manage the thread can change its word filters
+// Take a jsonWordFilterDesignator as input
+func removeWordFilter(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
+	if !f.HasThreadPermission(thread, user, f.PermissionManageThread) {
+		f.DebugPrintf("User is not allowed to remove a word filter in this thread\n")
+		http.Error(w, "User is not allowed to remove a word filter in this thread", http.StatusForbidden)
+		return
+	}
+
+	// Getting the form values
+	var filter jsonWordFilterDesignator
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&filter); err != nil {
+		f.ErrorPrintf("Error while decoding the JSON: %v\n", err)
+		http.Error(w, "Error while decoding the JSON", http.StatusBadRequest)
+		return
+	}
+
+	err := f.RemoveThreadWordFilter(thread, filter.FilterID)
+	if err != nil {
+		f.DebugPrintf("Error while removing the word filter: %v\n", err)
+		http.Error(w, "Word filter does not exist in this thread", http.StatusNotFound)
+		return
+	}
+
+	f.DebugPrintf("Word filter %d was removed from thread %s by %s\n", filter.FilterID, thread.ThreadName, user.Username)
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(`{"status":"success"}`))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// setApprovalSettings handles the set approval settings action
+// Only the users allowed to manage the thread can change them
+// Take a jsonApprovalSettings as input
+func setApprovalSettings(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
+	if !f.HasThreadPermission(thread, user, f.PermissionManageThread) {
+		f.DebugPrintf("User is not allowed to change the approval settings of this thread\n")
+		http.Error(w, "User is not allowed to change the approval settings of this thread", http.StatusForbidden)
+		return
+	}
+
+	// Getting the form values
+	var settings jsonApprovalSettings
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&settings); err != nil {
+		f.ErrorPrintf("Error while decoding the JSON: %v\n", err)
+		http.Error(w, "Error while decoding the JSON", http.StatusBadRequest)
+		return
+	}
+
+	// Check if the settings are valid
+	if !f.IsApprovalSettingValid(settings.MinPosts, settings.MinDays) {
+		f.DebugPrintf("Approval settings are not valid\n")
+		http.Error(w, "Approval settings are not valid", http.StatusBadRequest)
+		return
+	}
+
+	threadConfigs := f.GetThreadConfigFromThread(thread)
+	threadConfigs.ApprovalMinPosts = settings.MinPosts
+	threadConfigs.ApprovalMinDays = settings.MinDays
+	err := f.UpdateThreadConfigs(threadConfigs)
+	if err != nil {
+		f.ErrorPrintf("Error while updating the approval settings: %v\n", err)
+		http.Error(w, "Error while updating the approval settings", http.StatusInternalServerError)
+		return
+	}
+
+	f.DebugPrintf("Approval settings of thread %s set to %d posts and %d days by %s\n", thread.ThreadName, settings.MinPosts, settings.MinDays, user.Username)
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(`{"status":"success"}`))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// setReputationSettings handles the set reputation settings action
+// Only the users allowed to manage the thread can change them
+// Take a jsonReputationSettings as input
+func setReputationSettings(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
+	if !f.HasThreadPermission(thread, user, f.PermissionManageThread) {
+		f.DebugPrintf("User is not allowed to change the reputation settings of this thread\n")
+		http.Error(w, "User is not allowed to change the reputation settings of this thread", http.StatusForbidden)
+		return
+	}
+
+	// Getting the form values
+	var settings jsonReputationSettings
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&settings); err != nil {
+		f.ErrorPrintf("Error while decoding the JSON: %v\n", err)
+		http.Error(w, "Error while decoding the JSON", http.StatusBadRequest)
+		return
+	}
+
+	// Check if the settings are valid
+	if !f.IsReputationThresholdValid(settings.MinImages) || !f.IsReputationThresholdValid(settings.MinTags) || !f.IsReputationThresholdValid(settings.MinReports) {
+		f.DebugPrintf("Reputation settings are not valid\n")
+		http.Error(w, "Reputation settings are not valid", http.StatusBadRequest)
+		return
+	}
+
+	threadConfigs := f.GetThreadConfigFromThread(thread)
+	threadConfigs.MinReputationImages = settings.MinImages
+	threadConfigs.MinReputationTags = settings.MinTags
+	threadConfigs.MinReputationReports = settings.MinReports
+	err := f.UpdateThreadConfigs(threadConfigs)
+	if err != nil {
+		f.ErrorPrintf("Error while updating the reputation settings: %v\n", err)
+		http.Error(w, "Error while updating the reputation settings", http.StatusInternalServerError)
+		return
+	}
+
+	f.DebugPrintf("Reputation settings of thread %s set to %d (images), %d (tags) and %d (reports) by %s\n", thread.ThreadName, settings.MinImages, settings.MinTags, settings.MinReports, user.Username)
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(`{"status":"success"}`))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// setThreadCategory handles the set thread category action
+// Only the users allowed to manage the thread can change it
+// Take a jsonThreadCategory as input
+func setThreadCategory(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
+	if !f.HasThreadPermission(thread, user, f.PermissionManageThread) {
+		f.DebugPrintf("User is not allowed to change the category of this thread\n")
+		http.Error(w, "User is not allowed to change the category of this thread", http.StatusForbidden)
+		return
+	}
+
+	// Getting the form values
+	var category jsonThreadCategory
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&category); err != nil {
+		f.ErrorPrintf("Error while decoding the JSON: %v\n", err)
+		http.Error(w, "Error while decoding the JSON", http.StatusBadRequest)
+		return
+	}
+
+	// Check if the category is valid
+	if !f.IsThreadCategoryValid(category.Category) {
+		f.DebugPrintf("Thread category is not valid\n")
+		http.Error(w, "Thread category is not valid", http.StatusBadRequest)
+		return
+	}
+
+	threadConfigs := f.GetThreadConfigFromThread(thread)
+	threadConfigs.ThreadCategory = category.Category
+	err := f.UpdateThreadConfigs(threadConfigs)
+	if err != nil {
+		f.ErrorPrintf("Error while updating the thread category: %v\n", err)
+		http.Error(w, "Error while updating the thread category", http.StatusInternalServerError)
+		return
+	}
+
+	f.DebugPrintf("Category of thread %s set to %s by %s\n", thread.ThreadName, category.Category, user.Username)
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(`{"status":"success"}`))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// setAccessSettings handles the set access settings action
+// Only the users allowed to manage the thread can change who can see and join it
+// Take a jsonAccessSettings as input
+func setAccessSettings(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
+	if !f.HasThreadPermission(thread, user, f.PermissionManageThread) {
+		f.DebugPrintf("User is not allowed to change the access settings of this thread\n")
+		http.Error(w, "User is not allowed to change the access settings of this thread", http.StatusForbidden)
+		return
+	}
+
+	// Getting the form values
+	var settings jsonAccessSettings
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&settings); err != nil {
+		f.ErrorPrintf("Error while decoding the JSON: %v\n", err)
+		http.Error(w, "Error while decoding the JSON", http.StatusBadRequest)
+		return
+	}
+
+	// Check if the join policy is valid
+	if !f.IsThreadJoinPolicyValid(settings.JoinPolicy) {
+		f.DebugPrintf("Join policy is not valid\n")
+		http.Error(w, "Join policy is not valid", http.StatusBadRequest)
+		return
+	}
+
+	threadConfigs := f.GetThreadConfigFromThread(thread)
+	threadConfigs.JoinPolicy = settings.JoinPolicy
+	threadConfigs.IsPrivate = settings.IsPrivate
+	err := f.UpdateThreadConfigs(threadConfigs)
+	if err != nil {
+		f.ErrorPrintf("Error while updating the access settings: %v\n", err)
+		http.Error(w, "Error while updating the access settings", http.StatusInternalServerError)
+		return
+	}
+
+	f.DebugPrintf("Access settings of thread %s set to the %s join policy (private: %t) by %s\n", thread.ThreadName, settings.JoinPolicy, settings.IsPrivate, user.Username)
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(`{"status":"success"}`))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// reviewJoinRequest handles the approve join request and deny join request actions
+// Only the users allowed to manage the access to the thread can review the join requests
+// Take a jsonJoinRequestDesignator as input
+func reviewJoinRequest(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User, approve bool) {
+	if !f.HasThreadPermission(thread, user, f.PermissionManageAccess) {
+		f.DebugPrintf("User is not allowed to review the join requests of this thread\n")
+		http.Error(w, "User is not allowed to review the join requests of this thread", http.StatusForbidden)
+		return
+	}
+
+	// Getting the form values
+	var request jsonJoinRequestDesignator
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&request); err != nil {
+		f.ErrorPrintf("Error while decoding the JSON: %v\n", err)
+		http.Error(w, "Error while decoding the JSON", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := f.ReviewJoinRequest(thread, request.RequestID, approve)
+	if errors.Is(err, sql.ErrNoRows) {
+		f.DebugPrintf("Join request %d does not exist in the thread\n", request.RequestID)
+		http.Error(w, "Join request does not exist", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		f.ErrorPrintf("Error while reviewing the join request: %v\n", err)
+		http.Error(w, "Error while reviewing the join request", http.StatusInternalServerError)
+		return
+	}
+	action := f.ModerationJoinDenied
+	if approve {
+		action = f.ModerationJoinApproved
+	}
+	f.LogModerationAction(thread, user, action, userID, request.RequestID, "")
+
+	f.DebugPrintf("Join request %d of thread %s reviewed by %s (approved: %t)\n", request.RequestID, thread.ThreadName, user.Username, approve)
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(`{"status":"success"}`))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// createInvite handles the create invite action
+// Only the users allowed to manage the access to the thread can create invite links
+// Take a jsonInvite as input, returns the code of the invite
+func createInvite(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
+	if !f.HasThreadPermission(thread, user, f.PermissionManageAccess) {
+		f.DebugPrintf("User is not allowed to create an invite in this thread\n")
+		http.Error(w, "User is not allowed to create an invite in this thread", http.StatusForbidden)
+		return
+	}
+
+	// Getting the form values
+	var invite jsonInvite
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&invite); err != nil {
+		f.ErrorPrintf("Error while decoding the JSON: %v\n", err)
+		http.Error(w, "Error while decoding the JSON", http.StatusBadRequest)
+		return
+	}
+
+	// Check if the limits of the invite are valid
+	if !f.IsThreadInviteLimitValid(invite.MaxUses, invite.ExpiresInHours) {
+		f.DebugPrintf("Invite limits are not valid\n")
+		http.Error(w, "Invite limits are not valid", http.StatusBadRequest)
+		return
+	}
+	var expirationDate *time.Time
+	if invite.ExpiresInHours > 0 {
+		date := time.Now().Add(time.Duration(invite.ExpiresInHours) * time.Hour)
+		expirationDate = &date
+	}
+
+	threadInvite, err := f.CreateThreadInvite(thread, user, invite.MaxUses, expirationDate)
+	if err != nil {
+		f.ErrorPrintf("Error while creating the invite: %v\n", err)
+		http.Error(w, "Error while creating the invite", http.StatusInternalServerError)
+		return
+	}
+	f.LogModerationAction(thread, user, f.ModerationInviteCreated, 0, threadInvite.InviteID, "")
+
+	f.DebugPrintf("Invite %d created in thread %s by %s\n", threadInvite.InviteID, thread.ThreadName, user.Username)
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(fmt.Sprintf(`{"status":"success","inviteId":%d,"inviteCode":"%s"}`, threadInvite.InviteID, threadInvite.InviteCode)))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// deleteInvite handles the delete invite action
+// Only the users allowed to manage the access to the thread can delete invite links
+// Take a jsonInviteDesignator as input
+func deleteInvite(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
+	if !f.HasThreadPermission(thread, user, f.PermissionManageAccess) {
+		f.DebugPrintf("User is not allowed to delete an invite in this thread\n")
+		http.Error(w, "User is not allowed to delete an invite in this thread", http.StatusForbidden)
+		return
+	}
+
+	// Getting the form values
+	var invite jsonInviteDesignator
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&invite); err != nil {
+		f.ErrorPrintf("Error while decoding the JSON: %v\n", err)
+		http.Error(w, "Error while decoding the JSON", http.StatusBadRequest)
+		return
+	}
+
+	err := f.DeleteThreadInvite(thread, invite.InviteID)
+	if err != nil {
+		f.DebugPrintf("Error while deleting the invite: %v\n", err)
+		http.Error(w, "Error while deleting the invite", http.StatusBadRequest)
+		return
+	}
+	f.LogModerationAction(thread, user, f.ModerationInviteDeleted, 0, invite.InviteID, "")
+
+	f.DebugPrintf("Invite %d of thread %s deleted by %s\n", invite.InviteID, thread.ThreadName, user.Username)
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(`{"status":"success"}`))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// createRole handles the create role action
+// Only the users allowed to manage the roles of the thread can create a custom role
+// Take a jsonRole as input, returns the id of the role
+func createRole(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
+	if !f.HasThreadPermission(thread, user, f.PermissionManageRoles) {
+		f.DebugPrintf("User is not allowed to create a role in this thread\n")
+		http.Error(w, "User is not allowed to create a role in this thread", http.StatusForbidden)
+		return
+	}
+
+	// Getting the form values
+	var role jsonRole
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&role); err != nil {
+		f.ErrorPrintf("Error while decoding the JSON: %v\n", err)
+		http.Error(w, "Error while decoding the JSON", http.StatusBadRequest)
+		return
+	}
+
+	// Check if the role is valid
+	if !f.IsThreadRoleNameValid(role.RoleName) {
+		f.DebugPrintf("Role name is not valid\n")
+		http.Error(w, "Role name is not valid", http.StatusBadRequest)
+		return
+	}
+	var permissions []f.ThreadPermission
+	for _, permission := range role.Permissions {
+		if !f.IsThreadPermissionAssignable(f.ThreadPermission(permission)) {
+			f.DebugPrintf("Permission %s cannot be given by a role\n", permission)
+			http.Error(w, "Permission cannot be given by a role", http.StatusBadRequest)
+			return
+		}
+		permissions = append(permissions, f.ThreadPermission(permission))
+	}
+
+	threadRole, err := f.CreateThreadRole(thread, role.RoleName, permissions)
+	if errors.Is(err, f.ErrThreadRoleExists) {
+		f.DebugPrintf("Role %s already exists in thread %s\n", role.RoleName, thread.ThreadName)
+		http.Error(w, "A role with this name already exists", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		f.ErrorPrintf("Error while creating the role: %v\n", err)
+		http.Error(w, "Error while creating the role", http.StatusInternalServerError)
+		return
+	}
+	f.LogModerationAction(thread, user, f.ModerationRoleCreated, 0, threadRole.RoleID, threadRole.RoleName)
+
+	f.DebugPrintf("Role %d created in thread %s by %s\n", threadRole.RoleID, thread.ThreadName, user.Username)
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(fmt.Sprintf(`{"status":"success","roleId":%d}`, threadRole.RoleID)))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// deleteRole handles the delete role action
+// Only the users allowed to manage the roles of the thread can delete a custom role, its members keep their rank
+// Take a jsonRoleDesignator as input
+func deleteRole(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
+	if !f.HasThreadPermission(thread, user, f.PermissionManageRoles) {
+		f.DebugPrintf("User is not allowed to delete a role in this thread\n")
+		http.Error(w, "User is not allowed to delete a role in this thread", http.StatusForbidden)
+		return
+	}
+
+	// Getting the form values
+	var role jsonRoleDesignator
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&role); err != nil {
+		f.ErrorPrintf("Error while decoding the JSON: %v\n", err)
+		http.Error(w, "Error while decoding the JSON", http.StatusBadRequest)
+		return
+	}
+
+	err := f.DeleteThreadRole(thread, role.RoleID)
+	if errors.Is(err, sql.ErrNoRows) {
+		f.DebugPrintf("Role %d does not exist in thread %s\n", role.RoleID, thread.ThreadName)
+		http.Error(w, "Role does not exist", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		f.ErrorPrintf("Error while deleting the role: %v\n", err)
+		http.Error(w, "Error while deleting the role", http.StatusInternalServerError)
+		return
+	}
+	f.LogModerationAction(thread, user, f.ModerationRoleDeleted, 0, role.RoleID, "")
+
+	f.DebugPrintf("Role %d of thread %s deleted by %s\n", role.RoleID, thread.ThreadName, user.Username)
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(`{"status":"success"}`))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// setMemberRole handles the set member role action
+// Only the users allowed to manage the roles of the thread can give a custom role to a member
+// Take a jsonMemberRole as input
+func setMemberRole(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
+	if !f.HasThreadPermission(thread, user, f.PermissionManageRoles) {
+		f.DebugPrintf("User is not allowed to give a role in this thread\n")
+		http.Error(w, "User is not allowed to give a role in this thread", http.StatusForbidden)
+		return
+	}
+
+	// Getting the form values
+	var memberRole jsonMemberRole
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&memberRole); err != nil {
+		f.ErrorPrintf("Error while decoding the JSON: %v\n", err)
+		http.Error(w, "Error while decoding the JSON", http.StatusBadRequest)
+		return
+	}
+
+	// Check if the user is valid
+	member, err := f.GetUserFromUsername(memberRole.Username)
+	if err != nil || (member == f.User{}) {
+		f.DebugPrintln("User is not valid")
+		http.Error(w, "User is not valid", http.StatusBadRequest)
+		return
+	}
+
+	err = f.SetMemberThreadRole(thread, member, memberRole.RoleID)
+	if errors.Is(err, sql.ErrNoRows) {
+		f.DebugPrintf("User %s is not a member of thread %s or role %d does not exist\n", member.Username, thread.ThreadName, memberRole.RoleID)
+		http.Error(w, "User is not a member of the thread or the role does not exist", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		f.ErrorPrintf("Error while setting the role of the member: %v\n", err)
+		http.Error(w, "Error while setting the role of the member", http.StatusInternalServerError)
+		return
+	}
+	f.LogModerationAction(thread, user, f.ModerationRoleAssigned, member.UserID, memberRole.RoleID, "")
+
+	f.DebugPrintf("Role of %s in thread %s set to %d by %s\n", member.Username, thread.ThreadName, memberRole.RoleID, user.Username)
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(`{"status":"success"}`))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// reviewPendingMessage handles the approve message and reject message actions
+// Only the users allowed to approve the messages can review the messages of the approval queue
+// Take a jsonMessageDesignator as input
+func reviewPendingMessage(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User, approve bool) {
+	if !f.HasThreadPermission(thread, user, f.PermissionApproveMessages) {
+		f.DebugPrintf("User is not allowed to review the messages of this thread\n")
+		http.Error(w, "User is not allowed to review the messages of this thread", http.StatusForbidden)
+		return
+	}
+
+	// Getting the form values
+	var designator jsonMessageDesignator
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&designator); err != nil {
+		f.ErrorPrintf("Error while decoding the JSON: %v\n", err)
+		http.Error(w, "Error while decoding the JSON", http.StatusBadRequest)
+		return
+	}
+
+	message, err := f.GetMessageByID(designator.MessageID)
+	if err != nil || !f.MessageExistsInThread(thread, designator.MessageID) || message.ApprovalState != f.MessagePending {
+		f.DebugPrintf("Message %d is not waiting for approval\n", designator.MessageID)
+		http.Error(w, "Message is not waiting for approval", http.StatusNotFound)
+		return
+	}
+	authorID, _ := f.GetMessageAuthorID(designator.MessageID)
+
+	action := f.ModerationMessageApproved
+	if approve {
+		err = f.ApproveMessage(thread, designator.MessageID)
+	} else {
+		action = f.ModerationMessageRejected
+		err = f.RejectMessage(thread, designator.MessageID)
+	}
+	if err != nil {
+		f.ErrorPrintf("Error while reviewing the message: %v\n", err)
+		http.Error(w, "Error while reviewing the message", http.StatusInternalServerError)
+		return
+	}
+
+	f.DebugPrintf("Message %d of thread %s was reviewed (%s) by %s\n", designator.MessageID, thread.ThreadName, action, user.Username)
+	f.LogModerationAction(thread, user, action, authorID, designator.MessageID, message.MessageTitle)
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(`{"status":"success"}`))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// pinOrLockMessage handles the pin, unpin, lock and unlock message actions
+// Only the users allowed to pin the messages can pin and lock them, the pinned messages are shown first and the locked ones cannot be commented nor voted
+// Take a jsonMessageDesignator as input
+func pinOrLockMessage(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User, action f.ModerationAction) {
+	if !f.HasThreadPermission(thread, user, f.PermissionPinMessages) {
+		f.DebugPrintf("User is not allowed to pin or lock the messages of this thread\n")
+		http.Error(w, "User is not allowed to pin or lock the messages of this thread", http.StatusForbidden)
+		return
+	}
+
+	// Getting the form values
+	var designator jsonMessageDesignator
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&designator); err != nil {
+		f.ErrorPrintf("Error while decoding the JSON: %v\n", err)
+		http.Error(w, "Error while decoding the JSON", http.StatusBadRequest)
+		return
+	}
+
+	if !f.MessageExistsInThread(thread, designator.MessageID) {
+		f.DebugPrintf("Message %d does not exist in the thread\n", designator.MessageID)
+		http.Error(w, "Message does not exist", http.StatusNotFound)
+		return
+	}
+	message, err := f.GetMessageByID(designator.MessageID)
+	if err != nil || message.DeletionState != f.NotDeleted {
+		f.DebugPrintf("Message %d was deleted\n", designator.MessageID)
+		http.Error(w, "Message does not exist", http.StatusNotFound)
+		return
+	}
+	authorID, _ := f.GetMessageAuthorID(designator.MessageID)
+
+	switch action {
+	case f.ModerationMessagePinned, f.ModerationMessageUnpinned:
+		err = f.SetMessagePinned(thread, designator.MessageID, action == f.ModerationMessagePinned)
+	default:
+		err = f.SetMessageLocked(thread, designator.MessageID, action == f.ModerationMessageLocked)
+	}
+	if err != nil {
+		f.ErrorPrintf("Error while updating the message: %v\n", err)
+		http.Error(w, "Error while updating the message", http.StatusInternalServerError)
+		return
+	}
+
+	f.DebugPrintf("Message %d of thread %s was updated (%s) by %s\n", designator.MessageID, thread.ThreadName, action, user.Username)
+	f.LogModerationAction(thread, user, action, authorID, designator.MessageID, message.MessageTitle)
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(`{"status":"success"}`))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// _checkThreadContentVisibility checks if the user can see the content of the thread (members only threads)
+// It returns false and writes the error if he can't
+func _checkThreadContentVisibility(w http.ResponseWriter, thread f.ThreadGoForum, user f.User) bool {
+	if !f.GetThreadConfigFromThread(thread).IsOpenToNonMembers && !f.IsUserInThread(thread, user) {
+		f.DebugPrintf("User is not in the thread and the thread forbid non member to access it\n")
+		http.Error(w, "User is not in the thread", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// _checkMessageNotLocked checks if the message can still be commented and voted
+// It returns false and writes the error if it is locked
+func _checkMessageNotLocked(w http.ResponseWriter, messageID int) bool {
+	if f.IsMessageLocked(messageID) {
+		f.DebugPrintf("Message %d is locked\n", messageID)
+		http.Error(w, "Message is locked", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// _checkMessageNotDeleted checks if the message can still be voted, the deleted messages cannot until they are restored
+// It returns false and writes the error if it is deleted
+func _checkMessageNotDeleted(w http.ResponseWriter, messageID int) bool {
+	if f.IsMessageDeleted(messageID) {
+		f.DebugPrintf("Message %d is deleted\n", messageID)
+		http.Error(w, "Message is deleted", http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+// _checkCommentNotDeleted checks if the comment can still be voted, the deleted comments cannot until they are restored
+// It returns false and writes the error if it is deleted
+func _checkCommentNotDeleted(w http.ResponseWriter, commentID int) bool {
+	if f.IsCommentDeleted(commentID) {
+		f.DebugPrintf("Comment %d is deleted\n", commentID)
+		http.Error(w, "Comment is deleted", http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+// subscribeThread handles the subscribe thread action
+// This action is used to follow the new messages of a thread, it is independent of the thread membership
+func subscribeThread(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
+	if r.Method != "POST" {
+		f.DebugPrintf("Method is not POST\n")
+		http.Error(w, "Method is not POST", http.StatusMethodNotAllowed)
+		return
+	}
+	if !_checkThreadContentVisibility(w, thread, user) {
+		return
+	}
+
+	err := f.SubscribeToThread(thread, user)
+	if err != nil {
+		f.ErrorPrintf("Error while subscribing to the thread: %v\n", err)
+		http.Error(w, "Error while subscribing to the thread", http.StatusInternalServerError)
+		return
+	}
+	f.DebugPrintf("User %s subscribed to the thread %s\n", user.Username, thread.ThreadName)
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(`{"status":"success"}`))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// unsubscribeThread handles the unsubscribe thread action
+// This action is used to stop following a thread
+func unsubscribeThread(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
+	if r.Method != "POST" {
+		f.DebugPrintf("Method is not POST\n")
+		http.Error(w, "Method is not POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := f.UnsubscribeFromThread(thread, user)
+	if err != nil {
+		f.ErrorPrintf("Error while unsubscribing from the thread: %v\n", err)
+		http.Error(w, "Error while unsubscribing from the thread", http.StatusInternalServerError)
+		return
+	}
+	f.DebugPrintf("User %s unsubscribed from the thread %s\n", user.Username, thread.ThreadName)
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(`{"status":"success"}`))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// muteThread handles the mute and unmute thread actions
+// A muted thread stays subscribed but its messages are hidden from the 'Following' feed
+func muteThread(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User, muted bool) {
+	if r.Method != "POST" {
+		f.DebugPrintf("Method is not POST\n")
+		http.Error(w, "Method is not POST", http.StatusMethodNotAllowed)
+		return
+	}
+	if !_checkThreadContentVisibility(w, thread, user) {
+		return
+	}
+
+	err := f.SetThreadSubscriptionMuted(thread, user, muted)
+	if err != nil {
+		f.ErrorPrintf("Error while changing the mute state of the thread: %v\n", err)
+		http.Error(w, "Error while changing the mute state of the thread", http.StatusInternalServerError)
+		return
+	}
+	f.DebugPrintf("User %s set the mute state of the thread %s to %t\n", user.Username, thread.ThreadName, muted)
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(`{"status":"success"}`))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// subscribeMessage handles the subscribe message action
+// This action is used to follow the new comments of a message
+// Take a jsonMessageDesignator as input
+func subscribeMessage(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
+	id := _checkMessageApiCallValidity(w, r, thread)
+	if id < 0 {
+		return
+	}
+	if !_checkThreadContentVisibility(w, thread, user) {
+		return
+	}
+
+	err := f.SubscribeToMessage(id, user)
+	if err != nil {
+		f.ErrorPrintf("Error while subscribing to the message: %v\n", err)
+		http.Error(w, "Error while subscribing to the message", http.StatusInternalServerError)
+		return
+	}
+	f.DebugPrintf("User %s subscribed to the message %d\n", user.Username, id)
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(`{"status":"success"}`))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// unsubscribeMessage handles the unsubscribe message action
+// This action is used to stop following a message
+// Take a jsonMessageDesignator as input
+func unsubscribeMessage(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
+	id := _checkMessageApiCallValidity(w, r, thread)
+	if id < 0 {
+		return
+	}
+
+	err := f.UnsubscribeFromMessage(id, user)
+	if err != nil {
+		f.ErrorPrintf("Error while unsubscribing from the message: %v\n", err)
+		http.Error(w, "Error while unsubscribing from the message", http.StatusInternalServerError)
+		return
+	}
+	f.DebugPrintf("User %s unsubscribed from the message %d\n", user.Username, id)
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(`{"status":"success"}`))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// muteMessage handles the mute and unmute message actions
+// A muted message and its comments are hidden from the 'Following' feed, even if its thread is followed
+// Take a jsonMessageDesignator as input
+func muteMessage(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User, muted bool) {
+	id := _checkMessageApiCallValidity(w, r, thread)
+	if id < 0 {
+		return
+	}
+	if !_checkThreadContentVisibility(w, thread, user) {
+		return
+	}
+
+	err := f.SetMessageSubscriptionMuted(id, user, muted)
+	if err != nil {
+		f.ErrorPrintf("Error while changing the mute state of the message: %v\n", err)
+		http.Error(w, "Error while changing the mute state of the message", http.StatusInternalServerError)
+		return
+	}
+	f.DebugPrintf("User %s set the mute state of the message %d to %t\n", user.Username, id, muted)
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(`{"status":"success"}`))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// saveMessage handles the save message action
+// This action is used to keep a message in the saved items of the user, saving it again moves it to the given folder
+// Take a jsonSaveMessage as input
+func saveMessage(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
+	if r.Method != "POST" {
+		f.DebugPrintf("Method is not POST\n")
+		http.Error(w, "Method is not POST", http.StatusMethodNotAllowed)
+		return
+	}
+	var msg jsonSaveMessage
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&msg); err != nil {
+		f.ErrorPrintf("Error while decoding the JSON: %v\n", err)
+		http.Error(w, "Error while decoding the JSON", http.StatusBadRequest)
+		return
+	}
+	if msg.MessageID == 0 || !f.MessageExistsInThread(thread, msg.MessageID) {
+		f.DebugPrintf("Message MessageID is not valid\n")
+		http.Error(w, "Message MessageID is not valid", http.StatusBadRequest)
+		return
+	}
+	if !f.IsSavedFolderValid(msg.Folder) {
+		f.DebugPrintf("Saved folder is not valid\n")
+		http.Error(w, "Saved folder is not valid", http.StatusBadRequest)
+		return
+	}
+	if !_checkThreadContentVisibility(w, thread, user) {
+		return
+	}
+
+	err := f.SaveContent(user, msg.MessageID, 0, msg.Folder)
+	if err != nil {
+		f.ErrorPrintf("Error while saving the message: %v\n", err)
+		http.Error(w, "Error while saving the message", http.StatusInternalServerError)
+		return
+	}
+	f.DebugPrintf("User %s saved the message %d\n", user.Username, msg.MessageID)
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(`{"status":"success"}`))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// unsaveMessage handles the unsave message action
+// This action is used to remove a message from the saved items of the user
+// Take a jsonMessageDesignator as input
+func unsaveMessage(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
+	id := _checkMessageApiCallValidity(w, r, thread)
+	if id < 0 {
+		return
+	}
+
+	err := f.UnsaveContent(user, id, 0)
+	if err != nil {
+		f.ErrorPrintf("Error while removing the saved message: %v\n", err)
+		http.Error(w, "Error while removing the saved message", http.StatusInternalServerError)
+		return
+	}
+	f.DebugPrintf("User %s removed the message %d from his saved items\n", user.Username, id)
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(`{"status":"success"}`))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// saveComment handles the save comment action
+// This action is used to keep a comment in the saved items of the user, saving it again moves it to the given folder
+// Take a jsonSaveComment as input
+func saveComment(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
+	if r.Method != "POST" {
+		f.DebugPrintf("Method is not POST\n")
+		http.Error(w, "Method is not POST", http.StatusMethodNotAllowed)
+		return
+	}
+	var msg jsonSaveComment
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&msg); err != nil {
+		f.ErrorPrintf("Error while decoding the JSON: %v\n", err)
+		http.Error(w, "Error while decoding the JSON", http.StatusBadRequest)
+		return
+	}
+	if msg.CommentID == 0 || !f.MessageExistsInThread(thread, msg.MessageID) || !f.CommentExistsOnMessage(msg.MessageID, msg.CommentID) {
+		f.DebugPrintf("Content ID or Message ID is not valid\n")
+		http.Error(w, "Content ID or Message ID is not valid", http.StatusBadRequest)
+		return
+	}
+	if !f.IsSavedFolderValid(msg.Folder) {
+		f.DebugPrintf("Saved folder is not valid\n")
+		http.Error(w, "Saved folder is not valid", http.StatusBadRequest)
+		return
+	}
+	if !_checkThreadContentVisibility(w, thread, user) {
+		return
+	}
+
+	err := f.SaveContent(user, 0, msg.CommentID, msg.Folder)
+	if err != nil {
+		f.ErrorPrintf("Error while saving the comment: %v\n", err)
+		http.Error(w, "Error while saving the comment", http.StatusInternalServerError)
+		return
+	}
+	f.DebugPrintf("User %s saved the comment %d\n", user.Username, msg.CommentID)
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(`{"status":"success"}`))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// unsaveComment handles the unsave comment action
+// This action is used to remove a comment from the saved items of the user
+// Take a jsonCommentDesignator as input
+func unsaveComment(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum, user f.User) {
+	id := _checkCommentApiCallValidity(w, r, thread)
+	if id <= 0 {
+		return
+	}
+
+	err := f.UnsaveContent(user, 0, id)
+	if err != nil {
+		f.ErrorPrintf("Error while removing the saved comment: %v\n", err)
+		http.Error(w, "Error while removing the saved comment", http.StatusInternalServerError)
+		return
+	}
+	f.DebugPrintf("User %s removed the comment %d from his saved items\n", user.Username, id)
+	// Return the response
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(`{"status":"success"}`))
+	if err != nil {
+		f.ErrorPrintf("Error while writing the response: %v\n", err)
+		http.Error(w, "Error while writing the response", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,98 @@
+package apiPageHandlers
+
+import (
+	f "GoForum/functions"
+	"fmt"
+	"github.com/gorilla/mux"
+	"net/http"
+	"time"
+)
+
+// threadEventsKeepAlive is the interval between two keep alive comments, it prevents the proxies from closing an idle stream
+const threadEventsKeepAlive = 30 * time.Second
+
+// ThreadEventsHandler streams the events of a thread with Server-Sent Events
+// Its path is /t/{threadName}/events
+// The same access rules as the thread page are applied
+func ThreadEventsHandler(w http.ResponseWriter, r *http.Request) {
+	threadName := mux.Vars(r)["threadName"]
+
+	// Check if the thread name is empty or does not exist
+	if threadName == "" || !f.CheckIfThreadNameExists(threadName) {
+		f.DebugPrintf("Thread \"%s\" does not exist\n", threadName)
+		http.Error(w, "Thread does not exist or was not specified !", http.StatusNotFound)
+		return
+	}
+
+	thread := f.GetThreadFromName(threadName)
+	threadConfig := f.GetThreadConfigFromThread(thread)
+	isAuthenticated := f.IsAuthenticated(r)
+	user := f.GetUser(r)
+
+	// Check if the user is verified
+	if isAuthenticated && !f.IsUserVerified(r) {
+		f.DebugPrintf("User is not verified\n")
+		http.Error(w, "User is not verified", http.StatusUnauthorized)
+		return
+	}
+
+	// Check if the user is banned from the thread
+	if isAuthenticated && f.GetUserRankInThread(thread, user) < 0 {
+		f.DebugPrintf("User is banned from the thread he's trying to access\n")
+		http.Error(w, "User is banned from the thread", http.StatusForbidden)
+		return
+	}
+
+	// Check if the thread is open to the non-connected users
+	if !threadConfig.IsOpenToNonConnectedUsers && !isAuthenticated {
+		f.DebugPrintf("User is not authenticated and the thread forbid non-connected users to access it\n")
+		http.Error(w, "User is not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	// Check if the user is in the thread
+	if !threadConfig.IsOpenToNonMembers && !f.IsUserInThread(thread, user) {
+		f.DebugPrintf("User is not in the thread he's trying to access and the thread forbid non member to access it\n")
+		http.Error(w, "User is not in the thread", http.StatusForbidden)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		f.ErrorPrintf("The response writer does not support streaming\n")
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	events, unsubscribe := f.SubscribeToThreadEvents(thread)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	// Tell the browser to wait 5 seconds before reconnecting if the stream is cut
+	_, err := fmt.Fprint(w, "retry: 5000\n\n")
+	if err != nil {
+		return
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(threadEventsKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		case event := <-events:
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, event.Data)
+		}
+		if err != nil {
+			f.DebugPrintf("Thread events stream of %s closed: %v\n", threadName, err)
+			return
+		}
+		flusher.Flush()
+	}
+}
@@ -3,15 +3,20 @@ package apiPageHandlers
 import (
 	f "GoForum/functions"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"slices"
 	"strconv"
 )
 
+// ThreadMessageGetter returns a page of the messages of a thread as a JSON array
+// The pages are loaded with the 'cursor' query parameter, the cursor of the next page is given in the 'X-Next-Cursor' header (empty when there is nothing left to load)
+// The 'offset' query parameter is still accepted in place of the cursor
 func ThreadMessageGetter(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 	threadName := query.Get("thread")
 	offset := query.Get("offset")
+	cursor := query.Get("cursor")
 	order := query.Get("order")
 	tags := query.Get("tags")
 
@@ -21,19 +26,6 @@ func ThreadMessageGetter(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Thread does not exist or was not specified !", http.StatusNotFound)
 		return
 	}
-	// Check if the offset is empty or not a number
-	if offset == "" {
-		f.DebugPrintf("Offset is empty\n")
-		http.Error(w, "Offset is empty", http.StatusBadRequest)
-		return
-	}
-
-	if _, err := strconv.Atoi(offset); err != nil {
-		f.ErrorPrintf("Error parsing offset: %s\n", err)
-		http.Error(w, "Offset is not a number", http.StatusBadRequest)
-		return
-	}
-
 	// Check if the order is empty or not a valid order
 	if order == "" || !slices.Contains(f.OrderingList, order) {
 		f.DebugPrintf("Order is empty or not a valid order\n")
@@ -41,12 +33,16 @@ func ThreadMessageGetter(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Convert the offset to an int
-	offsetInt, err := strconv.Atoi(offset)
-	if err != nil {
-		f.ErrorPrintf("Error converting offset to int: %s\n", err)
-		http.Error(w, "Offset is not a number", http.StatusBadRequest)
-		return
+	// Convert the offset to an int if the messages are loaded with it
+	offsetInt := -1
+	if offset != "" {
+		var err error
+		offsetInt, err = strconv.Atoi(offset)
+		if err != nil || offsetInt < 0 {
+			f.DebugPrintf("Offset is not a valid number\n")
+			http.Error(w, "Offset is not a number", http.StatusBadRequest)
+			return
+		}
 	}
 
 	thread := f.GetThreadFromName(threadName)
@@ -81,13 +77,20 @@ func ThreadMessageGetter(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	Messages, err := f.GetMessagesFromThreadWithPOV(
-		f.GetThreadFromName(threadName),
-		offsetInt,
-		order,
-		user,
-		realTags,
-	)
+	var Messages []f.FormattedThreadMessage
+	var err error
+	if offsetInt >= 0 {
+		Messages, err = f.GetMessagesFromThreadWithPOV(thread, offsetInt, order, user, realTags)
+	} else {
+		var nextCursor string
+		Messages, nextCursor, err = f.GetMessagesFromThreadAfterCursor(thread, cursor, order, user, realTags)
+		w.Header().Set("X-Next-Cursor", nextCursor)
+	}
+	if errors.Is(err, f.ErrInvalidCursor) {
+		f.DebugPrintf("Cursor is not valid\n")
+		http.Error(w, "Cursor is not valid", http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		f.ErrorPrintf("Error getting messages from thread: %s\n", err)
 		http.Error(w, "Error getting messages from thread", http.StatusInternalServerError)
@@ -0,0 +1,258 @@
+package apiV1Handlers
+
+import (
+	f "GoForum/functions"
+	"errors"
+	"net/http"
+)
+
+// apiNewComment is the body expected to create or edit a comment
+type apiNewComment struct {
+	Content string `json:"content"`
+}
+
+// Comments handles /api/v1/threads/{threadName}/messages/{messageId}/comments
+// GET returns the comments of the message, paginated with the 'cursor' or 'offset' query parameter
+// POST creates a new comment on the message
+func Comments(w http.ResponseWriter, r *http.Request) {
+	if !isMethodAllowed(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+	scope := f.ApiTokenScopePost
+	if r.Method == http.MethodGet {
+		scope = f.ApiTokenScopeRead
+	}
+	user, ok := authenticateApiCall(w, r, scope)
+	if !ok {
+		return
+	}
+	thread, ok := getAccessibleThread(w, r, user)
+	if !ok {
+		return
+	}
+	messageID := getRouteMessageID(w, r, thread)
+	if messageID < 0 || !isMessageVisible(w, thread, user, messageID) {
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		var comment apiNewComment
+		if !decodeBody(w, r, &comment) {
+			return
+		}
+		if !f.IsMessageContentOrCommentContentValid(comment.Content) {
+			writeError(w, http.StatusUnprocessableEntity, "invalid_content", "Comment content is empty or not valid")
+			return
+		}
+		if !f.IsUserAllowedToSendMessageInThread(thread, user) {
+			writeError(w, http.StatusForbidden, "forbidden", "User is not allowed to send a comment in this thread")
+			return
+		}
+		if !isMessageOpen(w, messageID) {
+			return
+		}
+		verdict := f.FilterContent(thread, user, "", comment.Content, true)
+		if verdict.IsBlocked {
+			f.DebugPrintf("Comment of %s blocked by the content filters: %s\n", user.Username, verdict.BlockReason)
+			writeError(w, http.StatusUnprocessableEntity, "content_blocked", "Comment was blocked by the content filters: "+verdict.BlockReason)
+			return
+		}
+		commentID, err := f.AddCommentToPost(user, messageID, verdict.Content)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal_error", "Error while sending the comment")
+			return
+		}
+		f.DebugPrintf("Comment %d sent by %s through the api\n", commentID, user.Username)
+		f.ReportFlaggedComment(thread, commentID, messageID, verdict)
+		created, err := f.GetCommentByIDWithPOV(commentID, user)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal_error", "Error while getting the comment")
+			return
+		}
+		writeJSON(w, http.StatusCreated, created)
+		return
+	}
+
+	offset := getOffset(w, r)
+	if offset < 0 {
+		return
+	}
+	cursor, ok := getCursor(w, r, offset)
+	if !ok {
+		return
+	}
+	// The first page also gives the cursor of the next one, so the clients can use either of the paginations
+	var comments []f.FormattedMessageComment
+	var nextCursor string
+	var err error
+	if offset > 0 {
+		comments, err = f.GetCommentsFromMessageWithPOV(messageID, offset, user)
+	} else {
+		comments, nextCursor, err = f.GetCommentsFromMessageAfterCursor(messageID, cursor, user)
+	}
+	if errors.Is(err, f.ErrInvalidCursor) {
+		writeError(w, http.StatusBadRequest, "invalid_cursor", "Cursor is not valid")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "Error while getting the comments")
+		return
+	}
+	writeCursorList(w, comments, offset, cursor, nextCursor)
+}
+
+// Comment handles /api/v1/threads/{threadName}/messages/{messageId}/comments/{commentId}
+// GET returns the comment
+// PATCH edits the comment
+// DELETE deletes the comment
+func Comment(w http.ResponseWriter, r *http.Request) {
+	if !isMethodAllowed(w, r, http.MethodGet, http.MethodPatch, http.MethodDelete) {
+		return
+	}
+	scope := f.ApiTokenScopePost
+	if r.Method == http.MethodGet {
+		scope = f.ApiTokenScopeRead
+	}
+	user, ok := authenticateApiCall(w, r, scope)
+	if !ok {
+		return
+	}
+	thread, ok := getAccessibleThread(w, r, user)
+	if !ok {
+		return
+	}
+	messageID := getRouteMessageID(w, r, thread)
+	if messageID < 0 || !isMessageVisible(w, thread, user, messageID) {
+		return
+	}
+	commentID := getRouteCommentID(w, r, messageID)
+	if commentID < 0 {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPatch:
+		if !f.IsUserAllowedToEditComment(thread, user, commentID) {
+			writeError(w, http.StatusForbidden, "forbidden", "User is not allowed to edit this comment")
+			return
+		}
+		var comment apiNewComment
+		if !decodeBody(w, r, &comment) {
+			return
+		}
+		if !f.IsMessageContentOrCommentContentValid(comment.Content) {
+			writeError(w, http.StatusUnprocessableEntity, "invalid_content", "Comment content is empty or not valid")
+			return
+		}
+		verdict := f.FilterContent(thread, user, "", comment.Content, false)
+		if verdict.IsBlocked {
+			f.DebugPrintf("Edit of the comment %d blocked by the content filters: %s\n", commentID, verdict.BlockReason)
+			writeError(w, http.StatusUnprocessableEntity, "content_blocked", "Comment was blocked by the content filters: "+verdict.BlockReason)
+			return
+		}
+		err := f.EditCommentFromPost(commentID, verdict.Content)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal_error", "Error while editing the comment")
+			return
+		}
+		f.ReportFlaggedComment(thread, commentID, messageID, verdict)
+	case http.MethodDelete:
+		if !f.IsUserAllowedToDeleteComment(thread, user, commentID) {
+			writeError(w, http.StatusForbidden, "forbidden", "User is not allowed to delete this comment")
+			return
+		}
+		authorID, _ := f.GetCommentAuthorID(commentID)
+		comment, _ := f.GetCommentByIDWithPOV(commentID, f.User{})
+		if comment.DeletionState != f.NotDeleted {
+			writeError(w, http.StatusNotFound, "comment_not_found", "Comment was already deleted")
+			return
+		}
+		err := f.RemoveCommentFromPost(commentID, user)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal_error", "Error while deleting the comment")
+			return
+		}
+		f.DebugPrintf("Comment %d deleted by %s through the api\n", commentID, user.Username)
+		if authorID != user.UserID {
+			f.LogModerationAction(thread, user, f.ModerationCommentDeleted, authorID, commentID, comment.CommentContent)
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	comment, err := f.GetCommentByIDWithPOV(commentID, user)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "Error while getting the comment")
+		return
+	}
+	// The comments hidden by their reports are answered as if they did not exist, like on the web pages
+	if !f.CanUserSeeComment(thread, user, comment) {
+		writeError(w, http.StatusNotFound, "comment_not_found", "Comment does not exist on this message")
+		return
+	}
+	writeJSON(w, http.StatusOK, comment)
+}
+
+// CommentVote handles PUT /api/v1/threads/{threadName}/messages/{messageId}/comments/{commentId}/vote
+// Sets the vote of the token owner on the comment
+func CommentVote(w http.ResponseWriter, r *http.Request) {
+	if !isMethodAllowed(w, r, http.MethodPut) {
+		return
+	}
+	user, ok := authenticateApiCall(w, r, f.ApiTokenScopePost)
+	if !ok {
+		return
+	}
+	thread, ok := getAccessibleThread(w, r, user)
+	if !ok {
+		return
+	}
+	messageID := getRouteMessageID(w, r, thread)
+	if messageID < 0 || !isMessageVisible(w, thread, user, messageID) {
+		return
+	}
+	commentID := getRouteCommentID(w, r, messageID)
+	if commentID < 0 || !isMessageOpen(w, messageID) {
+		return
+	}
+	if f.IsCommentDeleted(commentID) {
+		writeError(w, http.StatusNotFound, "comment_not_found", "Comment was deleted")
+		return
+	}
+	var vote apiVote
+	if !decodeBody(w, r, &vote) {
+		return
+	}
+	if vote.Vote < -1 || vote.Vote > 1 {
+		writeError(w, http.StatusUnprocessableEntity, "invalid_vote", "Vote must be 1, 0 or -1")
+		return
+	}
+
+	var err error
+	current := f.HasUserAlreadyVotedOnComment(user, commentID)
+	switch {
+	case current == vote.Vote:
+		// Nothing to change
+	case vote.Vote == 0:
+		err = f.MessageCommentRemoveVote(commentID, user.UserID)
+	case current == 0:
+		err = f.MessageCommentVote(commentID, user.UserID, vote.Vote == 1)
+	default:
+		err = f.MessageCommentUpdateVote(commentID, user.UserID, vote.Vote == 1)
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "Error while voting on the comment")
+		return
+	}
+
+	comment, err := f.GetCommentByIDWithPOV(commentID, user)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "Error while getting the comment")
+		return
+	}
+	writeJSON(w, http.StatusOK, apiVoteState{
+		VoteState: comment.VoteState,
+		Upvotes:   comment.Upvotes,
+		Downvotes: comment.Downvotes,
+	})
+}
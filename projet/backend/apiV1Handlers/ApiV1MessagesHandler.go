@@ -0,0 +1,351 @@
+package apiV1Handlers
+
+import (
+	f "GoForum/functions"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// apiNewMessage is the body expected to create a message
+type apiNewMessage struct {
+	Title   string      `json:"title"`
+	Content string      `json:"content"`
+	Tags    []int       `json:"tags"`
+	Poll    *apiNewPoll `json:"poll"`
+}
+
+// apiNewPoll is the poll attached to a new message
+// The results are always visible if no visibility is given, and the poll never closes if no closing date is given
+type apiNewPoll struct {
+	Options           []string   `json:"options"`
+	MultipleChoice    bool       `json:"multiple_choice"`
+	ResultsVisibility string     `json:"results_visibility"`
+	ClosingDate       *time.Time `json:"closing_date"`
+}
+
+// apiMessageUpdate is the body expected to edit a message, the omitted fields are left unchanged
+type apiMessageUpdate struct {
+	Title   *string `json:"title"`
+	Content *string `json:"content"`
+}
+
+// apiVote is the body expected to vote on a message or a comment
+// 1 is an upvote, -1 a downvote and 0 removes the vote
+type apiVote struct {
+	Vote int `json:"vote"`
+}
+
+// apiVoteState is the representation of the vote of the token owner on a message or a comment
+type apiVoteState struct {
+	VoteState int `json:"vote_state"`
+	Upvotes   int `json:"up_votes"`
+	Downvotes int `json:"down_votes"`
+}
+
+// Messages handles /api/v1/threads/{threadName}/messages
+// GET returns the messages of the thread, paginated with the 'cursor' or 'offset' query parameter and filtered by the 'order' and 'tags' query parameters
+// POST creates a new message in the thread
+func Messages(w http.ResponseWriter, r *http.Request) {
+	if !isMethodAllowed(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+	if r.Method == http.MethodPost {
+		createMessage(w, r)
+		return
+	}
+	user, ok := authenticateApiCall(w, r, f.ApiTokenScopeRead)
+	if !ok {
+		return
+	}
+	thread, ok := getAccessibleThread(w, r, user)
+	if !ok {
+		return
+	}
+	offset := getOffset(w, r)
+	if offset < 0 {
+		return
+	}
+	cursor, ok := getCursor(w, r, offset)
+	if !ok {
+		return
+	}
+	order := r.URL.Query().Get("order")
+	if order == "" {
+		order = "desc"
+	}
+	if !slices.Contains(f.OrderingList, order) {
+		writeError(w, http.StatusBadRequest, "invalid_order", "Order must be one of: "+strings.Join(f.OrderingList, ", "))
+		return
+	}
+
+	// The tags are given as a comma separated list of tag names
+	var tags []f.ThreadTag
+	if strTags := r.URL.Query().Get("tags"); strTags != "" {
+		threadTags, err := f.GetThreadTags(thread)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal_error", "Error while getting the thread tags")
+			return
+		}
+		for _, tagName := range strings.Split(strTags, ",") {
+			for _, threadTag := range threadTags {
+				if threadTag.TagName == strings.TrimSpace(tagName) {
+					tags = append(tags, threadTag)
+				}
+			}
+		}
+	}
+
+	// The first page also gives the cursor of the next one, so the clients can use either of the paginations
+	var messages []f.FormattedThreadMessage
+	var nextCursor string
+	var err error
+	if offset > 0 {
+		messages, err = f.GetMessagesFromThreadWithPOV(thread, offset, order, user, tags)
+	} else {
+		messages, nextCursor, err = f.GetMessagesFromThreadAfterCursor(thread, cursor, order, user, tags)
+	}
+	if errors.Is(err, f.ErrInvalidCursor) {
+		writeError(w, http.StatusBadRequest, "invalid_cursor", "Cursor is not valid for this order")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "Error while getting the messages")
+		return
+	}
+	writeCursorList(w, messages, offset, cursor, nextCursor)
+}
+
+// createMessage handles POST /api/v1/threads/{threadName}/messages
+func createMessage(w http.ResponseWriter, r *http.Request) {
+	user, ok := authenticateApiCall(w, r, f.ApiTokenScopePost)
+	if !ok {
+		return
+	}
+	thread, ok := getAccessibleThread(w, r, user)
+	if !ok {
+		return
+	}
+	var msg apiNewMessage
+	if !decodeBody(w, r, &msg) {
+		return
+	}
+	if !f.IsMessageTitleValid(msg.Title) {
+		writeError(w, http.StatusUnprocessableEntity, "invalid_title", "Message title is empty or not valid")
+		return
+	}
+	if !f.IsMessageContentOrCommentContentValid(msg.Content) {
+		writeError(w, http.StatusUnprocessableEntity, "invalid_content", "Message content is empty or not valid")
+		return
+	}
+	for _, tagID := range msg.Tags {
+		isAssociated, err := f.IsTagIDAssociatedWithThread(thread, tagID)
+		if err != nil || !isAssociated {
+			writeError(w, http.StatusUnprocessableEntity, "invalid_tag", "A given tag does not belong to the thread")
+			return
+		}
+	}
+	if !f.IsUserAllowedToSendMessageInThread(thread, user) {
+		writeError(w, http.StatusForbidden, "forbidden", "User is not allowed to send a message in this thread")
+		return
+	}
+	var poll *f.NewPoll
+	if msg.Poll != nil {
+		poll = &f.NewPoll{
+			Options:           msg.Poll.Options,
+			IsMultipleChoice:  msg.Poll.MultipleChoice,
+			ResultsVisibility: f.PollResultsVisibility(msg.Poll.ResultsVisibility),
+			ClosingDate:       msg.Poll.ClosingDate,
+		}
+		if poll.ResultsVisibility == "" {
+			poll.ResultsVisibility = f.PollResultsAlways
+		}
+		if reason := f.IsNewPollValid(*poll); reason != "" {
+			writeError(w, http.StatusUnprocessableEntity, "invalid_poll", reason)
+			return
+		}
+	}
+
+	verdict := f.FilterContent(thread, user, msg.Title, msg.Content, true)
+	if verdict.IsBlocked {
+		f.DebugPrintf("Message of %s blocked by the content filters: %s\n", user.Username, verdict.BlockReason)
+		writeError(w, http.StatusUnprocessableEntity, "content_blocked", "Message was blocked by the content filters: "+verdict.BlockReason)
+		return
+	}
+
+	messageID, err := f.AddMessageInThread(thread, verdict.Title, verdict.Content, user, nil, msg.Tags, poll)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "Error while sending the message")
+		return
+	}
+	f.DebugPrintf("Message %d sent by %s through the api\n", messageID, user.Username)
+	f.ReportFlaggedMessage(thread, messageID, verdict)
+
+	message, err := f.GetMessageByIDWithPOV(messageID, user)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "Error while getting the message")
+		return
+	}
+	writeJSON(w, http.StatusCreated, message)
+}
+
+// Message handles /api/v1/threads/{threadName}/messages/{messageId}
+// GET returns the message
+// PATCH edits the message
+// DELETE deletes the message
+func Message(w http.ResponseWriter, r *http.Request) {
+	if !isMethodAllowed(w, r, http.MethodGet, http.MethodPatch, http.MethodDelete) {
+		return
+	}
+	scope := f.ApiTokenScopePost
+	if r.Method == http.MethodGet {
+		scope = f.ApiTokenScopeRead
+	}
+	user, ok := authenticateApiCall(w, r, scope)
+	if !ok {
+		return
+	}
+	thread, ok := getAccessibleThread(w, r, user)
+	if !ok {
+		return
+	}
+	messageID := getRouteMessageID(w, r, thread)
+	if messageID < 0 {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPatch:
+		if !f.IsUserAllowedToEditMessageInThread(thread, user, messageID) {
+			writeError(w, http.StatusForbidden, "forbidden", "User is not allowed to edit this message")
+			return
+		}
+		var update apiMessageUpdate
+		if !decodeBody(w, r, &update) {
+			return
+		}
+		current, err := f.GetMessageByID(messageID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal_error", "Error while getting the message")
+			return
+		}
+		if update.Title != nil {
+			if !f.IsMessageTitleValid(*update.Title) {
+				writeError(w, http.StatusUnprocessableEntity, "invalid_title", "Message title is empty or not valid")
+				return
+			}
+			current.MessageTitle = *update.Title
+		}
+		if update.Content != nil {
+			if !f.IsMessageContentOrCommentContentValid(*update.Content) {
+				writeError(w, http.StatusUnprocessableEntity, "invalid_content", "Message content is empty or not valid")
+				return
+			}
+			current.MessageContent = *update.Content
+		}
+		verdict := f.FilterContent(thread, user, current.MessageTitle, current.MessageContent, false)
+		if verdict.IsBlocked {
+			f.DebugPrintf("Edit of the message %d blocked by the content filters: %s\n", messageID, verdict.BlockReason)
+			writeError(w, http.StatusUnprocessableEntity, "content_blocked", "Message was blocked by the content filters: "+verdict.BlockReason)
+			return
+		}
+		err = f.EditMessageFromThread(thread, messageID, verdict.Title, verdict.Content)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal_error", "Error while editing the message")
+			return
+		}
+		f.ReportFlaggedMessage(thread, messageID, verdict)
+	case http.MethodDelete:
+		if !f.IsUserAllowedToDeleteMessage(thread, user, messageID) {
+			writeError(w, http.StatusForbidden, "forbidden", "User is not allowed to delete this message")
+			return
+		}
+		authorID, _ := f.GetMessageAuthorID(messageID)
+		message, _ := f.GetMessageByID(messageID)
+		if message.DeletionState != f.NotDeleted {
+			writeError(w, http.StatusNotFound, "message_not_found", "Message was already deleted")
+			return
+		}
+		err := f.RemoveMessageFromThread(thread, messageID, user)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal_error", "Error while deleting the message")
+			return
+		}
+		f.DebugPrintf("Message %d deleted by %s through the api\n", messageID, user.Username)
+		if authorID != user.UserID {
+			f.LogModerationAction(thread, user, f.ModerationMessageDeleted, authorID, messageID, message.MessageTitle)
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	message, err := f.GetMessageByIDWithPOV(messageID, user)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "Error while getting the message")
+		return
+	}
+	// The messages hidden by their reports or waiting for approval are not shown to everyone
+	if !f.CanUserSeeMessage(thread, user, message) {
+		writeError(w, http.StatusNotFound, "message_not_found", "Message does not exist in this thread")
+		return
+	}
+	writeJSON(w, http.StatusOK, message)
+}
+
+// MessageVote handles PUT /api/v1/threads/{threadName}/messages/{messageId}/vote
+// Sets the vote of the token owner on the message
+func MessageVote(w http.ResponseWriter, r *http.Request) {
+	if !isMethodAllowed(w, r, http.MethodPut) {
+		return
+	}
+	user, ok := authenticateApiCall(w, r, f.ApiTokenScopePost)
+	if !ok {
+		return
+	}
+	thread, ok := getAccessibleThread(w, r, user)
+	if !ok {
+		return
+	}
+	messageID := getRouteMessageID(w, r, thread)
+	if messageID < 0 || !isMessageVisible(w, thread, user, messageID) || !isMessageOpen(w, messageID) {
+		return
+	}
+	var vote apiVote
+	if !decodeBody(w, r, &vote) {
+		return
+	}
+	if vote.Vote < -1 || vote.Vote > 1 {
+		writeError(w, http.StatusUnprocessableEntity, "invalid_vote", "Vote must be 1, 0 or -1")
+		return
+	}
+
+	var err error
+	current := f.HasUserAlreadyVotedOnMessage(user, messageID)
+	switch {
+	case current == vote.Vote:
+		// Nothing to change
+	case vote.Vote == 0:
+		err = f.ThreadMessageRemoveVote(messageID, user.UserID)
+	case current == 0:
+		err = f.ThreadMessageAddVote(messageID, user.UserID, vote.Vote == 1)
+	default:
+		err = f.ThreadMessageUpdateVote(messageID, user.UserID, vote.Vote == 1)
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "Error while voting on the message")
+		return
+	}
+
+	message, err := f.GetMessageByIDWithPOV(messageID, user)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "Error while getting the message")
+		return
+	}
+	writeJSON(w, http.StatusOK, apiVoteState{
+		VoteState: message.VoteState,
+		Upvotes:   message.Upvotes,
+		Downvotes: message.Downvotes,
+	})
+}
@@ -0,0 +1,277 @@
+package apiV1Handlers
+
+import (
+	f "GoForum/functions"
+	"errors"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// apiThreadSummary is the representation of a thread in the thread list
+type apiThreadSummary struct {
+	Name         string    `json:"name"`
+	Description  string    `json:"description"`
+	Category     string    `json:"category"`
+	IconLink     string    `json:"icon_link"`
+	BannerLink   string    `json:"banner_link"`
+	CreationDate time.Time `json:"creation_date"`
+	MemberCount  int       `json:"member_count"`
+	PostCount    int       `json:"post_count"`
+	LastActivity time.Time `json:"last_activity"`
+}
+
+// apiThread is the full representation of a thread
+type apiThread struct {
+	Name                      string    `json:"name"`
+	Description               string    `json:"description"`
+	IconLink                  string    `json:"icon_link"`
+	BannerLink                string    `json:"banner_link"`
+	CreationDate              time.Time `json:"creation_date"`
+	MemberCount               int       `json:"member_count"`
+	IsOpenToNonMembers        bool      `json:"is_open_to_non_members"`
+	IsOpenToNonConnectedUsers bool      `json:"is_open_to_non_connected_users"`
+	JoinPolicy                string    `json:"join_policy"`
+	IsPrivate                 bool      `json:"is_private"`
+}
+
+// apiMembership is the representation of the membership of the token owner in a thread
+type apiMembership struct {
+	IsMember           bool                 `json:"is_member"`
+	Rank               int                  `json:"rank"`
+	Permissions        []f.ThreadPermission `json:"permissions"` // The permissions given by the rank and the custom role of the member
+	HasRequestedToJoin bool                 `json:"has_requested_to_join"`
+}
+
+// apiMe is the representation of the owner of the token
+type apiMe struct {
+	Username     string    `json:"username"`
+	CreationDate time.Time `json:"creation_date"`
+	Threads      []string  `json:"threads"`
+}
+
+// Me handles GET /api/v1/me
+// Returns the owner of the personal access token and the threads he is a member of
+func Me(w http.ResponseWriter, r *http.Request) {
+	if !isMethodAllowed(w, r, http.MethodGet) {
+		return
+	}
+	user, ok := authenticateApiCall(w, r, f.ApiTokenScopeRead)
+	if !ok {
+		return
+	}
+	me := apiMe{
+		Username:     user.Username,
+		CreationDate: user.CreatedAt,
+		Threads:      []string{},
+	}
+	for _, thread := range f.GetUserThreads(user) {
+		me.Threads = append(me.Threads, thread.ThreadName)
+	}
+	writeJSON(w, http.StatusOK, me)
+}
+
+// ThreadsList handles GET /api/v1/threads?q={search}&category={category}&sort={sort}&offset={offset}
+// Returns a page of the threads of the forum with their stats, the sort defaults to 'trending'
+func ThreadsList(w http.ResponseWriter, r *http.Request) {
+	if !isMethodAllowed(w, r, http.MethodGet) {
+		return
+	}
+	user, ok := authenticateApiCall(w, r, f.ApiTokenScopeRead)
+	if !ok {
+		return
+	}
+	offset := getOffset(w, r)
+	if offset < 0 {
+		return
+	}
+	options := f.ThreadDiscoveryOptions{
+		Search:   r.URL.Query().Get("q"),
+		Category: r.URL.Query().Get("category"),
+		Sort:     r.URL.Query().Get("sort"),
+		User:     user,
+		Offset:   offset,
+	}
+	if options.Category != "" && !f.IsThreadCategoryValid(options.Category) {
+		writeError(w, http.StatusBadRequest, "invalid_category", "Category must be one of: "+strings.Join(f.ThreadCategories, ", "))
+		return
+	}
+	if options.Sort == "" {
+		options.Sort = f.ThreadDiscoverySorts[0]
+	}
+	if !f.IsThreadDiscoverySortValid(options.Sort) {
+		writeError(w, http.StatusBadRequest, "invalid_sort", "Sort must be one of: "+strings.Join(f.ThreadDiscoverySorts, ", "))
+		return
+	}
+	formattedThreads, _, err := f.GetDiscoverableThreads(options)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "Error while getting the threads")
+		return
+	}
+	threads := []apiThreadSummary{}
+	for _, thread := range formattedThreads {
+		threads = append(threads, apiThreadSummary{
+			Name:         thread.ThreadName,
+			Description:  thread.ThreadDescription,
+			Category:     thread.ThreadCategory,
+			IconLink:     "/upload/" + thread.ThreadIconLink,
+			BannerLink:   "/upload/" + thread.ThreadBannerLink,
+			CreationDate: thread.CreationDate,
+			MemberCount:  thread.MemberCount,
+			PostCount:    thread.PostCount,
+			LastActivity: thread.LastActivity,
+		})
+	}
+	writeList(w, threads, offset)
+}
+
+// Thread handles GET /api/v1/threads/{threadName}
+// Returns the details of the thread
+func Thread(w http.ResponseWriter, r *http.Request) {
+	if !isMethodAllowed(w, r, http.MethodGet) {
+		return
+	}
+	user, ok := authenticateApiCall(w, r, f.ApiTokenScopeRead)
+	if !ok {
+		return
+	}
+	thread, ok := getAccessibleThread(w, r, user)
+	if !ok {
+		return
+	}
+	threadConfig := f.GetThreadConfigFromThread(thread)
+	memberCount, err := f.GetThreadMemberCount(thread)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "Error while getting the thread members")
+		return
+	}
+	writeJSON(w, http.StatusOK, apiThread{
+		Name:                      thread.ThreadName,
+		Description:               threadConfig.ThreadDescription,
+		IconLink:                  "/upload/" + f.GetMediaLinkFromID(threadConfig.ThreadIconID).MediaAddress,
+		BannerLink:                "/upload/" + f.GetMediaLinkFromID(threadConfig.ThreadBannerID).MediaAddress,
+		CreationDate:              thread.CreationDate,
+		MemberCount:               memberCount,
+		IsOpenToNonMembers:        threadConfig.IsOpenToNonMembers,
+		IsOpenToNonConnectedUsers: threadConfig.IsOpenToNonConnectedUsers,
+		JoinPolicy:                string(threadConfig.JoinPolicy),
+		IsPrivate:                 threadConfig.IsPrivate,
+	})
+}
+
+// ThreadTags handles GET /api/v1/threads/{threadName}/tags
+// Returns the tags of the thread
+func ThreadTags(w http.ResponseWriter, r *http.Request) {
+	if !isMethodAllowed(w, r, http.MethodGet) {
+		return
+	}
+	user, ok := authenticateApiCall(w, r, f.ApiTokenScopeRead)
+	if !ok {
+		return
+	}
+	thread, ok := getAccessibleThread(w, r, user)
+	if !ok {
+		return
+	}
+	tags, err := f.GetThreadTags(thread)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "Error while getting the thread tags")
+		return
+	}
+	writeList(w, tags, 0)
+}
+
+// Membership handles /api/v1/threads/{threadName}/membership
+// GET returns the membership of the token owner
+// PUT makes the token owner join the thread following its join policy, the optional 'invite' query parameter is the code of an invite link
+// DELETE makes the token owner leave the thread
+func Membership(w http.ResponseWriter, r *http.Request) {
+	if !isMethodAllowed(w, r, http.MethodGet, http.MethodPut, http.MethodDelete) {
+		return
+	}
+	scope := f.ApiTokenScopePost
+	if r.Method == http.MethodGet {
+		scope = f.ApiTokenScopeRead
+	}
+	user, ok := authenticateApiCall(w, r, scope)
+	if !ok {
+		return
+	}
+	thread, ok := getThreadForMembership(w, r, user)
+	if !ok {
+		return
+	}
+
+	status := http.StatusOK
+	switch r.Method {
+	case http.MethodPut:
+		if f.IsUserInThread(thread, user) {
+			writeError(w, http.StatusConflict, "already_member", "User is already in the thread")
+			return
+		}
+		joined, err := f.JoinThreadWithPolicy(thread, user, r.URL.Query().Get("invite"))
+		switch {
+		case errors.Is(err, f.ErrInviteOnlyThread):
+			writeError(w, http.StatusForbidden, "invite_only", "This thread can only be joined with an invite")
+			return
+		case errors.Is(err, f.ErrInvalidInvite):
+			writeError(w, http.StatusBadRequest, "invalid_invite", "The invite is not valid anymore")
+			return
+		case errors.Is(err, f.ErrJoinAlreadyRequested):
+			writeError(w, http.StatusConflict, "already_requested", "User already requested to join the thread")
+			return
+		case err != nil:
+			writeError(w, http.StatusInternalServerError, "internal_error", "Error while joining the thread")
+			return
+		}
+		// The join request waits for the review of the admins of the thread
+		if !joined {
+			status = http.StatusAccepted
+		}
+		f.DebugPrintf("User %s joined or requested to join the thread %s through the api\n", user.Username, thread.ThreadName)
+	case http.MethodDelete:
+		if !f.IsUserInThread(thread, user) {
+			writeError(w, http.StatusConflict, "not_member", "User is not in the thread")
+			return
+		}
+		// The owner can't leave his own thread
+		if f.IsThreadOwner(thread, user) {
+			writeError(w, http.StatusConflict, "owner_cannot_leave", "The owner of the thread can't leave it")
+			return
+		}
+		err := f.LeaveThread(thread, user)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal_error", "Error while leaving the thread")
+			return
+		}
+		f.DebugPrintf("User %s left the thread %s through the api\n", user.Username, thread.ThreadName)
+	}
+
+	permissions := f.GetUserThreadPermissions(thread, user)
+	if permissions == nil {
+		permissions = []f.ThreadPermission{}
+	}
+	writeJSON(w, status, apiMembership{
+		IsMember:           f.IsUserInThread(thread, user),
+		Rank:               f.GetUserRankInThread(thread, user),
+		Permissions:        permissions,
+		HasRequestedToJoin: f.HasUserRequestedToJoin(thread, user),
+	})
+}
+
+// getThreadForMembership returns the thread from the route
+// Unlike getAccessibleThread, a private thread can be targeted by a non member (he needs to join it first)
+func getThreadForMembership(w http.ResponseWriter, r *http.Request, user f.User) (f.ThreadGoForum, bool) {
+	threadName := mux.Vars(r)["threadName"]
+	if threadName == "" || !f.CheckIfThreadNameExists(threadName) {
+		writeError(w, http.StatusNotFound, "thread_not_found", "Thread does not exist")
+		return f.ThreadGoForum{}, false
+	}
+	thread := f.GetThreadFromName(threadName)
+	if f.GetUserRankInThread(thread, user) < 0 {
+		writeError(w, http.StatusForbidden, "banned", "User is banned from the thread")
+		return f.ThreadGoForum{}, false
+	}
+	return thread, true
+}
@@ -0,0 +1,253 @@
+package apiV1Handlers
+
+import (
+	f "GoForum/functions"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// apiError is the body returned by every endpoint of the public api when an error occurs
+// e.g. {"error":{"status":404,"code":"thread_not_found","message":"Thread does not exist"}}
+type apiError struct {
+	Error apiErrorContent `json:"error"`
+}
+
+type apiErrorContent struct {
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// apiList is the body returned by the endpoints returning a paginated list
+// NextOffset is nil when there is nothing left to load
+// NextCursor is only given by the lists that can be paginated with a cursor, it is omitted when there is nothing left to load
+type apiList struct {
+	Data       interface{} `json:"data"`
+	NextOffset *int        `json:"next_offset"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// writeJSON writes the given content as JSON with the given status code
+func writeJSON(w http.ResponseWriter, status int, content interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(content)
+	if err != nil {
+		f.ErrorPrintf("Error while writing the api response: %v\n", err)
+	}
+}
+
+// writeError writes a JSON error with the given status code, error code and message
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	writeJSON(w, status, apiError{
+		Error: apiErrorContent{
+			Status:  status,
+			Code:    code,
+			Message: message,
+		},
+	})
+}
+
+// writeList writes a paginated list, the next offset is computed from the given offset and the number of items
+func writeList[T any](w http.ResponseWriter, items []T, offset int) {
+	var nextOffset *int
+	if len(items) > 0 {
+		next := offset + len(items)
+		nextOffset = &next
+	}
+	if items == nil {
+		items = []T{}
+	}
+	writeJSON(w, http.StatusOK, apiList{
+		Data:       items,
+		NextOffset: nextOffset,
+	})
+}
+
+// writeCursorList writes a list paginated with a cursor, the next offset is only given when the list was not loaded with a cursor
+func writeCursorList[T any](w http.ResponseWriter, items []T, offset int, cursor string, nextCursor string) {
+	var nextOffset *int
+	if len(items) > 0 && cursor == "" {
+		next := offset + len(items)
+		nextOffset = &next
+	}
+	if items == nil {
+		items = []T{}
+	}
+	writeJSON(w, http.StatusOK, apiList{
+		Data:       items,
+		NextOffset: nextOffset,
+		NextCursor: nextCursor,
+	})
+}
+
+// NotFound is the handler used when no route of the public api matches the request
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotFound, "not_found", "This endpoint does not exist")
+}
+
+// isMethodAllowed checks if the method of the request is one of the given methods
+// Otherwise it writes the error (with the 'Allow' header) and returns false
+func isMethodAllowed(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	if slices.Contains(methods, r.Method) {
+		return true
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "This method is not allowed on this endpoint")
+	return false
+}
+
+// OpenApiDocument serves the OpenAPI document describing the public api
+func OpenApiDocument(w http.ResponseWriter, r *http.Request) {
+	if !isMethodAllowed(w, r, http.MethodGet) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	http.ServeFile(w, r, "statics/api/openapi.yaml")
+}
+
+// authenticateApiCall checks the personal access token given in the 'Authorization' header
+// The header must be of the form 'Authorization: Bearer <token>'
+// Returns the owner of the token and true if the token is valid and has the given scope
+// Otherwise it writes the error and returns false
+func authenticateApiCall(w http.ResponseWriter, r *http.Request, scope f.ApiTokenScope) (f.User, bool) {
+	header := r.Header.Get("Authorization")
+	if header == "" || !strings.HasPrefix(header, "Bearer ") {
+		f.DebugPrintf("Api call without personal access token\n")
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, "missing_token", "A personal access token is required")
+		return f.User{}, false
+	}
+	user, token, err := f.GetUserFromApiToken(strings.TrimSpace(strings.TrimPrefix(header, "Bearer ")))
+	if err != nil {
+		f.DebugPrintf("Api call with an invalid personal access token\n")
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, "invalid_token", "The personal access token is invalid or was revoked")
+		return f.User{}, false
+	}
+	if !user.EmailVerified {
+		f.DebugPrintf("Api call from the unverified user %s\n", user.Username)
+		writeError(w, http.StatusForbidden, "user_not_verified", "The owner of the token has not verified his email address")
+		return f.User{}, false
+	}
+	if !token.HasScope(scope) {
+		f.DebugPrintf("Api call from %s with a token missing the '%s' scope\n", user.Username, scope)
+		writeError(w, http.StatusForbidden, "insufficient_scope", "The personal access token does not have the '"+string(scope)+"' scope")
+		return f.User{}, false
+	}
+	return user, true
+}
+
+// getAccessibleThread returns the thread from the 'threadName' route variable
+// It checks that the thread exists and that the user is allowed to see its content (same rules as the thread page)
+// Otherwise it writes the error and returns false
+func getAccessibleThread(w http.ResponseWriter, r *http.Request, user f.User) (f.ThreadGoForum, bool) {
+	threadName := mux.Vars(r)["threadName"]
+	if threadName == "" || !f.CheckIfThreadNameExists(threadName) {
+		f.DebugPrintf("Thread \"%s\" does not exist\n", threadName)
+		writeError(w, http.StatusNotFound, "thread_not_found", "Thread does not exist")
+		return f.ThreadGoForum{}, false
+	}
+	thread := f.GetThreadFromName(threadName)
+	if f.GetUserRankInThread(thread, user) < 0 {
+		f.DebugPrintf("User %s is banned from the thread %s\n", user.Username, threadName)
+		writeError(w, http.StatusForbidden, "banned", "User is banned from the thread")
+		return f.ThreadGoForum{}, false
+	}
+	threadConfig := f.GetThreadConfigFromThread(thread)
+	if !threadConfig.IsOpenToNonMembers && !f.IsUserInThread(thread, user) {
+		f.DebugPrintf("User %s is not a member of the private thread %s\n", user.Username, threadName)
+		writeError(w, http.StatusForbidden, "members_only", "Only the members of the thread can access its content")
+		return f.ThreadGoForum{}, false
+	}
+	return thread, true
+}
+
+// getRouteMessageID returns the message id from the 'messageId' route variable
+// It checks that the message exists in the given thread
+// Otherwise it writes the error and returns -1
+func getRouteMessageID(w http.ResponseWriter, r *http.Request, thread f.ThreadGoForum) int {
+	messageID, err := strconv.Atoi(mux.Vars(r)["messageId"])
+	if err != nil || messageID < 1 || !f.MessageExistsInThread(thread, messageID) {
+		writeError(w, http.StatusNotFound, "message_not_found", "Message does not exist in this thread")
+		return -1
+	}
+	return messageID
+}
+
+// getRouteCommentID returns the comment id from the 'commentId' route variable
+// It checks that the comment exists on the given message
+// Otherwise it writes the error and returns -1
+func getRouteCommentID(w http.ResponseWriter, r *http.Request, messageID int) int {
+	commentID, err := strconv.Atoi(mux.Vars(r)["commentId"])
+	if err != nil || commentID < 1 || !f.CommentExistsOnMessage(messageID, commentID) {
+		writeError(w, http.StatusNotFound, "comment_not_found", "Comment does not exist on this message")
+		return -1
+	}
+	return commentID
+}
+
+// isMessageOpen checks that the message can still be commented and voted
+// Otherwise it writes the error and returns false
+func isMessageOpen(w http.ResponseWriter, messageID int) bool {
+	if f.IsMessageLocked(messageID) {
+		writeError(w, http.StatusForbidden, "message_locked", "Message is locked")
+		return false
+	}
+	return true
+}
+
+// isMessageVisible checks that the message is not deleted and that the user can see it (see f.CanUserSeeMessage)
+// The hidden messages and the ones waiting for approval are answered as if they did not exist, like on the web pages
+// Otherwise it writes the error and returns false
+func isMessageVisible(w http.ResponseWriter, thread f.ThreadGoForum, user f.User, messageID int) bool {
+	message, err := f.GetMessageByIDWithPOV(messageID, user)
+	if err != nil || message.DeletionState != f.NotDeleted || !f.CanUserSeeMessage(thread, user, message) {
+		writeError(w, http.StatusNotFound, "message_not_found", "Message does not exist in this thread")
+		return false
+	}
+	return true
+}
+
+// getOffset returns the 'offset' query parameter (0 if not given)
+// Writes the error and returns -1 if the offset is not a positive number
+func getOffset(w http.ResponseWriter, r *http.Request) int {
+	strOffset := r.URL.Query().Get("offset")
+	if strOffset == "" {
+		return 0
+	}
+	offset, err := strconv.Atoi(strOffset)
+	if err != nil || offset < 0 {
+		writeError(w, http.StatusBadRequest, "invalid_offset", "Offset must be a positive number")
+		return -1
+	}
+	return offset
+}
+
+// getCursor returns the 'cursor' query parameter of the request, it cannot be used with a non-zero offset
+// Writes the error and returns false if both are given
+func getCursor(w http.ResponseWriter, r *http.Request, offset int) (string, bool) {
+	cursor := r.URL.Query().Get("cursor")
+	if cursor != "" && offset > 0 {
+		writeError(w, http.StatusBadRequest, "invalid_cursor", "Cursor and offset cannot be used together")
+		return "", false
+	}
+	return cursor, true
+}
+
+// decodeBody decodes the JSON body of the request in the given struct
+// Writes the error and returns false if the body is not valid JSON
+func decodeBody(w http.ResponseWriter, r *http.Request, content interface{}) bool {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(content); err != nil {
+		f.DebugPrintf("Error while decoding the api JSON body: %v\n", err)
+		writeError(w, http.StatusBadRequest, "invalid_body", "The request body is not valid JSON for this endpoint")
+		return false
+	}
+	return true
+}
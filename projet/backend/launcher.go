@@ -2,6 +2,7 @@ package backend
 
 import (
 	"GoForum/backend/apiPageHandlers"
+	"GoForum/backend/apiV1Handlers"
 	"GoForum/backend/pagesHandlers"
 	f "GoForum/functions"
 	"fmt"
@@ -37,6 +38,12 @@ func LaunchWebApp() {
 	// Initialize the database
 	f.InitDatabaseConnection()
 
+	// Initialize the global word filters
+	f.InitGlobalWordFilters()
+
+	// Initialize the badges that can be awarded
+	f.InitBadges()
+
 	// Gestion de l'arrêt de l'application web via le terminal
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
@@ -51,14 +58,32 @@ func LaunchWebApp() {
 	}()
 
 	// Managing the program arguments
-	f.AddNoValueArg("debug", "d") // Argument to enable the debug mode
-	f.AddNoValueArg("log", "l")   // Argument to enable the log mode
+	f.AddNoValueArg("debug", "d")           // Argument to enable the debug mode
+	f.AddNoValueArg("log", "l")             // Argument to enable the log mode
+	f.AddNoValueArg("recompute-reputation") // Argument to recompute the reputation of every user from the votes instead of running the web app
+	f.AddNoValueArg("award-badges")         // Argument to award the badges every user reached (e.g. after adding badges) instead of running the web app
 	if isPresent, err := f.GetArgNoValue("debug", "d"); isPresent && err == nil {
 		f.SetShouldLogDebug(true)
 	}
 	if isPresent, err := f.GetArgNoValue("log", "l"); isPresent && err == nil {
 		f.InitLogger()
 	}
+	if isPresent, err := f.GetArgNoValue("recompute-reputation"); isPresent && err == nil {
+		err = f.RecomputeAllReputation()
+		f.CloseDatabase()
+		if err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if isPresent, err := f.GetArgNoValue("award-badges"); isPresent && err == nil {
+		err = f.AwardAllBadges()
+		f.CloseDatabase()
+		if err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
 	finalPort := fmt.Sprintf(":%s", strconv.Itoa(getPort()))
 
 	// Setting up the rate limiter
@@ -80,10 +105,13 @@ func LaunchWebApp() {
 
 	// Handle the routes
 	r.HandleFunc("/", pagesHandlers.HomePage).Methods("GET", "POST")
+	r.HandleFunc("/feed.atom", apiPageHandlers.GlobalFeedHandler).Methods("GET")
 	r.HandleFunc("/register", pagesHandlers.RegisterPage).Methods("GET", "POST")
 	r.HandleFunc("/auth/callback/{provider}", pagesHandlers.CallbackRedirection).Methods("GET", "POST")
 	r.HandleFunc("/profile", pagesHandlers.UserSelfProfilePage).Methods("GET", "POST")
+	r.HandleFunc("/profile/saved", pagesHandlers.UserSavedPage).Methods("GET", "POST")
 	r.HandleFunc("/profile/{user}", pagesHandlers.UserOtherProfilePage).Methods("GET", "POST")
+	r.HandleFunc("/profile/{user}/feed.atom", apiPageHandlers.UserFeedHandler).Methods("GET")
 	r.HandleFunc("/settings", pagesHandlers.UserSettingsPage).Methods("GET", "POST")
 	r.HandleFunc("/reset-password", pagesHandlers.ResetPasswordPage).Methods("GET", "POST")
 	r.HandleFunc("/confirm-email-address", pagesHandlers.ConfirmMailPage).Methods("GET", "POST")
@@ -92,13 +120,23 @@ func LaunchWebApp() {
 	r.HandleFunc("/t/{threadName}/edit", pagesHandlers.ThreadEditPage).Methods("GET", "POST")
 	r.HandleFunc("/t/{threadName}/p/{post}", pagesHandlers.ThreadPostPage).Methods("GET", "POST")
 	r.HandleFunc("/t/{threadName}/reports", pagesHandlers.ThreadReportsPage).Methods("GET", "POST")
+	r.HandleFunc("/t/{threadName}/modlog", pagesHandlers.ThreadModLogPage).Methods("GET")
+	r.HandleFunc("/t/{threadName}/queue", pagesHandlers.ThreadQueuePage).Methods("GET")
+	r.HandleFunc("/t/{threadName}/invite/{inviteCode}", pagesHandlers.ThreadInvitePage).Methods("GET")
+	r.HandleFunc("/t/{threadName}/feed.atom", apiPageHandlers.ThreadFeedHandler).Methods("GET")
+	r.HandleFunc("/t/{threadName}/events", apiPageHandlers.ThreadEventsHandler).Methods("GET")
 	r.HandleFunc("/tnm", pagesHandlers.ThreadSendMessagePage).Methods("GET", "POST")
 	r.HandleFunc("/api/messages", apiPageHandlers.ThreadMessageGetter).Methods("GET")
 	r.HandleFunc("/api/comments", apiPageHandlers.MessageCommentGetter).Methods("GET")
+	r.HandleFunc("/api/messages/{messageId}/revisions", apiPageHandlers.MessageRevisionsGetter).Methods("GET")
+	r.HandleFunc("/api/comments/{commentId}/revisions", apiPageHandlers.CommentRevisionsGetter).Methods("GET")
 	r.HandleFunc("/api/threadTags", apiPageHandlers.ThreadTagsGetterHandler).Methods("GET")
 	r.HandleFunc("/api/thread/{threadName}/{action}", apiPageHandlers.ThreadContentHandler).Methods("POST")
 	r.HandleFunc("/api/upload/{type}", apiPageHandlers.ImgUploader).Methods("POST")
 
+	// Handle the public api routes (authenticated with personal access tokens)
+	addApiV1Routes(r)
+
 	// Handle error 404 & 405
 	r.NotFoundHandler = http.HandlerFunc(pagesHandlers.ErrorPage404)
 	r.MethodNotAllowedHandler = http.HandlerFunc(pagesHandlers.ErrorPage405)
@@ -144,3 +182,22 @@ func getPort() int {
 	}
 	return port
 }
+
+// addApiV1Routes handles the routes of the public api (/api/v1) on the router
+// The allowed methods are checked by the handlers themselves to always answer with a JSON error
+func addApiV1Routes(r *mux.Router) {
+	v1 := r.PathPrefix("/api/v1").Subrouter()
+	v1.HandleFunc("/openapi.yaml", apiV1Handlers.OpenApiDocument)
+	v1.HandleFunc("/me", apiV1Handlers.Me)
+	v1.HandleFunc("/threads", apiV1Handlers.ThreadsList)
+	v1.HandleFunc("/threads/{threadName}", apiV1Handlers.Thread)
+	v1.HandleFunc("/threads/{threadName}/tags", apiV1Handlers.ThreadTags)
+	v1.HandleFunc("/threads/{threadName}/membership", apiV1Handlers.Membership)
+	v1.HandleFunc("/threads/{threadName}/messages", apiV1Handlers.Messages)
+	v1.HandleFunc("/threads/{threadName}/messages/{messageId:[0-9]+}", apiV1Handlers.Message)
+	v1.HandleFunc("/threads/{threadName}/messages/{messageId:[0-9]+}/vote", apiV1Handlers.MessageVote)
+	v1.HandleFunc("/threads/{threadName}/messages/{messageId:[0-9]+}/comments", apiV1Handlers.Comments)
+	v1.HandleFunc("/threads/{threadName}/messages/{messageId:[0-9]+}/comments/{commentId:[0-9]+}", apiV1Handlers.Comment)
+	v1.HandleFunc("/threads/{threadName}/messages/{messageId:[0-9]+}/comments/{commentId:[0-9]+}/vote", apiV1Handlers.CommentVote)
+	v1.NotFoundHandler = http.HandlerFunc(apiV1Handlers.NotFound)
+}
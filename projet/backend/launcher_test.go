@@ -0,0 +1,374 @@
+package backend
+
+import (
+	f "GoForum/functions"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gopkg.in/yaml.v3"
+)
+
+// apiCase is a request made to the public api by TestApiV1MatchesOpenApiDocument
+// The '{messageId}' and '{commentId}' parts of the path are replaced by the ids captured by the previous cases
+type apiCase struct {
+	method  string
+	path    string
+	token   string
+	body    string
+	status  int
+	capture string // Variable of the path set to the id of the created item, 'messageId' or 'commentId'
+}
+
+// capturedFields are the fields of the responses holding the ids captured by the cases
+var capturedFields = map[string]string{
+	"messageId": "message_id",
+	"commentId": "comment_id",
+}
+
+// openApiDocument is the OpenAPI document decoded as generic values
+type openApiDocument map[string]interface{}
+
+// routeVariableRegex matches the regular expression of a variable of a route, e.g. ':[0-9]+' in '{messageId:[0-9]+}'
+var routeVariableRegex = regexp.MustCompile(`:[^}]+}`)
+
+// setupApiTestDatabase opens a new empty database for the test from the root of the project, it is closed at the end of the test
+func setupApiTestDatabase(t *testing.T) {
+	t.Helper()
+	workingDirectory, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getting the working directory: %v", err)
+	}
+	// The default media files and the OpenAPI document are read from the root of the project
+	err = os.Chdir("..")
+	if err != nil {
+		t.Fatalf("moving to the root of the project: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(workingDirectory) })
+	t.Setenv("UPLOAD_FOLDER", t.TempDir())
+	t.Setenv("DB_NAME", filepath.Join(t.TempDir(), "test.db"))
+	f.InitUploadsDirectory()
+	f.InitDatabaseConnection()
+	t.Cleanup(f.CloseDatabase)
+}
+
+// newApiTestUser adds a user and creates a personal access token with the given scopes for him
+func newApiTestUser(t *testing.T, username string, verified bool, scopes ...f.ApiTokenScope) (f.User, string) {
+	t.Helper()
+	email := username + "@example.com"
+	err := f.AddUser(email, username, "Test", "User", "Password123!")
+	if err != nil {
+		t.Fatalf("adding the user %s: %v", username, err)
+	}
+	if verified {
+		err = f.VerifyEmail(email)
+		if err != nil {
+			t.Fatalf("verifying the user %s: %v", username, err)
+		}
+	}
+	user, err := f.GetUserFromUsername(username)
+	if err != nil {
+		t.Fatalf("getting the user %s: %v", username, err)
+	}
+	token, err := f.CreateApiToken(user, "test", scopes)
+	if err != nil {
+		t.Fatalf("creating the token of %s: %v", username, err)
+	}
+	return user, token
+}
+
+// loadOpenApiDocument reads the OpenAPI document served by the public api
+func loadOpenApiDocument(t *testing.T) openApiDocument {
+	t.Helper()
+	content, err := os.ReadFile("statics/api/openapi.yaml")
+	if err != nil {
+		t.Fatalf("reading the OpenAPI document: %v", err)
+	}
+	// The document is decoded in a plain map, so its nested objects are plain maps too
+	var document map[string]interface{}
+	err = yaml.Unmarshal(content, &document)
+	if err != nil {
+		t.Fatalf("decoding the OpenAPI document: %v", err)
+	}
+	return document
+}
+
+// resolve follows the '$ref' of the object, e.g. '#/components/schemas/Error'
+func (document openApiDocument) resolve(object map[string]interface{}) map[string]interface{} {
+	ref, ok := object["$ref"].(string)
+	if !ok {
+		return object
+	}
+	var current interface{} = map[string]interface{}(document)
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		current = current.(map[string]interface{})[part]
+	}
+	return document.resolve(current.(map[string]interface{}))
+}
+
+// operation returns the operation of the document for the path and the method, nil if it is not documented
+func (document openApiDocument) operation(specPath string, method string) map[string]interface{} {
+	pathItem, ok := document["paths"].(map[string]interface{})[specPath].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	operation, _ := pathItem[strings.ToLower(method)].(map[string]interface{})
+	return operation
+}
+
+// validate checks that the value decoded from JSON matches the schema
+// The objects of the responses cannot have properties missing from their schema, so the document stays complete
+// The schemas of an 'allOf' are not closed, the properties are checked against all of them
+// Returns the list of the differences, empty if the value matches
+func (document openApiDocument) validate(schema map[string]interface{}, value interface{}, location string, closed bool) []string {
+	schema = document.resolve(schema)
+	if value == nil && schema["nullable"] == true {
+		return nil
+	}
+	var errors []string
+	for _, subSchema := range asList(schema["allOf"]) {
+		errors = append(errors, document.validate(subSchema.(map[string]interface{}), value, location, false)...)
+	}
+	if object, ok := value.(map[string]interface{}); ok && closed && schema["allOf"] != nil {
+		for name := range object {
+			if !document.hasProperty(schema, name) {
+				errors = append(errors, fmt.Sprintf("%s has the undocumented property %s", location, name))
+			}
+		}
+	}
+	if value == nil {
+		if schema["type"] != nil {
+			errors = append(errors, location+" is null but not nullable")
+		}
+		return errors
+	}
+	if enum := asList(schema["enum"]); enum != nil && !slices.ContainsFunc(enum, func(v interface{}) bool { return fmt.Sprint(v) == fmt.Sprint(value) }) {
+		errors = append(errors, fmt.Sprintf("%s is %v, not one of %v", location, value, enum))
+	}
+
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return append(errors, location+" is not an object")
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for _, required := range asList(schema["required"]) {
+			if _, ok := object[required.(string)]; !ok {
+				errors = append(errors, fmt.Sprintf("%s misses the required property %s", location, required))
+			}
+		}
+		for name, propertyValue := range object {
+			propertySchema, ok := properties[name].(map[string]interface{})
+			if !ok {
+				// The maps (e.g. the names of a badge by language) describe their values with 'additionalProperties'
+				propertySchema, ok = schema["additionalProperties"].(map[string]interface{})
+			}
+			if !ok {
+				if closed {
+					errors = append(errors, fmt.Sprintf("%s has the undocumented property %s", location, name))
+				}
+				continue
+			}
+			errors = append(errors, document.validate(propertySchema, propertyValue, location+"."+name, true)...)
+		}
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return append(errors, location+" is not an array")
+		}
+		items, _ := schema["items"].(map[string]interface{})
+		for i, item := range array {
+			errors = append(errors, document.validate(items, item, location+"["+strconv.Itoa(i)+"]", true)...)
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return append(errors, location+" is not a string")
+		}
+		if _, err := time.Parse(time.RFC3339Nano, str); schema["format"] == "date-time" && err != nil {
+			errors = append(errors, location+" is not a date-time")
+		}
+	case "integer":
+		if number, ok := value.(float64); !ok || number != float64(int64(number)) {
+			errors = append(errors, location+" is not an integer")
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			errors = append(errors, location+" is not a number")
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			errors = append(errors, location+" is not a boolean")
+		}
+	}
+	return errors
+}
+
+// hasProperty checks if the property is documented by the schema or one of the schemas of its 'allOf'
+func (document openApiDocument) hasProperty(schema map[string]interface{}, name string) bool {
+	schema = document.resolve(schema)
+	if properties, ok := schema["properties"].(map[string]interface{}); ok && properties[name] != nil {
+		return true
+	}
+	return slices.ContainsFunc(asList(schema["allOf"]), func(subSchema interface{}) bool {
+		return document.hasProperty(subSchema.(map[string]interface{}), name)
+	})
+}
+
+// asList returns the value as a list, nil if it is not one
+func asList(value interface{}) []interface{} {
+	list, _ := value.([]interface{})
+	return list
+}
+
+// TestApiV1MatchesOpenApiDocument makes requests to every operation of the public api through its router
+// Each answer must have a status code documented for the operation and a body matching the documented schema
+func TestApiV1MatchesOpenApiDocument(t *testing.T) {
+	setupApiTestDatabase(t)
+	document := loadOpenApiDocument(t)
+	router := mux.NewRouter()
+	addApiV1Routes(router)
+
+	owner, ownerToken := newApiTestUser(t, "owner", true, f.ApiTokenScopeRead, f.ApiTokenScopePost)
+	_, memberToken := newApiTestUser(t, "member", true, f.ApiTokenScopeRead, f.ApiTokenScopePost)
+	_, readerToken := newApiTestUser(t, "reader", true, f.ApiTokenScopeRead)
+	_, unverifiedToken := newApiTestUser(t, "unverified", false, f.ApiTokenScopeRead)
+	if err := f.AddThread(owner, "openapi", "A thread used by the tests", f.DefaultThreadCategory); err != nil {
+		t.Fatalf("adding the thread: %v", err)
+	}
+	if err := f.AddThreadTag(f.GetThreadFromName("openapi"), "news", "#ff0000"); err != nil {
+		t.Fatalf("adding the tag: %v", err)
+	}
+
+	thread := "/api/v1/threads/openapi"
+	message := thread + "/messages/{messageId}"
+	comment := message + "/comments/{commentId}"
+	cases := []apiCase{
+		{method: http.MethodGet, path: "/api/v1/me", token: ownerToken, status: http.StatusOK},
+		{method: http.MethodGet, path: "/api/v1/me", status: http.StatusUnauthorized},
+		{method: http.MethodGet, path: "/api/v1/me", token: unverifiedToken, status: http.StatusForbidden},
+		{method: http.MethodGet, path: "/api/v1/threads", token: ownerToken, status: http.StatusOK},
+		{method: http.MethodGet, path: "/api/v1/threads?sort=nope", token: ownerToken, status: http.StatusBadRequest},
+		{method: http.MethodGet, path: thread, token: ownerToken, status: http.StatusOK},
+		{method: http.MethodGet, path: "/api/v1/threads/nothing", token: ownerToken, status: http.StatusNotFound},
+		{method: http.MethodGet, path: thread + "/tags", token: ownerToken, status: http.StatusOK},
+		{method: http.MethodGet, path: thread + "/membership", token: ownerToken, status: http.StatusOK},
+		{method: http.MethodPut, path: thread + "/membership", token: memberToken, status: http.StatusOK},
+		{method: http.MethodPut, path: thread + "/membership", token: memberToken, status: http.StatusConflict},
+		{method: http.MethodPut, path: thread + "/membership", token: readerToken, status: http.StatusForbidden},
+		{method: http.MethodPost, path: thread + "/messages", token: ownerToken, body: `{"title":"Hello world","content":"Some content here","tags":[1]}`, status: http.StatusCreated, capture: "messageId"},
+		{method: http.MethodPost, path: thread + "/messages", token: ownerToken, body: `{"title":"","content":"Some content here"}`, status: http.StatusUnprocessableEntity},
+		{method: http.MethodPost, path: thread + "/messages", token: ownerToken, body: `{"title":"A poll here","content":"Which option is best?","poll":{"options":["Yes","No"],"multiple_choice":true,"closing_date":"2999-01-01T00:00:00Z"}}`, status: http.StatusCreated},
+		{method: http.MethodPost, path: thread + "/messages", token: ownerToken, body: `{"title":"A poll here","content":"Some content here","poll":{"options":["Yes","Yes"]}}`, status: http.StatusUnprocessableEntity},
+		{method: http.MethodPost, path: thread + "/messages", token: ownerToken, body: `{"title":`, status: http.StatusBadRequest},
+		{method: http.MethodGet, path: thread + "/messages", token: memberToken, status: http.StatusOK},
+		{method: http.MethodGet, path: thread + "/messages?order=nope", token: memberToken, status: http.StatusBadRequest},
+		{method: http.MethodGet, path: message, token: memberToken, status: http.StatusOK},
+		{method: http.MethodGet, path: thread + "/messages/999", token: memberToken, status: http.StatusNotFound},
+		{method: http.MethodPatch, path: message, token: ownerToken, body: `{"title":"Edited title"}`, status: http.StatusOK},
+		{method: http.MethodPatch, path: message, token: ownerToken, body: `{"content":""}`, status: http.StatusUnprocessableEntity},
+		{method: http.MethodPatch, path: message, token: memberToken, body: `{"title":"Edited title"}`, status: http.StatusForbidden},
+		{method: http.MethodPut, path: message + "/vote", token: memberToken, body: `{"vote":1}`, status: http.StatusOK},
+		{method: http.MethodPut, path: message + "/vote", token: memberToken, body: `{"vote":2}`, status: http.StatusUnprocessableEntity},
+		{method: http.MethodPost, path: message + "/comments", token: memberToken, body: `{"content":"A comment on the message"}`, status: http.StatusCreated, capture: "commentId"},
+		{method: http.MethodPost, path: message + "/comments", token: memberToken, body: `{"content":""}`, status: http.StatusUnprocessableEntity},
+		{method: http.MethodGet, path: message + "/comments", token: ownerToken, status: http.StatusOK},
+		{method: http.MethodGet, path: comment, token: ownerToken, status: http.StatusOK},
+		{method: http.MethodGet, path: message + "/comments/999", token: ownerToken, status: http.StatusNotFound},
+		{method: http.MethodPatch, path: comment, token: memberToken, body: `{"content":"An edited comment"}`, status: http.StatusOK},
+		{method: http.MethodPut, path: comment + "/vote", token: ownerToken, body: `{"vote":-1}`, status: http.StatusOK},
+		{method: http.MethodDelete, path: comment, token: memberToken, status: http.StatusNoContent},
+		{method: http.MethodDelete, path: message, token: ownerToken, status: http.StatusNoContent},
+		{method: http.MethodDelete, path: thread + "/membership", token: memberToken, status: http.StatusOK},
+		{method: http.MethodDelete, path: thread + "/membership", token: memberToken, status: http.StatusConflict},
+	}
+
+	ids := map[string]string{}
+	tested := map[string]bool{}
+	for _, c := range cases {
+		path := c.path
+		for name, id := range ids {
+			path = strings.ReplaceAll(path, "{"+name+"}", id)
+		}
+		name := c.method + " " + path
+		req := httptest.NewRequest(c.method, path, strings.NewReader(c.body))
+		req.Header.Set("Content-Type", "application/json")
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+
+		// The documented path is the template of the route, without the regular expressions of its variables
+		var match mux.RouteMatch
+		if !router.Match(req, &match) || match.Route == nil {
+			t.Errorf("%s: no route matches", name)
+			continue
+		}
+		template, _ := match.Route.GetPathTemplate()
+		specPath := strings.TrimPrefix(routeVariableRegex.ReplaceAllString(template, "}"), "/api/v1")
+		operation := document.operation(specPath, c.method)
+		if operation == nil {
+			t.Errorf("%s: %s %s is not documented", name, c.method, specPath)
+			continue
+		}
+		tested[c.method+" "+specPath] = true
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		if recorder.Code != c.status {
+			t.Errorf("%s: status %d, expected %d (%s)", name, recorder.Code, c.status, recorder.Body.String())
+			continue
+		}
+		response, ok := operation["responses"].(map[string]interface{})[strconv.Itoa(recorder.Code)].(map[string]interface{})
+		if !ok {
+			t.Errorf("%s: status %d is not documented", name, recorder.Code)
+			continue
+		}
+		response = document.resolve(response)
+		content, hasContent := response["content"].(map[string]interface{})
+		if !hasContent {
+			if recorder.Body.Len() > 0 {
+				t.Errorf("%s: body %s, expected none", name, recorder.Body.String())
+			}
+			continue
+		}
+		if contentType := recorder.Header().Get("Content-Type"); contentType != "application/json" {
+			t.Errorf("%s: content type %q, expected application/json", name, contentType)
+		}
+		var body interface{}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+			t.Errorf("%s: body is not JSON: %v", name, err)
+			continue
+		}
+		schema := content["application/json"].(map[string]interface{})["schema"].(map[string]interface{})
+		for _, difference := range document.validate(schema, body, "body", true) {
+			t.Errorf("%s: %s", name, difference)
+		}
+		if c.capture != "" {
+			id, _ := body.(map[string]interface{})[capturedFields[c.capture]].(float64)
+			ids[c.capture] = strconv.Itoa(int(id))
+		}
+	}
+
+	// Every documented operation must be tested
+	for specPath, pathItem := range document["paths"].(map[string]interface{}) {
+		for method := range pathItem.(map[string]interface{}) {
+			if method == "parameters" {
+				continue
+			}
+			if !tested[strings.ToUpper(method)+" "+specPath] {
+				t.Errorf("%s %s is documented but not tested", strings.ToUpper(method), specPath)
+			}
+		}
+	}
+}
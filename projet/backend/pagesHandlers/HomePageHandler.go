@@ -4,8 +4,12 @@ import (
 	f "GoForum/functions"
 	"fmt"
 	"net/http"
+	"strconv"
 )
 
+// followingFeedLimit is the number of entries of the 'Following' feed shown on the home page
+const followingFeedLimit = 20
+
 func HomePage(w http.ResponseWriter, r *http.Request) {
 	PageInfo := f.NewContentInterface("home", r)
 	// Check the user rights
@@ -37,7 +41,65 @@ func HomePage(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	PageInfo["AllThreads"] = f.GetAllFormattedThreads()
+	// The thread list is filtered, sorted and paginated with the parameters of the search form
+	query := r.URL.Query()
+	options := f.ThreadDiscoveryOptions{
+		Search:   query.Get("q"),
+		Category: query.Get("category"),
+		Sort:     query.Get("sort"),
+	}
+	if !f.IsThreadCategoryValid(options.Category) {
+		options.Category = ""
+	}
+	// The 'joined' sort is only available to the connected users
+	if !f.IsThreadDiscoverySortValid(options.Sort) || (options.Sort == "joined" && !PageInfo["IsAuthenticated"].(bool)) {
+		options.Sort = f.ThreadDiscoverySorts[0]
+	}
+	if PageInfo["IsAuthenticated"].(bool) {
+		options.User = f.GetUser(r)
+	}
+	page, err := strconv.Atoi(query.Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	options.Offset = (page - 1) * f.ThreadDiscoveryPageSize
+	threads, hasNextPage, err := f.GetDiscoverableThreads(options)
+	if err != nil {
+		threads, hasNextPage = []f.FormattedThread{}, false
+	}
+	PageInfo["AllThreads"] = threads
+	PageInfo["ThreadSearch"] = options.Search
+	PageInfo["ThreadCategory"] = options.Category
+	PageInfo["ThreadSort"] = options.Sort
+	PageInfo["ThreadCategories"] = f.ThreadCategories
+	PageInfo["ThreadSorts"] = f.ThreadDiscoverySorts
+	PageInfo["ThreadPage"] = page
+	PageInfo["PreviousThreadPageURL"] = ""
+	PageInfo["NextThreadPageURL"] = ""
+	if page > 1 {
+		query.Set("page", strconv.Itoa(page-1))
+		PageInfo["PreviousThreadPageURL"] = "/?" + query.Encode()
+	}
+	if hasNextPage {
+		query.Set("page", strconv.Itoa(page+1))
+		PageInfo["NextThreadPageURL"] = "/?" + query.Encode()
+	}
+	PageInfo["FeedURL"] = "/feed.atom"
+
+	// The 'Following' feed is only shown to the verified users
+	PageInfo["FollowedThreads"] = []f.FollowedThread{}
+	PageInfo["FollowingFeed"] = []f.FollowingFeedItem{}
+	if PageInfo["IsAuthenticated"].(bool) {
+		user := f.GetUser(r)
+		followedThreads, err := f.GetFollowedThreads(user)
+		if err == nil {
+			PageInfo["FollowedThreads"] = followedThreads
+		}
+		followingFeed, err := f.GetFollowingFeed(user, followingFeedLimit)
+		if err == nil {
+			PageInfo["FollowingFeed"] = followingFeed
+		}
+	}
 
 	// Add additional styles to the content interface and make the template
 	f.AddAdditionalStylesToContentInterface(&PageInfo, "css/home.css")
@@ -29,7 +29,9 @@ func ThreadCreationPage(w http.ResponseWriter, r *http.Request) {
 
 	PageInfo["NameNotValid"] = false
 	PageInfo["DescriptionNotValid"] = false
+	PageInfo["CategoryNotValid"] = false
 	PageInfo["ErrorCreationThread"] = false
+	PageInfo["ThreadCategories"] = f.ThreadCategories
 
 	// Handle the thread creation form
 	if r.Method == "POST" {
@@ -39,13 +41,20 @@ func ThreadCreationPage(w http.ResponseWriter, r *http.Request) {
 			// Get the form values
 			threadName := r.FormValue("thread_name")
 			threadDescription := r.FormValue("thread_description")
+			threadCategory := r.FormValue("thread_category")
 
 			// Check if the thread name is valid
 			if f.IsThreadNameValid(threadName) {
 				// Check if the thread description is valid
-				if f.IsThreadDescriptionValid(threadDescription) {
+				if !f.IsThreadDescriptionValid(threadDescription) {
+					f.DebugPrintf("Thread description not valid : %s\n", threadDescription)
+					PageInfo["DescriptionNotValid"] = true
+				} else if !f.IsThreadCategoryValid(threadCategory) {
+					f.DebugPrintf("Thread category not valid : %s\n", threadCategory)
+					PageInfo["CategoryNotValid"] = true
+				} else {
 					// Create the thread
-					err := f.AddThread(f.GetUser(r), threadName, threadDescription)
+					err := f.AddThread(f.GetUser(r), threadName, threadDescription, threadCategory)
 					if err != nil {
 						f.ErrorPrintf("Error creating the thread : %s\n", err)
 						PageInfo["ErrorCreationThread"] = true
@@ -54,9 +63,6 @@ func ThreadCreationPage(w http.ResponseWriter, r *http.Request) {
 						// Redirect to the thread page
 						http.Redirect(w, r, fmt.Sprintf("/t/%s", threadName), http.StatusFound)
 					}
-				} else {
-					f.DebugPrintf("Thread description not valid : %s\n", threadDescription)
-					PageInfo["DescriptionNotValid"] = true
 				}
 			} else {
 				f.DebugPrintf("Thread name not valid : %s\n", threadName)
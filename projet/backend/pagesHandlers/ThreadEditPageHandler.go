@@ -7,6 +7,18 @@ import (
 	"net/http"
 )
 
+// threadWebhookWithDeliveries is a webhook of the thread along with its last delivery attempts
+type threadWebhookWithDeliveries struct {
+	Webhook    f.ThreadWebhook
+	Deliveries []f.WebhookDelivery
+}
+
+// threadDefaultRole is a rank of the thread along with the permissions it gives
+type threadDefaultRole struct {
+	Rank        int
+	Permissions []f.ThreadPermission
+}
+
 func ThreadEditPage(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	threadName := vars["threadName"]
@@ -41,9 +53,11 @@ func ThreadEditPage(w http.ResponseWriter, r *http.Request) {
 	threadConfig := f.GetThreadConfigFromThread(thread)
 	user := f.GetUser(r)
 
-	// If not the thread owner, redirect to the thread page
-	if thread.OwnerID != user.UserID {
-		f.DebugPrintf("User is not the owner of the thread he's trying to edit\n")
+	// If not allowed to manage the thread nor its tags, redirect to the thread page
+	PageInfo["CanManageThread"] = f.HasThreadPermission(thread, user, f.PermissionManageThread)
+	PageInfo["CanManageRoles"] = f.HasThreadPermission(thread, user, f.PermissionManageRoles)
+	if !PageInfo["CanManageThread"].(bool) && !f.HasThreadPermission(thread, user, f.PermissionManageTags) {
+		f.DebugPrintf("User is not allowed to edit the thread\n")
 		http.Redirect(w, r, fmt.Sprintf("/t/%s", threadName), http.StatusFound)
 		return
 	}
@@ -51,6 +65,57 @@ func ThreadEditPage(w http.ResponseWriter, r *http.Request) {
 	PageInfo["ErrorEditingThread"] = false
 	PageInfo["ThreadIconPath"] = f.GetMediaLinkFromID(threadConfig.ThreadIconID).MediaAddress
 	PageInfo["ThreadBannerPath"] = f.GetMediaLinkFromID(threadConfig.ThreadBannerID).MediaAddress
+	PageInfo["AutoHideReportThreshold"] = threadConfig.AutoHideReportThreshold
+	PageInfo["ApprovalMinPosts"] = threadConfig.ApprovalMinPosts
+	PageInfo["ApprovalMinDays"] = threadConfig.ApprovalMinDays
+	PageInfo["MinReputationImages"] = threadConfig.MinReputationImages
+	PageInfo["MinReputationTags"] = threadConfig.MinReputationTags
+	PageInfo["MinReputationReports"] = threadConfig.MinReputationReports
+	PageInfo["ThreadCategory"] = threadConfig.ThreadCategory
+	PageInfo["ThreadCategories"] = f.ThreadCategories
+	PageInfo["JoinPolicy"] = threadConfig.JoinPolicy
+	PageInfo["JoinPolicies"] = f.ThreadJoinPolicies
+	PageInfo["IsPrivate"] = threadConfig.IsPrivate
+
+	// Get the custom roles of the thread
+	threadRoles, err := f.GetThreadRoles(thread)
+	if err != nil {
+		f.ErrorPrintf("Error getting the roles of the thread : %s\n", err)
+	}
+	PageInfo["ThreadRoles"] = threadRoles
+	PageInfo["AssignablePermissions"] = f.AssignableThreadPermissions
+	var defaultRoles []threadDefaultRole
+	for _, rank := range []int{f.ThreadRankUser, f.ThreadRankModerator, f.ThreadRankAdmin, f.ThreadRankOwner} {
+		defaultRoles = append(defaultRoles, threadDefaultRole{Rank: rank, Permissions: f.DefaultThreadRoles[rank]})
+	}
+	PageInfo["DefaultRoles"] = defaultRoles
+
+	// Get the webhooks of the thread with their last 10 deliveries
+	var webhooksWithDeliveries []threadWebhookWithDeliveries
+	webhooks, err := f.GetThreadWebhooks(thread)
+	if err != nil {
+		f.ErrorPrintf("Error getting the webhooks of the thread : %s\n", err)
+	}
+	for _, webhook := range webhooks {
+		deliveries, err := f.GetWebhookDeliveries(webhook.WebhookID, 10)
+		if err != nil {
+			f.ErrorPrintf("Error getting the deliveries of the webhook : %s\n", err)
+		}
+		webhooksWithDeliveries = append(webhooksWithDeliveries, threadWebhookWithDeliveries{
+			Webhook:    webhook,
+			Deliveries: deliveries,
+		})
+	}
+	PageInfo["Webhooks"] = webhooksWithDeliveries
+	PageInfo["WebhookEvents"] = f.GetWebhookEventsAsStrings()
+
+	// Get the word filters of the thread
+	wordFilters, err := f.GetThreadWordFilters(thread)
+	if err != nil {
+		f.ErrorPrintf("Error getting the word filters of the thread : %s\n", err)
+	}
+	PageInfo["WordFilters"] = wordFilters
+	PageInfo["WordFilterActions"] = f.GetWordFilterActionsAsStrings()
 
 	// Handle the thread edit form
 	if r.Method == "POST" {
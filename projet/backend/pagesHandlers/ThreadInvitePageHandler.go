@@ -0,0 +1,61 @@
+package pagesHandlers
+
+import (
+	f "GoForum/functions"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/url"
+)
+
+// ThreadInvitePage makes the user join the thread with the invite link, then redirects him to the thread
+// The invite works whatever the join policy of the thread, as long as it has not expired nor reached its maximum number of uses
+func ThreadInvitePage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	threadName := vars["threadName"]
+	inviteCode := vars["inviteCode"]
+	if f.IsAuthenticated(r) {
+		// If the user is not verified, redirect him to the verify page
+		if !f.IsUserVerified(r) {
+			f.InfoPrintf("Thread invite page accessed at %s by unverified : %s\n", f.GetIP(r), f.GetUserEmail(r))
+			http.Redirect(w, r, "/confirmMail", http.StatusFound)
+			return
+		}
+		f.InfoPrintf("Thread invite page accessed at %s by verified : %s\n", f.GetIP(r), f.GetUserEmail(r))
+	} else {
+		// If not authenticated, redirect to the login page
+		f.InfoPrintf("Thread invite page accessed at %s\n", f.GetIP(r))
+		RedirectToLogin(w, r)
+		return
+	}
+
+	// Check if the thread name is empty or does not exist
+	if threadName == "" || !f.CheckIfThreadNameExists(threadName) {
+		f.DebugPrintf("Thread name is empty or does not exist : %s\n", threadName)
+		ErrorPage404(w, r)
+		return
+	}
+
+	thread := f.GetThreadFromName(threadName)
+	user := f.GetUser(r)
+	threadURL := fmt.Sprintf("/t/%s", url.PathEscape(threadName))
+	// The members (and the banned users) do not use the invite
+	if f.IsUserInThread(thread, user) {
+		http.Redirect(w, r, threadURL, http.StatusFound)
+		return
+	}
+
+	_, err := f.JoinThreadWithPolicy(thread, user, inviteCode)
+	if errors.Is(err, f.ErrInvalidInvite) {
+		f.DebugPrintf("Invalid invite used to join the thread %s : %s\n", threadName, inviteCode)
+		ErrorPage404(w, r)
+		return
+	}
+	if err != nil {
+		f.ErrorPrintf("Error while joining the thread %s with an invite : %s\n", threadName, err)
+		ErrorPage500(w, r)
+		return
+	}
+	http.Redirect(w, r, threadURL, http.StatusFound)
+}
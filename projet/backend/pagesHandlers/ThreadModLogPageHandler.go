@@ -0,0 +1,139 @@
+package pagesHandlers
+
+import (
+	f "GoForum/functions"
+	"encoding/csv"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// modLogDateLayout is the layout of the dates used to filter the moderation log (as sent by the date inputs)
+const modLogDateLayout = "2006-01-02"
+
+// ThreadModLogPage shows the moderation log of a thread to the users allowed to see it
+// The log can be filtered with the "action", "moderator", "target", "from" and "to" query parameters
+// With "format=csv" the filtered log is downloaded as a CSV file instead
+func ThreadModLogPage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	threadName := vars["threadName"]
+	PageInfo := f.NewContentInterface("thread_modlog", r)
+	// Check the user rights
+	f.GiveUserHisRights(&PageInfo, r)
+	if PageInfo["IsAuthenticated"].(bool) {
+		// If the user is not verified, redirect him to the verify page
+		if !PageInfo["IsAddressVerified"].(bool) {
+			f.InfoPrintf("Thread moderation log page accessed at %s by unverified : %s\n", f.GetIP(r), f.GetUserEmail(r))
+			http.Redirect(w, r, "/confirmMail", http.StatusFound)
+			return
+		}
+		if !f.HasThreadPermission(f.GetThreadFromName(threadName), f.GetUser(r), f.PermissionViewModLog) {
+			f.InfoPrintf("Thread moderation log page accessed at %s by verified non admin : %s\n", f.GetIP(r), f.GetUserEmail(r))
+			ErrorPage403(w, r) // Forbidden access
+			return
+		}
+		f.InfoPrintf("Thread moderation log page accessed at %s by verified admin : %s\n", f.GetIP(r), f.GetUserEmail(r))
+	} else {
+		// If not authenticated, redirect to the login page
+		f.InfoPrintf("Thread moderation log page accessed at %s\n", f.GetIP(r))
+		RedirectToLogin(w, r)
+		return
+	}
+
+	// Check if the thread name is empty or does not exist
+	if threadName == "" || !f.CheckIfThreadNameExists(threadName) {
+		f.DebugPrintf("Thread name is empty or does not exist : %s\n", threadName)
+		ErrorPage404(w, r)
+		return
+	}
+
+	thread := f.GetThreadFromName(threadName)
+
+	// Get the filters, the invalid ones are ignored
+	query := r.URL.Query()
+	filter := f.ModerationLogFilter{
+		ModeratorName: query.Get("moderator"),
+		TargetName:    query.Get("target"),
+	}
+	if action, err := f.GetModerationActionFromString(query.Get("action")); err == nil {
+		filter.Action = action
+	}
+	if from, err := time.Parse(modLogDateLayout, query.Get("from")); err == nil {
+		filter.From = from
+	}
+	if to, err := time.Parse(modLogDateLayout, query.Get("to")); err == nil {
+		// The last day is included
+		filter.To = to.AddDate(0, 0, 1)
+	}
+
+	entries, err := f.GetModerationLog(thread, filter)
+	if err != nil {
+		f.ErrorPrintf("Error while getting the moderation log for thread %s : %s\n", threadName, err)
+		ErrorPage500(w, r)
+		return
+	}
+
+	if query.Get("format") == "csv" {
+		writeModLogCSV(w, thread, entries)
+		return
+	}
+
+	// Handle the user logout/login
+	ConnectFromHeader(w, r, &PageInfo)
+
+	PageInfo["ThreadName"] = threadName
+	PageInfo["ModLog"] = entries
+	PageInfo["ModerationActions"] = f.GetModerationActionsAsStrings()
+	PageInfo["Filter"] = map[string]string{
+		"action":    string(filter.Action),
+		"moderator": filter.ModeratorName,
+		"target":    filter.TargetName,
+		"from":      query.Get("from"),
+		"to":        query.Get("to"),
+	}
+	exportQuery := r.URL.Query()
+	exportQuery.Set("format", "csv")
+	PageInfo["ExportQuery"] = exportQuery.Encode()
+
+	// Add additional styles to the content interface and make the template
+	f.AddAdditionalStylesToContentInterface(&PageInfo, "/css/threadReports.css", "/css/threadModLog.css")
+	f.MakeTemplateAndExecute(w, PageInfo, "templates/threadModLog.html")
+}
+
+// writeModLogCSV writes the moderation log entries as a CSV file in the response
+func writeModLogCSV(w http.ResponseWriter, thread f.ThreadGoForum, entries []f.ModerationLogEntry) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", "attachment; filename=\"modlog-"+strconv.Itoa(thread.ThreadID)+".csv\"")
+	w.WriteHeader(http.StatusOK)
+	writer := csv.NewWriter(w)
+	records := [][]string{{"log_id", "date", "moderator", "action", "target_user", "target_id", "details"}}
+	for _, entry := range entries {
+		targetID := ""
+		if entry.TargetID > 0 {
+			targetID = strconv.Itoa(entry.TargetID)
+		}
+		records = append(records, []string{
+			strconv.Itoa(entry.LogID),
+			entry.LogDate.UTC().Format(time.RFC3339),
+			escapeCSVFormula(entry.ModeratorName),
+			string(entry.Action),
+			escapeCSVFormula(entry.TargetName),
+			targetID,
+			escapeCSVFormula(entry.Details),
+		})
+	}
+	err := writer.WriteAll(records)
+	if err != nil {
+		f.ErrorPrintf("Error while writing the moderation log as CSV : %s\n", err)
+	}
+}
+
+// escapeCSVFormula prevents the spreadsheet programs from reading a user provided value as a formula
+func escapeCSVFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -4,6 +4,8 @@ import (
 	f "GoForum/functions"
 	"github.com/gorilla/mux"
 	"net/http"
+	"net/url"
+	"slices"
 )
 
 func ThreadPage(w http.ResponseWriter, r *http.Request) {
@@ -43,12 +45,19 @@ func ThreadPage(w http.ResponseWriter, r *http.Request) {
 	if (user != f.User{}) {
 		userRank := f.GetUserRankInThread(thread, user)
 		if userRank < 0 { // If the user is banned from the thread we show him the YOU ARE BANNED page
-			f.MakeTemplateAndExecute(w, PageInfo, "templates/youAreBanned.html")
+			ShowBannedPage(w, PageInfo, thread, user)
 			return
 		}
 		PageInfo["Username"] = user.Username
 		PageInfo["UserRank"] = userRank
 	}
+	// The permissions of the user decide which moderation options are shown
+	userPermissions := f.GetUserThreadPermissions(thread, user)
+	PageInfo["UserPermissions"] = userPermissions
+	PageInfo["CanBanUsers"] = slices.Contains(userPermissions, f.PermissionBanUsers)
+	PageInfo["CanManageReports"] = slices.Contains(userPermissions, f.PermissionManageReports)
+	PageInfo["CanSeeHiddenContent"] = slices.Contains(userPermissions, f.PermissionSeeHiddenContent)
+	PageInfo["CanEditThread"] = slices.Contains(userPermissions, f.PermissionManageThread) || slices.Contains(userPermissions, f.PermissionManageTags)
 
 	threadConfig := f.GetThreadConfigFromThread(thread)
 	threadIcon := f.GetMediaLinkFromID(threadConfig.ThreadIconID).MediaAddress
@@ -70,6 +79,9 @@ func ThreadPage(w http.ResponseWriter, r *http.Request) {
 	PageInfo["ThreadIcon"] = threadIcon
 	PageInfo["ThreadBanner"] = threadBanner
 	PageInfo["IsAMember"] = false
+	PageInfo["HasRequestedToJoin"] = f.HasUserRequestedToJoin(thread, user)
+	PageInfo["Subscription"] = f.Subscription{}
+	PageInfo["LastReadMessageID"] = 0
 
 	// If the user is not verified and this thread does not accept non-connected users, do not display the thread and open the login popup
 	if !threadConfig.IsOpenToNonConnectedUsers && !PageInfo["IsAuthenticated"].(bool) {
@@ -84,6 +96,26 @@ func ThreadPage(w http.ResponseWriter, r *http.Request) {
 		PageInfo["ShowContent"] = true
 		PageInfo["IsAMember"] = f.IsThreadMember(thread, r)
 
+		// Keep the previous read position to highlight the new messages, then move it to the last message
+		if PageInfo["IsAuthenticated"].(bool) {
+			subscription, err := f.GetThreadSubscription(thread, user)
+			if err == nil {
+				PageInfo["Subscription"] = subscription
+			}
+			PageInfo["LastReadMessageID"] = f.GetThreadLastReadMessageID(thread, user)
+			_ = f.MarkThreadAsRead(thread, user)
+		}
+
+		// The feed of a thread not open to everyone contains the feed token of the user
+		PageInfo["FeedURL"] = "/t/" + url.PathEscape(threadName) + "/feed.atom"
+		if !threadConfig.IsOpenToNonConnectedUsers || !threadConfig.IsOpenToNonMembers {
+			feedToken, err := f.GetUserFeedToken(user)
+			if err != nil {
+				PageInfo["FeedURL"] = ""
+			} else {
+				PageInfo["FeedURL"] = PageInfo["FeedURL"].(string) + "?token=" + feedToken
+			}
+		}
 	}
 	// Add the thread moderation team to the page
 	PageInfo["ThreadModerationTeam"] = f.GetThreadModerationTeam(thread)
@@ -96,3 +128,12 @@ func ThreadPage(w http.ResponseWriter, r *http.Request) {
 	f.AddAdditionalScriptsToContentInterface(&PageInfo, "/js/threadScript.js", "/js/threadPageScript.js", "/js/imgUploaderScript.js")
 	f.MakeTemplateAndExecute(w, PageInfo, "templates/thread.html")
 }
+
+// ShowBannedPage shows the YOU ARE BANNED page with the reason and the end of the ban
+func ShowBannedPage(w http.ResponseWriter, PageInfo map[string]interface{}, thread f.ThreadGoForum, user f.User) {
+	ban, err := f.GetActiveThreadBan(thread, user)
+	if err == nil {
+		PageInfo["Ban"] = ban
+	}
+	f.MakeTemplateAndExecute(w, PageInfo, "templates/youAreBanned.html")
+}
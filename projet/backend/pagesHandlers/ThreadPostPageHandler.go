@@ -4,6 +4,7 @@ import (
 	f "GoForum/functions"
 	"github.com/gorilla/mux"
 	"net/http"
+	"slices"
 	"strconv"
 )
 
@@ -44,12 +45,19 @@ func ThreadPostPage(w http.ResponseWriter, r *http.Request) {
 	if (user != f.User{}) {
 		userRank := f.GetUserRankInThread(thread, user)
 		if userRank < 0 { // If the user is banned from the thread we show him the YOU ARE BANNED page
-			f.MakeTemplateAndExecute(w, PageInfo, "templates/youAreBanned.html")
+			ShowBannedPage(w, PageInfo, thread, user)
 			return
 		}
 		PageInfo["Username"] = user.Username
 		PageInfo["UserRank"] = userRank
 	}
+	// The permissions of the user decide which moderation options are shown
+	userPermissions := f.GetUserThreadPermissions(thread, user)
+	PageInfo["UserPermissions"] = userPermissions
+	PageInfo["CanBanUsers"] = slices.Contains(userPermissions, f.PermissionBanUsers)
+	PageInfo["CanManageReports"] = slices.Contains(userPermissions, f.PermissionManageReports)
+	PageInfo["CanSeeHiddenContent"] = slices.Contains(userPermissions, f.PermissionSeeHiddenContent)
+	PageInfo["CanEditThread"] = slices.Contains(userPermissions, f.PermissionManageThread) || slices.Contains(userPermissions, f.PermissionManageTags)
 
 	// Check if the post MessageID is empty or does not exist
 	if postID == "" {
@@ -83,7 +91,21 @@ func ThreadPostPage(w http.ResponseWriter, r *http.Request) {
 		ErrorPage404(w, r)
 		return
 	}
+	// The posts hidden by their reports or waiting for approval are not shown to everyone
+	if !f.CanUserSeeMessage(thread, user, post) {
+		f.DebugPrintf("Post \"%s\" is hidden or waiting for approval\n", postID)
+		ErrorPage404(w, r)
+		return
+	}
 	PageInfo["Post"] = post
+	PageInfo["Subscription"] = f.Subscription{}
+	if PageInfo["IsAddressVerified"].(bool) {
+		subscription, err := f.GetMessageSubscription(postIDInt, user)
+		if err == nil {
+			PageInfo["Subscription"] = subscription
+		}
+		_ = f.MarkMessageAsRead(postIDInt, user)
+	}
 	PageInfo["ThreadName"] = threadName
 	PageInfo["ReportReasons"] = f.GetReportTypesAsStrings()
 
@@ -0,0 +1,82 @@
+package pagesHandlers
+
+import (
+	f "GoForum/functions"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+// ThreadQueuePage shows the approval queue and the join requests of the thread to the users allowed to review them
+func ThreadQueuePage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	threadName := vars["threadName"]
+	PageInfo := f.NewContentInterface("thread_queue", r)
+	// Check the user rights
+	f.GiveUserHisRights(&PageInfo, r)
+	if PageInfo["IsAuthenticated"].(bool) {
+		// If the user is not verified, redirect him to the verify page
+		if !PageInfo["IsAddressVerified"].(bool) {
+			f.InfoPrintf("Thread queue page accessed at %s by unverified : %s\n", f.GetIP(r), f.GetUserEmail(r))
+			http.Redirect(w, r, "/confirmMail", http.StatusFound)
+			return
+		}
+		thread := f.GetThreadFromName(threadName)
+		if !f.HasThreadPermission(thread, f.GetUser(r), f.PermissionApproveMessages) && !f.HasThreadPermission(thread, f.GetUser(r), f.PermissionManageAccess) {
+			f.InfoPrintf("Thread queue page accessed at %s by verified non moderation team member : %s\n", f.GetIP(r), f.GetUserEmail(r))
+			ErrorPage403(w, r) // Forbidden access
+			return
+		}
+		f.InfoPrintf("Thread queue page accessed at %s by verified moderation team member : %s\n", f.GetIP(r), f.GetUserEmail(r))
+	} else {
+		// If not authenticated, redirect to the login page
+		f.InfoPrintf("Thread queue page accessed at %s\n", f.GetIP(r))
+		RedirectToLogin(w, r)
+		return
+	}
+
+	// Check if the thread name is empty or does not exist
+	if threadName == "" || !f.CheckIfThreadNameExists(threadName) {
+		f.DebugPrintf("Thread name is empty or does not exist : %s\n", threadName)
+		ErrorPage404(w, r)
+		return
+	}
+
+	// Handle the user logout/login
+	ConnectFromHeader(w, r, &PageInfo)
+
+	thread := f.GetThreadFromName(threadName)
+	PageInfo["ThreadName"] = threadName
+
+	// The approval queue is shown to the users allowed to approve the messages
+	PageInfo["CanApproveMessages"] = f.HasThreadPermission(thread, f.GetUser(r), f.PermissionApproveMessages)
+	PageInfo["PendingMessages"] = []f.FormattedThreadMessage{}
+	if PageInfo["CanApproveMessages"].(bool) {
+		pendingMessages, err := f.GetPendingMessagesInThread(thread)
+		if err != nil {
+			f.ErrorPrintf("Error while getting the pending messages for thread %s : %s\n", threadName, err)
+			ErrorPage404(w, r)
+			return
+		}
+		PageInfo["PendingMessages"] = pendingMessages
+	}
+
+	// The join requests and the invite links are shown to the users allowed to manage the access to the thread
+	PageInfo["CanManageAccess"] = f.HasThreadPermission(thread, f.GetUser(r), f.PermissionManageAccess)
+	PageInfo["JoinRequests"] = []f.JoinRequest{}
+	PageInfo["ThreadInvites"] = []f.ThreadInvite{}
+	if PageInfo["CanManageAccess"].(bool) {
+		joinRequests, err := f.GetJoinRequests(thread)
+		if err == nil {
+			PageInfo["JoinRequests"] = joinRequests
+		}
+		threadInvites, err := f.GetThreadInvites(thread)
+		if err == nil {
+			PageInfo["ThreadInvites"] = threadInvites
+		}
+	}
+
+	// Add additional styles to the content interface and make the template
+	f.AddAdditionalStylesToContentInterface(&PageInfo, "/css/threadReports.css")
+	f.AddAdditionalScriptsToContentInterface(&PageInfo, "/js/threadScript.js", "/js/threadQueue.js")
+	f.MakeTemplateAndExecute(w, PageInfo, "templates/threadQueue.html")
+}
@@ -19,7 +19,7 @@ func ThreadReportsPage(w http.ResponseWriter, r *http.Request) {
 			http.Redirect(w, r, "/confirmMail", http.StatusFound)
 			return
 		}
-		if !(f.GetUserRankInThread(f.GetThreadFromName(threadName), f.GetUser(r)) >= 1) {
+		if !f.HasThreadPermission(f.GetThreadFromName(threadName), f.GetUser(r), f.PermissionManageReports) {
 			f.InfoPrintf("Thread option page accessed at %s by verified non moderation team member : %s\n", f.GetIP(r), f.GetUserEmail(r))
 			ErrorPage403(w, r) // Forbidden access
 			return
@@ -44,15 +44,44 @@ func ThreadReportsPage(w http.ResponseWriter, r *http.Request) {
 
 	thread := f.GetThreadFromName(threadName)
 
-	reports, err := f.GetReportedContentInThread(thread)
+	// Get the filters, the active reports are shown by default
+	query := r.URL.Query()
+	filter := f.ReportFilter{States: f.ActiveReportStates}
+	stateFilter := query.Get("state")
+	if stateFilter == "all" {
+		filter.States = nil
+	} else if state, err := f.GetReportStateFromString(stateFilter); err == nil {
+		filter.States = []f.ReportState{state}
+	} else {
+		stateFilter = ""
+	}
+	if reportType, err := f.GetReportTypeFromString(query.Get("type")); err == nil {
+		filter.Type = reportType
+	}
+
+	reportGroups, err := f.GetReportGroupsInThread(thread, filter)
 	if err != nil {
 		f.ErrorPrintf("Error while getting the reports for thread %s : %s\n", threadName, err)
 		ErrorPage404(w, r)
 		return
 	}
-	PageInfo["Reports"] = reports
+	PageInfo["ReportGroups"] = reportGroups
+	PageInfo["ReportStates"] = f.GetReportStatesAsStrings()
+	PageInfo["ReportTypes"] = f.GetReportTypesAsStrings()
+	PageInfo["StateFilter"] = stateFilter
+	PageInfo["TypeFilter"] = string(filter.Type)
 	PageInfo["ThreadName"] = threadName
 
+	// The bans are only shown to the users allowed to lift them
+	PageInfo["CanManageBans"] = f.IsUserAllowedToBanUserInThread(thread, f.GetUser(r))
+	PageInfo["Bans"] = []f.ThreadBan{}
+	if PageInfo["CanManageBans"].(bool) {
+		bans, err := f.GetThreadBans(thread)
+		if err == nil {
+			PageInfo["Bans"] = bans
+		}
+	}
+
 	// Add additional styles to the content interface and make the template
 	f.AddAdditionalStylesToContentInterface(&PageInfo, "/css/threadReports.css")
 	f.AddAdditionalScriptsToContentInterface(&PageInfo, "/js/threadScript.js", "/js/threadReports.js")
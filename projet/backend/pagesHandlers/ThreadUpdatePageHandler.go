@@ -20,7 +20,7 @@ func ThreadOptionPage(w http.ResponseWriter, r *http.Request) {
 			http.Redirect(w, r, "/confirmMail", http.StatusFound)
 			return
 		}
-		if !f.IsThreadOwner(f.GetThreadFromName(threadName), f.GetUser(r)) {
+		if !f.HasThreadPermission(f.GetThreadFromName(threadName), f.GetUser(r), f.PermissionManageThread) {
 			f.InfoPrintf("Thread option page accessed at %s by verified non owner : %s\n", f.GetIP(r), f.GetUserEmail(r))
 			ErrorPage404(w, r)
 			return
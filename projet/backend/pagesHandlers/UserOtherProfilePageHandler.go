@@ -4,6 +4,8 @@ import (
 	f "GoForum/functions"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 
 	"github.com/gorilla/mux"
 )
@@ -51,8 +53,72 @@ func UserOtherProfilePage(w http.ResponseWriter, r *http.Request) {
 	PageInfo["myUserLang"] = myUserConfig.Lang
 	PageInfo["myUserPfpAddress"] = myUserPfp
 	PageInfo["myUserThreads"] = myUserThreads
+	PageInfo["FeedURL"] = "/profile/" + url.PathEscape(myUser.Username) + "/feed.atom"
+	addUserProfileDetails(r, &PageInfo, myUser, myUserConfig, "/profile/"+url.PathEscape(myUser.Username))
 
 	// Add additional styles to the content interface
 	f.AddAdditionalStylesToContentInterface(&PageInfo, "/css/userSelfProfile.css", "/css/generalElementStyling.css")
-	f.MakeTemplateAndExecute(w, PageInfo, "templates/userProfile.html")
+	f.MakeTemplateAndExecute(w, PageInfo, "templates/userProfile.html", "templates/profileDetails.html")
+}
+
+// addUserProfileDetails adds the profile fields, the stats, the badges and the selected activity tab of the user to the PageInfo
+// The activity tab ('posts' or 'comments') and its page are read from the 'tab' and 'page' url parameters
+// The content is filtered from the point of view of the user looking at the profile
+func addUserProfileDetails(r *http.Request, PageInfo *map[string]interface{}, profileUser f.User, profileConfig f.UserConfigs, profileURL string) {
+	viewer := f.GetUser(r)
+	(*PageInfo)["myUserDisplayName"] = profileConfig.DisplayName
+	(*PageInfo)["myUserBio"] = profileConfig.Bio
+	(*PageInfo)["myUserWebsite"] = profileConfig.Website
+	(*PageInfo)["myUserLocation"] = profileConfig.Location
+
+	stats, err := f.GetUserProfileStats(profileUser, viewer)
+	if err != nil {
+		stats = f.UserProfileStats{}
+	}
+	(*PageInfo)["myUserStats"] = stats
+
+	userBadges, err := f.GetUserBadges(profileUser)
+	if err != nil {
+		userBadges = []f.UserBadge{}
+	}
+	(*PageInfo)["myUserBadges"] = userBadges
+
+	query := r.URL.Query()
+	tab := query.Get("tab")
+	if tab != "comments" {
+		tab = "posts"
+	}
+	page, err := strconv.Atoi(query.Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	offset := (page - 1) * f.UserProfileActivityPageSize
+	hasNextPage := false
+	(*PageInfo)["ProfileMessages"] = []f.FeedMessage{}
+	(*PageInfo)["ProfileComments"] = []f.ProfileComment{}
+	if tab == "posts" {
+		messages, nextPage, err := f.GetUserProfileMessages(profileUser, viewer, offset)
+		if err == nil {
+			(*PageInfo)["ProfileMessages"] = messages
+			hasNextPage = nextPage
+		}
+	} else {
+		comments, nextPage, err := f.GetUserProfileComments(profileUser, viewer, offset)
+		if err == nil {
+			(*PageInfo)["ProfileComments"] = comments
+			hasNextPage = nextPage
+		}
+	}
+	(*PageInfo)["ProfileTab"] = tab
+	(*PageInfo)["ProfilePage"] = page
+	(*PageInfo)["ProfilePostsURL"] = profileURL + "?tab=posts"
+	(*PageInfo)["ProfileCommentsURL"] = profileURL + "?tab=comments"
+	(*PageInfo)["PreviousProfilePageURL"] = ""
+	(*PageInfo)["NextProfilePageURL"] = ""
+	if page > 1 {
+		(*PageInfo)["PreviousProfilePageURL"] = fmt.Sprintf("%s?tab=%s&page=%d", profileURL, tab, page-1)
+	}
+	if hasNextPage {
+		(*PageInfo)["NextProfilePageURL"] = fmt.Sprintf("%s?tab=%s&page=%d", profileURL, tab, page+1)
+	}
 }
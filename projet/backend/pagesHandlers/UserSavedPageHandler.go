@@ -0,0 +1,128 @@
+package pagesHandlers
+
+import (
+	f "GoForum/functions"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// UserSavedPage handles the saved items page of the authenticated user
+// The items of a folder are shown when the 'folder' url parameter is given, the page is read from the 'page' url parameter
+// The items can be moved to another folder or removed from the saved items with the forms of the page
+func UserSavedPage(w http.ResponseWriter, r *http.Request) {
+	PageInfo := f.NewContentInterface("saved_items", r)
+	// Check the user rights
+	f.GiveUserHisRights(&PageInfo, r)
+	if PageInfo["IsAuthenticated"].(bool) {
+		// If the user is not verified, redirect him to the verify page
+		if !PageInfo["IsAddressVerified"].(bool) {
+			f.InfoPrintf("User Saved page accessed at %s by unverified : %s\n", f.GetIP(r), f.GetUserEmail(r))
+			http.Redirect(w, r, "/confirm-email-address", http.StatusFound)
+			return
+		}
+		f.InfoPrintf("User Saved page accessed at %s by verified : %s\n", f.GetIP(r), f.GetUserEmail(r))
+	} else {
+		f.InfoPrintf("User Saved page accessed at %s\n", f.GetIP(r))
+		// If the user is not authenticated, show him a forbidden page
+		ErrorPage403(w, r)
+		return
+	}
+
+	// Handle the user logout/login
+	ConnectFromHeader(w, r, &PageInfo)
+
+	user := f.GetUser(r)
+	folder := r.URL.Query().Get("folder")
+
+	// Check if the user is moving or removing a saved item
+	if r.Method == "POST" && r.FormValue("action") != "" {
+		updateSavedItem(w, r, user, folder)
+		return
+	}
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	items, hasNextPage, err := f.GetUserSavedItems(user, folder, (page-1)*f.SavedItemsPageSize)
+	if err != nil {
+		ErrorPage(w, r, http.StatusInternalServerError)
+		return
+	}
+	folders, err := f.GetUserSavedFolders(user)
+	if err != nil {
+		ErrorPage(w, r, http.StatusInternalServerError)
+		return
+	}
+	PageInfo["SavedItems"] = items
+	PageInfo["SavedFolders"] = folders
+	PageInfo["SavedFolder"] = folder
+	PageInfo["SavedFolderMaxLength"] = f.SavedFolderMaxLength
+	PageInfo["PreviousSavedPageURL"] = ""
+	PageInfo["NextSavedPageURL"] = ""
+	if page > 1 {
+		PageInfo["PreviousSavedPageURL"] = savedPageURL(folder, page-1)
+	}
+	if hasNextPage {
+		PageInfo["NextSavedPageURL"] = savedPageURL(folder, page+1)
+	}
+
+	f.AddAdditionalStylesToContentInterface(&PageInfo, "/css/userSelfProfile.css", "/css/generalElementStyling.css")
+	f.MakeTemplateAndExecute(w, PageInfo, "templates/userSaved.html")
+}
+
+// updateSavedItem moves the saved item of the form to another folder ('move' action) or removes it ('unsave' action)
+// The user is sent back to the folder he was looking at
+func updateSavedItem(w http.ResponseWriter, r *http.Request, user f.User, folder string) {
+	messageID, errMessage := strconv.Atoi(r.FormValue("message_id"))
+	commentID, errComment := strconv.Atoi(r.FormValue("comment_id"))
+	if errMessage != nil || errComment != nil || (messageID == 0) == (commentID == 0) {
+		f.DebugPrintf("Saved item ids are not valid\n")
+		ErrorPage(w, r, http.StatusBadRequest)
+		return
+	}
+	// The comments are saved on their own, without the id of their message
+	if commentID != 0 {
+		messageID = 0
+	}
+
+	var err error
+	switch r.FormValue("action") {
+	case "move":
+		newFolder := r.FormValue("folder")
+		if !f.IsSavedFolderValid(newFolder) {
+			f.DebugPrintf("Saved folder is not valid\n")
+			ErrorPage(w, r, http.StatusBadRequest)
+			return
+		}
+		err = f.MoveSavedContent(user, messageID, commentID, newFolder)
+	case "unsave":
+		err = f.UnsaveContent(user, messageID, commentID)
+	default:
+		f.DebugPrintf("Saved items action is not valid\n")
+		ErrorPage(w, r, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		ErrorPage(w, r, http.StatusBadRequest)
+		return
+	}
+	http.Redirect(w, r, savedPageURL(folder, 1), http.StatusFound)
+}
+
+// savedPageURL returns the url of the page of the saved items of the folder (all the items if it is empty)
+func savedPageURL(folder string, page int) string {
+	query := url.Values{}
+	if folder != "" {
+		query.Set("folder", folder)
+	}
+	if page > 1 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	if len(query) == 0 {
+		return "/profile/saved"
+	}
+	return fmt.Sprintf("/profile/saved?%s", query.Encode())
+}
@@ -39,8 +39,10 @@ func UserSelfProfilePage(w http.ResponseWriter, r *http.Request) {
 	PageInfo["myUserCreatedAt"] = fmt.Sprintf("%d/%d/%d", myUser.CreatedAt.Day(), myUser.CreatedAt.Month(), myUser.CreatedAt.Year())
 	PageInfo["myUserLang"] = myUserConfig.Lang
 	PageInfo["myUserThreads"] = myUserThreads
+	addUserProfileDetails(r, &PageInfo, myUser, myUserConfig, "/profile")
+
 	// Add additional styles to the content interface
 
 	f.AddAdditionalStylesToContentInterface(&PageInfo, "/css/userSelfProfile.css", "/css/generalElementStyling.css")
-	f.MakeTemplateAndExecute(w, PageInfo, "templates/userSelfProfile.html")
+	f.MakeTemplateAndExecute(w, PageInfo, "templates/userSelfProfile.html", "templates/profileDetails.html")
 }
@@ -4,6 +4,8 @@ import (
 	f "GoForum/functions"
 	"net/http"
 	"slices"
+	"strconv"
+	"strings"
 )
 
 func UserSettingsPage(w http.ResponseWriter, r *http.Request) {
@@ -36,6 +38,21 @@ func UserSettingsPage(w http.ResponseWriter, r *http.Request) {
 			ErrorPage(w, r, http.StatusInternalServerError)
 			return
 		}
+		// The personal access tokens are also managed from the settings page
+		switch r.Form.Get("action") {
+		case "createApiToken":
+			createApiToken(w, r, user, &PageInfo)
+			return
+		case "revokeApiToken":
+			revokeApiToken(w, r, user)
+			return
+		case "resetFeedToken":
+			resetFeedToken(w, r, user)
+			return
+		case "updateProfile":
+			updateUserProfile(w, r, user, &PageInfo)
+			return
+		}
 		lang := r.Form.Get("lang")
 		theme := r.Form.Get("theme")
 		if lang == "" || theme == "" {
@@ -68,15 +85,123 @@ func UserSettingsPage(w http.ResponseWriter, r *http.Request) {
 		f.GiveUserHisRights(&PageInfo, r)
 	}
 
-	PageInfo["LangList"] = f.LangListToStrList(f.GetLangList())
-	PageInfo["UserLang"] = userConfig.Lang
-	PageInfo["UserTheme"] = userConfig.Theme
+	showUserSettingsPage(w, r, user, &PageInfo)
+}
+
+// showUserSettingsPage fills the remaining PageInfo fields and shows the user settings page
+func showUserSettingsPage(w http.ResponseWriter, r *http.Request, user f.User, PageInfo *map[string]interface{}) {
+	userConfig := f.GetUserConfig(user)
+	(*PageInfo)["LangList"] = f.LangListToStrList(f.GetLangList())
+	(*PageInfo)["UserLang"] = userConfig.Lang
+	(*PageInfo)["UserTheme"] = userConfig.Theme
+	(*PageInfo)["UserDisplayName"] = userConfig.DisplayName
+	(*PageInfo)["UserBio"] = userConfig.Bio
+	(*PageInfo)["UserWebsite"] = userConfig.Website
+	(*PageInfo)["UserLocation"] = userConfig.Location
+
+	// Get the personal access tokens of the user
+	apiTokens, err := f.GetUserApiTokens(user)
+	if err != nil {
+		f.ErrorPrintf("Error while getting the personal access tokens of %s : %s\n", user.Username, err)
+	}
+	(*PageInfo)["ApiTokens"] = apiTokens
+	(*PageInfo)["ApiTokenScopes"] = f.GetApiTokenScopesAsStrings()
 
 	// Handle the user logout/login
-	ConnectFromHeader(w, r, &PageInfo)
+	ConnectFromHeader(w, r, PageInfo)
 
 	// Add additional styles to the content interface
-	f.AddAdditionalStylesToContentInterface(&PageInfo, "/css/userSettings.css")
-	f.AddAdditionalScriptsToContentInterface(&PageInfo, "/js/userSettingsScript.js", "/js/imgUploaderScript.js")
-	f.MakeTemplateAndExecute(w, PageInfo, "templates/userSettings.html")
+	f.AddAdditionalStylesToContentInterface(PageInfo, "/css/userSettings.css")
+	f.AddAdditionalScriptsToContentInterface(PageInfo, "/js/userSettingsScript.js", "/js/imgUploaderScript.js")
+	f.MakeTemplateAndExecute(w, *PageInfo, "templates/userSettings.html")
+}
+
+// createApiToken creates a new personal access token from the settings form
+// The token is only shown once, right after its creation
+func createApiToken(w http.ResponseWriter, r *http.Request, user f.User, PageInfo *map[string]interface{}) {
+	tokenName := strings.TrimSpace(r.Form.Get("token_name"))
+	if !f.IsApiTokenNameValid(tokenName) {
+		f.DebugPrintf("Personal access token name \"%s\" is not valid\n", tokenName)
+		ErrorPage(w, r, http.StatusBadRequest)
+		return
+	}
+	var scopes []f.ApiTokenScope
+	for _, strScope := range r.Form["token_scopes"] {
+		scope, err := f.GetApiTokenScopeFromString(strScope)
+		if err != nil {
+			f.DebugPrintf("Personal access token scope \"%s\" is not valid\n", strScope)
+			ErrorPage(w, r, http.StatusBadRequest)
+			return
+		}
+		scopes = append(scopes, scope)
+	}
+	if len(scopes) == 0 {
+		f.DebugPrintf("Personal access token created without scope\n")
+		ErrorPage(w, r, http.StatusBadRequest)
+		return
+	}
+	token, err := f.CreateApiToken(user, tokenName, scopes)
+	if err != nil {
+		f.ErrorPrintf("Error while creating the personal access token : %s\n", err)
+		ErrorPage(w, r, http.StatusInternalServerError)
+		return
+	}
+	f.InfoPrintf("Personal access token \"%s\" created by %s\n", tokenName, user.Username)
+	(*PageInfo)["NewApiToken"] = token
+	showUserSettingsPage(w, r, user, PageInfo)
+}
+
+// revokeApiToken revokes one of the personal access tokens of the user from the settings form
+func revokeApiToken(w http.ResponseWriter, r *http.Request, user f.User) {
+	tokenID, err := strconv.Atoi(r.Form.Get("token_id"))
+	if err != nil {
+		f.DebugPrintf("Personal access token id is not valid\n")
+		ErrorPage(w, r, http.StatusBadRequest)
+		return
+	}
+	err = f.RevokeApiToken(user, tokenID)
+	if err != nil {
+		f.ErrorPrintf("Error while revoking the personal access token : %s\n", err)
+		ErrorPage(w, r, http.StatusInternalServerError)
+		return
+	}
+	f.InfoPrintf("Personal access token %d revoked by %s\n", tokenID, user.Username)
+	http.Redirect(w, r, "/settings", http.StatusFound)
+}
+
+// resetFeedToken replaces the feed token of the user, the private feed urls he shared stop working
+func resetFeedToken(w http.ResponseWriter, r *http.Request, user f.User) {
+	_, err := f.ResetUserFeedToken(user)
+	if err != nil {
+		f.ErrorPrintf("Error while resetting the feed token : %s\n", err)
+		ErrorPage(w, r, http.StatusInternalServerError)
+		return
+	}
+	f.InfoPrintf("Feed token reset by %s\n", user.Username)
+	http.Redirect(w, r, "/settings", http.StatusFound)
+}
+
+// updateUserProfile saves the profile fields of the user from the settings form
+// If a field is not valid, the settings page is shown again with the name of the invalid field
+func updateUserProfile(w http.ResponseWriter, r *http.Request, user f.User, PageInfo *map[string]interface{}) {
+	userConfig := f.GetUserConfig(user)
+	userConfig.DisplayName = strings.TrimSpace(r.Form.Get("display_name"))
+	userConfig.Bio = strings.TrimSpace(r.Form.Get("bio"))
+	userConfig.Website = strings.TrimSpace(r.Form.Get("website"))
+	userConfig.Location = strings.TrimSpace(r.Form.Get("location"))
+	invalidField := f.IsUserProfileValid(userConfig)
+	if invalidField != "" {
+		f.DebugPrintf("User profile field \"%s\" of %s is not valid\n", invalidField, user.Username)
+		(*PageInfo)["ProfileError"] = invalidField
+		showUserSettingsPage(w, r, user, PageInfo)
+		return
+	}
+	err := f.UpdateUserProfile(userConfig)
+	if err != nil {
+		f.ErrorPrintf("Error while saving the user profile : %s\n", err)
+		ErrorPage(w, r, http.StatusInternalServerError)
+		return
+	}
+	f.InfoPrintf("Profile updated by %s\n", user.Username)
+	http.Redirect(w, r, "/profile", http.StatusFound)
 }
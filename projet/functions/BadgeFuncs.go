@@ -0,0 +1,215 @@
+package functions
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// BadgeMetric is a type used to determine the value measured by the rule of a badge
+type BadgeMetric string
+
+// Constants used to determine the value measured by the rule of a badge
+const (
+	BadgeMetricMessages         BadgeMetric = "messages"          // Number of published messages of the user
+	BadgeMetricComments         BadgeMetric = "comments"          // Number of comments of the user
+	BadgeMetricUpvotesReceived  BadgeMetric = "upvotes_received"  // Number of upvotes received by the messages and comments of the user
+	BadgeMetricReputation       BadgeMetric = "reputation"        // Reputation of the user in every thread, see GetUserReputation
+	BadgeMetricThreadsFounded   BadgeMetric = "threads_founded"   // Number of threads owned by the user
+	BadgeMetricThreadsModerated BadgeMetric = "threads_moderated" // Number of threads where the user is a moderator or an admin
+)
+
+// BadgeMetrics is a list of possible badge metrics
+var BadgeMetrics = []BadgeMetric{
+	BadgeMetricMessages,
+	BadgeMetricComments,
+	BadgeMetricUpvotesReceived,
+	BadgeMetricReputation,
+	BadgeMetricThreadsFounded,
+	BadgeMetricThreadsModerated,
+}
+
+// BadgeEvent is a type used to determine the events after which the badges of a user are evaluated
+type BadgeEvent string
+
+// Constants used to determine the events after which the badges of a user are evaluated
+const (
+	BadgeEventMessagePosted  BadgeEvent = "message_posted"  // A message of the user was published
+	BadgeEventCommentPosted  BadgeEvent = "comment_posted"  // The user commented a message
+	BadgeEventVoteReceived   BadgeEvent = "vote_received"   // A message or a comment of the user was voted on
+	BadgeEventThreadCreated  BadgeEvent = "thread_created"  // The user created a thread
+	BadgeEventMemberPromoted BadgeEvent = "member_promoted" // The user was promoted in a thread
+)
+
+// badgeMetricEvents are the events that can change the value of each metric
+// Only the badges whose metric can be changed by an event are evaluated after it
+var badgeMetricEvents = map[BadgeMetric][]BadgeEvent{
+	BadgeMetricMessages:         {BadgeEventMessagePosted},
+	BadgeMetricComments:         {BadgeEventCommentPosted},
+	BadgeMetricUpvotesReceived:  {BadgeEventVoteReceived},
+	BadgeMetricReputation:       {BadgeEventVoteReceived},
+	BadgeMetricThreadsFounded:   {BadgeEventThreadCreated},
+	BadgeMetricThreadsModerated: {BadgeEventThreadCreated, BadgeEventMemberPromoted},
+}
+
+// Badge is a badge a user is awarded when the value of its metric reaches its threshold
+// The names and the descriptions are indexed by language, the english ones being used when the language of the user is missing
+type Badge struct {
+	BadgeID      string            `json:"id"`
+	Icon         string            `json:"icon"`
+	Names        map[string]string `json:"names"`
+	Descriptions map[string]string `json:"descriptions"`
+	Metric       BadgeMetric       `json:"metric"`
+	Threshold    int               `json:"threshold"`
+}
+
+// UserBadge is a badge awarded to a user
+type UserBadge struct {
+	BadgeID      string            `json:"id"`
+	Icon         string            `json:"icon"`
+	Names        map[string]string `json:"names"`
+	Descriptions map[string]string `json:"descriptions"`
+	AwardDate    time.Time         `json:"award_date"`
+}
+
+// DefaultBadges are the badges used when there is no badges file
+var DefaultBadges = []Badge{
+	{
+		BadgeID:      "first_post",
+		Icon:         "✏️",
+		Names:        map[string]string{"en": "First post", "fr": "Premier message"},
+		Descriptions: map[string]string{"en": "Published a first message.", "fr": "A publié un premier message."},
+		Metric:       BadgeMetricMessages,
+		Threshold:    1,
+	},
+	{
+		BadgeID:      "first_comment",
+		Icon:         "💬",
+		Names:        map[string]string{"en": "First comment", "fr": "Premier commentaire"},
+		Descriptions: map[string]string{"en": "Commented a message for the first time.", "fr": "A commenté un message pour la première fois."},
+		Metric:       BadgeMetricComments,
+		Threshold:    1,
+	},
+	{
+		BadgeID:      "upvotes_100",
+		Icon:         "⭐",
+		Names:        map[string]string{"en": "100 upvotes", "fr": "100 votes positifs"},
+		Descriptions: map[string]string{"en": "Received 100 upvotes on messages and comments.", "fr": "A reçu 100 votes positifs sur ses messages et commentaires."},
+		Metric:       BadgeMetricUpvotesReceived,
+		Threshold:    100,
+	},
+	{
+		BadgeID:      "thread_founder",
+		Icon:         "🏛️",
+		Names:        map[string]string{"en": "Thread founder", "fr": "Fondateur de thread"},
+		Descriptions: map[string]string{"en": "Created a thread.", "fr": "A créé un thread."},
+		Metric:       BadgeMetricThreadsFounded,
+		Threshold:    1,
+	},
+	{
+		BadgeID:      "moderator",
+		Icon:         "🛡️",
+		Names:        map[string]string{"en": "Moderator", "fr": "Modérateur"},
+		Descriptions: map[string]string{"en": "Is part of the moderation team of a thread.", "fr": "Fait partie de l'équipe de modération d'un thread."},
+		Metric:       BadgeMetricThreadsModerated,
+		Threshold:    1,
+	},
+}
+
+// badges are the badges that can be awarded, see InitBadges
+var badges = DefaultBadges
+
+// badgeIDRegex is the format of the id of a badge
+var badgeIDRegex = regexp.MustCompile(`^[a-z0-9_]{1,30}$`)
+
+// InitBadges reads the badges that can be awarded
+// The file is given by BADGES_FILE ('badges.json' by default), it is a JSON list of Badge
+// A missing file means that the DefaultBadges are used, the invalid badges of the file are ignored
+func InitBadges() {
+	path := os.Getenv("BADGES_FILE")
+	if path == "" {
+		path = "badges.json"
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		DebugPrintf("No badges file (%s), using the default badges\n", path)
+		return
+	}
+	var fileBadges []Badge
+	err = json.Unmarshal(content, &fileBadges)
+	if err != nil {
+		ErrorPrintf("Error reading the badges file %s: %v\n", path, err)
+		return
+	}
+
+	var validBadges []Badge
+	for index, badge := range fileBadges {
+		if !IsBadgeValid(badge) || slices.ContainsFunc(validBadges, func(b Badge) bool { return b.BadgeID == badge.BadgeID }) {
+			ErrorPrintf("Invalid or duplicated badge at index %d of %s\n", index, path)
+			continue
+		}
+		validBadges = append(validBadges, badge)
+	}
+	badges = validBadges
+	InfoPrintf("%d badges loaded from %s\n", len(badges), path)
+}
+
+// IsBadgeValid checks if the badge has a valid id, an english name, a known metric and a positive threshold
+func IsBadgeValid(badge Badge) bool {
+	return badgeIDRegex.MatchString(badge.BadgeID) &&
+		badge.Names["en"] != "" &&
+		IsBadgeMetricValid(badge.Metric) &&
+		badge.Threshold > 0
+}
+
+// IsBadgeMetricValid checks if the metric is one of the BadgeMetrics
+func IsBadgeMetricValid(metric BadgeMetric) bool {
+	return slices.Contains(BadgeMetrics, metric)
+}
+
+// GetBadges returns the badges that can be awarded
+func GetBadges() []Badge {
+	return badges
+}
+
+// GetBadgeFromID returns the badge with the given id
+// Returns false if there is no such badge
+func GetBadgeFromID(badgeID string) (Badge, bool) {
+	for _, badge := range badges {
+		if badge.BadgeID == badgeID {
+			return badge, true
+		}
+	}
+	return Badge{}, false
+}
+
+// getBadgesForEvent returns the badges whose metric can be changed by the event
+func getBadgesForEvent(event BadgeEvent) []Badge {
+	var eventBadges []Badge
+	for _, badge := range badges {
+		if slices.Contains(badgeMetricEvents[badge.Metric], event) {
+			eventBadges = append(eventBadges, badge)
+		}
+	}
+	return eventBadges
+}
+
+// localizedBadgeText returns the text in the given language, falling back to the english one
+func localizedBadgeText(texts map[string]string, lang string) string {
+	if text, ok := texts[lang]; ok && text != "" {
+		return text
+	}
+	return texts["en"]
+}
+
+// NameIn returns the name of the badge in the given language
+func (badge UserBadge) NameIn(lang string) string {
+	return localizedBadgeText(badge.Names, lang)
+}
+
+// DescriptionIn returns the description of the badge in the given language
+func (badge UserBadge) DescriptionIn(lang string) string {
+	return localizedBadgeText(badge.Descriptions, lang)
+}
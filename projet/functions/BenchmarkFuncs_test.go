@@ -0,0 +1,183 @@
+package functions
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Size of the database seeded by the benchmarks
+const (
+	benchmarkUsers              = 1000
+	benchmarkMessages           = 20000
+	benchmarkCommentsPerMessage = 5
+)
+
+// benchmarkQueryCount is the number of queries run on the database opened by the benchmarks
+var benchmarkQueryCount atomic.Int64
+
+func init() {
+	sql.Register("sqlite3_benchmark", &countingSQLiteDriver{})
+}
+
+// countingSQLiteDriver is the sqlite3 driver counting the queries run through its connections
+type countingSQLiteDriver struct {
+	sqlite3.SQLiteDriver
+}
+
+// Open opens a sqlite3 connection counting its queries
+func (d *countingSQLiteDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.SQLiteDriver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &countingSQLiteConn{conn.(*sqlite3.SQLiteConn)}, nil
+}
+
+// countingSQLiteConn is a sqlite3 connection counting the queries run through it
+type countingSQLiteConn struct {
+	*sqlite3.SQLiteConn
+}
+
+func (c *countingSQLiteConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	benchmarkQueryCount.Add(1)
+	return c.SQLiteConn.QueryContext(ctx, query, args)
+}
+
+func (c *countingSQLiteConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	benchmarkQueryCount.Add(1)
+	return c.SQLiteConn.ExecContext(ctx, query, args)
+}
+
+func (c *countingSQLiteConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	benchmarkQueryCount.Add(1)
+	return c.SQLiteConn.PrepareContext(ctx, query)
+}
+
+// setupBenchmarkDatabase opens a test database counting its queries and seeds it, see seedBenchmarkDatabase
+func setupBenchmarkDatabase(b *testing.B) {
+	b.Helper()
+	// The background jobs would add their own queries to the count
+	for _, job := range []string{"AUTO_DELETE_OLD_EMAIL_IDENTIFICATIONS", "AUTO_DELETE_USELESS_MEDIA_LINKS", "AUTO_LIFT_EXPIRED_BANS", "AUTO_PURGE_DELETED_CONTENT"} {
+		b.Setenv(job, "false")
+	}
+	setupTestDatabase(b)
+	_ = db.Close()
+	countingDB, err := sql.Open("sqlite3_benchmark", os.Getenv("DB_NAME"))
+	if err != nil {
+		b.Fatalf("opening the benchmark database: %v", err)
+	}
+	db = countingDB
+	if err = seedBenchmarkDatabase(); err != nil {
+		b.Fatalf("seeding the benchmark database: %v", err)
+	}
+}
+
+// seedBenchmarkDatabase fills the benchmark database with users, a thread, tagged messages with media, polls, comments and votes
+// Returns an error if there is one
+func seedBenchmarkDatabase() error {
+	// sequence generates the numbers from 1 to the given number
+	const sequence = "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < %d) "
+	seed := []string{
+		fmt.Sprintf(sequence+"INSERT INTO Users (email, username, firstname, lastname, email_verified) SELECT 'bench' || i || '@goforum.local', 'bench' || i, 'Bench', 'User', TRUE FROM n", benchmarkUsers),
+		"INSERT INTO UserConfigs (user_id) SELECT user_id FROM Users",
+		"INSERT INTO ThreadGoForum (thread_name, owner_id) VALUES ('BenchmarkThread', 1)",
+		"INSERT INTO ThreadGoForumConfigs (thread_id, thread_description) VALUES (1, 'The thread seeded by the benchmark')",
+		"INSERT INTO ThreadGoForumMembers (user_id, thread_id, rights_level) SELECT user_id, 1, CASE WHEN user_id = 1 THEN 3 ELSE 0 END FROM Users",
+		"INSERT INTO ThreadGoForumTags (thread_id, tag_name, tag_color) VALUES (1, 'Tag1', '#FF0000'), (1, 'Tag2', '#00FF00'), (1, 'Tag3', '#0000FF')",
+		fmt.Sprintf(sequence+"INSERT INTO ThreadMessages (user_id, thread_id, message_title, message_content, creation_date) SELECT 1 + i %% %d, 1, 'Benchmark message ' || i, 'The content of the benchmark message ' || i, datetime('now', '-' || (%d - i) || ' minutes') FROM n", benchmarkMessages, benchmarkUsers, benchmarkMessages),
+		"INSERT INTO ThreadMessageTags (message_id, tag_id) SELECT message_id, 1 + message_id % 3 FROM ThreadMessages",
+		"INSERT INTO ThreadMessageTags (message_id, tag_id) SELECT message_id, 1 + (message_id + 1) % 3 FROM ThreadMessages WHERE message_id % 2 = 0",
+		fmt.Sprintf("INSERT INTO MediaLink (media_type, media_address) SELECT '%s', 'benchmark_' || message_id || '.png' FROM ThreadMessages WHERE message_id %% 3 = 0", ThreadMessagePicture),
+		"INSERT INTO ThreadMessageMediaLinks (message_id, media_id) SELECT CAST(SUBSTR(media_address, 11) AS INTEGER), media_id FROM MediaLink WHERE media_address LIKE 'benchmark_%'",
+		"INSERT INTO ThreadMessagePolls (message_id) SELECT message_id FROM ThreadMessages WHERE message_id % 5 = 0",
+		"INSERT INTO ThreadMessagePollOptions (poll_id, option_text, option_position) SELECT poll_id, 'Yes', 0 FROM ThreadMessagePolls UNION ALL SELECT poll_id, 'No', 1 FROM ThreadMessagePolls",
+		fmt.Sprintf(sequence+"INSERT INTO ThreadComments (message_id, user_id, comment_content) SELECT m.message_id, 1 + (m.message_id * n.i) %% %d, 'A benchmark comment' FROM ThreadMessages m, n", benchmarkCommentsPerMessage, benchmarkUsers),
+		"INSERT INTO ThreadVotes (message_id, comment_id, user_id, is_upvote) SELECT m.message_id, NULL, u.user_id, (m.message_id + u.user_id) % 3 != 0 FROM ThreadMessages m JOIN Users u ON u.user_id % 50 = m.message_id % 50",
+		"INSERT INTO ThreadVotes (message_id, comment_id, user_id, is_upvote) SELECT NULL, c.comment_id, u.user_id, (c.comment_id + u.user_id) % 2 = 0 FROM ThreadComments c JOIN Users u ON u.user_id % 250 = c.comment_id % 250",
+	}
+	for _, query := range seed {
+		_, err := db.Exec(query)
+		if err != nil {
+			return err
+		}
+	}
+	return UpdateAllMessageScores()
+}
+
+// runBenchmarkRequest runs the request b.N times and reports the number of queries it makes
+func runBenchmarkRequest(b *testing.B, request func() error) {
+	b.Helper()
+	benchmarkQueryCount.Store(0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := request(); err != nil {
+			b.Fatalf("running the request: %v", err)
+		}
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(benchmarkQueryCount.Load())/float64(b.N), "queries/op")
+}
+
+// BenchmarkPageLoads measures the requests loading the messages and the comments on a large seeded database
+// The database is seeded once for all the requests
+//
+// Latency baseline (go test ./functions -run '^$' -bench BenchmarkPageLoads -benchtime 3x):
+//   - views regrouping all the votes and filtering on the thread name: about 1s for every page of messages, 1.5s for a page of comments
+//   - views on the stored scores and filtering on the thread id: 2ms for the first page in the default order, about 60ms for the
+//     other orders (sorted without an index) and 1ms for a page of comments
+//   - date orders sorted on the indexes of the thread: 2ms for the first page in both directions, 1.6ms for the deep page with the
+//     cursor against 7.7ms with the offset
+//   - hot, popularity and top orders sorted on the indexes of the stored scores: 2ms for the first page, the controversial and
+//     most commented orders are still computed for every message (about 60ms)
+func BenchmarkPageLoads(b *testing.B) {
+	setupBenchmarkDatabase(b)
+	thread := GetThreadFromName("BenchmarkThread")
+	// The requests are made by the owner of the thread, who voted on some of the messages
+	viewer, err := GetUserFromUsername("bench1")
+	if err != nil {
+		b.Fatalf("getting the benchmark user: %v", err)
+	}
+
+	for _, order := range OrderingList {
+		b.Run("MessagesFirstPage/"+order, func(b *testing.B) {
+			runBenchmarkRequest(b, func() error {
+				_, _, err := GetMessagesFromThreadAfterCursor(thread, "", order, viewer, nil)
+				return err
+			})
+		})
+	}
+
+	// The cursor of a deep page, the offset pagination has to skip all the messages before it
+	deepOffset := benchmarkMessages / 2
+	deepPosition := PageCursor{Order: "asc", ID: deepOffset}
+	err = db.QueryRow(fmt.Sprintf("SELECT %s FROM ThreadMessages WHERE message_id = ?", messageDateKeySQL), deepPosition.ID).Scan(&deepPosition.SortKey)
+	if err != nil {
+		b.Fatalf("getting the position of the deep page: %v", err)
+	}
+	deepCursor := EncodePageCursor(deepPosition)
+	b.Run("MessagesDeepPage/cursor", func(b *testing.B) {
+		runBenchmarkRequest(b, func() error {
+			_, _, err := GetMessagesFromThreadAfterCursor(thread, deepCursor, "asc", viewer, nil)
+			return err
+		})
+	})
+	b.Run("MessagesDeepPage/offset", func(b *testing.B) {
+		runBenchmarkRequest(b, func() error {
+			_, err := GetMessagesFromThreadWithPOV(thread, deepOffset, "asc", viewer, nil)
+			return err
+		})
+	})
+	b.Run("CommentsFirstPage", func(b *testing.B) {
+		runBenchmarkRequest(b, func() error {
+			_, _, err := GetCommentsFromMessageAfterCursor(1, "", viewer)
+			return err
+		})
+	})
+}
@@ -0,0 +1,225 @@
+package functions
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SystemReporterName is the name of the reporter of the reports made by the content filters
+// It is not a valid username, so it can never be mistaken for a user
+const SystemReporterName = "[system]"
+
+// linkRegex matches the links counted by the link density heuristic
+var linkRegex = regexp.MustCompile(`(?i)https?://\S+|www\.\S+`)
+
+// globalWordFilters is the global list of filtered words, applied to every thread
+var globalWordFilters []WordFilter
+
+// ContentFlag is a reason for a message or a comment to be reviewed by the moderation team
+type ContentFlag struct {
+	ReportType ReportType
+	Reason     string
+}
+
+// ContentFilterVerdict is the result of the content filters on a message or a comment
+type ContentFilterVerdict struct {
+	Title       string        // The title with the masked words replaced by asterisks
+	Content     string        // The content with the masked words replaced by asterisks
+	IsBlocked   bool          // The message or comment must be refused
+	BlockReason string        // Why the message or comment was refused
+	Flags       []ContentFlag // Why the message or comment must be reviewed, a system report is made for each flag
+}
+
+// getContentFilterSetting returns the positive integer in the environment variable or the default value if it is not set or not valid
+func getContentFilterSetting(name string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || value < 1 {
+		return defaultValue
+	}
+	return value
+}
+
+// InitGlobalWordFilters reads the global list of filtered words
+// The file is given by WORD_FILTERS_FILE ('word_filters.txt' by default), each line is '<action> <word>' (e.g. 'mask darn')
+// Empty lines and lines starting with '#' are ignored, a missing file means that there is no global filter
+func InitGlobalWordFilters() {
+	path := os.Getenv("WORD_FILTERS_FILE")
+	if path == "" {
+		path = "word_filters.txt"
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		DebugPrintf("No global word filters file (%s)\n", path)
+		return
+	}
+	defer func(file *os.File) {
+		err := file.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the global word filters file: %v\n", err)
+		}
+	}(file)
+
+	var filters []WordFilter
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		strAction, word, found := strings.Cut(line, " ")
+		word = strings.TrimSpace(word)
+		action, err := GetWordFilterActionFromString(strAction)
+		if !found || err != nil || !IsWordFilterValid(word) {
+			ErrorPrintf("Invalid global word filter on line %d of %s\n", lineNumber, path)
+			continue
+		}
+		filters = append(filters, WordFilter{Word: strings.ToLower(word), Action: action})
+	}
+	if err := scanner.Err(); err != nil {
+		ErrorPrintf("Error reading the global word filters file: %v\n", err)
+		return
+	}
+	globalWordFilters = filters
+	InfoPrintf("%d global word filters loaded\n", len(filters))
+}
+
+// GetGlobalWordFilters returns the global list of filtered words
+func GetGlobalWordFilters() []WordFilter {
+	return globalWordFilters
+}
+
+// isWordCharacter checks if the rune can be part of a word
+func isWordCharacter(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// findWord returns the positions of the word in the text, ignoring the case
+// Only the whole words are found, 'ass' is not found in 'class'
+func findWord(text string, word string) [][]int {
+	wordRegex, err := regexp.Compile(`(?i)` + regexp.QuoteMeta(word))
+	if err != nil {
+		return nil
+	}
+	var positions [][]int
+	for _, position := range wordRegex.FindAllStringIndex(text, -1) {
+		before, _ := utf8.DecodeLastRuneInString(text[:position[0]])
+		after, _ := utf8.DecodeRuneInString(text[position[1]:])
+		if (position[0] > 0 && isWordCharacter(before)) || (position[1] < len(text) && isWordCharacter(after)) {
+			continue
+		}
+		positions = append(positions, position)
+	}
+	return positions
+}
+
+// maskWord replaces the word in the text by as many asterisks as it has characters
+func maskWord(text string, word string) string {
+	positions := findWord(text, word)
+	// Replace from the end so the positions stay valid
+	for i := len(positions) - 1; i >= 0; i-- {
+		start, end := positions[i][0], positions[i][1]
+		text = text[:start] + strings.Repeat("*", utf8.RuneCountInString(text[start:end])) + text[end:]
+	}
+	return text
+}
+
+// FilterContent runs the word filters of the thread (and the global ones) and the spam heuristics on a message or a comment
+// The title is empty for the comments, 'isNewPost' is false when a message is edited (the posting heuristics are skipped)
+// The heuristics are:
+// - the link density, too many links flag the content
+// - the repeated posts, the same content sent again by the user within SPAM_DUPLICATE_WINDOW minutes (60 by default) is blocked
+// - the posting rate of the new accounts, the accounts younger than NEW_ACCOUNT_AGE hours (24 by default) cannot send more than NEW_ACCOUNT_MAX_POSTS posts (5 by default) within 10 minutes
+func FilterContent(thread ThreadGoForum, user User, title string, content string, isNewPost bool) ContentFilterVerdict {
+	verdict := ContentFilterVerdict{Title: title, Content: content}
+
+	// Word filters
+	threadFilters, err := GetThreadWordFilters(thread)
+	if err != nil {
+		ErrorPrintf("Error getting the word filters of the thread %s: %v\n", thread.ThreadName, err)
+	}
+	filters := append(append([]WordFilter{}, GetGlobalWordFilters()...), threadFilters...)
+	for _, filter := range filters {
+		if len(findWord(verdict.Title, filter.Word)) == 0 && len(findWord(verdict.Content, filter.Word)) == 0 {
+			continue
+		}
+		switch filter.Action {
+		case WordFilterBlock:
+			verdict.IsBlocked = true
+			verdict.BlockReason = fmt.Sprintf("contains the blocked word \"%s\"", filter.Word)
+			return verdict
+		case WordFilterMask:
+			verdict.Title = maskWord(verdict.Title, filter.Word)
+			verdict.Content = maskWord(verdict.Content, filter.Word)
+		case WordFilterFlag:
+			verdict.Flags = append(verdict.Flags, ContentFlag{
+				ReportType: OtherReport,
+				Reason:     fmt.Sprintf("Contains the flagged word \"%s\"", filter.Word),
+			})
+		}
+	}
+
+	// Link density
+	links := len(linkRegex.FindAllString(content, -1))
+	words := len(strings.Fields(content))
+	maxLinks := getContentFilterSetting("SPAM_MAX_LINKS", 5)
+	minWordsPerLink := getContentFilterSetting("SPAM_MIN_WORDS_PER_LINK", 10)
+	if links > maxLinks || (links >= 2 && words < links*minWordsPerLink) {
+		verdict.Flags = append(verdict.Flags, ContentFlag{
+			ReportType: SpamReport,
+			Reason:     fmt.Sprintf("High link density (%d links in %d words)", links, words),
+		})
+	}
+
+	if !isNewPost {
+		return verdict
+	}
+
+	// Repeated posts
+	duplicates, err := CountUserPostsWithContent(user, content, getContentFilterSetting("SPAM_DUPLICATE_WINDOW", 60))
+	if err == nil && duplicates > 0 {
+		verdict.IsBlocked = true
+		verdict.BlockReason = "the same content was already sent recently"
+		return verdict
+	}
+
+	// Posting rate of the new accounts
+	newAccountAge := time.Duration(getContentFilterSetting("NEW_ACCOUNT_AGE", 24)) * time.Hour
+	if time.Since(user.CreatedAt) < newAccountAge {
+		recentPosts, err := CountUserPostsSince(user, 10)
+		if err == nil && recentPosts >= getContentFilterSetting("NEW_ACCOUNT_MAX_POSTS", 5) {
+			verdict.IsBlocked = true
+			verdict.BlockReason = "new accounts cannot post that fast"
+			return verdict
+		}
+	}
+	return verdict
+}
+
+// ReportFlaggedMessage makes a system report on the message for each flag of the verdict
+func ReportFlaggedMessage(thread ThreadGoForum, messageID int, verdict ContentFilterVerdict) {
+	for _, flag := range verdict.Flags {
+		err := AddReportedMessage(User{Username: SystemReporterName}, thread, messageID, flag.ReportType, flag.Reason)
+		if err != nil {
+			ErrorPrintf("Error reporting the flagged message %d: %v\n", messageID, err)
+		}
+	}
+}
+
+// ReportFlaggedComment makes a system report on the comment for each flag of the verdict
+func ReportFlaggedComment(thread ThreadGoForum, commentID int, messageID int, verdict ContentFilterVerdict) {
+	for _, flag := range verdict.Flags {
+		err := AddReportedComment(User{Username: SystemReporterName}, thread, commentID, messageID, flag.ReportType, flag.Reason)
+		if err != nil {
+			ErrorPrintf("Error reporting the flagged comment %d: %v\n", commentID, err)
+		}
+	}
+}
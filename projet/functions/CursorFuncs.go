@@ -0,0 +1,71 @@
+package functions
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// PageCursor is the position of the last item of a loaded page of messages or comments
+// The next page starts right after it, so the items added or removed in the meantime do not shift the pages
+// It is given to the clients as an opaque token, see EncodePageCursor and DecodePageCursor
+type PageCursor struct {
+	Order    string  `json:"o"`           // The order the page was loaded with, a cursor cannot be reused with another one
+	IsPinned bool    `json:"p,omitempty"` // If the last item is pinned (only for the messages)
+	SortKey  float64 `json:"k"`           // The value of the sort key of the last item (e.g. creation date in seconds, vote score or hot score)
+	ID       int     `json:"i"`           // The id of the last item, used to break the ties of the sort key
+	At       int64   `json:"t,omitempty"` // The date (unix seconds) the time dependent orders were computed at for the first page
+}
+
+// ErrInvalidCursor is returned when a cursor token is malformed or does not match the requested order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// EncodePageCursor returns the opaque token of the cursor
+func EncodePageCursor(cursor PageCursor) string {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		ErrorPrintf("Error encoding the page cursor: %v\n", err)
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodePageCursor returns the cursor of the given token
+// Returns nil if the token is empty (the first page is requested)
+// Returns ErrInvalidCursor if the token is malformed or was made for another order
+func DecodePageCursor(token string, order string) (*PageCursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor PageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Order != order {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// keysetConditionSQL returns the condition selecting the rows coming after the cursor
+// keySQL and idSQL are the sort key and the id of the rows, descending tells if the rows are sorted in descending order
+// The ties of the sort key are broken by the id, in ascending order if idAscending is true or in the order of the sort key otherwise
+// The bound on the sort key comes first so SQLite can seek the index of the sort key to the cursor instead of scanning all the rows before it
+// Returns the condition and its arguments
+func keysetConditionSQL(keySQL string, idSQL string, descending bool, idAscending bool, cursor PageCursor) (string, []interface{}) {
+	keyComparator := ">"
+	if descending {
+		keyComparator = "<"
+	}
+	idComparator := keyComparator
+	if idAscending {
+		idComparator = ">"
+	}
+	condition := fmt.Sprintf("(%[1]s %[3]s= ? AND (%[1]s %[3]s ? OR %[2]s %[4]s ?))", keySQL, idSQL, keyComparator, idComparator)
+	return condition, []interface{}{cursor.SortKey, cursor.SortKey, cursor.ID}
+}
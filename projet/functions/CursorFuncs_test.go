@@ -0,0 +1,107 @@
+package functions
+
+import (
+	"fmt"
+	"testing"
+)
+
+// newTestPagingThread adds a thread of the owner with messages of various dates, scores and numbers of comments, two of them being pinned
+func newTestPagingThread(t *testing.T, threadName string, owner User, voters []User) ThreadGoForum {
+	t.Helper()
+	thread := newTestThread(t, owner, threadName)
+	for i := 0; i < 12; i++ {
+		messageID, err := AddMessageInThread(thread, fmt.Sprintf("Message %d", i), "The content of the message", owner, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("adding the message %d: %v", i, err)
+		}
+		// Some messages share their date and their scores, so the ties have to be broken by their id
+		_, err = db.Exec("UPDATE ThreadMessages SET creation_date = datetime('now', ?) WHERE message_id = ?", fmt.Sprintf("-%d hours", i/2), messageID)
+		if err != nil {
+			t.Fatalf("moving the date of the message %d: %v", i, err)
+		}
+		for v, voter := range voters[:i%len(voters)] {
+			err = ThreadMessageAddVote(messageID, voter.UserID, (i+v)%3 != 0)
+			if err != nil {
+				t.Fatalf("voting on the message %d: %v", i, err)
+			}
+		}
+		for c := 0; c < i%3; c++ {
+			_, err = AddCommentToPost(owner, messageID, "The content of the comment")
+			if err != nil {
+				t.Fatalf("commenting the message %d: %v", i, err)
+			}
+		}
+		if i == 4 || i == 9 {
+			err = SetMessagePinned(thread, messageID, true)
+			if err != nil {
+				t.Fatalf("pinning the message %d: %v", i, err)
+			}
+		}
+	}
+	if err := UpdateAllMessageScores(); err != nil {
+		t.Fatalf("updating the scores: %v", err)
+	}
+	return thread
+}
+
+func TestGetMessagesFromThreadAfterCursor(t *testing.T) {
+	setupTestDatabase(t)
+	t.Setenv("MAX_MESSAGES_PER_PAGE_LOAD", "3")
+	owner := newTestUser(t, "owner")
+	voters := make([]User, 4)
+	for i := range voters {
+		voters[i] = newTestUser(t, fmt.Sprintf("voter%d", i))
+	}
+
+	for _, order := range OrderingList {
+		t.Run(order, func(t *testing.T) {
+			thread := newTestPagingThread(t, "paging_"+order, owner, voters)
+			// The messages loaded with the offsets while nothing changes are the ones to find with the cursor
+			var expected []int
+			for {
+				messages, _, err := getMessagesPageFromThread(thread, len(expected), nil, order, owner, nil)
+				if err != nil {
+					t.Fatalf("getting the messages: %v", err)
+				}
+				if len(messages) == 0 {
+					break
+				}
+				for _, message := range messages {
+					expected = append(expected, message.MessageID)
+				}
+			}
+			if len(expected) == 0 {
+				t.Fatalf("no message to page through")
+			}
+
+			// A message is added before each page, it must neither shift the pages nor be loaded twice
+			seen := make(map[int]bool)
+			cursor := ""
+			for page := 0; page == 0 || cursor != ""; page++ {
+				if page > len(expected) {
+					t.Fatalf("the cursor never reached the end of the messages")
+				}
+				messages, nextCursor, err := GetMessagesFromThreadAfterCursor(thread, cursor, order, owner, nil)
+				if err != nil {
+					t.Fatalf("getting the page %d: %v", page, err)
+				}
+				for _, message := range messages {
+					if seen[message.MessageID] {
+						t.Errorf("the message %d was loaded twice", message.MessageID)
+					}
+					seen[message.MessageID] = true
+				}
+				cursor = nextCursor
+				_, err = AddMessageInThread(thread, fmt.Sprintf("Added before %d", page), "A message added while paging", owner, nil, nil, nil)
+				if err != nil {
+					t.Fatalf("adding a message: %v", err)
+				}
+			}
+			for _, messageID := range expected {
+				if !seen[messageID] {
+					t.Errorf("the message %d was skipped", messageID)
+				}
+			}
+		})
+	}
+}
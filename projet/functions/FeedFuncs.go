@@ -0,0 +1,124 @@
+package functions
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// AtomFeed is the root element of an Atom feed (RFC 4287)
+type AtomFeed struct {
+	XMLName  xml.Name    `xml:"feed"`
+	XMLNS    string      `xml:"xmlns,attr"`
+	ID       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Links    []AtomLink  `xml:"link"`
+	Entries  []AtomEntry `xml:"entry"`
+}
+
+// AtomLink is a link of an Atom feed or entry
+type AtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+// AtomAuthor is the author of an Atom entry
+type AtomAuthor struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+// AtomCategory is a category of an Atom entry, used for the thread and the tags of a message
+type AtomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// AtomContent is the content of an Atom entry
+type AtomContent struct {
+	Type    string `xml:"type,attr"`
+	Content string `xml:",chardata"`
+}
+
+// AtomEntry is an entry of an Atom feed, each entry is a message
+type AtomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published"`
+	Author     AtomAuthor     `xml:"author"`
+	Link       AtomLink       `xml:"link"`
+	Categories []AtomCategory `xml:"category"`
+	Content    AtomContent    `xml:"content"`
+}
+
+// GetBaseURL returns the scheme and host the request was made to (e.g. 'https://example.com')
+// It is used to build the absolute urls needed in the feeds
+func GetBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// NewAtomFeed makes an Atom feed with the given title and messages
+// The 'selfPath' is the path of the feed and the 'alternatePath' the path of the page it represents
+func NewAtomFeed(r *http.Request, title string, subtitle string, selfPath string, alternatePath string, messages []FeedMessage) AtomFeed {
+	baseURL := GetBaseURL(r)
+	feed := AtomFeed{
+		XMLNS:    "http://www.w3.org/2005/Atom",
+		ID:       baseURL + selfPath,
+		Title:    title,
+		Subtitle: subtitle,
+		Updated:  time.Now().UTC().Format(time.RFC3339),
+		Links: []AtomLink{
+			{Href: baseURL + selfPath, Rel: "self", Type: "application/atom+xml"},
+			{Href: baseURL + alternatePath, Rel: "alternate", Type: "text/html"},
+		},
+	}
+	// The feed was last updated when its most recent message was posted
+	if len(messages) > 0 {
+		feed.Updated = messages[0].Message.CreationDate.UTC().Format(time.RFC3339)
+	}
+	for _, message := range messages {
+		messageURL := fmt.Sprintf("%s/t/%s/p/%d", baseURL, message.ThreadName, message.Message.MessageID)
+		entry := AtomEntry{
+			ID:        messageURL,
+			Title:     message.Message.MessageTitle,
+			Updated:   message.Message.CreationDate.UTC().Format(time.RFC3339),
+			Published: message.Message.CreationDate.UTC().Format(time.RFC3339),
+			Author: AtomAuthor{
+				Name: message.Message.UserName,
+				URI:  baseURL + "/profile/" + message.Message.UserName,
+			},
+			Link:       AtomLink{Href: messageURL, Rel: "alternate", Type: "text/html"},
+			Categories: []AtomCategory{{Term: message.ThreadName}},
+			Content:    AtomContent{Type: "text", Content: message.Message.MessageContent},
+		}
+		for _, tag := range message.Message.MessageTags {
+			entry.Categories = append(entry.Categories, AtomCategory{Term: tag.TagName})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return feed
+}
+
+// WriteAtomFeed writes the feed as XML in the response
+func WriteAtomFeed(w http.ResponseWriter, feed AtomFeed) {
+	content, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		ErrorPrintf("Error encoding the atom feed: %v\n", err)
+		http.Error(w, "Error encoding the feed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(append([]byte(xml.Header), content...))
+	if err != nil {
+		ErrorPrintf("Error writing the atom feed: %v\n", err)
+	}
+}
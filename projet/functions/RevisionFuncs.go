@@ -0,0 +1,114 @@
+package functions
+
+import (
+	"regexp"
+	"time"
+)
+
+// DiffPartType is the kind of change of a part of a diff
+type DiffPartType string
+
+const (
+	DiffEqual   DiffPartType = "equal"   // The text is in both versions
+	DiffAdded   DiffPartType = "added"   // The text is only in the new version
+	DiffRemoved DiffPartType = "removed" // The text is only in the old version
+)
+
+// DiffPart is a piece of text of a diff between two versions
+type DiffPart struct {
+	Type DiffPartType `json:"type"`
+	Text string       `json:"text"`
+}
+
+// diffTokenRegex splits a text into words and the spaces between them
+var diffTokenRegex = regexp.MustCompile(`\s+|\S+`)
+
+// maxDiffCells is the size of the comparison table above which the texts are not compared word by word
+const maxDiffCells = 1000000
+
+// appendDiffPart adds the text to the diff, merging it with the last part if they have the same type
+func appendDiffPart(parts []DiffPart, partType DiffPartType, text string) []DiffPart {
+	if text == "" {
+		return parts
+	}
+	if len(parts) > 0 && parts[len(parts)-1].Type == partType {
+		parts[len(parts)-1].Text += text
+		return parts
+	}
+	return append(parts, DiffPart{Type: partType, Text: text})
+}
+
+// DiffWords returns the changes between the old and the new text, word by word
+// The parts are in the order of the texts, the removed parts come before the added ones
+func DiffWords(oldText string, newText string) []DiffPart {
+	if oldText == newText {
+		return appendDiffPart(nil, DiffEqual, newText)
+	}
+	oldTokens := diffTokenRegex.FindAllString(oldText, -1)
+	newTokens := diffTokenRegex.FindAllString(newText, -1)
+	// Too long to be compared word by word, the whole text is replaced
+	if (len(oldTokens)+1)*(len(newTokens)+1) > maxDiffCells {
+		return appendDiffPart(appendDiffPart(nil, DiffRemoved, oldText), DiffAdded, newText)
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of oldTokens[i:] and newTokens[j:]
+	lcs := make([][]int, len(oldTokens)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(newTokens)+1)
+	}
+	for i := len(oldTokens) - 1; i >= 0; i-- {
+		for j := len(newTokens) - 1; j >= 0; j-- {
+			if oldTokens[i] == newTokens[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var parts []DiffPart
+	i, j := 0, 0
+	for i < len(oldTokens) && j < len(newTokens) {
+		switch {
+		case oldTokens[i] == newTokens[j]:
+			parts = appendDiffPart(parts, DiffEqual, oldTokens[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			parts = appendDiffPart(parts, DiffRemoved, oldTokens[i])
+			i++
+		default:
+			parts = appendDiffPart(parts, DiffAdded, newTokens[j])
+			j++
+		}
+	}
+	for ; i < len(oldTokens); i++ {
+		parts = appendDiffPart(parts, DiffRemoved, oldTokens[i])
+	}
+	for ; j < len(newTokens); j++ {
+		parts = appendDiffPart(parts, DiffAdded, newTokens[j])
+	}
+	return parts
+}
+
+// BuildRevisionHistory returns every version of a message or a comment, from the original to the current one
+// The previous versions come from GetMessageRevisions or GetCommentRevisions, the current one is the shown title and content
+// Each version is numbered, dated and compared with the one before it
+func BuildRevisionHistory(previous []ContentRevision, current ContentRevision, creationDate time.Time) []ContentRevision {
+	current.IsCurrent = true
+	revisions := append(append([]ContentRevision{}, previous...), current)
+	for i := range revisions {
+		revisions[i].RevisionNumber = i + 1
+		if i == 0 {
+			revisions[i].RevisionDate = creationDate
+			continue
+		}
+		// A version was written when the one before it was replaced
+		revisions[i].RevisionDate = revisions[i-1].replacedDate
+		if revisions[i].Title != "" || revisions[i-1].Title != "" {
+			revisions[i].TitleDiff = DiffWords(revisions[i-1].Title, revisions[i].Title)
+		}
+		revisions[i].ContentDiff = DiffWords(revisions[i-1].Content, revisions[i].Content)
+	}
+	return revisions
+}
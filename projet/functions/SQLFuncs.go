@@ -1,21 +1,30 @@
 package functions
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	_ "github.com/mattn/go-sqlite3"
 	"golang.org/x/crypto/bcrypt"
 	"io"
 	"log"
+	"math"
 	mr "math/rand"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 var databaseInitialised = false
@@ -37,10 +46,14 @@ type User struct {
 
 // UserConfigs is a struct used to represent the user configs
 type UserConfigs struct {
-	UserID int
-	Lang   string
-	Theme  string
-	PfpID  int
+	UserID      int
+	Lang        string
+	Theme       string
+	PfpID       int
+	DisplayName string
+	Bio         string
+	Website     string
+	Location    string
 }
 
 type SimplifiedUser struct {
@@ -92,12 +105,101 @@ type ThreadGoForumConfigs struct {
 	AllowImages               bool
 	AllowLinks                bool
 	AllowTextFormatting       bool
+	AutoHideReportThreshold   int    // Number of distinct reporters hiding a message or a comment until it is reviewed, 0 disables it
+	ApprovalMinPosts          int    // Number of approved messages a member needs before posting without approval, 0 disables it
+	ApprovalMinDays           int    // Number of days a member needs to be in the thread before posting without approval, 0 disables it
+	ThreadCategory            string // One of the ThreadCategories
+	JoinPolicy                ThreadJoinPolicy
+	IsPrivate                 bool // A private thread is only listed on the home page for its members
+	MinReputationImages       int  // Reputation in the thread a member needs to post images, 0 disables it
+	MinReputationTags         int  // Reputation in the thread a member needs to create tags, 0 disables it
+	MinReputationReports      int  // Reputation in the thread a member needs to report content, 0 disables it
 }
 
+// ReputationCapability is a type used to determine the capabilities unlocked by the reputation of a user in a thread
+type ReputationCapability string
+
+// Constants used to determine the capabilities unlocked by the reputation
+const (
+	ReputationPostImages ReputationCapability = "post_images" // Post messages with images
+	ReputationCreateTags ReputationCapability = "create_tags" // Create the tags of the thread
+	ReputationReport     ReputationCapability = "report"      // Report messages and comments
+)
+
+// Reputation points given by a vote to the author of the voted content
+const (
+	ReputationMessageUpvote   = 10
+	ReputationMessageDownvote = -2
+	ReputationCommentUpvote   = 5
+	ReputationCommentDownvote = -1
+	ReputationDailyCap        = 200 // Maximum number of points a user can gain from the upvotes in a day, the downvotes are not capped
+)
+
+// ThreadJoinPolicy is a type used to determine how the users can join a thread
+type ThreadJoinPolicy string
+
+// Constants used to determine the join policy of a thread
+const (
+	ThreadJoinOpen    ThreadJoinPolicy = "open"    // Anyone can join the thread
+	ThreadJoinRequest ThreadJoinPolicy = "request" // The users send a join request that the admins of the thread approve or deny
+	ThreadJoinInvite  ThreadJoinPolicy = "invite"  // The users can only join the thread with an invite link
+)
+
+// ThreadJoinPolicies is a list of possible join policies
+var ThreadJoinPolicies = []ThreadJoinPolicy{
+	ThreadJoinOpen,
+	ThreadJoinRequest,
+	ThreadJoinInvite,
+}
+
+// JoinRequest is a request of a user to join a thread with the 'request' join policy
+type JoinRequest struct {
+	RequestID    int
+	ThreadID     int
+	UserID       int
+	Username     string
+	CreationDate time.Time
+}
+
+// ThreadInvite is an invite link of a thread, it makes the users join the thread whatever its join policy
+// MaxUses is 0 if the invite can be used without limit, ExpirationDate is nil if the invite never expires
+type ThreadInvite struct {
+	InviteID       int
+	ThreadID       int
+	CreatorID      int
+	InviteCode     string
+	MaxUses        int
+	UsesCount      int
+	ExpirationDate *time.Time
+	CreationDate   time.Time
+}
+
+// Errors returned when a user cannot join a thread, see JoinThreadWithPolicy
+var (
+	ErrInviteOnlyThread     = errors.New("the thread can only be joined with an invite")
+	ErrInvalidInvite        = errors.New("the invite does not exist, has expired or has reached its maximum number of uses")
+	ErrJoinAlreadyRequested = errors.New("the user already requested to join the thread")
+)
+
 type FormattedThread struct {
-	ThreadName       string
-	ThreadIconLink   string
-	ThreadBannerLink string
+	ThreadName        string
+	ThreadIconLink    string
+	ThreadBannerLink  string
+	ThreadDescription string
+	ThreadCategory    string
+	CreationDate      time.Time
+	MemberCount       int
+	PostCount         int       // Number of approved messages that are not deleted
+	LastActivity      time.Time // Date of the last message or comment, or the creation date of the thread if there is none
+}
+
+// ThreadDiscoveryOptions are the filters and the sort of the thread list of the home page
+type ThreadDiscoveryOptions struct {
+	Search   string // Searched in the names and the descriptions of the threads, ignored if empty
+	Category string // One of the ThreadCategories, ignored if empty
+	Sort     string // One of the ThreadDiscoverySorts
+	User     User   // The user looking at the list, the private threads are only listed for their members
+	Offset   int
 }
 
 type MediaType string
@@ -127,35 +229,87 @@ type MediaLink struct {
 // FormattedThreadMessage is a struct used to represent a thread message with limited information
 // It is used to display the thread message in the thread page
 type FormattedThreadMessage struct {
-	MessageID        int         `json:"message_id"`
-	MessageTitle     string      `json:"message_title"`
-	MessageContent   string      `json:"message_content"`
-	WasEdited        bool        `json:"was_edited"`
-	CreationDate     time.Time   `json:"creation_date"`
-	UserName         string      `json:"user_name"`
-	UserPfpAddress   string      `json:"user_pfp_address"`
-	Upvotes          int         `json:"up_votes"`
-	Downvotes        int         `json:"down_votes"`
-	NumberOfComments int         `json:"number_of_comments"`
-	MediaLinks       []string    `json:"media_links"`
-	MessageTags      []ThreadTag `json:"message_tags"`
-	VoteState        int         `json:"vote_state"`
+	MessageID        int           `json:"message_id"`
+	MessageTitle     string        `json:"message_title"`
+	MessageContent   string        `json:"message_content"`
+	WasEdited        bool          `json:"was_edited"`
+	CreationDate     time.Time     `json:"creation_date"`
+	UserName         string        `json:"user_name"`
+	UserPfpAddress   string        `json:"user_pfp_address"`
+	Upvotes          int           `json:"up_votes"`
+	Downvotes        int           `json:"down_votes"`
+	NumberOfComments int           `json:"number_of_comments"`
+	MediaLinks       []string      `json:"media_links"`
+	MessageTags      []ThreadTag   `json:"message_tags"`
+	VoteState        int           `json:"vote_state"`
+	IsHidden         bool          `json:"is_hidden"`
+	ApprovalState    ApprovalState `json:"approval_state"`
+	DeletionState    DeletionState `json:"deletion_state"` // Empty if the message is not deleted
+	CanRestore       bool          `json:"can_restore"`    // The user can restore the deleted message
+	IsPinned         bool          `json:"is_pinned"`
+	IsLocked         bool          `json:"is_locked"`
+	Poll             *MessagePoll  `json:"poll"`        // nil if the message has no poll
+	UserBadges       []UserBadge   `json:"user_badges"` // Badges of the author
+	Saved            bool          `json:"saved"`       // The user saved the message
 }
 
 // FormattedMessageComment is a struct used to represent a message comment with limited information
 type FormattedMessageComment struct {
-	CommentID      int       `json:"comment_id"`
-	CommentContent string    `json:"comment_content"`
-	WasEdited      bool      `json:"was_edited"`
-	CreationDate   time.Time `json:"creation_date"`
-	UserName       string    `json:"user_name"`
-	UserPfpAddress string    `json:"user_pfp_address"`
-	Upvotes        int       `json:"up_votes"`
-	Downvotes      int       `json:"down_votes"`
-	VoteState      int       `json:"vote_state"`
-}
-
-var OrderingList = []string{"asc", "desc", "popular", "unpopular"}
+	CommentID      int           `json:"comment_id"`
+	CommentContent string        `json:"comment_content"`
+	WasEdited      bool          `json:"was_edited"`
+	CreationDate   time.Time     `json:"creation_date"`
+	UserName       string        `json:"user_name"`
+	UserPfpAddress string        `json:"user_pfp_address"`
+	Upvotes        int           `json:"up_votes"`
+	Downvotes      int           `json:"down_votes"`
+	VoteState      int           `json:"vote_state"`
+	IsHidden       bool          `json:"is_hidden"`
+	DeletionState  DeletionState `json:"deletion_state"` // Empty if the comment is not deleted
+	CanRestore     bool          `json:"can_restore"`    // The user can restore the deleted comment
+	UserBadges     []UserBadge   `json:"user_badges"`    // Badges of the author
+	Saved          bool          `json:"saved"`          // The user saved the comment
+}
+
+// OrderingList is the list of the orders the messages of a thread can be sorted by
+// 'popular' and 'unpopular' use the lifetime score, 'hot' and 'rising' favour the recent messages
+// 'top_day', 'top_week', 'top_month' and 'top_all' only keep the messages of the period, sorted by score
+// 'controversial' favours the messages with many votes evenly split, 'most_commented' the ones with the most comments
+var OrderingList = []string{"asc", "desc", "popular", "unpopular", "hot", "rising", "top_day", "top_week", "top_month", "top_all", "controversial", "most_commented"}
+
+// ThreadCategories is the list of the categories a thread can be in
+var ThreadCategories = []string{"general", "technology", "gaming", "science", "art", "music", "sports", "news", "other"}
+
+// DefaultThreadCategory is the category of the threads created without one
+const DefaultThreadCategory = "general"
+
+// ThreadDiscoverySorts is the list of the sorts of the thread list of the home page
+// 'trending' sorts by the activity of the last days, 'newest' by creation date, 'largest' by number of members
+// 'joined' only keeps the threads the user is a member of, sorted by last activity
+var ThreadDiscoverySorts = []string{"trending", "newest", "largest", "joined"}
+
+// ThreadDiscoveryPageSize is the number of threads of a page of the thread list of the home page
+const ThreadDiscoveryPageSize = 20
+
+// trendingThreadsPeriod is the period in seconds of the activity counted by the 'trending' sort of the threads
+const trendingThreadsPeriod = 7 * 24 * 3600
+
+// topOrderingPeriods is the period in seconds of the messages kept by the 'top' orders, 0 keeps all of them
+var topOrderingPeriods = map[string]int64{
+	"top_day":   24 * 60 * 60,
+	"top_week":  7 * 24 * 60 * 60,
+	"top_month": 30 * 24 * 60 * 60,
+	"top_all":   0,
+}
+
+// risingOrderingPeriod is the age in seconds of the oldest messages kept by the 'rising' order
+const risingOrderingPeriod = 24 * 60 * 60
+
+// hotScoreEpoch is the date from which the age of the messages is counted in their hot score (2024-01-01)
+const hotScoreEpoch = 1704067200
+
+// hotScoreHalfDay is the number of seconds worth ten times more votes in the hot score
+const hotScoreHalfDay = 45000
 
 type ThreadTag struct {
 	TagID    int    `json:"tag_id"`
@@ -183,15 +337,415 @@ var ReportTypes = []ReportType{
 	OtherReport,
 }
 
+// ReportState is a type used to determine where a report is in the moderation workflow
+type ReportState string
+
+// Constants used to determine the state of a report
+const (
+	ReportOpen      ReportState = "open"      // Nobody took care of the report yet
+	ReportClaimed   ReportState = "claimed"   // A member of the moderation team is taking care of the report
+	ReportActioned  ReportState = "actioned"  // The report was closed after an action was taken (e.g. the content was deleted)
+	ReportDismissed ReportState = "dismissed" // The report was closed without any action
+)
+
+// ReportStates is a list of possible report states
+var ReportStates = []ReportState{
+	ReportOpen,
+	ReportClaimed,
+	ReportActioned,
+	ReportDismissed,
+}
+
+// ActiveReportStates are the states of the reports still waiting for the moderation team
+var ActiveReportStates = []ReportState{
+	ReportOpen,
+	ReportClaimed,
+}
+
 // ReportedContent is a struct used to represent a reported content
+// ReportedUserName is the author of the reported content, AssigneeName is the member of the moderation team who claimed or closed the report
 type ReportedContent struct {
-	ReportID              int        `json:"report_id"`
-	UserName              string     `json:"username"`
-	ReportedContentID     int        `json:"reported_content_id"`
-	IsAPostAndNotAComment bool       `json:"is_a_post_and_not_a_comment"`
-	PostID                int        `json:"post_id"`
-	ReportType            ReportType `json:"report_type"`
-	ReportContent         string     `json:"report_content"`
+	ReportID              int         `json:"report_id"`
+	UserName              string      `json:"username"`
+	ReportedContentID     int         `json:"reported_content_id"`
+	IsAPostAndNotAComment bool        `json:"is_a_post_and_not_a_comment"`
+	PostID                int         `json:"post_id"`
+	ReportType            ReportType  `json:"report_type"`
+	ReportContent         string      `json:"report_content"`
+	ReportedUserName      string      `json:"reported_user"`
+	State                 ReportState `json:"state"`
+	AssigneeName          string      `json:"assignee"`
+	ResolutionNote        string      `json:"resolution_note"`
+	ReportDate            time.Time   `json:"report_date"`
+}
+
+// ReportGroup gathers the reports made about the same content that are in the same state
+// AuthorName is the author of the reported content, ContentPreview is empty when the content was deleted
+type ReportGroup struct {
+	PostID                int
+	ReportedContentID     int
+	IsAPostAndNotAComment bool
+	AuthorName            string
+	ContentPreview        string
+	State                 ReportState
+	AssigneeName          string
+	ResolutionNote        string
+	Reports               []ReportedContent
+}
+
+// IsActive checks if the reports of the group are still waiting for the moderation team
+func (group ReportGroup) IsActive() bool {
+	return group.State == ReportOpen || group.State == ReportClaimed
+}
+
+// ReportFilter is used to filter the reports of a thread
+// An empty States list means every state, an empty Type means every type
+type ReportFilter struct {
+	States []ReportState
+	Type   ReportType
+}
+
+// ApiTokenScope is a type used to determine what a personal access token is allowed to do
+type ApiTokenScope string
+
+// Constants used to determine the scope of a personal access token
+const (
+	ApiTokenScopeRead ApiTokenScope = "read" // Read only access to the threads, messages, comments and tags
+	ApiTokenScopePost ApiTokenScope = "post" // Allows to post, edit and delete messages and comments, to vote and to join or leave threads
+)
+
+// ApiTokenScopes is a list of possible personal access token scopes
+var ApiTokenScopes = []ApiTokenScope{
+	ApiTokenScopeRead,
+	ApiTokenScopePost,
+}
+
+// ApiToken is a struct used to represent a personal access token
+// The token itself is never stored, only its hash and its first characters (to help the user recognise it)
+type ApiToken struct {
+	TokenID      int
+	UserID       int
+	TokenName    string
+	TokenPrefix  string
+	Scopes       []ApiTokenScope
+	CreationDate time.Time
+	LastUsedDate sql.NullTime
+}
+
+// WebhookEvent is a type used to determine the event that triggers a thread webhook
+type WebhookEvent string
+
+// Constants used to determine the events that trigger a thread webhook
+const (
+	WebhookMessageCreated WebhookEvent = "message.created" // A message was sent in the thread
+	WebhookCommentCreated WebhookEvent = "comment.created" // A comment was sent on a message of the thread
+	WebhookReportCreated  WebhookEvent = "report.created"  // A message or a comment of the thread was reported
+	WebhookMemberJoined   WebhookEvent = "member.joined"   // A user joined the thread
+	WebhookMemberBanned   WebhookEvent = "member.banned"   // A member was banned from the thread
+)
+
+// WebhookEvents is a list of possible webhook events
+var WebhookEvents = []WebhookEvent{
+	WebhookMessageCreated,
+	WebhookCommentCreated,
+	WebhookReportCreated,
+	WebhookMemberJoined,
+	WebhookMemberBanned,
+}
+
+// ThreadWebhook is a struct used to represent a webhook registered on a thread
+type ThreadWebhook struct {
+	WebhookID     int
+	ThreadID      int
+	WebhookURL    string
+	WebhookSecret string
+	Events        []WebhookEvent
+	CreationDate  time.Time
+}
+
+// WebhookDelivery is a struct used to represent one delivery attempt of a webhook
+// ResponseStatus is 0 when no response was received (e.g. timeout, connection refused)
+type WebhookDelivery struct {
+	DeliveryID     int
+	WebhookID      int
+	DeliveryUUID   string
+	Event          WebhookEvent
+	Attempt        int
+	ResponseStatus int
+	ErrorMessage   string
+	DeliveryDate   time.Time
+}
+
+// FeedMessage is a message along with the name of its thread, it is used to build the feeds spanning several threads
+type FeedMessage struct {
+	ThreadName string
+	Message    FormattedThreadMessage
+}
+
+// ProfileComment is a comment shown in the activity of a user profile, along with the message it was posted on
+type ProfileComment struct {
+	ThreadName   string
+	MessageID    int
+	MessageTitle string
+	Comment      FormattedMessageComment
+}
+
+// UserProfileStats are the aggregate stats shown on a user profile
+// The karma is the sum of the upvotes minus the downvotes received by the messages and comments of the user
+// The reputation is the sum of his reputation in every thread, see ReputationEvents
+type UserProfileStats struct {
+	Karma            int
+	Reputation       int
+	MessagesCount    int
+	CommentsCount    int
+	ModeratedThreads []string
+}
+
+// UserProfileActivityPageSize is the number of messages or comments of a page of the activity of a user profile
+const UserProfileActivityPageSize = 20
+
+// SavedItem is a message or a comment saved by a user, as listed on his saved items page
+type SavedItem struct {
+	ThreadName   string
+	MessageID    int // The saved message or the message of the saved comment
+	MessageTitle string
+	CommentID    int    // 0 when the saved item is a message
+	Content      string // Content of the saved message or comment
+	UserName     string // Author of the saved message or comment
+	CreationDate time.Time
+	Folder       string // Empty when the item is in no folder
+	SaveDate     time.Time
+}
+
+// SavedItemsPageSize is the number of items of a page of the saved items of a user
+const SavedItemsPageSize = 20
+
+// SavedFolderMaxLength is the maximum length of the name of a folder of saved items
+const SavedFolderMaxLength = 30
+
+// Subscription is the state of the subscription of a user to a thread or a message
+// A muted subscription is kept but its content is not shown in the 'Following' feed
+type Subscription struct {
+	IsSubscribed bool
+	IsMuted      bool
+}
+
+// FollowedThread is a thread the user subscribed to, along with the number of messages he did not read yet
+type FollowedThread struct {
+	ThreadName     string
+	IsMuted        bool
+	UnreadMessages int
+}
+
+// FollowingFeedItem is an entry of the 'Following' feed of a user
+// It is a new message of a subscribed thread or a new comment of a subscribed message (CommentID is 0 for a message)
+type FollowingFeedItem struct {
+	ThreadName   string
+	MessageID    int
+	MessageTitle string
+	CommentID    int
+	UserName     string
+	CreationDate time.Time
+	IsUnread     bool
+}
+
+// ThreadBan is the record of a ban in a thread
+// The note is only shown to the moderation team, the reason is also shown to the banned user
+// A ban without expiration date is permanent, BanID is 0 for the bans made before the bans were recorded
+type ThreadBan struct {
+	BanID          int       `json:"ban_id"`
+	Username       string    `json:"username"`
+	ModeratorName  string    `json:"moderator_name"`
+	BanReason      string    `json:"ban_reason"`
+	BanNote        string    `json:"ban_note"`
+	BanDate        time.Time `json:"ban_date"`
+	ExpirationDate time.Time `json:"expiration_date"`
+	IsPermanent    bool      `json:"is_permanent"`
+}
+
+// ModerationAction is a type used to determine the action recorded in the moderation log
+type ModerationAction string
+
+// Constants used to determine the actions recorded in the moderation log
+const (
+	ModerationMessageDeleted  ModerationAction = "message.deleted"  // A message was deleted by someone else than its author
+	ModerationCommentDeleted  ModerationAction = "comment.deleted"  // A comment was deleted by someone else than its author
+	ModerationUserBanned      ModerationAction = "user.banned"      // A member was banned from the thread
+	ModerationUserUnbanned    ModerationAction = "user.unbanned"    // A ban was lifted by a moderator
+	ModerationUserPromoted    ModerationAction = "user.promoted"    // A member was promoted
+	ModerationUserDemoted     ModerationAction = "user.demoted"     // A member was demoted
+	ModerationTagCreated      ModerationAction = "tag.created"      // A tag was created in the thread
+	ModerationTagEdited       ModerationAction = "tag.edited"       // A tag of the thread was edited
+	ModerationTagDeleted      ModerationAction = "tag.deleted"      // A tag of the thread was deleted
+	ModerationReportClaimed   ModerationAction = "report.claimed"   // The reports about a content were claimed
+	ModerationReportResolved  ModerationAction = "report.resolved"  // The reports about a content were closed after an action
+	ModerationReportDismissed ModerationAction = "report.dismissed" // The reports about a content were closed without any action
+	ModerationMessageApproved ModerationAction = "message.approved" // A message of the approval queue was approved
+	ModerationMessageRejected ModerationAction = "message.rejected" // A message of the approval queue was rejected
+	ModerationMessageRestored ModerationAction = "message.restored" // A deleted message was restored by someone else than its author
+	ModerationCommentRestored ModerationAction = "comment.restored" // A deleted comment was restored by someone else than its author
+	ModerationMessagePinned   ModerationAction = "message.pinned"   // A message was pinned
+	ModerationMessageUnpinned ModerationAction = "message.unpinned" // A message was unpinned
+	ModerationMessageLocked   ModerationAction = "message.locked"   // A message was locked
+	ModerationMessageUnlocked ModerationAction = "message.unlocked" // A message was unlocked
+	ModerationJoinApproved    ModerationAction = "join.approved"    // A join request was approved
+	ModerationJoinDenied      ModerationAction = "join.denied"      // A join request was denied
+	ModerationInviteCreated   ModerationAction = "invite.created"   // An invite link was created
+	ModerationInviteDeleted   ModerationAction = "invite.deleted"   // An invite link was deleted
+	ModerationRoleCreated     ModerationAction = "role.created"     // A custom role was created
+	ModerationRoleDeleted     ModerationAction = "role.deleted"     // A custom role was deleted
+	ModerationRoleAssigned    ModerationAction = "role.assigned"    // The custom role of a member was changed (TargetID is 0 when it was removed)
+)
+
+// ModerationActions is a list of possible moderation actions
+var ModerationActions = []ModerationAction{
+	ModerationMessageDeleted,
+	ModerationCommentDeleted,
+	ModerationUserBanned,
+	ModerationUserUnbanned,
+	ModerationUserPromoted,
+	ModerationUserDemoted,
+	ModerationTagCreated,
+	ModerationTagEdited,
+	ModerationTagDeleted,
+	ModerationReportClaimed,
+	ModerationReportResolved,
+	ModerationReportDismissed,
+	ModerationMessageApproved,
+	ModerationMessageRejected,
+	ModerationMessageRestored,
+	ModerationCommentRestored,
+	ModerationMessagePinned,
+	ModerationMessageUnpinned,
+	ModerationMessageLocked,
+	ModerationMessageUnlocked,
+	ModerationJoinApproved,
+	ModerationJoinDenied,
+	ModerationInviteCreated,
+	ModerationInviteDeleted,
+	ModerationRoleCreated,
+	ModerationRoleDeleted,
+	ModerationRoleAssigned,
+}
+
+// ModerationLogEntry is an entry of the moderation log of a thread
+// TargetName is empty when the action has no target user, TargetID is the id of the message, comment, tag or report (0 if none)
+type ModerationLogEntry struct {
+	LogID         int              `json:"log_id"`
+	ModeratorName string           `json:"moderator"`
+	Action        ModerationAction `json:"action"`
+	TargetName    string           `json:"target_user"`
+	TargetID      int              `json:"target_id"`
+	Details       string           `json:"details"`
+	LogDate       time.Time        `json:"log_date"`
+}
+
+// ModerationLogFilter is used to filter the moderation log, the zero value of each field disables its filter
+type ModerationLogFilter struct {
+	Action        ModerationAction
+	ModeratorName string
+	TargetName    string
+	From          time.Time
+	To            time.Time
+}
+
+// WordFilterAction is a type used to determine what happens to a message or a comment containing a filtered word
+type WordFilterAction string
+
+// Constants used to determine the actions of the word filters
+const (
+	WordFilterBlock WordFilterAction = "block" // The message or comment is refused
+	WordFilterMask  WordFilterAction = "mask"  // The word is replaced by asterisks
+	WordFilterFlag  WordFilterAction = "flag"  // The message or comment is sent but reported to the moderation team
+)
+
+// WordFilterActions is a list of possible word filter actions
+var WordFilterActions = []WordFilterAction{
+	WordFilterBlock,
+	WordFilterMask,
+	WordFilterFlag,
+}
+
+// WordFilter is a struct used to represent a filtered word
+// ThreadID is 0 for the words of the global list
+type WordFilter struct {
+	FilterID     int
+	ThreadID     int
+	Word         string
+	Action       WordFilterAction
+	CreationDate time.Time
+}
+
+// ApprovalState is a type used to determine if a message went through the approval queue of its thread
+type ApprovalState string
+
+// Constants used to determine the approval state of a message
+const (
+	MessageApproved ApprovalState = "approved" // The message is visible to everyone
+	MessagePending  ApprovalState = "pending"  // The message waits in the approval queue, only its author and the moderation team see it
+	MessageRejected ApprovalState = "rejected" // The message was rejected, only its author sees it
+)
+
+// ContentRevision is a version of an edited message or comment
+type ContentRevision struct {
+	RevisionNumber int        `json:"revision_number"`        // 1 for the original version
+	Title          string     `json:"title,omitempty"`        // Empty for the comments
+	Content        string     `json:"content"`                // The content of this version
+	RevisionDate   time.Time  `json:"revision_date"`          // When this version was written
+	IsCurrent      bool       `json:"is_current"`             // This version is the one shown
+	TitleDiff      []DiffPart `json:"title_diff,omitempty"`   // The changes of the title since the previous version
+	ContentDiff    []DiffPart `json:"content_diff,omitempty"` // The changes of the content since the previous version
+	replacedDate   time.Time  // When this version was replaced by the next one
+}
+
+// DeletionState tells if a message or a comment was deleted and by whom
+type DeletionState string
+
+const (
+	NotDeleted         DeletionState = ""        // The content is not deleted
+	DeletedByAuthor    DeletionState = "deleted" // The content was deleted by its author
+	RemovedByModerator DeletionState = "removed" // The content was removed by the moderation team
+)
+
+// PollResultsVisibility tells when the results of a poll are shown to a user
+type PollResultsVisibility string
+
+const (
+	PollResultsAlways    PollResultsVisibility = "always"     // The results are shown to everyone
+	PollResultsAfterVote PollResultsVisibility = "after_vote" // The results are shown once the user voted or the poll is closed
+)
+
+// PollResultsVisibilities is a list of possible visibilities of the results of a poll
+var PollResultsVisibilities = []PollResultsVisibility{PollResultsAlways, PollResultsAfterVote}
+
+// PollOption is a choice of a poll with its number of votes
+// The number of votes is 0 when the results are not shown to the user
+type PollOption struct {
+	OptionID   int    `json:"option_id"`
+	OptionText string `json:"option_text"`
+	Votes      int    `json:"votes"`
+}
+
+// MessagePoll is a poll attached to a message viewed from the point of view of a user
+type MessagePoll struct {
+	PollID            int                   `json:"poll_id"`
+	IsMultipleChoice  bool                  `json:"is_multiple_choice"`
+	ResultsVisibility PollResultsVisibility `json:"results_visibility"`
+	ClosingDate       *time.Time            `json:"closing_date"` // nil if the poll never closes
+	IsClosed          bool                  `json:"is_closed"`
+	HasVoted          bool                  `json:"has_voted"`
+	ShowResults       bool                  `json:"show_results"`
+	NumberOfVoters    int                   `json:"number_of_voters"` // 0 when the results are not shown to the user
+	VotedOptions      []int                 `json:"voted_options"`
+	Options           []PollOption          `json:"options"`
+}
+
+// NewPoll is the description of a poll to attach to a new message
+type NewPoll struct {
+	Options           []string
+	IsMultipleChoice  bool
+	ResultsVisibility PollResultsVisibility
+	ClosingDate       *time.Time // nil if the poll never closes
 }
 
 const ThreadRankBanned = -1
@@ -200,6 +754,103 @@ const ThreadRankModerator = 1
 const ThreadRankAdmin = 2
 const ThreadRankOwner = 3
 
+// ThreadPermission is a type used to determine what a member is allowed to do in a thread
+type ThreadPermission string
+
+// Constants used to determine the permissions of the members of a thread
+const (
+	PermissionSendMessages     ThreadPermission = "messages.send"      // Send, edit and comment messages
+	PermissionDeleteMessages   ThreadPermission = "messages.delete"    // Delete the messages of the other members
+	PermissionDeleteComments   ThreadPermission = "comments.delete"    // Delete the comments of the other members
+	PermissionSeeHiddenContent ThreadPermission = "content.see_hidden" // See the hidden, pending and deleted content and the revisions, restore the deleted content
+	PermissionApproveMessages  ThreadPermission = "messages.approve"   // Approve or reject the messages of the approval queue
+	PermissionPinMessages      ThreadPermission = "messages.pin"       // Pin and lock the messages
+	PermissionManageReports    ThreadPermission = "reports.manage"     // See the reports and resolve them
+	PermissionBanUsers         ThreadPermission = "users.ban"          // Ban and unban the members
+	PermissionPromoteMembers   ThreadPermission = "members.promote"    // Promote and demote the members of a lower rank
+	PermissionManageAccess     ThreadPermission = "members.access"     // Review the join requests and manage the invite links
+	PermissionManageTags       ThreadPermission = "tags.manage"        // Create, edit and delete the tags
+	PermissionViewModLog       ThreadPermission = "modlog.view"        // See the moderation log
+	PermissionManageThread     ThreadPermission = "thread.manage"      // Change the settings, the pictures, the webhooks and the word filters of the thread
+	PermissionManageRoles      ThreadPermission = "roles.manage"       // Create, delete and assign the custom roles
+)
+
+// ThreadPermissions is a list of every permission of a thread
+var ThreadPermissions = []ThreadPermission{
+	PermissionSendMessages,
+	PermissionDeleteMessages,
+	PermissionDeleteComments,
+	PermissionSeeHiddenContent,
+	PermissionApproveMessages,
+	PermissionPinMessages,
+	PermissionManageReports,
+	PermissionBanUsers,
+	PermissionPromoteMembers,
+	PermissionManageAccess,
+	PermissionManageTags,
+	PermissionViewModLog,
+	PermissionManageThread,
+	PermissionManageRoles,
+}
+
+// AssignableThreadPermissions is a list of the permissions a custom role can give
+// Managing the thread, its roles and the ranks of its members stays reserved to the default roles
+var AssignableThreadPermissions = []ThreadPermission{
+	PermissionDeleteMessages,
+	PermissionDeleteComments,
+	PermissionSeeHiddenContent,
+	PermissionApproveMessages,
+	PermissionPinMessages,
+	PermissionManageReports,
+	PermissionBanUsers,
+	PermissionManageAccess,
+	PermissionManageTags,
+	PermissionViewModLog,
+}
+
+// DefaultThreadRoles gives the permissions of each rank, the ranks are the default roles of every thread
+// A banned member has no permission
+var DefaultThreadRoles = map[int][]ThreadPermission{
+	ThreadRankUser: {
+		PermissionSendMessages,
+	},
+	ThreadRankModerator: {
+		PermissionSendMessages,
+		PermissionDeleteMessages,
+		PermissionSeeHiddenContent,
+		PermissionApproveMessages,
+		PermissionPinMessages,
+		PermissionManageReports,
+		PermissionPromoteMembers,
+	},
+	ThreadRankAdmin: {
+		PermissionSendMessages,
+		PermissionDeleteMessages,
+		PermissionDeleteComments,
+		PermissionSeeHiddenContent,
+		PermissionApproveMessages,
+		PermissionPinMessages,
+		PermissionManageReports,
+		PermissionBanUsers,
+		PermissionPromoteMembers,
+		PermissionManageAccess,
+		PermissionViewModLog,
+	},
+	ThreadRankOwner: ThreadPermissions,
+}
+
+// ThreadRole is a custom role of a thread
+// Its permissions are given to its members on top of the ones of their rank
+type ThreadRole struct {
+	RoleID      int                `json:"role_id"`
+	RoleName    string             `json:"role_name"`
+	Permissions []ThreadPermission `json:"permissions"`
+	MemberCount int                `json:"member_count"`
+}
+
+// ErrThreadRoleExists is returned when a thread already has a role with the same name
+var ErrThreadRoleExists = errors.New("the thread already has a role with this name")
+
 // InitDatabaseConnection initialises the database connection
 func InitDatabaseConnection() {
 	if !databaseInitialised {
@@ -322,7 +973,7 @@ func GetUser(r *http.Request) User {
 
 // GetUserConfig returns the user configs
 func GetUserConfig(user User) UserConfigs {
-	getUserConfig := "SELECT * FROM UserConfigs WHERE user_id = (SELECT user_id FROM Users WHERE user_id = ?)"
+	getUserConfig := "SELECT user_id, lang, theme, pfp_id, display_name, bio, website, location FROM UserConfigs WHERE user_id = (SELECT user_id FROM Users WHERE user_id = ?)"
 	rows, err := db.Query(getUserConfig, user.UserID)
 	if err != nil {
 		ErrorPrintf("Error getting the user configs: %v\n", err)
@@ -336,7 +987,7 @@ func GetUserConfig(user User) UserConfigs {
 	}(rows)
 	if rows.Next() {
 		var userConfigs UserConfigs
-		err := rows.Scan(&userConfigs.UserID, &userConfigs.Lang, &userConfigs.Theme, &userConfigs.PfpID)
+		err := rows.Scan(&userConfigs.UserID, &userConfigs.Lang, &userConfigs.Theme, &userConfigs.PfpID, &userConfigs.DisplayName, &userConfigs.Bio, &userConfigs.Website, &userConfigs.Location)
 		if err != nil {
 			ErrorPrintf("Error scanning the rows in GetUserConfig: %v\n", err)
 			return UserConfigs{}
@@ -359,6 +1010,62 @@ func UpdateUserConfig(userConfigs UserConfigs) error {
 	return nil
 }
 
+// UpdateUserProfile saves the profile fields of the user configs (display name, bio, website and location)
+// The fields must be validated beforehand with IsUserProfileValid
+// Returns an error if there is one
+func UpdateUserProfile(userConfigs UserConfigs) error {
+	saveUserProfile := "UPDATE UserConfigs SET display_name = ?, bio = ?, website = ?, location = ? WHERE user_id = ?"
+	_, err := db.Exec(saveUserProfile, userConfigs.DisplayName, userConfigs.Bio, userConfigs.Website, userConfigs.Location, userConfigs.UserID)
+	if err != nil {
+		ErrorPrintf("Error saving the user profile: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// IsDisplayNameValid checks if the display name is valid (empty or up to 30 characters without line break)
+func IsDisplayNameValid(displayName string) bool {
+	return utf8.RuneCountInString(displayName) <= 30 && !strings.ContainsAny(displayName, "\r\n")
+}
+
+// IsBioValid checks if the bio is valid (empty or up to 500 characters)
+func IsBioValid(bio string) bool {
+	return utf8.RuneCountInString(bio) <= 500
+}
+
+// IsWebsiteValid checks if the website is valid (empty or an http(s) url of up to 200 characters)
+func IsWebsiteValid(website string) bool {
+	if website == "" {
+		return true
+	}
+	if len(website) > 200 {
+		return false
+	}
+	parsedURL, err := url.ParseRequestURI(website)
+	return err == nil && (parsedURL.Scheme == "http" || parsedURL.Scheme == "https") && parsedURL.Host != ""
+}
+
+// IsLocationValid checks if the location is valid (empty or up to 50 characters without line break)
+func IsLocationValid(location string) bool {
+	return utf8.RuneCountInString(location) <= 50 && !strings.ContainsAny(location, "\r\n")
+}
+
+// IsUserProfileValid checks if every profile field of the user configs is valid
+// Returns the name of the first invalid field, or an empty string if they are all valid
+func IsUserProfileValid(userConfigs UserConfigs) string {
+	switch {
+	case !IsDisplayNameValid(userConfigs.DisplayName):
+		return "display_name"
+	case !IsBioValid(userConfigs.Bio):
+		return "bio"
+	case !IsWebsiteValid(userConfigs.Website):
+		return "website"
+	case !IsLocationValid(userConfigs.Location):
+		return "location"
+	}
+	return ""
+}
+
 // CheckIfEmailLinkedToOAuth checks if the email is already linked to an OAuth account
 // Returns true and the OAuth provider as a string if the email is linked to an OAuth provider
 // Returns false and an empty string otherwise
@@ -993,9 +1700,9 @@ func AutoDeleteOldEmailIdentification() {
 	}
 }
 
-// AddThread adds a thread to the database.
+// AddThread adds a thread in the given category (from the ThreadCategories) to the database.
 // Returns an error if there is one.
-func AddThread(owner User, threadName string, description string) error {
+func AddThread(owner User, threadName string, description string, category string) error {
 	insertThread := "INSERT INTO ThreadGoForum (thread_name, owner_id, creation_date) VALUES (?, ?, ?)"
 	_, err := db.Exec(insertThread, threadName, owner.UserID, time.Now())
 	if err != nil {
@@ -1003,8 +1710,8 @@ func AddThread(owner User, threadName string, description string) error {
 		return err
 	}
 	// Insert the thread config into the ThreadGoForumConfigs table
-	insertThreadConfig := "INSERT INTO ThreadGoForumConfigs (thread_id, thread_description) VALUES ((SELECT thread_id FROM ThreadGoForum WHERE thread_name = ?), ?)"
-	_, err = db.Exec(insertThreadConfig, threadName, description)
+	insertThreadConfig := "INSERT INTO ThreadGoForumConfigs (thread_id, thread_description, thread_category) VALUES ((SELECT thread_id FROM ThreadGoForum WHERE thread_name = ?), ?, ?)"
+	_, err = db.Exec(insertThreadConfig, threadName, description, category)
 	if err != nil {
 		ErrorPrintf("Error inserting the thread config into the database: %v\n", err)
 		return err
@@ -1016,6 +1723,7 @@ func AddThread(owner User, threadName string, description string) error {
 		ErrorPrintf("Error inserting the thread owner into the database: %v\n", err)
 		return err
 	}
+	_ = AwardBadgesForEvent(owner, BadgeEventThreadCreated)
 	return nil
 }
 
@@ -1045,21 +1753,70 @@ func GetThreadFromName(threadName string) ThreadGoForum {
 	return ThreadGoForum{}
 }
 
-// GetAllFormattedThreads returns a slice of all the threads in the database
-func GetAllFormattedThreads() []FormattedThread {
-	getAllThreads := `
-		SELECT 
-			t.thread_name AS ThreadName,
-			COALESCE(mi.media_address, 'default_thread_icon.png') AS ThreadIconLink,
-			COALESCE(mb.media_address, 'default_thread_banner.gif') AS ThreadBannerLink
-		FROM ThreadGoForum t
-		LEFT JOIN ThreadGoForumConfigs tc ON t.thread_id = tc.thread_id
-		LEFT JOIN MediaLink mi ON tc.thread_icon_id = mi.media_id
-		LEFT JOIN MediaLink mb ON tc.thread_banner_id = mb.media_id;`
-	rows, err := db.Query(getAllThreads)
-	if err != nil {
-		ErrorPrintf("Error getting all the threads: %v\n", err)
-		return []FormattedThread{}
+// GetDiscoverableThreads returns a page of the threads of the home page matching the options, with their stats
+// Also returns if there are more threads after this page
+// Returns an error if there is one
+func GetDiscoverableThreads(options ThreadDiscoveryOptions) ([]FormattedThread, bool, error) {
+	memberSQL := fmt.Sprintf("EXISTS (SELECT 1 FROM ThreadGoForumMembers m WHERE m.thread_id = t.thread_id AND m.user_id = ? AND m.rights_level > %d)", ThreadRankBanned)
+	filters := []string{"(tc.is_private = FALSE OR " + memberSQL + ")"}
+	// The trending score counts the messages and comments posted since this date
+	args := []interface{}{time.Now().Unix() - trendingThreadsPeriod, time.Now().Unix() - trendingThreadsPeriod, options.User.UserID}
+	if options.Search != "" {
+		filters = append(filters, "(t.thread_name LIKE ? ESCAPE '\\' OR tc.thread_description LIKE ? ESCAPE '\\')")
+		search := "%" + escapeLikePattern(options.Search) + "%"
+		args = append(args, search, search)
+	}
+	if options.Category != "" {
+		filters = append(filters, "tc.thread_category = ?")
+		args = append(args, options.Category)
+	}
+	var orderBy string
+	switch options.Sort {
+	case "newest":
+		orderBy = "creation_unix DESC, t.thread_id DESC"
+	case "largest":
+		orderBy = "member_count DESC, t.thread_id ASC"
+	case "joined":
+		filters = append(filters, memberSQL)
+		args = append(args, options.User.UserID)
+		orderBy = "last_activity DESC, t.thread_id ASC"
+	default:
+		orderBy = "trending_score DESC, member_count DESC, t.thread_id ASC"
+	}
+	where := "WHERE " + strings.Join(filters, " AND ")
+	// One more thread than the page size is loaded to know if there is a next page
+	args = append(args, ThreadDiscoveryPageSize+1, options.Offset)
+	getThreads := fmt.Sprintf(`
+		SELECT
+			thread_name, icon_link, banner_link, thread_description, thread_category, creation_unix, member_count, post_count,
+			MAX(creation_unix, COALESCE(last_message, 0), COALESCE(last_comment, 0)) AS last_activity
+		FROM (
+			SELECT
+				t.thread_id,
+				t.thread_name,
+				COALESCE(mi.media_address, 'default_thread_icon.png') AS icon_link,
+				COALESCE(mb.media_address, 'default_thread_banner.gif') AS banner_link,
+				tc.thread_description,
+				tc.thread_category,
+				CAST(strftime('%%s', t.creation_date) AS INTEGER) AS creation_unix,
+				(SELECT COUNT(*) FROM ThreadGoForumMembers m WHERE m.thread_id = t.thread_id AND m.rights_level > %[1]d) AS member_count,
+				(SELECT COUNT(*) FROM ThreadMessages tm WHERE tm.thread_id = t.thread_id AND %[2]s) AS post_count,
+				(SELECT MAX(CAST(strftime('%%s', tm.creation_date) AS INTEGER)) FROM ThreadMessages tm WHERE tm.thread_id = t.thread_id AND %[2]s) AS last_message,
+				(SELECT MAX(CAST(strftime('%%s', c.creation_date) AS INTEGER)) FROM ThreadComments c JOIN ThreadMessages tm ON tm.message_id = c.message_id WHERE tm.thread_id = t.thread_id AND c.deletion_date IS NULL AND %[2]s) AS last_comment,
+				(SELECT COUNT(*) FROM ThreadMessages tm WHERE tm.thread_id = t.thread_id AND %[2]s AND CAST(strftime('%%s', tm.creation_date) AS INTEGER) >= ?)
+					+ (SELECT COUNT(*) FROM ThreadComments c JOIN ThreadMessages tm ON tm.message_id = c.message_id WHERE tm.thread_id = t.thread_id AND c.deletion_date IS NULL AND %[2]s AND CAST(strftime('%%s', c.creation_date) AS INTEGER) >= ?) AS trending_score
+			FROM ThreadGoForum t
+			LEFT JOIN ThreadGoForumConfigs tc ON t.thread_id = tc.thread_id
+			LEFT JOIN MediaLink mi ON tc.thread_icon_id = mi.media_id
+			LEFT JOIN MediaLink mb ON tc.thread_banner_id = mb.media_id
+			%[3]s
+		) t
+		ORDER BY %[4]s
+		LIMIT ? OFFSET ?`, ThreadRankBanned, "tm.approval_state = 'approved' AND tm.deletion_date IS NULL", where, orderBy)
+	rows, err := db.Query(getThreads, args...)
+	if err != nil {
+		ErrorPrintf("Error getting the discoverable threads: %v\n", err)
+		return nil, false, err
 	}
 	defer func(rows *sql.Rows) {
 		err := rows.Close()
@@ -1070,14 +1827,35 @@ func GetAllFormattedThreads() []FormattedThread {
 	var threads []FormattedThread
 	for rows.Next() {
 		var thread FormattedThread
-		err := rows.Scan(&thread.ThreadName, &thread.ThreadIconLink, &thread.ThreadBannerLink)
+		var creationDate, lastActivity int64
+		err := rows.Scan(&thread.ThreadName, &thread.ThreadIconLink, &thread.ThreadBannerLink, &thread.ThreadDescription, &thread.ThreadCategory, &creationDate, &thread.MemberCount, &thread.PostCount, &lastActivity)
 		if err != nil {
-			ErrorPrintf("Error scanning the rows in GetAllFormattedThreads: %v\n", err)
-			return []FormattedThread{}
+			ErrorPrintf("Error scanning the rows in GetDiscoverableThreads: %v\n", err)
+			return nil, false, err
 		}
+		thread.CreationDate = time.Unix(creationDate, 0)
+		thread.LastActivity = time.Unix(lastActivity, 0)
 		threads = append(threads, thread)
 	}
-	return threads
+	if len(threads) > ThreadDiscoveryPageSize {
+		return threads[:ThreadDiscoveryPageSize], true, nil
+	}
+	return threads, false, nil
+}
+
+// escapeLikePattern escapes the wildcards of a LIKE pattern, the escape character is '\'
+func escapeLikePattern(pattern string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(pattern)
+}
+
+// IsThreadCategoryValid checks if the category is one of the ThreadCategories
+func IsThreadCategoryValid(category string) bool {
+	return slices.Contains(ThreadCategories, category)
+}
+
+// IsThreadDiscoverySortValid checks if the sort is one of the ThreadDiscoverySorts
+func IsThreadDiscoverySortValid(sort string) bool {
+	return slices.Contains(ThreadDiscoverySorts, sort)
 }
 
 // GetThreadConfigsFromID returns the ThreadGoForumConfigs from the thread id
@@ -1106,6 +1884,15 @@ func GetThreadConfigsFromID(threadID int) ThreadGoForumConfigs {
 			&threadConfig.AllowImages,
 			&threadConfig.AllowLinks,
 			&threadConfig.AllowTextFormatting,
+			&threadConfig.AutoHideReportThreshold,
+			&threadConfig.ApprovalMinPosts,
+			&threadConfig.ApprovalMinDays,
+			&threadConfig.ThreadCategory,
+			&threadConfig.JoinPolicy,
+			&threadConfig.IsPrivate,
+			&threadConfig.MinReputationImages,
+			&threadConfig.MinReputationTags,
+			&threadConfig.MinReputationReports,
 		)
 		if err != nil {
 			ErrorPrintf("Error scanning the rows in GetThreadConfigsFromID: %v\n", err)
@@ -1151,7 +1938,16 @@ func UpdateThreadConfigs(threadConfigs ThreadGoForumConfigs) error {
 			is_open_to_non_connected_users = ?,
 			allow_images = ?,
 			allow_links = ?,
-			allow_text_formatting = ?
+			allow_text_formatting = ?,
+			auto_hide_report_threshold = ?,
+			approval_min_posts = ?,
+			approval_min_days = ?,
+			thread_category = ?,
+			join_policy = ?,
+			is_private = ?,
+			min_reputation_images = ?,
+			min_reputation_tags = ?,
+			min_reputation_reports = ?
 		WHERE thread_id = ?
 		`
 	_, err := db.Exec(updateThreadConfig,
@@ -1163,6 +1959,15 @@ func UpdateThreadConfigs(threadConfigs ThreadGoForumConfigs) error {
 		threadConfigs.AllowImages,
 		threadConfigs.AllowLinks,
 		threadConfigs.AllowTextFormatting,
+		threadConfigs.AutoHideReportThreshold,
+		threadConfigs.ApprovalMinPosts,
+		threadConfigs.ApprovalMinDays,
+		threadConfigs.ThreadCategory,
+		string(threadConfigs.JoinPolicy),
+		threadConfigs.IsPrivate,
+		threadConfigs.MinReputationImages,
+		threadConfigs.MinReputationTags,
+		threadConfigs.MinReputationReports,
 		threadConfigs.ThreadID)
 	if err != nil {
 		ErrorPrintf("Error updating the thread configs: %v\n", err)
@@ -1328,6 +2133,7 @@ func JoinThread(thread ThreadGoForum, user User) error {
 		return err
 	}
 	InfoPrintf("User %s joined the thread %s\n", user.Email, thread.ThreadName)
+	TriggerThreadWebhooks(thread, WebhookMemberJoined, WebhookMemberData{Username: user.Username})
 	return nil
 }
 
@@ -1344,111 +2150,431 @@ func LeaveThread(thread ThreadGoForum, user User) error {
 	return nil
 }
 
-// PromoteUserInThread promotes the user in the thread
-// Returns an error if there is one
-func PromoteUserInThread(thread ThreadGoForum, user User) error {
-	rightLevel := GetThreadMemberRightsLevel(thread, user)
-	if rightLevel >= 2 {
-		ErrorPrintf("Error: user %s is already an admin or owner in the thread %s\n", user.Email, thread.ThreadName)
-		return fmt.Errorf("user %s is already an admin or owner in the thread %s", user.Email, thread.ThreadName)
+// JoinThreadWithPolicy makes the user join the thread following its join policy
+// A valid invite code of the thread makes the user join it whatever the policy, an empty code is ignored
+// With the 'request' policy a join request is sent instead and joined is false
+// Returns ErrInviteOnlyThread, ErrInvalidInvite, ErrJoinAlreadyRequested or the error if there is one
+func JoinThreadWithPolicy(thread ThreadGoForum, user User, inviteCode string) (bool, error) {
+	if inviteCode != "" {
+		invite, err := GetThreadInviteFromCode(inviteCode)
+		if err != nil {
+			return false, ErrInvalidInvite
+		}
+		err = UseThreadInvite(thread, invite, user)
+		if err != nil {
+			return false, err
+		}
+		return true, nil
 	}
-	rightLevel++
-	updateThreadMember := "UPDATE ThreadGoForumMembers SET rights_level = ? WHERE thread_id = ? AND user_id = ?"
-	_, err := db.Exec(updateThreadMember, rightLevel, thread.ThreadID, user.UserID)
-	if err != nil {
-		ErrorPrintf("Error promoting the user in the thread: %v\n", err)
-		return err
+	switch GetThreadConfigFromThread(thread).JoinPolicy {
+	case ThreadJoinRequest:
+		if HasUserRequestedToJoin(thread, user) {
+			return false, ErrJoinAlreadyRequested
+		}
+		return false, AddJoinRequest(thread, user)
+	case ThreadJoinInvite:
+		return false, ErrInviteOnlyThread
+	default:
+		return true, JoinThread(thread, user)
 	}
-	InfoPrintf("User %s promoted in the thread %s\n", user.Email, thread.ThreadName)
-	return nil
 }
 
-// DemoteUserInThread demotes the user in the thread
+// AddJoinRequest adds a request of the user to join the thread
 // Returns an error if there is one
-func DemoteUserInThread(thread ThreadGoForum, user User) error {
-	rightLevel := GetThreadMemberRightsLevel(thread, user)
-	if rightLevel <= 0 {
-		ErrorPrintf("Error: user %s is already a banned member in the thread %s\n", user.Email, thread.ThreadName)
-		return fmt.Errorf("user %s is already a banned member in the thread %s", user.Email, thread.ThreadName)
-	}
-	rightLevel--
-	updateThreadMember := "UPDATE ThreadGoForumMembers SET rights_level = ? WHERE thread_id = ? AND user_id = ?"
-	_, err := db.Exec(updateThreadMember, rightLevel, thread.ThreadID, user.UserID)
+func AddJoinRequest(thread ThreadGoForum, user User) error {
+	addRequest := "INSERT INTO ThreadJoinRequests (thread_id, user_id) VALUES (?, ?)"
+	_, err := db.Exec(addRequest, thread.ThreadID, user.UserID)
 	if err != nil {
-		ErrorPrintf("Error demoting the user in the thread: %v\n", err)
+		ErrorPrintf("Error adding the join request: %v\n", err)
 		return err
 	}
-	InfoPrintf("User %s demoted in the thread %s\n", user.Email, thread.ThreadName)
+	InfoPrintf("User %s requested to join the thread %s\n", user.Email, thread.ThreadName)
 	return nil
 }
 
-// AddUserToThread adds the user to the thread
-// Returns an error if there is one
-func AddUserToThread(thread ThreadGoForum, user User, rightLevel int) error {
-	if rightLevel == -1 {
-		InfoPrintf("Adding user %s to the thread %s as a banned member\n", user.Email, thread.ThreadName)
-	} else if rightLevel == 0 {
-		InfoPrintf("Adding user %s to the thread %s as a member\n", user.Email, thread.ThreadName)
-	} else if rightLevel == 1 {
-		InfoPrintf("Adding user %s to the thread %s as a moderator\n", user.Email, thread.ThreadName)
-	} else if rightLevel == 2 {
-		InfoPrintf("Adding user %s to the thread %s as an admin\n", user.Email, thread.ThreadName)
-	} else if rightLevel == 3 {
-		InfoPrintf("Adding user %s to the thread %s as a owner\n", user.Email, thread.ThreadName)
-	} else {
-		ErrorPrintf("Error: unknown rights level %d\n", rightLevel)
-		return fmt.Errorf("unknown rights level %d", rightLevel)
-	}
-	insertThreadOwner := "INSERT INTO ThreadGoForumMembers (thread_id, user_id, rights_level) VALUES ((SELECT thread_id FROM ThreadGoForum WHERE thread_name = ?), ?, ?)"
-	_, err := db.Exec(insertThreadOwner, thread.ThreadName, user.UserID, rightLevel)
+// HasUserRequestedToJoin checks if the user has a pending join request in the thread
+func HasUserRequestedToJoin(thread ThreadGoForum, user User) bool {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM ThreadJoinRequests WHERE thread_id = ? AND user_id = ?", thread.ThreadID, user.UserID).Scan(&count)
 	if err != nil {
-		ErrorPrintf("Error inserting the thread owner into the database: %v\n", err)
-		return err
+		ErrorPrintf("Error checking the join request of the user: %v\n", err)
+		return false
 	}
-	return nil
+	return count > 0
 }
 
-// IsAMediaType checks if the string is a media type
-func IsAMediaType(mediaType string) bool {
-	for _, v := range MediaTypes {
-		if string(v) == mediaType {
-			return true
+// GetJoinRequests returns the pending join requests of the thread, the oldest first
+// Returns an error if there is one
+func GetJoinRequests(thread ThreadGoForum) ([]JoinRequest, error) {
+	getRequests := `
+		SELECT r.request_id, r.thread_id, r.user_id, u.username, r.creation_date
+		FROM ThreadJoinRequests r
+		JOIN Users u ON u.user_id = r.user_id
+		WHERE r.thread_id = ?
+		ORDER BY r.creation_date, r.request_id`
+	rows, err := db.Query(getRequests, thread.ThreadID)
+	if err != nil {
+		ErrorPrintf("Error getting the join requests: %v\n", err)
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	var requests []JoinRequest
+	for rows.Next() {
+		var request JoinRequest
+		err := rows.Scan(&request.RequestID, &request.ThreadID, &request.UserID, &request.Username, &request.CreationDate)
+		if err != nil {
+			ErrorPrintf("Error scanning the rows in GetJoinRequests: %v\n", err)
+			return nil, err
 		}
+		requests = append(requests, request)
 	}
-	return false
+	return requests, nil
 }
 
-// GetMediaTypeFromString returns the media type from the string
-// Returns an error if the media type is not valid
-func GetMediaTypeFromString(mediaType string) (MediaType, error) {
-	for _, v := range MediaTypes {
-		if string(v) == mediaType {
-			return v, nil
+// ReviewJoinRequest removes the join request from the thread, the user joins the thread if the request is approved
+// Returns the id of the user of the request
+// Returns sql.ErrNoRows if the request does not exist in the thread or the error if there is one
+func ReviewJoinRequest(thread ThreadGoForum, requestID int, approve bool) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		ErrorPrintf("Error starting the review of the join request: %v\n", err)
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	var userID int
+	var username string
+	getRequest := `
+		SELECT r.user_id, u.username
+		FROM ThreadJoinRequests r
+		JOIN Users u ON r.user_id = u.user_id
+		WHERE r.request_id = ? AND r.thread_id = ?`
+	err = tx.QueryRow(getRequest, requestID, thread.ThreadID).Scan(&userID, &username)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			ErrorPrintf("Error getting the join request: %v\n", err)
 		}
+		return 0, err
 	}
-	return "", fmt.Errorf("invalid media type: %s", mediaType)
-}
-
-// IsAReportType checks if the string is a report type
-func IsAReportType(reportType string) bool {
-	for _, v := range ReportTypes {
-		if string(v) == reportType {
-			return true
+	_, err = tx.Exec("DELETE FROM ThreadJoinRequests WHERE request_id = ?", requestID)
+	if err != nil {
+		ErrorPrintf("Error removing the join request: %v\n", err)
+		return 0, err
+	}
+	joined := false
+	if approve {
+		result, err := tx.Exec("INSERT OR IGNORE INTO ThreadGoForumMembers (thread_id, user_id) VALUES (?, ?)", thread.ThreadID, userID)
+		if err != nil {
+			ErrorPrintf("Error adding the user of the join request to the thread: %v\n", err)
+			return 0, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			ErrorPrintf("Error getting the added members: %v\n", err)
+			return 0, err
 		}
+		joined = affected > 0
 	}
-	return false
+	err = tx.Commit()
+	if err != nil {
+		ErrorPrintf("Error committing the review of the join request: %v\n", err)
+		return 0, err
+	}
+	if joined {
+		InfoPrintf("User %s joined the thread %s with an approved join request\n", username, thread.ThreadName)
+		TriggerThreadWebhooks(thread, WebhookMemberJoined, WebhookMemberData{Username: username})
+	}
+	return userID, nil
 }
 
-// GetReportTypesAsStrings returns the report types as a string
-func GetReportTypesAsStrings() []string {
-	var reportTypes []string
-	for _, v := range ReportTypes {
-		reportTypes = append(reportTypes, string(v))
+// CreateThreadInvite creates an invite link of the thread
+// maxUses is 0 for an invite without limit of uses, expirationDate is nil for an invite that never expires
+// Returns the invite and an error if there is one
+func CreateThreadInvite(thread ThreadGoForum, creator User, maxUses int, expirationDate *time.Time) (ThreadInvite, error) {
+	randomBytes := make([]byte, 12)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		ErrorPrintf("Error generating the invite code: %v\n", err)
+		return ThreadInvite{}, err
 	}
-	return reportTypes
+	invite := ThreadInvite{
+		ThreadID:       thread.ThreadID,
+		CreatorID:      creator.UserID,
+		InviteCode:     hex.EncodeToString(randomBytes),
+		MaxUses:        maxUses,
+		ExpirationDate: expirationDate,
+		CreationDate:   time.Now(),
+	}
+	addInvite := "INSERT INTO ThreadInvites (thread_id, creator_id, invite_code, max_uses, expiration_date, creation_date) VALUES (?, ?, ?, ?, ?, ?)"
+	result, err := db.Exec(addInvite, invite.ThreadID, invite.CreatorID, invite.InviteCode, invite.MaxUses, invite.ExpirationDate, invite.CreationDate)
+	if err != nil {
+		ErrorPrintf("Error adding the invite: %v\n", err)
+		return ThreadInvite{}, err
+	}
+	inviteID, err := result.LastInsertId()
+	if err != nil {
+		ErrorPrintf("Error getting the id of the invite: %v\n", err)
+		return ThreadInvite{}, err
+	}
+	invite.InviteID = int(inviteID)
+	return invite, nil
 }
 
-// GetReportTypeFromString returns the report type from the string
+// DeleteThreadInvite deletes the invite link from the thread
+// Returns an error if there is one or if the invite does not belong to the thread
+func DeleteThreadInvite(thread ThreadGoForum, inviteID int) error {
+	result, err := db.Exec("DELETE FROM ThreadInvites WHERE invite_id = ? AND thread_id = ?", inviteID, thread.ThreadID)
+	if err != nil {
+		ErrorPrintf("Error deleting the invite: %v\n", err)
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		ErrorPrintf("Error getting the deleted invites: %v\n", err)
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("invite %d does not exist in the thread %s", inviteID, thread.ThreadName)
+	}
+	return nil
+}
+
+// threadInviteColumns are the columns scanned by scanThreadInvite
+const threadInviteColumns = "invite_id, thread_id, creator_id, invite_code, max_uses, uses_count, expiration_date, creation_date"
+
+// scanThreadInvite scans a row of the ThreadInvites table selected with the threadInviteColumns
+func scanThreadInvite(scanner interface{ Scan(...any) error }) (ThreadInvite, error) {
+	var invite ThreadInvite
+	var expirationDate sql.NullTime
+	err := scanner.Scan(&invite.InviteID, &invite.ThreadID, &invite.CreatorID, &invite.InviteCode, &invite.MaxUses, &invite.UsesCount, &expirationDate, &invite.CreationDate)
+	if expirationDate.Valid {
+		invite.ExpirationDate = &expirationDate.Time
+	}
+	return invite, err
+}
+
+// GetThreadInvites returns the invite links of the thread, the newest first
+// Returns an error if there is one
+func GetThreadInvites(thread ThreadGoForum) ([]ThreadInvite, error) {
+	rows, err := db.Query("SELECT "+threadInviteColumns+" FROM ThreadInvites WHERE thread_id = ? ORDER BY invite_id DESC", thread.ThreadID)
+	if err != nil {
+		ErrorPrintf("Error getting the invites: %v\n", err)
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	var invites []ThreadInvite
+	for rows.Next() {
+		invite, err := scanThreadInvite(rows)
+		if err != nil {
+			ErrorPrintf("Error scanning the rows in GetThreadInvites: %v\n", err)
+			return nil, err
+		}
+		invites = append(invites, invite)
+	}
+	return invites, nil
+}
+
+// GetThreadInviteFromCode returns the invite with the given code
+// Returns sql.ErrNoRows if there is none or the error if there is one
+func GetThreadInviteFromCode(inviteCode string) (ThreadInvite, error) {
+	invite, err := scanThreadInvite(db.QueryRow("SELECT "+threadInviteColumns+" FROM ThreadInvites WHERE invite_code = ?", inviteCode))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		ErrorPrintf("Error getting the invite from the code: %v\n", err)
+	}
+	return invite, err
+}
+
+// IsUsable checks if the invite has not expired and has not reached its maximum number of uses
+func (invite ThreadInvite) IsUsable() bool {
+	if invite.ExpirationDate != nil && !invite.ExpirationDate.After(time.Now()) {
+		return false
+	}
+	return invite.MaxUses == 0 || invite.UsesCount < invite.MaxUses
+}
+
+// UseThreadInvite makes the user join the thread of the invite and counts the use of the invite
+// The pending join request of the user in the thread is removed
+// Returns ErrInvalidInvite if the invite is not one of the thread or cannot be used anymore, or the error if there is one
+func UseThreadInvite(thread ThreadGoForum, invite ThreadInvite, user User) error {
+	if invite.ThreadID != thread.ThreadID {
+		return ErrInvalidInvite
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		ErrorPrintf("Error starting the use of the invite: %v\n", err)
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	// The use is counted only if the invite is still usable, so two users cannot take its last use
+	useInvite := `
+		UPDATE ThreadInvites SET uses_count = uses_count + 1
+		WHERE invite_id = ? AND (max_uses = 0 OR uses_count < max_uses) AND (expiration_date IS NULL OR expiration_date > ?)`
+	result, err := tx.Exec(useInvite, invite.InviteID, time.Now())
+	if err != nil {
+		ErrorPrintf("Error using the invite: %v\n", err)
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		ErrorPrintf("Error getting the used invites: %v\n", err)
+		return err
+	}
+	if affected == 0 {
+		return ErrInvalidInvite
+	}
+	_, err = tx.Exec("INSERT INTO ThreadGoForumMembers (thread_id, user_id) VALUES (?, ?)", invite.ThreadID, user.UserID)
+	if err != nil {
+		ErrorPrintf("Error adding the user of the invite to the thread: %v\n", err)
+		return err
+	}
+	_, err = tx.Exec("DELETE FROM ThreadJoinRequests WHERE thread_id = ? AND user_id = ?", invite.ThreadID, user.UserID)
+	if err != nil {
+		ErrorPrintf("Error removing the join request of the user of the invite: %v\n", err)
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		ErrorPrintf("Error committing the use of the invite: %v\n", err)
+		return err
+	}
+	InfoPrintf("User %s joined the thread %s with the invite %d\n", user.Username, thread.ThreadName, invite.InviteID)
+	TriggerThreadWebhooks(thread, WebhookMemberJoined, WebhookMemberData{Username: user.Username})
+	return nil
+}
+
+// IsThreadInviteLimitValid checks if the limits of an invite link are valid
+// The number of uses must be between 0 and 1000 and the duration between 0 and 8760 hours (a year), 0 disables the limit
+func IsThreadInviteLimitValid(maxUses int, expiresInHours int) bool {
+	return maxUses >= 0 && maxUses <= 1000 && expiresInHours >= 0 && expiresInHours <= 8760
+}
+
+// IsThreadJoinPolicyValid checks if the join policy is one of the ThreadJoinPolicies
+func IsThreadJoinPolicyValid(policy ThreadJoinPolicy) bool {
+	return slices.Contains(ThreadJoinPolicies, policy)
+}
+
+// PromoteUserInThread promotes the user in the thread
+// Returns an error if there is one
+func PromoteUserInThread(thread ThreadGoForum, user User) error {
+	rightLevel := GetThreadMemberRightsLevel(thread, user)
+	if rightLevel >= 2 {
+		ErrorPrintf("Error: user %s is already an admin or owner in the thread %s\n", user.Email, thread.ThreadName)
+		return fmt.Errorf("user %s is already an admin or owner in the thread %s", user.Email, thread.ThreadName)
+	}
+	rightLevel++
+	updateThreadMember := "UPDATE ThreadGoForumMembers SET rights_level = ? WHERE thread_id = ? AND user_id = ?"
+	_, err := db.Exec(updateThreadMember, rightLevel, thread.ThreadID, user.UserID)
+	if err != nil {
+		ErrorPrintf("Error promoting the user in the thread: %v\n", err)
+		return err
+	}
+	InfoPrintf("User %s promoted in the thread %s\n", user.Email, thread.ThreadName)
+	_ = AwardBadgesForEvent(user, BadgeEventMemberPromoted)
+	return nil
+}
+
+// DemoteUserInThread demotes the user in the thread
+// Returns an error if there is one
+func DemoteUserInThread(thread ThreadGoForum, user User) error {
+	rightLevel := GetThreadMemberRightsLevel(thread, user)
+	if rightLevel <= 0 {
+		ErrorPrintf("Error: user %s is already a banned member in the thread %s\n", user.Email, thread.ThreadName)
+		return fmt.Errorf("user %s is already a banned member in the thread %s", user.Email, thread.ThreadName)
+	}
+	rightLevel--
+	updateThreadMember := "UPDATE ThreadGoForumMembers SET rights_level = ? WHERE thread_id = ? AND user_id = ?"
+	_, err := db.Exec(updateThreadMember, rightLevel, thread.ThreadID, user.UserID)
+	if err != nil {
+		ErrorPrintf("Error demoting the user in the thread: %v\n", err)
+		return err
+	}
+	InfoPrintf("User %s demoted in the thread %s\n", user.Email, thread.ThreadName)
+	return nil
+}
+
+// AddUserToThread adds the user to the thread
+// Returns an error if there is one
+func AddUserToThread(thread ThreadGoForum, user User, rightLevel int) error {
+	if rightLevel == -1 {
+		InfoPrintf("Adding user %s to the thread %s as a banned member\n", user.Email, thread.ThreadName)
+	} else if rightLevel == 0 {
+		InfoPrintf("Adding user %s to the thread %s as a member\n", user.Email, thread.ThreadName)
+	} else if rightLevel == 1 {
+		InfoPrintf("Adding user %s to the thread %s as a moderator\n", user.Email, thread.ThreadName)
+	} else if rightLevel == 2 {
+		InfoPrintf("Adding user %s to the thread %s as an admin\n", user.Email, thread.ThreadName)
+	} else if rightLevel == 3 {
+		InfoPrintf("Adding user %s to the thread %s as a owner\n", user.Email, thread.ThreadName)
+	} else {
+		ErrorPrintf("Error: unknown rights level %d\n", rightLevel)
+		return fmt.Errorf("unknown rights level %d", rightLevel)
+	}
+	insertThreadOwner := "INSERT INTO ThreadGoForumMembers (thread_id, user_id, rights_level) VALUES ((SELECT thread_id FROM ThreadGoForum WHERE thread_name = ?), ?, ?)"
+	_, err := db.Exec(insertThreadOwner, thread.ThreadName, user.UserID, rightLevel)
+	if err != nil {
+		ErrorPrintf("Error inserting the thread owner into the database: %v\n", err)
+		return err
+	}
+	if rightLevel >= ThreadRankModerator {
+		_ = AwardBadgesForEvent(user, BadgeEventMemberPromoted)
+	}
+	return nil
+}
+
+// IsAMediaType checks if the string is a media type
+func IsAMediaType(mediaType string) bool {
+	for _, v := range MediaTypes {
+		if string(v) == mediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// GetMediaTypeFromString returns the media type from the string
+// Returns an error if the media type is not valid
+func GetMediaTypeFromString(mediaType string) (MediaType, error) {
+	for _, v := range MediaTypes {
+		if string(v) == mediaType {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("invalid media type: %s", mediaType)
+}
+
+// IsAReportType checks if the string is a report type
+func IsAReportType(reportType string) bool {
+	for _, v := range ReportTypes {
+		if string(v) == reportType {
+			return true
+		}
+	}
+	return false
+}
+
+// GetReportTypesAsStrings returns the report types as a string
+func GetReportTypesAsStrings() []string {
+	var reportTypes []string
+	for _, v := range ReportTypes {
+		reportTypes = append(reportTypes, string(v))
+	}
+	return reportTypes
+}
+
+// GetReportTypeFromString returns the report type from the string
 // Returns an error if the report type is not valid
 func GetReportTypeFromString(reportType string) (ReportType, error) {
 	for _, v := range ReportTypes {
@@ -1631,32 +2757,14 @@ func AutoDeleteUselessMediaLinks() {
 // IsUserAllowedToSendMessageInThread checks if the user is allowed to send a message in the thread
 // Returns true if the user is allowed to send a message and false otherwise
 func IsUserAllowedToSendMessageInThread(thread ThreadGoForum, user User) bool {
-	if thread.ThreadID <= 0 {
-		return false
-	}
-	if thread.OwnerID == user.UserID {
-		return true
-	}
-	if IsUserInThread(thread, user) {
-		if IsUserBannedFromThread(thread, user) {
-			return false
-		}
-		return true
-	}
-	return false
+	return HasThreadPermission(thread, user, PermissionSendMessages)
 }
 
 // IsUserAllowedToEditMessageInThread checks if the user is allowed to edit a message in the thread
 // Returns true if the user is allowed to edit a message and false otherwise
 func IsUserAllowedToEditMessageInThread(thread ThreadGoForum, user User, messageID int) bool {
-	if thread.ThreadID <= 0 {
-		return false
-	}
-	if IsUserInThread(thread, user) {
-		if IsUserBannedFromThread(thread, user) {
-			return false
-		}
-		checkIfOwner := "SELECT message_id FROM ThreadMessages WHERE thread_id = ? AND message_id = ? AND user_id = ?"
+	if HasThreadPermission(thread, user, PermissionSendMessages) {
+		checkIfOwner := "SELECT message_id FROM ThreadMessages WHERE thread_id = ? AND message_id = ? AND user_id = ? AND deletion_date IS NULL"
 		rows, err := db.Query(checkIfOwner, thread.ThreadID, messageID, user.UserID)
 		if err != nil {
 			ErrorPrintf("Error checking if the user is the owner of the message: %v\n", err)
@@ -1677,12 +2785,9 @@ func IsUserAllowedToEditMessageInThread(thread ThreadGoForum, user User, message
 
 // IsUserAllowedToDeleteMessage checks if the user is allowed to delete the message
 // Returns true if the user is allowed to delete the message and false otherwise
-// A user is allowed to delete a message if he is the owner of the message or if he is the owner or an admin of the thread
+// A user is allowed to delete a message if he is the owner of the message or if he has the PermissionDeleteMessages permission
 func IsUserAllowedToDeleteMessage(thread ThreadGoForum, user User, messageID int) bool {
-	if thread.OwnerID == user.UserID {
-		return true
-	}
-	if GetUserRankInThread(thread, user) >= 1 {
+	if HasThreadPermission(thread, user, PermissionDeleteMessages) {
 		return true
 	}
 	// Check if the user is the owner of the message
@@ -1714,19 +2819,31 @@ func IsUserAllowedToDeleteMessage(thread ThreadGoForum, user User, messageID int
 
 // IsUserAllowedToBanUserInThread checks if the user is allowed to ban a user in the thread
 // Returns true if the user is allowed to ban a user and false otherwise
-// A user is allowed to ban a user if he is the owner or an admin of the thread
+// A user is allowed to ban a user if he has the PermissionBanUsers permission
 func IsUserAllowedToBanUserInThread(thread ThreadGoForum, user User) bool {
-	if GetUserRankInThread(thread, user) >= 2 {
-		return true
-	}
-	return false
+	return HasThreadPermission(thread, user, PermissionBanUsers)
 }
 
-// AddMessageInThread adds a message to the thread
+// AddMessageInThread adds a message to the thread with its media links, its tags and its poll (nil if the message has no poll)
+// Everything is added in a single transaction, the message is only announced once it is committed
+// See IsNewPollValid for the checks to make on the poll before
 // Returns an error if there is one
-func AddMessageInThread(thread ThreadGoForum, title string, content string, user User, mediaLinksID []int, TagIDs []int) (int, error) {
-	insertMessage := "INSERT INTO ThreadMessages (thread_id, user_id, message_title, message_content) VALUES (?, ?, ?, ?)"
-	res, err := db.Exec(insertMessage, thread.ThreadID, user.UserID, title, content)
+func AddMessageInThread(thread ThreadGoForum, title string, content string, user User, mediaLinksID []int, TagIDs []int, poll *NewPoll) (int, error) {
+	// The messages of the new members wait in the approval queue if the thread asks for it
+	approvalState := MessageApproved
+	if NeedsPostApproval(thread, user) {
+		approvalState = MessagePending
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		ErrorPrintf("Error starting the transaction of the new message: %v\n", err)
+		return -1, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	insertMessage := "INSERT INTO ThreadMessages (thread_id, user_id, message_title, message_content, approval_state) VALUES (?, ?, ?, ?, ?)"
+	res, err := tx.Exec(insertMessage, thread.ThreadID, user.UserID, title, content, string(approvalState))
 	if err != nil {
 		ErrorPrintf("Error inserting the message into the database: %v\n", err)
 		return -1, err
@@ -1736,10 +2853,22 @@ func AddMessageInThread(thread ThreadGoForum, title string, content string, user
 		ErrorPrintf("Error getting the last insert id: %v\n", err)
 		return -1, err
 	}
+	// The hot score of a new message depends on its creation date, it has no votes yet (see updateMessageScores)
+	var creationDate time.Time
+	err = tx.QueryRow("SELECT creation_date FROM ThreadMessages WHERE message_id = ?", messageID).Scan(&creationDate)
+	if err != nil {
+		ErrorPrintf("Error getting the creation date of the message: %v\n", err)
+		return -1, err
+	}
+	_, err = tx.Exec("UPDATE ThreadMessages SET hot_score = ? WHERE message_id = ?", hotScore(0, 0, creationDate), messageID)
+	if err != nil {
+		ErrorPrintf("Error updating the scores of the message: %v\n", err)
+		return -1, err
+	}
 	// Add the media links to the message
 	for _, mediaLinkID := range mediaLinksID {
 		insertMediaLink := "INSERT INTO ThreadMessageMediaLinks (message_id, media_id) VALUES (?, ?)"
-		_, err = db.Exec(insertMediaLink, messageID, mediaLinkID)
+		_, err = tx.Exec(insertMediaLink, messageID, mediaLinkID)
 		if err != nil {
 			ErrorPrintf("Error inserting the media link into the message: %v\n", err)
 			return -1, err
@@ -1751,16 +2880,45 @@ func AddMessageInThread(thread ThreadGoForum, title string, content string, user
 	for _, tagID := range TagIDs {
 		DebugPrintf("messageID: %d, tagID: %d\n", messageID, tagID)
 		insertTag := "INSERT INTO ThreadMessageTags (message_id, tag_id) VALUES (?, ?)"
-		_, err = db.Exec(insertTag, messageID, tagID)
+		_, err = tx.Exec(insertTag, messageID, tagID)
 		if err != nil {
 			ErrorPrintf("Error inserting the tag into the message: %v\n", err)
 			return -1, err
 		}
 		DebugPrintf("Tag %d added to message %d\n", tagID, messageID)
 	}
+
+	// Add the poll to the message
+	if poll != nil {
+		err = addPollToMessage(tx, int(messageID), *poll)
+		if err != nil {
+			return -1, err
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		ErrorPrintf("Error committing the new message: %v\n", err)
+		return -1, err
+	}
+	// The pending messages are announced when they are approved
+	if approvalState == MessageApproved {
+		announceNewMessage(thread, int(messageID), user.Username)
+	}
 	return int(messageID), nil
 }
 
+// announceNewMessage triggers the webhooks and publishes the event of a new message of the thread
+// The badges of the author are evaluated since he has one more published message
+func announceNewMessage(thread ThreadGoForum, messageID int, username string) {
+	if author, err := GetUserFromUsername(username); err == nil {
+		_ = AwardBadgesForEvent(author, BadgeEventMessagePosted)
+	}
+	if message, err := GetMessageByID(messageID); err == nil {
+		TriggerThreadWebhooks(thread, WebhookMessageCreated, message)
+	}
+	PublishThreadEvent(thread, ThreadEventMessageCreated, ThreadEventMessageData{MessageID: messageID, UserName: username})
+}
+
 // IsMessageTitleValid checks if the message title is valid
 // Message title must be at least 5 characters long
 // Message title must be at most 50 characters long
@@ -1780,27 +2938,61 @@ func IsMessageContentOrCommentContentValid(messageContent string) bool {
 	return messageContentRegex.MatchString(messageContent)
 }
 
-// RemoveMessageFromThread removes the message from the thread
-// Returns an error if there is one
-func RemoveMessageFromThread(thread ThreadGoForum, messageID int) error {
-	removeMessage := "DELETE FROM ThreadMessages WHERE thread_id = ? AND message_id = ?"
-	_, err := db.Exec(removeMessage, thread.ThreadID, messageID)
+// RemoveMessageFromThread deletes the message from the thread
+// The message is only marked as deleted by the user, it can be restored until it is purged (see AutoPurgeDeletedContent)
+// Returns an error if there is one or if the message is already deleted
+func RemoveMessageFromThread(thread ThreadGoForum, messageID int, deletedBy User) error {
+	removeMessage := "UPDATE ThreadMessages SET deleted_by = ?, deletion_date = CURRENT_TIMESTAMP WHERE thread_id = ? AND message_id = ? AND deletion_date IS NULL"
+	result, err := db.Exec(removeMessage, deletedBy.UserID, thread.ThreadID, messageID)
 	if err != nil {
 		ErrorPrintf("Error removing the message from the database: %v\n", err)
 		return err
 	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		ErrorPrintf("Error getting the removed messages: %v\n", err)
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("message %d is already deleted", messageID)
+	}
+	PublishThreadEvent(thread, ThreadEventMessageDeleted, ThreadEventMessageData{MessageID: messageID})
 	return nil
 }
 
-// EditMessageFromThread edits the message in the thread
+// EditMessageFromThread edits the message in the thread, the previous version is kept in its revisions
 // Returns an error if there is one
 func EditMessageFromThread(thread ThreadGoForum, messageID int, newTitle string, newContent string) error {
+	// The revision is saved with the edit, a failed edit does not leave a revision of the version that is still shown
+	tx, err := db.Begin()
+	if err != nil {
+		ErrorPrintf("Error starting the edit of the message: %v\n", err)
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	// Keep the previous version in the history, unless nothing changed
+	saveRevision := `
+		INSERT INTO ThreadMessageRevisions (message_id, message_title, message_content)
+		SELECT message_id, message_title, message_content FROM ThreadMessages
+		WHERE thread_id = ? AND message_id = ? AND (message_title != ? OR message_content != ?)`
+	_, err = tx.Exec(saveRevision, thread.ThreadID, messageID, newTitle, newContent)
+	if err != nil {
+		ErrorPrintf("Error saving the revision of the message: %v\n", err)
+		return err
+	}
 	editMessage := "UPDATE ThreadMessages SET message_title = ? , message_content = ? , was_edited = true WHERE thread_id = ? AND message_id = ?"
-	_, err := db.Exec(editMessage, newTitle, newContent, thread.ThreadID, messageID)
+	_, err = tx.Exec(editMessage, newTitle, newContent, thread.ThreadID, messageID)
 	if err != nil {
 		ErrorPrintf("Error editing the message in the database: %v\n", err)
 		return err
 	}
+	err = tx.Commit()
+	if err != nil {
+		ErrorPrintf("Error committing the edit of the message: %v\n", err)
+		return err
+	}
 	return nil
 }
 
@@ -1867,15 +3059,9 @@ func GetNumberOfMessagesInThread(thread ThreadGoForum) (int, error) {
 // IsUserAllowedToEditComment checks if the user is allowed to edit a comment
 // Returns true if the user is allowed to edit a comment and false otherwise
 func IsUserAllowedToEditComment(thread ThreadGoForum, user User, commentID int) bool {
-	if thread.ThreadID <= 0 {
-		return false
-	}
-	if IsUserInThread(thread, user) {
-		if IsUserBannedFromThread(thread, user) {
-			return false
-		}
+	if HasThreadPermission(thread, user, PermissionSendMessages) {
 		InfoPrintln("Checking if the user is allowed to edit the comment")
-		checkIfOwner := "SELECT comment_id FROM ThreadComments WHERE comment_id = ? AND user_id = ?"
+		checkIfOwner := "SELECT comment_id FROM ThreadComments WHERE comment_id = ? AND user_id = ? AND deletion_date IS NULL"
 		rows, err := db.Query(checkIfOwner, commentID, user.UserID)
 		if err != nil {
 			ErrorPrintf("Error checking if the user is the owner of the comment: %v\n", err)
@@ -1896,19 +3082,13 @@ func IsUserAllowedToEditComment(thread ThreadGoForum, user User, commentID int)
 
 // IsUserAllowedToDeleteComment checks if the user is allowed to delete a comment
 // Returns true if the user is allowed to delete a comment and false otherwise
-// A user is allowed to delete a comment if he is the owner of the comment or if he is the owner or an admin of the thread
+// A user is allowed to delete a comment if he is the owner of the comment or if he has the PermissionDeleteComments permission
 func IsUserAllowedToDeleteComment(thread ThreadGoForum, user User, commentID int) bool {
-	if thread.OwnerID == user.UserID {
-		return true
-	}
-	if IsThreadOwner(thread, user) {
-		return true
-	}
-	if IsThreadAdmin(thread, user) {
+	if HasThreadPermission(thread, user, PermissionDeleteComments) {
 		return true
 	}
 	// Check if the user is the owner of the comment
-	checkIfCommentOwner := "SELECT user_id FROM ThreadComments WHERE thread_id = ? AND comment_id = ?"
+	checkIfCommentOwner := "SELECT tc.user_id FROM ThreadComments tc JOIN ThreadMessages tm ON tc.message_id = tm.message_id WHERE tm.thread_id = ? AND tc.comment_id = ?"
 	rows, err := db.Query(checkIfCommentOwner, thread.ThreadID, commentID)
 	if err != nil {
 		ErrorPrintf("Error checking if the user is the owner of the comment: %v\n", err)
@@ -1948,37 +3128,81 @@ func AddCommentToPost(user User, messageID int, content string) (int, error) {
 		ErrorPrintf("Error getting the last insert id: %v\n", err)
 		return -1, err
 	}
+	// The comments of the messages waiting for approval or hidden by their reports are not announced, like the messages themselves
+	thread := GetThreadFromMessageID(messageID)
+	message, err := GetMessageByIDWithPOV(messageID, User{})
+	if err == nil && message.ApprovalState == MessageApproved && !message.IsHidden {
+		if comment, err := GetCommentByIDWithPOV(int(commentID), User{}); err == nil {
+			TriggerThreadWebhooks(thread, WebhookCommentCreated, WebhookCommentData{MessageID: messageID, Comment: comment})
+		}
+		PublishThreadEvent(thread, ThreadEventCommentCreated, ThreadEventCommentData{MessageID: messageID, CommentID: int(commentID), UserName: user.Username})
+	}
+	_ = AwardBadgesForEvent(user, BadgeEventCommentPosted)
 	return int(commentID), nil
 }
 
-// RemoveCommentFromPost removes the comment from the post
-// Returns an error if there is one
-func RemoveCommentFromPost(commentID int) error {
-	removeComment := "DELETE FROM ThreadComments WHERE comment_id = ?"
-	_, err := db.Exec(removeComment, commentID)
+// RemoveCommentFromPost deletes the comment from the post
+// The comment is only marked as deleted by the user, it can be restored until it is purged (see AutoPurgeDeletedContent)
+// Returns an error if there is one or if the comment is already deleted
+func RemoveCommentFromPost(commentID int, deletedBy User) error {
+	removeComment := "UPDATE ThreadComments SET deleted_by = ?, deletion_date = CURRENT_TIMESTAMP WHERE comment_id = ? AND deletion_date IS NULL"
+	result, err := db.Exec(removeComment, deletedBy.UserID, commentID)
 	if err != nil {
 		ErrorPrintf("Error removing the comment from the database: %v\n", err)
 		return err
 	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		ErrorPrintf("Error getting the removed comments: %v\n", err)
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("comment %d is already deleted", commentID)
+	}
+	messageID := GetMessageIDFromCommentID(commentID)
+	PublishThreadEvent(GetThreadFromMessageID(messageID), ThreadEventCommentDeleted, ThreadEventCommentData{MessageID: messageID, CommentID: commentID})
 	return nil
 }
 
-// EditCommentFromPost edits the comment in the post
+// EditCommentFromPost edits the comment in the post, the previous version is kept in its revisions
 // Returns an error if there is one
 func EditCommentFromPost(commentID int, newContent string) error {
+	// The revision is saved with the edit, a failed edit does not leave a revision of the version that is still shown
+	tx, err := db.Begin()
+	if err != nil {
+		ErrorPrintf("Error starting the edit of the comment: %v\n", err)
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	// Keep the previous version in the history, unless nothing changed
+	saveRevision := `
+		INSERT INTO ThreadCommentRevisions (comment_id, comment_content)
+		SELECT comment_id, comment_content FROM ThreadComments WHERE comment_id = ? AND comment_content != ?`
+	_, err = tx.Exec(saveRevision, commentID, newContent)
+	if err != nil {
+		ErrorPrintf("Error saving the revision of the comment: %v\n", err)
+		return err
+	}
 	editComment := "UPDATE ThreadComments SET comment_content = ?, was_edited = true WHERE comment_id = ?"
-	_, err := db.Exec(editComment, newContent, commentID)
+	_, err = tx.Exec(editComment, newContent, commentID)
 	if err != nil {
 		ErrorPrintf("Error editing the comment in the database: %v\n", err)
 		return err
 	}
+	err = tx.Commit()
+	if err != nil {
+		ErrorPrintf("Error committing the edit of the comment: %v\n", err)
+		return err
+	}
 	return nil
 }
 
-// GetNumberOfCommentsInMessage returns the number of comments in the message
+// GetNumberOfCommentsInMessage returns the number of comments in the message, the deleted comments are not counted
 // Returns the number of comments and an error if there is one
 func GetNumberOfCommentsInMessage(messageID int) (int, error) {
-	getNumberOfComments := "SELECT COUNT(*) FROM ThreadComments WHERE message_id = ?"
+	getNumberOfComments := "SELECT COUNT(*) FROM ThreadComments WHERE message_id = ? AND deletion_date IS NULL"
 	rows, err := db.Query(getNumberOfComments, messageID)
 	if err != nil {
 		ErrorPrintf("Error getting the number of comments in the message: %v\n", err)
@@ -2085,15 +3309,100 @@ func HasUserAlreadyVotedOnComment(user User, commentID int) int {
 	return 0
 }
 
-// BanUserFromThread bans the user from the thread
+// getUserVotes returns the votes of the user on the given messages or comments, indexed by their id
+// The column is either 'message_id' or 'comment_id', a vote is 1 for an upvote and -1 for a downvote
+// The contents the user has not voted on are not in the map
 // Returns an error if there is one
-func BanUserFromThread(thread ThreadGoForum, user User) error {
-	banUser := "UPDATE ThreadGoForumMembers SET rights_level = -1 WHERE thread_id = ? AND user_id = ?"
-	_, err := db.Exec(banUser, thread.ThreadID, user.UserID)
+func getUserVotes(user User, column string, ids []int) (map[int]int, error) {
+	votes := make(map[int]int)
+	if user.UserID == 0 || len(ids) == 0 {
+		return votes, nil
+	}
+	placeholders, args := idListSQL(ids)
+	getVotes := fmt.Sprintf("SELECT %[1]s, is_upvote FROM ThreadVotes WHERE user_id = ? AND %[1]s IN (%[2]s)", column, placeholders)
+	rows, err := db.Query(getVotes, append([]interface{}{user.UserID}, args...)...)
 	if err != nil {
-		ErrorPrintf("Error banning the user from the thread: %v\n", err)
-		return err
+		ErrorPrintf("Error getting the votes of the user: %v\n", err)
+		return nil, err
 	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	for rows.Next() {
+		var id int
+		var isUpvote bool
+		err := rows.Scan(&id, &isUpvote)
+		if err != nil {
+			ErrorPrintf("Error scanning the rows in getUserVotes: %v\n", err)
+			return nil, err
+		}
+		if isUpvote {
+			votes[id] = 1
+		} else {
+			votes[id] = -1
+		}
+	}
+	return votes, nil
+}
+
+// idListSQL returns the placeholders and the arguments to use the given ids in an 'IN (...)' condition
+func idListSQL(ids []int) (string, []interface{}) {
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+	return strings.Join(placeholders, ", "), args
+}
+
+// BanUserFromThread bans the user from the thread and records the ban along with his rank, given back when the ban ends
+// The ban lasts the given number of hours, a duration of 0 makes it permanent
+// Returns an error if there is one
+func BanUserFromThread(thread ThreadGoForum, user User, moderator User, reason string, note string, durationHours int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		ErrorPrintf("Error starting the ban of the user: %v\n", err)
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	previousRank := ThreadRankUser
+	err = tx.QueryRow("SELECT rights_level FROM ThreadGoForumMembers WHERE thread_id = ? AND user_id = ?", thread.ThreadID, user.UserID).Scan(&previousRank)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		ErrorPrintf("Error getting the rank of the user to ban: %v\n", err)
+		return err
+	}
+	previousRank = max(previousRank, ThreadRankUser)
+	banUser := "UPDATE ThreadGoForumMembers SET rights_level = ? WHERE thread_id = ? AND user_id = ?"
+	_, err = tx.Exec(banUser, ThreadRankBanned, thread.ThreadID, user.UserID)
+	if err != nil {
+		ErrorPrintf("Error banning the user from the thread: %v\n", err)
+		return err
+	}
+	// The expiration date is computed by sqlite so it can be compared with CURRENT_TIMESTAMP
+	var expiration interface{}
+	if durationHours > 0 {
+		expiration = fmt.Sprintf("+%d hours", durationHours)
+	}
+	recordBan := `
+		INSERT INTO ThreadBans (thread_id, user_id, moderator_id, ban_reason, ban_note, expiration_date, previous_rank)
+		VALUES (?, ?, ?, ?, ?, datetime('now', ?), ?)`
+	_, err = tx.Exec(recordBan, thread.ThreadID, user.UserID, moderator.UserID, strings.TrimSpace(reason), strings.TrimSpace(note), expiration, previousRank)
+	if err != nil {
+		ErrorPrintf("Error recording the ban of the user: %v\n", err)
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		ErrorPrintf("Error committing the ban of the user: %v\n", err)
+		return err
+	}
+	TriggerThreadWebhooks(thread, WebhookMemberBanned, WebhookMemberData{Username: user.Username})
 	return nil
 }
 
@@ -2106,10 +3415,50 @@ func GetMessageByID(messageID int) (FormattedThreadMessage, error) {
 	return GetMessageByIDWithPOV(messageID, User{})
 }
 
+// IsAutoHideReportThresholdValid checks if the auto hide threshold of a thread is valid (between 0 and 100, 0 disables it)
+func IsAutoHideReportThresholdValid(threshold int) bool {
+	return threshold >= 0 && threshold <= 100
+}
+
+// CanUserSeeHiddenContent checks if the user can see the messages and comments hidden by their reports (the moderation team)
+func CanUserSeeHiddenContent(thread ThreadGoForum, user User) bool {
+	return HasThreadPermission(thread, user, PermissionSeeHiddenContent)
+}
+
+// hiddenMessageSQL returns the SQL condition telling if a message of ViewThreadMessagesWithVotes is hidden by its reports
+// A message is hidden when the number of distinct users who reported it reaches the threshold, until the reports are closed
+// The reports of the content filters (SystemReporterName) are not counted, they only bring the message to the moderation team
+// The condition is always false when the threshold is 0
+func hiddenMessageSQL(threshold int) string {
+	if threshold <= 0 {
+		return "0"
+	}
+	return fmt.Sprintf(`((
+		SELECT COUNT(DISTINCT r.username) FROM Reports r
+		WHERE r.message_id = ViewThreadMessagesWithVotes.message_id AND r.comment_id = 0 AND r.report_state IN ('%s', '%s')
+		AND r.username != '%s'
+	) >= %d)`, ReportOpen, ReportClaimed, SystemReporterName, threshold)
+}
+
+// hiddenCommentSQL returns the SQL condition telling if a comment of ViewMessageCommentsWithVotes is hidden by its reports
+// It works the same way as hiddenMessageSQL
+func hiddenCommentSQL(threshold int) string {
+	if threshold <= 0 {
+		return "0"
+	}
+	return fmt.Sprintf(`((
+		SELECT COUNT(DISTINCT r.username) FROM Reports r
+		WHERE r.comment_id = ViewMessageCommentsWithVotes.comment_id AND r.report_state IN ('%s', '%s')
+		AND r.username != '%s'
+	) >= %d)`, ReportOpen, ReportClaimed, SystemReporterName, threshold)
+}
+
 // GetMessageByIDWithPOV returns the message from the thread with the given id view from the point of view of the user
 // Returns the message and an error if there is one
 func GetMessageByIDWithPOV(messageID int, user User) (FormattedThreadMessage, error) {
-	getMessage := `
+	thread := GetThreadFromMessageID(messageID)
+	threadConfig := GetThreadConfigFromThread(thread)
+	getMessage := fmt.Sprintf(`
 		SELECT
 			message_id,
 			message_title,
@@ -2119,8 +3468,14 @@ func GetMessageByIDWithPOV(messageID int, user User) (FormattedThreadMessage, er
 			username,
 			pfp_media_address,
 			upvotes,
-			downvotes
-		FROM ViewThreadMessagesWithVotes WHERE message_id = ?`
+			downvotes,
+			%s AS is_hidden,
+			%s AS approval_state,
+			%s AS deletion_state,
+			%s AS deletion_date,
+			%s AS is_pinned,
+			%s AS is_locked
+		FROM ViewThreadMessagesWithVotes WHERE message_id = ?`, hiddenMessageSQL(threadConfig.AutoHideReportThreshold), messageApprovalSQL, messageDeletionSQL, messageDeletionDateSQL, messagePinnedSQL, messageLockedSQL)
 	rows, err := db.Query(getMessage, messageID)
 	if err != nil {
 		ErrorPrintf("Error getting the message from the thread: %v\n", err)
@@ -2134,6 +3489,7 @@ func GetMessageByIDWithPOV(messageID int, user User) (FormattedThreadMessage, er
 	}(rows)
 	if rows.Next() {
 		var message FormattedThreadMessage
+		var deletionDate sql.NullTime
 		err := rows.Scan(
 			&message.MessageID,
 			&message.MessageTitle,
@@ -2143,11 +3499,21 @@ func GetMessageByIDWithPOV(messageID int, user User) (FormattedThreadMessage, er
 			&message.UserName,
 			&message.UserPfpAddress,
 			&message.Upvotes,
-			&message.Downvotes)
+			&message.Downvotes,
+			&message.IsHidden,
+			&message.ApprovalState,
+			&message.DeletionState,
+			&deletionDate,
+			&message.IsPinned,
+			&message.IsLocked)
 		if err != nil {
 			ErrorPrintf("Error scanning the rows in GetMessageFromThreadWithID: %v\n", err)
 			return FormattedThreadMessage{}, err
 		}
+		if message.DeletionState != NotDeleted {
+			applyMessageTombstone(thread, user, &message, deletionDate)
+			return message, nil
+		}
 		// Get the media links for the message
 		getMessageMediaLinks := `
 			SELECT ml.media_address
@@ -2187,12 +3553,26 @@ func GetMessageByIDWithPOV(messageID int, user User) (FormattedThreadMessage, er
 		}
 		message.MessageTags = tags
 
+		// Add the badges of the author to the message
+		message.UserBadges, err = GetUserBadges(User{Username: message.UserName})
+		if err != nil {
+			return FormattedThreadMessage{}, err
+		}
+
+		// Add the poll to the message
+		message.Poll, err = GetMessagePollWithPOV(message.MessageID, user)
+		if err != nil {
+			ErrorPrintf("Error getting the poll for the message: %v\n", err)
+			return FormattedThreadMessage{}, err
+		}
+
 		// Add the message pov (point of view) to the message
 		// Sets FormattedThreadMessage.VoteState to -1 if the user disliked the message
 		// Sets FormattedThreadMessage.VoteState to 1 if the user liked the message
 		// Sets FormattedThreadMessage.VoteState to 0 if the user has not voted
 		if user.UserID != 0 {
 			message.VoteState = HasUserAlreadyVotedOnMessage(user, message.MessageID)
+			message.Saved = IsContentSavedByUser(user, message.MessageID, 0)
 		} else {
 			message.VoteState = 0
 		}
@@ -2201,10 +3581,122 @@ func GetMessageByIDWithPOV(messageID int, user User) (FormattedThreadMessage, er
 	return FormattedThreadMessage{}, nil
 }
 
+// messagesOrderingSQL returns the sort key of the messages for the given order (from the OrderingList), if they are sorted in descending order
+// and the condition restricting the messages of the order (empty if there is none)
+// The time dependent orders are computed at the given date (unix seconds) so the pages of a same listing stay consistent
+func messagesOrderingSQL(order string, at int64) (string, bool, string) {
+	switch order {
+	case "desc": // descending order
+		return messageDateKeySQL, true, ""
+	case "popular": // popular order
+		return messageStoredScoreKeySQL, true, ""
+	case "unpopular": // unpopular order
+		return messageStoredScoreKeySQL, false, ""
+	case "hot": // the score with a time decay
+		return messageHotKeySQL, true, ""
+	case "rising": // the score per hour of the recent messages
+		rising := fmt.Sprintf("(%s * 3600000 / (%d - %s + 7200))", messageStoredScoreKeySQL, at, messageDateKeySQL)
+		return rising, true, fmt.Sprintf("AND %s BETWEEN %d AND %d", messageDateKeySQL, at-risingOrderingPeriod, at)
+	case "top_day", "top_week", "top_month", "top_all": // the score of the messages of the period
+		if topOrderingPeriods[order] == 0 {
+			return messageStoredScoreKeySQL, true, ""
+		}
+		return messageStoredScoreKeySQL, true, fmt.Sprintf("AND %s >= %d", messageDateKeySQL, at-topOrderingPeriods[order])
+	case "controversial": // the number of votes weighted by how evenly they are split
+		return messageControversyKeySQL, true, ""
+	case "most_commented": // the number of comments
+		return "comments_number", true, ""
+	default: // ascending order
+		return messageDateKeySQL, false, ""
+	}
+}
+
+// hotScore returns the hot score of a message, the order of magnitude of its score plus its age
+// A message needs ten times more votes than one posted 12.5 hours after it to be as hot
+func hotScore(upvotes int, downvotes int, creationDate time.Time) float64 {
+	score := float64(upvotes - downvotes)
+	magnitude := math.Log10(math.Max(math.Abs(score), 1))
+	if score < 0 {
+		magnitude = -magnitude
+	}
+	return magnitude + float64(creationDate.Unix()-hotScoreEpoch)/hotScoreHalfDay
+}
+
+// updateMessageScores updates the vote counts and the hot score stored in the message from its votes
+// The stored scores let the messages be sorted without counting all the votes at each page load
+// Returns an error if there is one
+func updateMessageScores(messageID int) error {
+	var upvotes, downvotes int
+	var creationDate time.Time
+	getVotes := `
+		SELECT
+			(SELECT COUNT(*) FROM ThreadVotes WHERE message_id = tm.message_id AND is_upvote = 1),
+			(SELECT COUNT(*) FROM ThreadVotes WHERE message_id = tm.message_id AND is_upvote = 0),
+			tm.creation_date
+		FROM ThreadMessages tm WHERE tm.message_id = ?`
+	err := db.QueryRow(getVotes, messageID).Scan(&upvotes, &downvotes, &creationDate)
+	if err != nil {
+		ErrorPrintf("Error getting the votes of the message: %v\n", err)
+		return err
+	}
+	updateScores := "UPDATE ThreadMessages SET upvotes_count = ?, downvotes_count = ?, hot_score = ? WHERE message_id = ?"
+	_, err = db.Exec(updateScores, upvotes, downvotes, hotScore(upvotes, downvotes, creationDate), messageID)
+	if err != nil {
+		ErrorPrintf("Error updating the scores of the message: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// UpdateAllMessageScores updates the vote counts and the hot score stored in every message, see updateMessageScores
+// It is used when the scores are added to an existing database
+// Returns an error if there is one
+func UpdateAllMessageScores() error {
+	updateCounts := `
+		UPDATE ThreadMessages SET
+			upvotes_count = (SELECT COUNT(*) FROM ThreadVotes v WHERE v.message_id = ThreadMessages.message_id AND v.is_upvote = 1),
+			downvotes_count = (SELECT COUNT(*) FROM ThreadVotes v WHERE v.message_id = ThreadMessages.message_id AND v.is_upvote = 0)`
+	_, err := db.Exec(updateCounts)
+	if err != nil {
+		ErrorPrintf("Error updating the vote counts of the messages: %v\n", err)
+		return err
+	}
+	rows, err := db.Query("SELECT message_id, upvotes_count, downvotes_count, creation_date FROM ThreadMessages")
+	if err != nil {
+		ErrorPrintf("Error getting the messages scores: %v\n", err)
+		return err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	hotScores := make(map[int]float64)
+	for rows.Next() {
+		var messageID, upvotes, downvotes int
+		var creationDate time.Time
+		err := rows.Scan(&messageID, &upvotes, &downvotes, &creationDate)
+		if err != nil {
+			ErrorPrintf("Error scanning the rows in UpdateAllMessageScores: %v\n", err)
+			return err
+		}
+		hotScores[messageID] = hotScore(upvotes, downvotes, creationDate)
+	}
+	for messageID, score := range hotScores {
+		_, err := db.Exec("UPDATE ThreadMessages SET hot_score = ? WHERE message_id = ?", score, messageID)
+		if err != nil {
+			ErrorPrintf("Error updating the hot score of the message: %v\n", err)
+			return err
+		}
+	}
+	return nil
+}
+
 // GetMessagesFromThreadWithPOV returns the messages from the thread viewed from the point of view of the user
 // Returns a slice of messages and an error if there is one
-// The messages are ordered by the given order (from the OrderingList)
-// The offset is used to paginate the messages
+// The messages are ordered by the given order (from the OrderingList), the pinned messages always come first
+// The offset is used to paginate the messages, GetMessagesFromThreadAfterCursor should be preferred as the pages do not shift when messages are added
 // By default the function returns a maximum of 10 messages or is equal to the environment variable 'MAX_MESSAGES_PER_PAGE_LOAD'
 func GetMessagesFromThreadWithPOV(thread ThreadGoForum, offset int, order string, user User, tags []ThreadTag) ([]FormattedThreadMessage, error) {
 	// Check if there is still Messages to load
@@ -2216,7 +3708,31 @@ func GetMessagesFromThreadWithPOV(thread ThreadGoForum, offset int, order string
 	if offset >= numberOfMessages {
 		return nil, nil
 	}
+	messages, _, err := getMessagesPageFromThread(thread, offset, nil, order, user, tags)
+	return messages, err
+}
+
+// GetMessagesFromThreadAfterCursor returns the messages from the thread coming after the given cursor viewed from the point of view of the user
+// The cursor is a token returned by a previous call with the same order, an empty cursor returns the first page
+// Returns the messages, the cursor of the next page (empty when there is nothing left to load) and an error if there is one
+// Returns ErrInvalidCursor if the cursor is malformed or was made for another order
+// By default the function returns a maximum of 10 messages or is equal to the environment variable 'MAX_MESSAGES_PER_PAGE_LOAD'
+func GetMessagesFromThreadAfterCursor(thread ThreadGoForum, cursor string, order string, user User, tags []ThreadTag) ([]FormattedThreadMessage, string, error) {
+	pageCursor, err := DecodePageCursor(cursor, order)
+	if err != nil {
+		return nil, "", err
+	}
+	messages, nextCursor, err := getMessagesPageFromThread(thread, 0, pageCursor, order, user, tags)
+	if err != nil || nextCursor == nil {
+		return messages, "", err
+	}
+	return messages, EncodePageCursor(*nextCursor), nil
+}
 
+// getMessagesPageFromThread returns a page of the messages from the thread viewed from the point of view of the user
+// The page starts after the cursor if it is not nil, otherwise after the given number of messages
+// Returns the messages, the cursor of the last message if the page is full (nil otherwise) and an error if there is one
+func getMessagesPageFromThread(thread ThreadGoForum, offset int, cursor *PageCursor, order string, user User, tags []ThreadTag) ([]FormattedThreadMessage, *PageCursor, error) {
 	// Get the max Messages per page load from the environment variable
 	maxMessagesPerPageLoad := 10
 	if os.Getenv("MAX_MESSAGES_PER_PAGE_LOAD") != "" {
@@ -2251,22 +3767,39 @@ func GetMessagesFromThreadWithPOV(thread ThreadGoForum, offset int, order string
 		`, strings.Join(strSlice, ","), len(tagIDs))
 	}
 
-	// Get the ordering of the messages
-	var orderFilter string
-	switch order {
-	case "desc": // descending order
-		orderFilter = `creation_date DESC`
-		break
-	case "popular": // popular order
-		orderFilter = `(upvotes - downvotes) DESC`
-		break
-	case "unpopular": // unpopular order
-		orderFilter = `(upvotes - downvotes) ASC`
-		break
-	default: // ascending order
-		orderFilter = `creation_date ASC`
-		break
+	// Get the ordering of the messages, the ties are broken by the message id so the order is the same on every page load
+	// The time dependent orders are computed at the date of the first page
+	at := time.Now().Unix()
+	if cursor != nil && cursor.At != 0 {
+		at = cursor.At
+	}
+	sortKey, descending, orderFilter := messagesOrderingSQL(order, at)
+	direction := "ASC"
+	if descending {
+		direction = "DESC"
+	}
+	// The messages hidden by their reports are only shown to the moderation team
+	// The messages waiting for approval are only shown to their author and the moderation team, the rejected ones to their author
+	isHidden := hiddenMessageSQL(GetThreadConfigFromThread(thread).AutoHideReportThreshold)
+	hiddenFilter := fmt.Sprintf("AND (%s != '%s' OR username = ?)", messageApprovalSQL, MessageRejected)
+	if !CanUserSeeHiddenContent(thread, user) {
+		hiddenFilter = fmt.Sprintf("AND NOT %s AND (%s = '%s' OR username = ?)", isHidden, messageApprovalSQL, MessageApproved)
+	}
+	args := []interface{}{thread.ThreadID, user.Username}
+
+	// Only keep the messages after the cursor, as the pinned messages come first they are either less pinned or as pinned and after it in the order
+	// After an unpinned message only the unpinned messages are left, so the condition stays on a single range of the index
+	cursorFilter := ""
+	if cursor != nil {
+		keyCondition, keyArgs := keysetConditionSQL(sortKey, "message_id", descending, false, *cursor)
+		if cursor.IsPinned {
+			cursorFilter = fmt.Sprintf("AND (%[1]s = FALSE OR (%[1]s = TRUE AND %[2]s))", messagePinnedSQL, keyCondition)
+		} else {
+			cursorFilter = fmt.Sprintf("AND %s = FALSE AND %s", messagePinnedSQL, keyCondition)
+		}
+		args = append(args, keyArgs...)
 	}
+
 	getMessages := fmt.Sprintf(`
 			SELECT
 				message_id,
@@ -2278,14 +3811,33 @@ func GetMessagesFromThreadWithPOV(thread ThreadGoForum, offset int, order string
 				pfp_media_address,
 				upvotes,
 				downvotes,
-				comments_number
-			FROM ViewThreadMessagesWithVotes WHERE thread_name = ? %s ORDER BY %s LIMIT ? OFFSET ?`,
+				comments_number,
+				%s AS is_hidden,
+				%s AS approval_state,
+				%s AS deletion_state,
+				%s AS deletion_date,
+				%s AS is_pinned,
+				%s AS is_locked,
+				%s AS sort_key
+			FROM ViewThreadMessagesWithVotes WHERE thread_id = ? %s %s %s %s ORDER BY is_pinned DESC, sort_key %s, message_id %s LIMIT ? OFFSET ?`,
+		isHidden,
+		messageApprovalSQL,
+		messageDeletionSQL,
+		messageDeletionDateSQL,
+		messagePinnedSQL,
+		messageLockedSQL,
+		sortKey,
+		orderFilter,
 		tagFilter,
-		orderFilter)
-	rows, err := db.Query(getMessages, thread.ThreadName, maxMessagesPerPageLoad, offset)
+		hiddenFilter,
+		cursorFilter,
+		direction,
+		direction)
+	args = append(args, maxMessagesPerPageLoad, offset)
+	rows, err := db.Query(getMessages, args...)
 	if err != nil {
 		ErrorPrintf("Error getting all the incompleteMessages from the thread: %v\n", err)
-		return nil, err
+		return nil, nil, err
 	}
 	defer func(rows *sql.Rows) {
 		err := rows.Close()
@@ -2294,8 +3846,10 @@ func GetMessagesFromThreadWithPOV(thread ThreadGoForum, offset int, order string
 		}
 	}(rows)
 	var incompleteMessages []FormattedThreadMessage
+	lastMessage := PageCursor{Order: order, At: at}
 	for rows.Next() {
 		var message FormattedThreadMessage
+		var deletionDate sql.NullTime
 		err := rows.Scan(
 			&message.MessageID,
 			&message.MessageTitle,
@@ -2306,59 +3860,78 @@ func GetMessagesFromThreadWithPOV(thread ThreadGoForum, offset int, order string
 			&message.UserPfpAddress,
 			&message.Upvotes,
 			&message.Downvotes,
-			&message.NumberOfComments)
+			&message.NumberOfComments,
+			&message.IsHidden,
+			&message.ApprovalState,
+			&message.DeletionState,
+			&deletionDate,
+			&message.IsPinned,
+			&message.IsLocked,
+			&lastMessage.SortKey)
 		if err != nil {
 			ErrorPrintf("Error scanning the rows in GetMessagesFromThread: %v\n", err)
-			return nil, err
+			return nil, nil, err
+		}
+		lastMessage.IsPinned = message.IsPinned
+		lastMessage.ID = message.MessageID
+		if message.DeletionState != NotDeleted {
+			applyMessageTombstone(thread, user, &message, deletionDate)
 		}
 		incompleteMessages = append(incompleteMessages, message)
 	}
-	// Get the media links for each message
-	var Messages []FormattedThreadMessage
+	// A page that is not full is the last one
+	var nextCursor *PageCursor
+	if len(incompleteMessages) == maxMessagesPerPageLoad {
+		nextCursor = &lastMessage
+	}
+	// Load the media links, the tags, the polls, the badges of the authors and the votes and the saved items of the user of the whole page at once
+	var messageIDs []int
+	var authors []string
 	for _, message := range incompleteMessages {
-		// Add the media links to the message
-		getMessageMediaLinks := `
-			SELECT ml.media_address 
-			FROM ThreadMessageMediaLinks tmml JOIN MediaLink ml ON tmml.media_id = ml.media_id
-			WHERE tmml.message_id = ?;`
-		rows, err := db.Query(getMessageMediaLinks, message.MessageID)
-		if err != nil {
-			ErrorPrintf("Error getting the incompleteMessages from the thread: %v\n", err)
-			return nil, err
-		}
-		for rows.Next() {
-			var mediaLinkAddress string
-			err := rows.Scan(&mediaLinkAddress)
-			if err != nil {
-				ErrorPrintf("Error scanning the rows in GetMessagesFromThread: %v\n", err)
-				return nil, err
-			}
-			message.MediaLinks = append(message.MediaLinks, mediaLinkAddress)
-		}
-		err = rows.Close()
-		if err != nil {
-			ErrorPrintf("Error closing the rows: %v\n", err)
-		}
-		// Add the tags to the message
-		tags, err := GetMessageTags(message.MessageID)
-		if err != nil {
-			ErrorPrintf("Error getting the tags for the message: %v\n", err)
-			return nil, err
+		// The deleted messages only show their tombstone
+		if message.DeletionState == NotDeleted {
+			messageIDs = append(messageIDs, message.MessageID)
+			authors = append(authors, message.UserName)
 		}
-		message.MessageTags = tags
-
-		// Adding the message pov (point of view) to the message
-		// Sets FormattedThreadMessage.VoteState to -1 if the user disliked the message
-		// Sets FormattedThreadMessage.VoteState to 1 if the user liked the message
-		// Sets FormattedThreadMessage.VoteState to 0 if the user has not voted
-		if (user != User{}) {
-			message.VoteState = HasUserAlreadyVotedOnMessage(user, message.MessageID)
-		} else {
-			message.VoteState = 0
+	}
+	mediaLinks, err := getMessagesMediaLinks(messageIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	messagesTags, err := getMessagesTags(messageIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	polls, err := getMessagesPollsWithPOV(messageIDs, user)
+	if err != nil {
+		return nil, nil, err
+	}
+	badges, err := getUsersBadges(authors)
+	if err != nil {
+		return nil, nil, err
+	}
+	votes, err := getUserVotes(user, "message_id", messageIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	saved, err := getUserSavedItems(user, "message_id", messageIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	var Messages []FormattedThreadMessage
+	for _, message := range incompleteMessages {
+		if message.DeletionState == NotDeleted {
+			message.MediaLinks = mediaLinks[message.MessageID]
+			message.MessageTags = messagesTags[message.MessageID]
+			message.Poll = polls[message.MessageID]
+			message.UserBadges = badges[message.UserName]
+			// Sets FormattedThreadMessage.VoteState to -1 if the user disliked the message, 1 if he liked it and 0 if he has not voted
+			message.VoteState = votes[message.MessageID]
+			message.Saved = saved[message.MessageID]
 		}
 		Messages = append(Messages, message)
 	}
-	return Messages, nil
+	return Messages, nextCursor, nil
 }
 
 // GetCommentsFromMessage returns the comments from the message
@@ -2371,19 +3944,36 @@ func GetCommentsFromMessage(messageID int, offset int) ([]FormattedMessageCommen
 
 // GetCommentsFromMessageWithPOV returns the comments from the message
 // Returns a slice of comments and an error if there is one
-// The offset is used to paginate the comments
+// The offset is used to paginate the comments, GetCommentsFromMessageAfterCursor should be preferred as the pages do not shift when comments are added
 // By default the function returns a maximum of 10 comments or is equal to the environment variable 'MAX_COMMENTS_PER_PAGE_LOAD'
 func GetCommentsFromMessageWithPOV(messageID int, offset int, user User) ([]FormattedMessageComment, error) {
-	// Check if there is still comments to load
-	numberOfComments, err := GetNumberOfCommentsInMessage(messageID)
+	// The deleted comments are loaded as tombstones, so the number of comments cannot tell if there is still comments to load
+	comments, _, err := getCommentsPageFromMessage(messageID, offset, nil, user)
+	return comments, err
+}
+
+// GetCommentsFromMessageAfterCursor returns the comments from the message coming after the given cursor viewed from the point of view of the user
+// The cursor is a token returned by a previous call, an empty cursor returns the first page
+// Returns the comments, the cursor of the next page (empty when there is nothing left to load) and an error if there is one
+// Returns ErrInvalidCursor if the cursor is malformed
+// By default the function returns a maximum of 10 comments or is equal to the environment variable 'MAX_COMMENTS_PER_PAGE_LOAD'
+func GetCommentsFromMessageAfterCursor(messageID int, cursor string, user User) ([]FormattedMessageComment, string, error) {
+	pageCursor, err := DecodePageCursor(cursor, commentsOrder)
 	if err != nil {
-		ErrorPrintf("Error getting the number of comments in the Message: %v\n", err)
-		return nil, err
+		return nil, "", err
 	}
-	if offset >= numberOfComments {
-		return nil, nil
+	comments, nextCursor, err := getCommentsPageFromMessage(messageID, 0, pageCursor, user)
+	if err != nil || nextCursor == nil {
+		return comments, "", err
 	}
+	return comments, EncodePageCursor(*nextCursor), nil
+}
 
+// getCommentsPageFromMessage returns a page of the comments from the message viewed from the point of view of the user
+// The comments are ordered by the number of votes so the most popular comments are first, the ties are broken by the oldest comment
+// The page starts after the cursor if it is not nil, otherwise after the given number of comments
+// Returns the comments, the cursor of the last comment if the page is full (nil otherwise) and an error if there is one
+func getCommentsPageFromMessage(messageID int, offset int, cursor *PageCursor, user User) ([]FormattedMessageComment, *PageCursor, error) {
 	// Get the max comments per page load from the environment variable
 	maxCommentsPerPageLoad := 10
 	if os.Getenv("MAX_COMMENTS_PER_PAGE_LOAD") != "" {
@@ -2394,7 +3984,24 @@ func GetCommentsFromMessageWithPOV(messageID int, offset int, user User) ([]Form
 			maxCommentsPerPageLoad = 10
 		}
 	}
-	getComments := `
+	// The comments hidden by their reports are only shown to the moderation team
+	thread := GetThreadFromMessageID(messageID)
+	isHidden := hiddenCommentSQL(GetThreadConfigFromThread(thread).AutoHideReportThreshold)
+	hiddenFilter := ""
+	if !CanUserSeeHiddenContent(thread, user) {
+		hiddenFilter = "AND NOT " + isHidden
+	}
+	args := []interface{}{messageID}
+
+	// Only keep the comments after the cursor
+	cursorFilter := ""
+	if cursor != nil {
+		keyCondition, keyArgs := keysetConditionSQL(commentScoreKeySQL, "comment_id", true, true, *cursor)
+		cursorFilter = "AND " + keyCondition
+		args = append(args, keyArgs...)
+	}
+
+	getComments := fmt.Sprintf(`
 		SELECT
 			comment_id,
 			comment_content,
@@ -2403,13 +4010,18 @@ func GetCommentsFromMessageWithPOV(messageID int, offset int, user User) ([]Form
 			username,
 			pfp_media_address,
 			upvotes,
-			downvotes
+			downvotes,
+			%s AS is_hidden,
+			%s AS deletion_state,
+			%s AS deletion_date,
+			%s AS sort_key
 		FROM ViewMessageCommentsWithVotes
-		WHERE message_id = ? ORDER BY (upvotes - downvotes) DESC LIMIT ? OFFSET ?` // ordered by the number of votes by default so the most popular comments are first
-	rows, err := db.Query(getComments, messageID, maxCommentsPerPageLoad, offset)
+		WHERE message_id = ? %s %s ORDER BY sort_key DESC, comment_id ASC LIMIT ? OFFSET ?`, isHidden, commentDeletionSQL, commentDeletionDateSQL, commentScoreKeySQL, hiddenFilter, cursorFilter)
+	args = append(args, maxCommentsPerPageLoad, offset)
+	rows, err := db.Query(getComments, args...)
 	if err != nil {
 		ErrorPrintf("Error getting all the incompleteMessages from the thread: %v\n", err)
-		return nil, err
+		return nil, nil, err
 	}
 	defer func(rows *sql.Rows) {
 		err := rows.Close()
@@ -2419,8 +4031,10 @@ func GetCommentsFromMessageWithPOV(messageID int, offset int, user User) ([]Form
 	}(rows)
 
 	var comments []FormattedMessageComment
+	lastComment := PageCursor{Order: commentsOrder}
 	for rows.Next() {
 		var comment FormattedMessageComment
+		var deletionDate sql.NullTime
 		err := rows.Scan(
 			&comment.CommentID,
 			&comment.CommentContent,
@@ -2429,20 +4043,122 @@ func GetCommentsFromMessageWithPOV(messageID int, offset int, user User) ([]Form
 			&comment.UserName,
 			&comment.UserPfpAddress,
 			&comment.Upvotes,
-			&comment.Downvotes)
+			&comment.Downvotes,
+			&comment.IsHidden,
+			&comment.DeletionState,
+			&deletionDate,
+			&lastComment.SortKey)
 		if err != nil {
 			ErrorPrintf("Error scanning the rows in GetCommentsFromMessageWithPOV: %v\n", err)
-			return nil, err
+			return nil, nil, err
 		}
-		if (user != User{}) {
-			comment.VoteState = HasUserAlreadyVotedOnComment(user, comment.CommentID)
-		} else {
-			comment.VoteState = 0
+		lastComment.ID = comment.CommentID
+		if comment.DeletionState != NotDeleted {
+			applyCommentTombstone(thread, user, &comment, deletionDate)
 		}
 		comments = append(comments, comment)
 	}
 
-	return comments, nil
+	// Load the votes and the saved items of the user and the badges of the authors on the whole page at once
+	commentIDs := make([]int, len(comments))
+	var authors []string
+	for i, comment := range comments {
+		commentIDs[i] = comment.CommentID
+		if comment.DeletionState == NotDeleted {
+			authors = append(authors, comment.UserName)
+		}
+	}
+	votes, err := getUserVotes(user, "comment_id", commentIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	badges, err := getUsersBadges(authors)
+	if err != nil {
+		return nil, nil, err
+	}
+	saved, err := getUserSavedItems(user, "comment_id", commentIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range comments {
+		comments[i].VoteState = votes[comments[i].CommentID]
+		comments[i].Saved = saved[comments[i].CommentID]
+		if comments[i].DeletionState == NotDeleted {
+			comments[i].UserBadges = badges[comments[i].UserName]
+		}
+	}
+
+	// A page that is not full is the last one
+	if len(comments) < maxCommentsPerPageLoad {
+		return comments, nil, nil
+	}
+	return comments, &lastComment, nil
+}
+
+// GetCommentByIDWithPOV returns the comment with the given id viewed from the point of view of the user
+// Returns the comment and an error if there is one
+func GetCommentByIDWithPOV(commentID int, user User) (FormattedMessageComment, error) {
+	thread := GetThreadFromMessageID(GetMessageIDFromCommentID(commentID))
+	getComment := fmt.Sprintf(`
+		SELECT
+			comment_id,
+			comment_content,
+			was_edited,
+			creation_date,
+			username,
+			pfp_media_address,
+			upvotes,
+			downvotes,
+			%s AS is_hidden,
+			%s AS deletion_state,
+			%s AS deletion_date
+		FROM ViewMessageCommentsWithVotes
+		WHERE comment_id = ?`, hiddenCommentSQL(GetThreadConfigFromThread(thread).AutoHideReportThreshold), commentDeletionSQL, commentDeletionDateSQL)
+	rows, err := db.Query(getComment, commentID)
+	if err != nil {
+		ErrorPrintf("Error getting the comment: %v\n", err)
+		return FormattedMessageComment{}, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	if rows.Next() {
+		var comment FormattedMessageComment
+		var deletionDate sql.NullTime
+		err := rows.Scan(
+			&comment.CommentID,
+			&comment.CommentContent,
+			&comment.WasEdited,
+			&comment.CreationDate,
+			&comment.UserName,
+			&comment.UserPfpAddress,
+			&comment.Upvotes,
+			&comment.Downvotes,
+			&comment.IsHidden,
+			&comment.DeletionState,
+			&deletionDate)
+		if err != nil {
+			ErrorPrintf("Error scanning the rows in GetCommentByIDWithPOV: %v\n", err)
+			return FormattedMessageComment{}, err
+		}
+		if comment.DeletionState != NotDeleted {
+			applyCommentTombstone(thread, user, &comment, deletionDate)
+		} else {
+			comment.UserBadges, err = GetUserBadges(User{Username: comment.UserName})
+			if err != nil {
+				return FormattedMessageComment{}, err
+			}
+		}
+		if user.UserID != 0 {
+			comment.VoteState = HasUserAlreadyVotedOnComment(user, comment.CommentID)
+			comment.Saved = IsContentSavedByUser(user, 0, comment.CommentID)
+		}
+		return comment, nil
+	}
+	return FormattedMessageComment{}, fmt.Errorf("comment %d does not exist", commentID)
 }
 
 // CommentExistsOnMessage checks if the comment exists on the message
@@ -2489,31 +4205,251 @@ func GetUserRankInThread(thread ThreadGoForum, user User) int {
 			ErrorPrintf("Error scanning the rows in GetUserRankInThread: %v\n", err)
 			return 0
 		}
+		// A temporary ban is lifted as soon as it expires, without waiting for the auto lift
+		if rank == ThreadRankBanned {
+			// The rows must be closed before writing in the database
+			_ = rows.Close()
+			lifted, err := liftExpiredBansOf(thread, user)
+			if err == nil && lifted > 0 {
+				return ThreadRankUser
+			}
+		}
 		return rank
 	}
 	return 0
 }
 
-// GetThreadModerationTeam returns the moderation team of the thread
-// Returns a slice of SimplifiedUser
-func GetThreadModerationTeam(forum ThreadGoForum) []SimplifiedUser {
-	getThreadModerationTeam := `
-		SELECT u.username, ml.media_address, tgm.rights_level
-		FROM ThreadGoForumMembers tgm
-		    JOIN Users u ON tgm.user_id = u.user_id
-		    JOIN UserConfigs uc ON u.user_id = uc.user_id
-		    JOIN MediaLink ml ON uc.pfp_id = ml.media_id
-		WHERE tgm.thread_id = ? AND tgm.rights_level > 0
-		ORDER BY tgm.rights_level DESC;`
-	rows, err := db.Query(getThreadModerationTeam, forum.ThreadID)
+// GetUserThreadPermissions resolves the permissions of the user in the thread
+// They are the permissions of the rank of the user (see DefaultThreadRoles) and of his custom role if he has one
+// The users who are not members of the thread and the banned members have no permission
+func GetUserThreadPermissions(thread ThreadGoForum, user User) []ThreadPermission {
+	if thread.ThreadID <= 0 || user.UserID == 0 {
+		return nil
+	}
+	getMemberRole := `
+		SELECT m.rights_level, COALESCE(r.permissions, '')
+		FROM ThreadGoForumMembers m
+		    LEFT JOIN ThreadRoles r ON r.role_id = m.role_id AND r.thread_id = m.thread_id
+		WHERE m.thread_id = ? AND m.user_id = ?`
+	var rank int
+	var rolePermissions string
+	err := db.QueryRow(getMemberRole, thread.ThreadID, user.UserID).Scan(&rank, &rolePermissions)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
 	if err != nil {
-		ErrorPrintf("Error getting the thread moderation team: %v\n", err)
+		ErrorPrintf("Error getting the role of the user in the thread: %v\n", err)
 		return nil
 	}
-	defer func(rows *sql.Rows) {
-		err := rows.Close()
-		if err != nil {
-			ErrorPrintf("Error closing the rows: %v\n", err)
+	if thread.OwnerID == user.UserID {
+		rank = ThreadRankOwner
+	}
+	if rank == ThreadRankBanned {
+		// Let GetUserRankInThread lift the ban if it expired
+		rank = GetUserRankInThread(thread, user)
+		if rank == ThreadRankBanned {
+			return nil
+		}
+	}
+	permissions := slices.Clone(DefaultThreadRoles[rank])
+	for _, permission := range parseThreadPermissions(rolePermissions) {
+		if !slices.Contains(permissions, permission) {
+			permissions = append(permissions, permission)
+		}
+	}
+	return permissions
+}
+
+// HasThreadPermission checks if the user has the given permission in the thread
+// Every check of what a member is allowed to do in a thread goes through this function
+func HasThreadPermission(thread ThreadGoForum, user User, permission ThreadPermission) bool {
+	return slices.Contains(GetUserThreadPermissions(thread, user), permission)
+}
+
+// IsThreadPermissionAssignable checks if the permission can be given by a custom role
+func IsThreadPermissionAssignable(permission ThreadPermission) bool {
+	return slices.Contains(AssignableThreadPermissions, permission)
+}
+
+// IsThreadRoleNameValid checks if the name of a custom role is valid (between 1 and 30 characters)
+func IsThreadRoleNameValid(roleName string) bool {
+	length := utf8.RuneCountInString(roleName)
+	return strings.TrimSpace(roleName) == roleName && length >= 1 && length <= 30
+}
+
+// parseThreadPermissions parses the permissions of a custom role as they are stored in the database
+// The permissions that do not exist anymore are ignored
+func parseThreadPermissions(permissions string) []ThreadPermission {
+	var parsed []ThreadPermission
+	for _, permission := range strings.Split(permissions, ",") {
+		if IsThreadPermissionAssignable(ThreadPermission(permission)) {
+			parsed = append(parsed, ThreadPermission(permission))
+		}
+	}
+	return parsed
+}
+
+// CreateThreadRole creates a custom role in the thread with the given permissions
+// Returns ErrThreadRoleExists if the thread already has a role with this name
+func CreateThreadRole(thread ThreadGoForum, roleName string, permissions []ThreadPermission) (ThreadRole, error) {
+	var storedPermissions []string
+	for _, permission := range permissions {
+		if !IsThreadPermissionAssignable(permission) {
+			return ThreadRole{}, fmt.Errorf("the permission %s cannot be given by a role", permission)
+		}
+		if !slices.Contains(storedPermissions, string(permission)) {
+			storedPermissions = append(storedPermissions, string(permission))
+		}
+	}
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM ThreadRoles WHERE thread_id = ? AND role_name = ? COLLATE NOCASE)", thread.ThreadID, roleName).Scan(&exists)
+	if err != nil {
+		ErrorPrintf("Error checking if the role already exists: %v\n", err)
+		return ThreadRole{}, err
+	}
+	if exists {
+		return ThreadRole{}, ErrThreadRoleExists
+	}
+	insertRole := "INSERT INTO ThreadRoles (thread_id, role_name, permissions) VALUES (?, ?, ?)"
+	res, err := db.Exec(insertRole, thread.ThreadID, roleName, strings.Join(storedPermissions, ","))
+	if err != nil {
+		ErrorPrintf("Error inserting the role into the database: %v\n", err)
+		return ThreadRole{}, err
+	}
+	roleID, err := res.LastInsertId()
+	if err != nil {
+		ErrorPrintf("Error getting the id of the new role: %v\n", err)
+		return ThreadRole{}, err
+	}
+	return ThreadRole{
+		RoleID:      int(roleID),
+		RoleName:    roleName,
+		Permissions: parseThreadPermissions(strings.Join(storedPermissions, ",")),
+	}, nil
+}
+
+// DeleteThreadRole deletes the custom role of the thread, its members keep their rank
+// Returns sql.ErrNoRows if the thread has no role with this id
+func DeleteThreadRole(thread ThreadGoForum, roleID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		ErrorPrintf("Error starting the transaction: %v\n", err)
+		return err
+	}
+	defer func(tx *sql.Tx) {
+		_ = tx.Rollback()
+	}(tx)
+	res, err := tx.Exec("DELETE FROM ThreadRoles WHERE role_id = ? AND thread_id = ?", roleID, thread.ThreadID)
+	if err != nil {
+		ErrorPrintf("Error deleting the role: %v\n", err)
+		return err
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		ErrorPrintf("Error getting the number of deleted roles: %v\n", err)
+		return err
+	}
+	if deleted == 0 {
+		return sql.ErrNoRows
+	}
+	_, err = tx.Exec("UPDATE ThreadGoForumMembers SET role_id = NULL WHERE thread_id = ? AND role_id = ?", thread.ThreadID, roleID)
+	if err != nil {
+		ErrorPrintf("Error removing the role from its members: %v\n", err)
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetThreadRoles returns the custom roles of the thread with their number of members, sorted by name
+// Returns an error if there is one
+func GetThreadRoles(thread ThreadGoForum) ([]ThreadRole, error) {
+	getRoles := `
+		SELECT r.role_id, r.role_name, r.permissions,
+		    (SELECT COUNT(*) FROM ThreadGoForumMembers m WHERE m.thread_id = r.thread_id AND m.role_id = r.role_id)
+		FROM ThreadRoles r
+		WHERE r.thread_id = ?
+		ORDER BY r.role_name COLLATE NOCASE`
+	rows, err := db.Query(getRoles, thread.ThreadID)
+	if err != nil {
+		ErrorPrintf("Error getting the roles of the thread: %v\n", err)
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	var roles []ThreadRole
+	for rows.Next() {
+		var role ThreadRole
+		var permissions string
+		err := rows.Scan(&role.RoleID, &role.RoleName, &permissions, &role.MemberCount)
+		if err != nil {
+			ErrorPrintf("Error scanning the rows in GetThreadRoles: %v\n", err)
+			return nil, err
+		}
+		role.Permissions = parseThreadPermissions(permissions)
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
+// SetMemberThreadRole gives the custom role to the member of the thread, a roleID of 0 removes his role
+// Returns sql.ErrNoRows if the user is not a member of the thread or if the thread has no role with this id
+func SetMemberThreadRole(thread ThreadGoForum, user User, roleID int) error {
+	var role interface{}
+	if roleID != 0 {
+		var exists bool
+		err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM ThreadRoles WHERE role_id = ? AND thread_id = ?)", roleID, thread.ThreadID).Scan(&exists)
+		if err != nil {
+			ErrorPrintf("Error checking if the role exists: %v\n", err)
+			return err
+		}
+		if !exists {
+			return sql.ErrNoRows
+		}
+		role = roleID
+	}
+	res, err := db.Exec("UPDATE ThreadGoForumMembers SET role_id = ? WHERE thread_id = ? AND user_id = ?", role, thread.ThreadID, user.UserID)
+	if err != nil {
+		ErrorPrintf("Error setting the role of the member: %v\n", err)
+		return err
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		ErrorPrintf("Error getting the number of updated members: %v\n", err)
+		return err
+	}
+	if updated == 0 {
+		return sql.ErrNoRows
+	}
+	if roleID != 0 {
+		_ = AwardBadgesForEvent(user, BadgeEventMemberPromoted)
+	}
+	return nil
+}
+
+// GetThreadModerationTeam returns the moderation team of the thread
+// Returns a slice of SimplifiedUser
+func GetThreadModerationTeam(forum ThreadGoForum) []SimplifiedUser {
+	getThreadModerationTeam := `
+		SELECT u.username, ml.media_address, tgm.rights_level
+		FROM ThreadGoForumMembers tgm
+		    JOIN Users u ON tgm.user_id = u.user_id
+		    JOIN UserConfigs uc ON u.user_id = uc.user_id
+		    JOIN MediaLink ml ON uc.pfp_id = ml.media_id
+		WHERE tgm.thread_id = ? AND tgm.rights_level > 0
+		ORDER BY tgm.rights_level DESC;`
+	rows, err := db.Query(getThreadModerationTeam, forum.ThreadID)
+	if err != nil {
+		ErrorPrintf("Error getting the thread moderation team: %v\n", err)
+		return nil
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
 		}
 	}(rows)
 	var moderationTeam []SimplifiedUser
@@ -2562,12 +4498,21 @@ func GetUserThreads(user User) []ThreadGoForum {
 // if voteType is true, it means the user upvoted the message
 // if voteType is false, it means the user downvoted the message
 func ThreadMessageAddVote(messageID int, userID int, voteType bool) error {
-	addVote := "INSERT INTO ThreadVotes (message_id, user_id, is_upvote) VALUES (?, ?, ?)"
+	addVote := "INSERT INTO ThreadVotes (message_id, user_id, is_upvote, vote_date) VALUES (?, ?, ?, CURRENT_TIMESTAMP)"
 	_, err := db.Exec(addVote, messageID, userID, voteType)
 	if err != nil {
 		ErrorPrintf("Error adding the vote to the message: %v\n", err)
 		return err
 	}
+	err = updateVoteReputation(userID, messageID, 0)
+	if err != nil {
+		return err
+	}
+	err = updateMessageScores(messageID)
+	if err != nil {
+		return err
+	}
+	publishMessageVotes(messageID)
 	return nil
 }
 
@@ -2592,6 +4537,15 @@ func ThreadMessageRemoveVote(messageID int, userID int) error {
 		ErrorPrintf("Error removing the vote from the message: %v\n", err)
 		return err
 	}
+	err = updateVoteReputation(userID, messageID, 0)
+	if err != nil {
+		return err
+	}
+	err = updateMessageScores(messageID)
+	if err != nil {
+		return err
+	}
+	publishMessageVotes(messageID)
 	return nil
 }
 
@@ -2599,12 +4553,21 @@ func ThreadMessageRemoveVote(messageID int, userID int) error {
 // Returns an error if there is one
 // It updates the vote of the message to the new vote
 func ThreadMessageUpdateVote(messageID int, userID int, voteType bool) error {
-	updateVote := "UPDATE ThreadVotes SET is_upvote = ? WHERE message_id = ? AND user_id = ?"
+	updateVote := "UPDATE ThreadVotes SET is_upvote = ?, vote_date = CURRENT_TIMESTAMP WHERE message_id = ? AND user_id = ?"
 	_, err := db.Exec(updateVote, voteType, messageID, userID)
 	if err != nil {
 		ErrorPrintf("Error updating the vote of the message: %v\n", err)
 		return err
 	}
+	err = updateVoteReputation(userID, messageID, 0)
+	if err != nil {
+		return err
+	}
+	err = updateMessageScores(messageID)
+	if err != nil {
+		return err
+	}
+	publishMessageVotes(messageID)
 	return nil
 }
 
@@ -2613,12 +4576,17 @@ func ThreadMessageUpdateVote(messageID int, userID int, voteType bool) error {
 // if voteType is true, it means the user upvoted the comment
 // if voteType is false, it means the user downvoted the comment
 func MessageCommentVote(commentID int, userID int, voteType bool) error {
-	addVote := "INSERT INTO ThreadVotes (comment_id, user_id, is_upvote) VALUES (?, ?, ?)"
+	addVote := "INSERT INTO ThreadVotes (comment_id, user_id, is_upvote, vote_date) VALUES (?, ?, ?, CURRENT_TIMESTAMP)"
 	_, err := db.Exec(addVote, commentID, userID, voteType)
 	if err != nil {
 		ErrorPrintf("Error adding the vote to the comment: %v\n", err)
 		return err
 	}
+	err = updateVoteReputation(userID, 0, commentID)
+	if err != nil {
+		return err
+	}
+	publishCommentVotes(commentID)
 	return nil
 }
 
@@ -2643,6 +4611,11 @@ func MessageCommentRemoveVote(commentID int, userID int) error {
 		ErrorPrintf("Error removing the vote from the comment: %v\n", err)
 		return err
 	}
+	err = updateVoteReputation(userID, 0, commentID)
+	if err != nil {
+		return err
+	}
+	publishCommentVotes(commentID)
 	return nil
 }
 
@@ -2650,152 +4623,560 @@ func MessageCommentRemoveVote(commentID int, userID int) error {
 // Returns an error if there is one
 // It updates the vote of the comment to the new vote
 func MessageCommentUpdateVote(commentID int, userID int, voteType bool) error {
-	updateVote := "UPDATE ThreadVotes SET is_upvote = ? WHERE comment_id = ? AND user_id = ?"
+	updateVote := "UPDATE ThreadVotes SET is_upvote = ?, vote_date = CURRENT_TIMESTAMP WHERE comment_id = ? AND user_id = ?"
 	_, err := db.Exec(updateVote, voteType, commentID, userID)
 	if err != nil {
 		ErrorPrintf("Error updating the vote of the comment: %v\n", err)
 		return err
 	}
+	err = updateVoteReputation(userID, 0, commentID)
+	if err != nil {
+		return err
+	}
+	publishCommentVotes(commentID)
 	return nil
 }
 
-func isValidHexColor(color string) bool {
-	// Check if the color is a valid hexadecimal color code
-	// The color must start with # and be followed by 6 or 3 hexadecimal digits
-	if len(color) != 7 && len(color) != 4 {
-		return false
-	}
-	if color[0] != '#' {
-		return false
-	}
-	for i := 1; i < len(color); i++ {
-		if !((color[i] >= '0' && color[i] <= '9') || (color[i] >= 'a' && color[i] <= 'f') || (color[i] >= 'A' && color[i] <= 'F')) {
-			return false
-		}
+// reputationVoteWeight returns the reputation points given by a vote to the author of the voted content
+func reputationVoteWeight(isComment bool, isUpvote bool) int {
+	switch {
+	case isComment && isUpvote:
+		return ReputationCommentUpvote
+	case isComment:
+		return ReputationCommentDownvote
+	case isUpvote:
+		return ReputationMessageUpvote
+	default:
+		return ReputationMessageDownvote
 	}
-	return true
 }
 
-// TagAlreadyExists checks if the tag already exists in the thread
-// Returns true if the tag already exists and false otherwise
-func TagAlreadyExists(thread ThreadGoForum, tagName string) (bool, error) {
-	getTag := "SELECT tag_name FROM ThreadGoForumTags WHERE thread_id = ? AND tag_name = ?"
-	rows, err := db.Query(getTag, thread.ThreadID, tagName)
-	if err != nil {
-		ErrorPrintf("Error checking if the tag already exists: %v\n", err)
-		return false, err
-	}
-	defer func(rows *sql.Rows) {
-		err := rows.Close()
-		if err != nil {
-			ErrorPrintf("Error closing the rows: %v\n", err)
-		}
-	}(rows)
-	if rows.Next() {
-		return true, nil
+// capReputationPoints returns the points the author can still gain with the given points, gained being what he already gained on the same day
+// The negative points are not capped
+func capReputationPoints(points int, gained int) int {
+	if points <= 0 {
+		return points
 	}
-	return false, nil
+	return max(0, min(points, ReputationDailyCap-gained))
 }
 
-// IsTagIDAssociatedWithThread checks if the tag id is associated with the thread
-// Returns true if the tag id is associated with the thread and false otherwise
-func IsTagIDAssociatedWithThread(thread ThreadGoForum, tagID int) (bool, error) {
-	getTag := "SELECT tag_id FROM ThreadGoForumTags WHERE thread_id = ? AND tag_id = ?"
-	rows, err := db.Query(getTag, thread.ThreadID, tagID)
+// updateVoteReputation updates the reputation given by the vote of the voter on the message or the comment (the other id being 0)
+// It is called after every change of the vote, the previous points of the vote are replaced by the ones of its current state
+// The votes of the authors on their own content do not give any reputation
+// Returns an error if there is one
+func updateVoteReputation(voterID int, messageID int, commentID int) error {
+	_, err := db.Exec("DELETE FROM ReputationEvents WHERE voter_id = ? AND message_id = ? AND comment_id = ?", voterID, messageID, commentID)
 	if err != nil {
-		ErrorPrintf("Error checking if the tag id is associated with the thread: %v\n", err)
-		return false, err
+		ErrorPrintf("Error removing the reputation of the vote: %v\n", err)
+		return err
 	}
-	defer func(rows *sql.Rows) {
-		err := rows.Close()
-		if err != nil {
-			ErrorPrintf("Error closing the rows: %v\n", err)
-		}
-	}(rows)
-	if rows.Next() {
-		return true, nil
+
+	// Get the current vote along with the author and the thread of the voted content
+	getVote := `
+		SELECT v.is_upvote, tm.user_id, tm.thread_id
+		FROM ThreadVotes v JOIN ThreadMessages tm ON v.message_id = tm.message_id
+		WHERE v.user_id = ? AND v.message_id = ?`
+	voteArgs := []interface{}{voterID, messageID}
+	if commentID > 0 {
+		getVote = `
+			SELECT v.is_upvote, tc.user_id, tm.thread_id
+			FROM ThreadVotes v
+			JOIN ThreadComments tc ON v.comment_id = tc.comment_id
+			JOIN ThreadMessages tm ON tc.message_id = tm.message_id
+			WHERE v.user_id = ? AND v.comment_id = ?`
+		voteArgs = []interface{}{voterID, commentID}
+	}
+	var isUpvote bool
+	var authorID, threadID int
+	err = db.QueryRow(getVote, voteArgs...).Scan(&isUpvote, &authorID, &threadID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && authorID == voterID) {
+		// The vote was removed or the author voted on his own content
+		return nil
+	}
+	if err != nil {
+		ErrorPrintf("Error getting the vote for the reputation: %v\n", err)
+		return err
 	}
-	return false, nil
-}
 
-// IsTagNameValid checks if the tag name is valid
-// Returns true if the tag name is valid and false otherwise
-func IsTagNameValid(tagName string) bool {
-	// Check if the tag name is at least 3 characters long
-	if len(tagName) < 3 {
-		return false
+	var gained int
+	err = db.QueryRow("SELECT COALESCE(SUM(points), 0) FROM ReputationEvents WHERE user_id = ? AND points > 0 AND date(event_date) = date('now')", authorID).Scan(&gained)
+	if err != nil {
+		ErrorPrintf("Error getting the reputation gained today: %v\n", err)
+		return err
 	}
-	// Check if the tag name is at most 30 characters long
-	if len(tagName) > 30 {
-		return false
+	points := capReputationPoints(reputationVoteWeight(commentID > 0, isUpvote), gained)
+	addEvent := "INSERT INTO ReputationEvents (voter_id, message_id, comment_id, user_id, thread_id, points) VALUES (?, ?, ?, ?, ?, ?)"
+	_, err = db.Exec(addEvent, voterID, messageID, commentID, authorID, threadID, points)
+	if err != nil {
+		ErrorPrintf("Error adding the reputation of the vote: %v\n", err)
+		return err
 	}
-	return true
+	_ = AwardBadgesForEvent(User{UserID: authorID}, BadgeEventVoteReceived)
+	return nil
 }
 
-// IsStringHexColor checks if the string is a valid hexadecimal color code
-// Returns true if the string is a valid hexadecimal color code and false otherwise
-func IsStringHexColor(color string) bool {
-	// Check if the color is a valid hexadecimal color code
-	// The color must start with # and be followed by 6 or 3 hexadecimal digits
-	if len(color) != 7 && len(color) != 4 {
-		return false
+// RecomputeAllReputation computes again the reputation of every user from all the votes
+// The votes are replayed in the order they were cast so the daily cap is applied as it would have been
+// Returns an error if there is one
+func RecomputeAllReputation() error {
+	getVotes := `
+		SELECT v.user_id, COALESCE(v.message_id, 0), COALESCE(v.comment_id, 0), v.is_upvote, COALESCE(tc.user_id, tm.user_id), tm.thread_id,
+			datetime(COALESCE(v.vote_date, tc.creation_date, tm.creation_date)) AS vote_date
+		FROM ThreadVotes v
+		LEFT JOIN ThreadComments tc ON v.comment_id = tc.comment_id
+		JOIN ThreadMessages tm ON tm.message_id = COALESCE(v.message_id, tc.message_id)
+		ORDER BY vote_date, v.rowid`
+	rows, err := db.Query(getVotes)
+	if err != nil {
+		ErrorPrintf("Error getting the votes for the reputation: %v\n", err)
+		return err
 	}
-	if color[0] != '#' {
-		return false
+	type reputationEvent struct {
+		voterID, messageID, commentID, authorID, threadID, points int
+		date                                                      string
 	}
-	for i := 1; i < len(color); i++ {
-		if !((color[i] >= '0' && color[i] <= '9') || (color[i] >= 'a' && color[i] <= 'f') || (color[i] >= 'A' && color[i] <= 'F')) {
-			return false
+	var events []reputationEvent
+	gained := make(map[string]int) // Points gained by each author on each day
+	for rows.Next() {
+		var event reputationEvent
+		var isUpvote bool
+		err := rows.Scan(&event.voterID, &event.messageID, &event.commentID, &isUpvote, &event.authorID, &event.threadID, &event.date)
+		if err != nil {
+			_ = rows.Close()
+			ErrorPrintf("Error scanning the rows in RecomputeAllReputation: %v\n", err)
+			return err
+		}
+		if event.authorID == event.voterID {
+			continue
+		}
+		day := fmt.Sprintf("%d %.10s", event.authorID, event.date)
+		event.points = capReputationPoints(reputationVoteWeight(event.commentID > 0, isUpvote), gained[day])
+		if event.points > 0 {
+			gained[day] += event.points
 		}
+		events = append(events, event)
 	}
-	return true
-}
-
-// AddThreadTag adds a tag to the thread
-// Returns an error if there is one
-// The tag name must be unique in the thread
-// The tag color must be a valid hexadecimal color code
-// The tag name must be at least 3 characters long
-// The tag name must be at most 30 characters long
-func AddThreadTag(thread ThreadGoForum, tagName string, tagColor string) error {
-	if len(tagName) < 3 || len(tagName) > 30 {
-		return fmt.Errorf("tag name must be between 3 and 30 characters long")
+	err = rows.Close()
+	if err != nil {
+		ErrorPrintf("Error closing the rows: %v\n", err)
+		return err
 	}
-	if !isValidHexColor(tagColor) {
-		return fmt.Errorf("tag color must be a valid hexadecimal color code")
+
+	tx, err := db.Begin()
+	if err != nil {
+		ErrorPrintf("Error starting the reputation transaction: %v\n", err)
+		return err
 	}
-	exists, err := TagAlreadyExists(thread, tagName)
+	_, err = tx.Exec("DELETE FROM ReputationEvents")
 	if err != nil {
-		ErrorPrintf("Error checking if the tag already exists: %v\n", err)
+		_ = tx.Rollback()
+		ErrorPrintf("Error removing the reputation events: %v\n", err)
 		return err
 	}
-	if exists {
-		return fmt.Errorf("tag name already exists in the thread")
+	addEvent := "INSERT INTO ReputationEvents (voter_id, message_id, comment_id, user_id, thread_id, points, event_date) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	for _, event := range events {
+		_, err = tx.Exec(addEvent, event.voterID, event.messageID, event.commentID, event.authorID, event.threadID, event.points, event.date)
+		if err != nil {
+			_ = tx.Rollback()
+			ErrorPrintf("Error adding the reputation event: %v\n", err)
+			return err
+		}
 	}
-	insertTag := "INSERT INTO ThreadGoForumTags (thread_id, tag_name, tag_color) VALUES (?, ?, ?)"
-	_, err = db.Exec(insertTag, thread.ThreadID, tagName, tagColor)
+	err = tx.Commit()
 	if err != nil {
-		ErrorPrintf("Error adding the tag to the thread: %v\n", err)
+		ErrorPrintf("Error committing the reputation transaction: %v\n", err)
 		return err
 	}
-	DebugPrintf("Added the tag '%s' to the thread '%s'\n", tagName, thread.ThreadName)
+	InfoPrintf("Reputation recomputed from %d votes\n", len(events))
 	return nil
 }
 
-// RemoveThreadTag removes the tag from the thread
-// Returns an error if there is one
-func RemoveThreadTag(thread ThreadGoForum, tagName string) error {
-	removeTag := "DELETE FROM ThreadGoForumTags WHERE thread_id = ? AND tag_name = ?"
-	_, err := db.Exec(removeTag, thread.ThreadID, tagName)
+// GetUserThreadReputation returns the reputation of the user in the thread
+func GetUserThreadReputation(thread ThreadGoForum, user User) int {
+	var reputation int
+	err := db.QueryRow("SELECT COALESCE(SUM(points), 0) FROM ReputationEvents WHERE user_id = ? AND thread_id = ?", user.UserID, thread.ThreadID).Scan(&reputation)
 	if err != nil {
-		ErrorPrintf("Error removing the tag from the thread: %v\n", err)
-		return err
+		ErrorPrintf("Error getting the reputation of the user in the thread: %v\n", err)
+		return 0
 	}
-	return nil
+	return reputation
 }
 
-// UpdateThreadTag updates the tag of the thread
-// Returns an error if there is one
+// GetUserReputation returns the reputation of the user, the sum of his reputation in every thread
+func GetUserReputation(user User) int {
+	var reputation int
+	err := db.QueryRow("SELECT COALESCE(SUM(points), 0) FROM ReputationEvents WHERE user_id = ?", user.UserID).Scan(&reputation)
+	if err != nil {
+		ErrorPrintf("Error getting the reputation of the user: %v\n", err)
+		return 0
+	}
+	return reputation
+}
+
+// HasReputationFor checks if the reputation of the user in the thread unlocks the capability
+// The users allowed to manage the thread are not limited by their reputation
+func HasReputationFor(thread ThreadGoForum, user User, capability ReputationCapability) bool {
+	threadConfig := GetThreadConfigFromThread(thread)
+	var threshold int
+	switch capability {
+	case ReputationPostImages:
+		threshold = threadConfig.MinReputationImages
+	case ReputationCreateTags:
+		threshold = threadConfig.MinReputationTags
+	case ReputationReport:
+		threshold = threadConfig.MinReputationReports
+	}
+	if threshold <= 0 || HasThreadPermission(thread, user, PermissionManageThread) {
+		return true
+	}
+	return GetUserThreadReputation(thread, user) >= threshold
+}
+
+// IsReputationThresholdValid checks if a reputation threshold is valid (between 0 and 100000, 0 disables it)
+func IsReputationThresholdValid(threshold int) bool {
+	return threshold >= 0 && threshold <= 100000
+}
+
+// getBadgeMetricValue returns the current value of the metric for the user
+// Returns an error if there is one
+func getBadgeMetricValue(user User, metric BadgeMetric) (int, error) {
+	var getValue string
+	var args []interface{}
+	switch metric {
+	case BadgeMetricMessages:
+		getValue = "SELECT COUNT(*) FROM ThreadMessages WHERE user_id = ? AND approval_state = ? AND deletion_date IS NULL"
+		args = []interface{}{user.UserID, MessageApproved}
+	case BadgeMetricComments:
+		getValue = "SELECT COUNT(*) FROM ThreadComments WHERE user_id = ? AND deletion_date IS NULL"
+		args = []interface{}{user.UserID}
+	case BadgeMetricUpvotesReceived:
+		// The votes of the user on his own content are not counted
+		getValue = `
+			SELECT
+				(SELECT COUNT(*) FROM ThreadVotes v JOIN ThreadMessages tm ON v.message_id = tm.message_id
+				WHERE tm.user_id = ? AND v.user_id != ? AND v.is_upvote = 1) +
+				(SELECT COUNT(*) FROM ThreadVotes v JOIN ThreadComments tc ON v.comment_id = tc.comment_id
+				WHERE tc.user_id = ? AND v.user_id != ? AND v.is_upvote = 1)`
+		args = []interface{}{user.UserID, user.UserID, user.UserID, user.UserID}
+	case BadgeMetricReputation:
+		return GetUserReputation(user), nil
+	case BadgeMetricThreadsFounded:
+		getValue = "SELECT COUNT(*) FROM ThreadGoForum WHERE owner_id = ?"
+		args = []interface{}{user.UserID}
+	case BadgeMetricThreadsModerated:
+		// Same moderated threads as the ones of the profile stats, see GetUserProfileStats
+		getValue = "SELECT COUNT(*) FROM ThreadGoForumMembers WHERE user_id = ? AND (rights_level >= ? OR (rights_level >= 0 AND role_id IS NOT NULL))"
+		args = []interface{}{user.UserID, ThreadRankModerator}
+	default:
+		return 0, fmt.Errorf("unknown badge metric %s", metric)
+	}
+	var value int
+	err := db.QueryRow(getValue, args...).Scan(&value)
+	if err != nil {
+		ErrorPrintf("Error getting the value of the badge metric %s: %v\n", metric, err)
+		return 0, err
+	}
+	return value, nil
+}
+
+// awardBadges awards the badges the user does not have yet and whose threshold is reached
+// Returns the number of awarded badges and an error if there is one
+func awardBadges(user User, candidates []Badge) (int, error) {
+	if len(candidates) == 0 {
+		return 0, nil
+	}
+	owned := make(map[string]bool)
+	rows, err := db.Query("SELECT badge_id FROM UserBadges WHERE user_id = ?", user.UserID)
+	if err != nil {
+		ErrorPrintf("Error getting the badges of the user: %v\n", err)
+		return 0, err
+	}
+	for rows.Next() {
+		var badgeID string
+		err := rows.Scan(&badgeID)
+		if err != nil {
+			_ = rows.Close()
+			ErrorPrintf("Error scanning the rows in awardBadges: %v\n", err)
+			return 0, err
+		}
+		owned[badgeID] = true
+	}
+	err = rows.Close()
+	if err != nil {
+		ErrorPrintf("Error closing the rows: %v\n", err)
+		return 0, err
+	}
+
+	awarded := 0
+	values := make(map[BadgeMetric]int) // Each metric is only computed once
+	for _, badge := range candidates {
+		if owned[badge.BadgeID] {
+			continue
+		}
+		value, ok := values[badge.Metric]
+		if !ok {
+			value, err = getBadgeMetricValue(user, badge.Metric)
+			if err != nil {
+				return awarded, err
+			}
+			values[badge.Metric] = value
+		}
+		if value < badge.Threshold {
+			continue
+		}
+		_, err = db.Exec("INSERT OR IGNORE INTO UserBadges (user_id, badge_id) VALUES (?, ?)", user.UserID, badge.BadgeID)
+		if err != nil {
+			ErrorPrintf("Error awarding the badge %s: %v\n", badge.BadgeID, err)
+			return awarded, err
+		}
+		awarded++
+		DebugPrintf("Badge %s awarded to the user %d\n", badge.BadgeID, user.UserID)
+	}
+	return awarded, nil
+}
+
+// AwardBadgesForEvent evaluates the badges whose metric can be changed by the event and awards the ones the user reached
+// Returns an error if there is one
+func AwardBadgesForEvent(user User, event BadgeEvent) error {
+	_, err := awardBadges(user, getBadgesForEvent(event))
+	return err
+}
+
+// AwardAllBadges evaluates every badge for every user, used to award the badges added to the configuration
+// Returns an error if there is one
+func AwardAllBadges() error {
+	rows, err := db.Query("SELECT user_id FROM Users")
+	if err != nil {
+		ErrorPrintf("Error getting the users for the badges: %v\n", err)
+		return err
+	}
+	var users []User
+	for rows.Next() {
+		var user User
+		err := rows.Scan(&user.UserID)
+		if err != nil {
+			_ = rows.Close()
+			ErrorPrintf("Error scanning the rows in AwardAllBadges: %v\n", err)
+			return err
+		}
+		users = append(users, user)
+	}
+	err = rows.Close()
+	if err != nil {
+		ErrorPrintf("Error closing the rows: %v\n", err)
+		return err
+	}
+
+	total := 0
+	for _, user := range users {
+		awarded, err := awardBadges(user, GetBadges())
+		if err != nil {
+			return err
+		}
+		total += awarded
+	}
+	InfoPrintf("%d badges awarded to %d users\n", total, len(users))
+	return nil
+}
+
+// GetUserBadges returns the badges awarded to the user that are still defined, from the oldest to the newest
+// Returns an error if there is one
+func GetUserBadges(user User) ([]UserBadge, error) {
+	userBadges, err := getUsersBadges([]string{user.Username})
+	if err != nil {
+		return nil, err
+	}
+	return userBadges[user.Username], nil
+}
+
+// getUsersBadges returns the badges awarded to each of the users that are still defined, indexed by username
+// Returns an error if there is one
+func getUsersBadges(usernames []string) (map[string][]UserBadge, error) {
+	userBadges := make(map[string][]UserBadge)
+	if len(usernames) == 0 {
+		return userBadges, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(usernames)), ",")
+	args := make([]interface{}, len(usernames))
+	for i, username := range usernames {
+		args[i] = username
+	}
+	getBadges := `
+		SELECT u.username, b.badge_id, b.award_date
+		FROM UserBadges b JOIN Users u ON b.user_id = u.user_id
+		WHERE u.username IN (` + placeholders + `)
+		ORDER BY b.award_date, b.badge_id`
+	rows, err := db.Query(getBadges, args...)
+	if err != nil {
+		ErrorPrintf("Error getting the badges of the users: %v\n", err)
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	for rows.Next() {
+		var username, badgeID string
+		var awardDate time.Time
+		err := rows.Scan(&username, &badgeID, &awardDate)
+		if err != nil {
+			ErrorPrintf("Error scanning the rows in getUsersBadges: %v\n", err)
+			return nil, err
+		}
+		badge, ok := GetBadgeFromID(badgeID)
+		if !ok {
+			continue
+		}
+		userBadges[username] = append(userBadges[username], UserBadge{
+			BadgeID:      badge.BadgeID,
+			Icon:         badge.Icon,
+			Names:        badge.Names,
+			Descriptions: badge.Descriptions,
+			AwardDate:    awardDate,
+		})
+	}
+	return userBadges, nil
+}
+
+func isValidHexColor(color string) bool {
+	// Check if the color is a valid hexadecimal color code
+	// The color must start with # and be followed by 6 or 3 hexadecimal digits
+	if len(color) != 7 && len(color) != 4 {
+		return false
+	}
+	if color[0] != '#' {
+		return false
+	}
+	for i := 1; i < len(color); i++ {
+		if !((color[i] >= '0' && color[i] <= '9') || (color[i] >= 'a' && color[i] <= 'f') || (color[i] >= 'A' && color[i] <= 'F')) {
+			return false
+		}
+	}
+	return true
+}
+
+// TagAlreadyExists checks if the tag already exists in the thread
+// Returns true if the tag already exists and false otherwise
+func TagAlreadyExists(thread ThreadGoForum, tagName string) (bool, error) {
+	getTag := "SELECT tag_name FROM ThreadGoForumTags WHERE thread_id = ? AND tag_name = ?"
+	rows, err := db.Query(getTag, thread.ThreadID, tagName)
+	if err != nil {
+		ErrorPrintf("Error checking if the tag already exists: %v\n", err)
+		return false, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	if rows.Next() {
+		return true, nil
+	}
+	return false, nil
+}
+
+// IsTagIDAssociatedWithThread checks if the tag id is associated with the thread
+// Returns true if the tag id is associated with the thread and false otherwise
+func IsTagIDAssociatedWithThread(thread ThreadGoForum, tagID int) (bool, error) {
+	getTag := "SELECT tag_id FROM ThreadGoForumTags WHERE thread_id = ? AND tag_id = ?"
+	rows, err := db.Query(getTag, thread.ThreadID, tagID)
+	if err != nil {
+		ErrorPrintf("Error checking if the tag id is associated with the thread: %v\n", err)
+		return false, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	if rows.Next() {
+		return true, nil
+	}
+	return false, nil
+}
+
+// IsTagNameValid checks if the tag name is valid
+// Returns true if the tag name is valid and false otherwise
+func IsTagNameValid(tagName string) bool {
+	// Check if the tag name is at least 3 characters long
+	if len(tagName) < 3 {
+		return false
+	}
+	// Check if the tag name is at most 30 characters long
+	if len(tagName) > 30 {
+		return false
+	}
+	return true
+}
+
+// IsStringHexColor checks if the string is a valid hexadecimal color code
+// Returns true if the string is a valid hexadecimal color code and false otherwise
+func IsStringHexColor(color string) bool {
+	// Check if the color is a valid hexadecimal color code
+	// The color must start with # and be followed by 6 or 3 hexadecimal digits
+	if len(color) != 7 && len(color) != 4 {
+		return false
+	}
+	if color[0] != '#' {
+		return false
+	}
+	for i := 1; i < len(color); i++ {
+		if !((color[i] >= '0' && color[i] <= '9') || (color[i] >= 'a' && color[i] <= 'f') || (color[i] >= 'A' && color[i] <= 'F')) {
+			return false
+		}
+	}
+	return true
+}
+
+// AddThreadTag adds a tag to the thread
+// Returns an error if there is one
+// The tag name must be unique in the thread
+// The tag color must be a valid hexadecimal color code
+// The tag name must be at least 3 characters long
+// The tag name must be at most 30 characters long
+func AddThreadTag(thread ThreadGoForum, tagName string, tagColor string) error {
+	if len(tagName) < 3 || len(tagName) > 30 {
+		return fmt.Errorf("tag name must be between 3 and 30 characters long")
+	}
+	if !isValidHexColor(tagColor) {
+		return fmt.Errorf("tag color must be a valid hexadecimal color code")
+	}
+	exists, err := TagAlreadyExists(thread, tagName)
+	if err != nil {
+		ErrorPrintf("Error checking if the tag already exists: %v\n", err)
+		return err
+	}
+	if exists {
+		return fmt.Errorf("tag name already exists in the thread")
+	}
+	insertTag := "INSERT INTO ThreadGoForumTags (thread_id, tag_name, tag_color) VALUES (?, ?, ?)"
+	_, err = db.Exec(insertTag, thread.ThreadID, tagName, tagColor)
+	if err != nil {
+		ErrorPrintf("Error adding the tag to the thread: %v\n", err)
+		return err
+	}
+	DebugPrintf("Added the tag '%s' to the thread '%s'\n", tagName, thread.ThreadName)
+	return nil
+}
+
+// RemoveThreadTag removes the tag from the thread
+// Returns an error if there is one
+func RemoveThreadTag(thread ThreadGoForum, tagName string) error {
+	removeTag := "DELETE FROM ThreadGoForumTags WHERE thread_id = ? AND tag_name = ?"
+	_, err := db.Exec(removeTag, thread.ThreadID, tagName)
+	if err != nil {
+		ErrorPrintf("Error removing the tag from the thread: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// UpdateThreadTag updates the tag of the thread
+// Returns an error if there is one
 // Does not check if the tag name already exists or if the tag color is valid
 func UpdateThreadTag(thread ThreadGoForum, tagID int, tagName string, tagColor string) error {
 	updateTag := "UPDATE ThreadGoForumTags SET tag_name = ?, tag_color = ? WHERE thread_id = ? AND tag_id = ?"
@@ -2902,39 +5283,114 @@ func GetMessageTags(messageID int) ([]ThreadTag, error) {
 	return tags, nil
 }
 
-// SetReportAsResolved sets the report as resolved
+// getMessagesTags returns the tags of the given messages, indexed by message id
 // Returns an error if there is one
-func SetReportAsResolved(reportID int) error {
-	setReportAsResolved := "UPDATE Reports SET is_resolved = 1 WHERE report_id = ?"
-	_, err := db.Exec(setReportAsResolved, reportID)
-	if err != nil {
-		ErrorPrintf("Error setting the report as resolved: %v\n", err)
-		return err
+func getMessagesTags(messageIDs []int) (map[int][]ThreadTag, error) {
+	tags := make(map[int][]ThreadTag)
+	if len(messageIDs) == 0 {
+		return tags, nil
 	}
-	return nil
-}
-
-// AddReportedMessage adds the reported message to the database
-// Returns an error if there is one
-func AddReportedMessage(user User, thread ThreadGoForum, messageID int, reportType ReportType, content string) error {
-	addReport := "INSERT INTO Reports (username, message_id, report_type, report_content, thread_id) VALUES (?, ?, ?, ?, ?)"
-	_, err := db.Exec(addReport, user.Username, messageID, string(reportType), content, thread.ThreadID)
+	placeholders, args := idListSQL(messageIDs)
+	getTags := fmt.Sprintf("SELECT tmt.message_id, tt.tag_id, tt.thread_id, tt.tag_name, tt.tag_color FROM ThreadGoForumTags tt JOIN ThreadMessageTags tmt ON tt.tag_id = tmt.tag_id WHERE tmt.message_id IN (%s)", placeholders)
+	rows, err := db.Query(getTags, args...)
 	if err != nil {
-		ErrorPrintf("Error adding the reported message to the database: %v\n", err)
-		return err
+		ErrorPrintf("Error getting the tags from the messages: %v\n", err)
+		return nil, err
 	}
-	return nil
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	for rows.Next() {
+		var messageID int
+		var tag ThreadTag
+		err := rows.Scan(&messageID, &tag.TagID, &tag.ThreadID, &tag.TagName, &tag.TagColor)
+		if err != nil {
+			ErrorPrintf("Error scanning the rows in getMessagesTags: %v\n", err)
+			return nil, err
+		}
+		tags[messageID] = append(tags[messageID], tag)
+	}
+	return tags, nil
 }
 
-// AddReportedComment adds the reported comment to the database
+// getMessagesMediaLinks returns the addresses of the media of the given messages, indexed by message id
 // Returns an error if there is one
-func AddReportedComment(user User, thread ThreadGoForum, commentID, messageID int, reportType ReportType, content string) error {
-	addReport := "INSERT INTO Reports (username, comment_id, message_id, report_type, report_content, thread_id) VALUES (?, ?, ?, ?, ?, ?)"
-	_, err := db.Exec(addReport, user.Username, commentID, messageID, string(reportType), content, thread.ThreadID)
+func getMessagesMediaLinks(messageIDs []int) (map[int][]string, error) {
+	mediaLinks := make(map[int][]string)
+	if len(messageIDs) == 0 {
+		return mediaLinks, nil
+	}
+	placeholders, args := idListSQL(messageIDs)
+	getMediaLinks := fmt.Sprintf(`
+		SELECT tmml.message_id, ml.media_address
+		FROM ThreadMessageMediaLinks tmml JOIN MediaLink ml ON tmml.media_id = ml.media_id
+		WHERE tmml.message_id IN (%s)`, placeholders)
+	rows, err := db.Query(getMediaLinks, args...)
+	if err != nil {
+		ErrorPrintf("Error getting the media links of the messages: %v\n", err)
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	for rows.Next() {
+		var messageID int
+		var mediaLinkAddress string
+		err := rows.Scan(&messageID, &mediaLinkAddress)
+		if err != nil {
+			ErrorPrintf("Error scanning the rows in getMessagesMediaLinks: %v\n", err)
+			return nil, err
+		}
+		mediaLinks[messageID] = append(mediaLinks[messageID], mediaLinkAddress)
+	}
+	return mediaLinks, nil
+}
+
+// SetReportAsResolved sets the report as resolved
+// Returns an error if there is one
+func SetReportAsResolved(reportID int) error {
+	setReportAsResolved := "UPDATE Reports SET is_resolved = 1, report_state = ?, closed_date = CURRENT_TIMESTAMP WHERE report_id = ?"
+	_, err := db.Exec(setReportAsResolved, ReportActioned, reportID)
+	if err != nil {
+		ErrorPrintf("Error setting the report as resolved: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// AddReportedMessage adds the reported message to the database
+// Returns an error if there is one
+func AddReportedMessage(user User, thread ThreadGoForum, messageID int, reportType ReportType, content string) error {
+	addReport := `
+		INSERT INTO Reports (username, message_id, report_type, report_content, thread_id, reported_user_id, report_date)
+		VALUES (?, ?, ?, ?, ?, (SELECT user_id FROM ThreadMessages WHERE message_id = ?), CURRENT_TIMESTAMP)`
+	_, err := db.Exec(addReport, user.Username, messageID, string(reportType), content, thread.ThreadID, messageID)
+	if err != nil {
+		ErrorPrintf("Error adding the reported message to the database: %v\n", err)
+		return err
+	}
+	TriggerThreadWebhooks(thread, WebhookReportCreated, WebhookReportData{ReportType: reportType, MessageID: messageID})
+	return nil
+}
+
+// AddReportedComment adds the reported comment to the database
+// Returns an error if there is one
+func AddReportedComment(user User, thread ThreadGoForum, commentID, messageID int, reportType ReportType, content string) error {
+	addReport := `
+		INSERT INTO Reports (username, comment_id, message_id, report_type, report_content, thread_id, reported_user_id, report_date)
+		VALUES (?, ?, ?, ?, ?, ?, (SELECT user_id FROM ThreadComments WHERE comment_id = ?), CURRENT_TIMESTAMP)`
+	_, err := db.Exec(addReport, user.Username, commentID, messageID, string(reportType), content, thread.ThreadID, commentID)
 	if err != nil {
 		ErrorPrintf("Error adding the reported comment to the database: %v\n", err)
 		return err
 	}
+	TriggerThreadWebhooks(thread, WebhookReportCreated, WebhookReportData{ReportType: reportType, MessageID: messageID, CommentID: commentID})
 	return nil
 }
 
@@ -2976,41 +5432,2856 @@ func GetReportedContentInThread(thread ThreadGoForum) ([]ReportedContent, error)
 			report.ReportedContentID = messageID
 			report.IsAPostAndNotAComment = true
 		}
-		report.PostID = messageID
-		reports = append(reports, report)
+		report.PostID = messageID
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// RemoveReportedContent removes the reported content from the database
+// Returns an error if there is one
+func RemoveReportedContent(reportID int) error {
+	removeReport := "DELETE FROM Reports WHERE report_id = ?"
+	_, err := db.Exec(removeReport, reportID)
+	if err != nil {
+		ErrorPrintf("Error removing the reported content from the database: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+func ReportExistsInThread(thread ThreadGoForum, reportID int) bool {
+	getReport := "SELECT report_id FROM Reports WHERE thread_id = ? AND report_id = ?"
+	rows, err := db.Query(getReport, thread.ThreadID, reportID)
+	if err != nil {
+		ErrorPrintf("Error checking if the report exists in the thread: %v\n", err)
+		return false
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	if rows.Next() {
+		return true
+	}
+	return false
+}
+
+// IsAnApiTokenScope checks if the string is a personal access token scope
+func IsAnApiTokenScope(scope string) bool {
+	for _, v := range ApiTokenScopes {
+		if string(v) == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// GetApiTokenScopeFromString returns the personal access token scope from the string
+// Returns an error if the scope is not valid
+func GetApiTokenScopeFromString(scope string) (ApiTokenScope, error) {
+	for _, v := range ApiTokenScopes {
+		if string(v) == scope {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("invalid api token scope: %s", scope)
+}
+
+// GetApiTokenScopesAsStrings returns the personal access token scopes as a string
+func GetApiTokenScopesAsStrings() []string {
+	var scopes []string
+	for _, v := range ApiTokenScopes {
+		scopes = append(scopes, string(v))
+	}
+	return scopes
+}
+
+// IsApiTokenNameValid checks if the personal access token name is valid
+// Token name must be between 3 and 50 characters long
+// Token name must only contain letters, numbers, underscores, hyphens and spaces
+func IsApiTokenNameValid(tokenName string) bool {
+	tokenNameRegex := regexp.MustCompile(`^[a-zA-Z0-9 _-]{3,50}$`)
+	return tokenNameRegex.MatchString(tokenName)
+}
+
+// HasScope checks if the personal access token has the given scope
+// The 'post' scope also grants the 'read' scope
+func (token ApiToken) HasScope(scope ApiTokenScope) bool {
+	for _, s := range token.Scopes {
+		if s == scope || (s == ApiTokenScopePost && scope == ApiTokenScopeRead) {
+			return true
+		}
+	}
+	return false
+}
+
+// hashApiToken returns the hexadecimal sha256 hash of the given personal access token
+func hashApiToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// scopesToString converts a slice of scopes to the comma separated string stored in the database
+func scopesToString(scopes []ApiTokenScope) string {
+	strScopes := make([]string, len(scopes))
+	for i, scope := range scopes {
+		strScopes[i] = string(scope)
+	}
+	return strings.Join(strScopes, ",")
+}
+
+// stringToScopes converts the comma separated string stored in the database to a slice of scopes
+// Unknown scopes are ignored
+func stringToScopes(strScopes string) []ApiTokenScope {
+	var scopes []ApiTokenScope
+	for _, strScope := range strings.Split(strScopes, ",") {
+		scope, err := GetApiTokenScopeFromString(strings.TrimSpace(strScope))
+		if err == nil {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// CreateApiToken creates a new personal access token for the user with the given name and scopes
+// Returns the token in clear (it is the only time it is available) and an error if there is one
+func CreateApiToken(user User, tokenName string, scopes []ApiTokenScope) (string, error) {
+	if len(scopes) == 0 {
+		return "", fmt.Errorf("a personal access token needs at least one scope")
+	}
+	randomBytes := make([]byte, 24)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		ErrorPrintf("Error generating the personal access token: %v\n", err)
+		return "", err
+	}
+	token := "gfpat_" + hex.EncodeToString(randomBytes)
+	insertToken := "INSERT INTO UserApiTokens (user_id, token_name, token_hash, token_prefix, scopes) VALUES (?, ?, ?, ?, ?)"
+	_, err = db.Exec(insertToken, user.UserID, tokenName, hashApiToken(token), token[:12], scopesToString(scopes))
+	if err != nil {
+		ErrorPrintf("Error inserting the personal access token into the database: %v\n", err)
+		return "", err
+	}
+	return token, nil
+}
+
+// GetUserApiTokens returns the personal access tokens of the user
+// Returns a slice of ApiToken and an error if there is one
+func GetUserApiTokens(user User) ([]ApiToken, error) {
+	getTokens := "SELECT token_id, user_id, token_name, token_prefix, scopes, creation_date, last_used_date FROM UserApiTokens WHERE user_id = ? ORDER BY creation_date DESC"
+	rows, err := db.Query(getTokens, user.UserID)
+	if err != nil {
+		ErrorPrintf("Error getting the personal access tokens of the user: %v\n", err)
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	var tokens []ApiToken
+	for rows.Next() {
+		var token ApiToken
+		var strScopes string
+		err := rows.Scan(
+			&token.TokenID,
+			&token.UserID,
+			&token.TokenName,
+			&token.TokenPrefix,
+			&strScopes,
+			&token.CreationDate,
+			&token.LastUsedDate)
+		if err != nil {
+			ErrorPrintf("Error scanning the rows in GetUserApiTokens: %v\n", err)
+			return nil, err
+		}
+		token.Scopes = stringToScopes(strScopes)
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+// RevokeApiToken removes the personal access token with the given id if it belongs to the user
+// Returns an error if there is one
+func RevokeApiToken(user User, tokenID int) error {
+	removeToken := "DELETE FROM UserApiTokens WHERE token_id = ? AND user_id = ?"
+	_, err := db.Exec(removeToken, tokenID, user.UserID)
+	if err != nil {
+		ErrorPrintf("Error removing the personal access token from the database: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// GetUserFromApiToken returns the user owning the given personal access token as well as the token information
+// It also updates the last time the token was used
+// Returns an error if the token does not exist or if there is one
+func GetUserFromApiToken(token string) (User, ApiToken, error) {
+	getToken := `
+		SELECT
+			t.token_id, t.user_id, t.token_name, t.token_prefix, t.scopes, t.creation_date, t.last_used_date,
+			u.user_id, u.email, u.username, u.firstname, u.lastname, u.password_hash, u.email_verified, u.oauth_provider, u.oauth_id, u.creation_date
+		FROM UserApiTokens t
+		JOIN Users u ON t.user_id = u.user_id
+		WHERE t.token_hash = ?`
+	rows, err := db.Query(getToken, hashApiToken(token))
+	if err != nil {
+		ErrorPrintf("Error getting the personal access token: %v\n", err)
+		return User{}, ApiToken{}, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	if !rows.Next() {
+		return User{}, ApiToken{}, fmt.Errorf("unknown personal access token")
+	}
+	var user User
+	var apiToken ApiToken
+	var strScopes string
+	err = rows.Scan(
+		&apiToken.TokenID,
+		&apiToken.UserID,
+		&apiToken.TokenName,
+		&apiToken.TokenPrefix,
+		&strScopes,
+		&apiToken.CreationDate,
+		&apiToken.LastUsedDate,
+		&user.UserID,
+		&user.Email,
+		&user.Username,
+		&user.Firstname,
+		&user.Lastname,
+		&user.PasswordHash,
+		&user.EmailVerified,
+		&user.OAuthProvider,
+		&user.OAuthID,
+		&user.CreatedAt)
+	if err != nil {
+		ErrorPrintf("Error scanning the rows in GetUserFromApiToken: %v\n", err)
+		return User{}, ApiToken{}, err
+	}
+	apiToken.Scopes = stringToScopes(strScopes)
+	// Close the rows before updating the token, sqlite does not like concurrent read and write on the same table
+	err = rows.Close()
+	if err != nil {
+		ErrorPrintf("Error closing the rows: %v\n", err)
+	}
+
+	updateLastUsed := "UPDATE UserApiTokens SET last_used_date = CURRENT_TIMESTAMP WHERE token_id = ?"
+	_, err = db.Exec(updateLastUsed, apiToken.TokenID)
+	if err != nil {
+		ErrorPrintf("Error updating the last use of the personal access token: %v\n", err)
+	}
+	return user, apiToken, nil
+}
+
+// GetThreadMemberCount returns the number of members of the thread (banned users excluded)
+// Returns an error if there is one
+func GetThreadMemberCount(thread ThreadGoForum) (int, error) {
+	getCount := "SELECT COUNT(*) FROM ThreadGoForumMembers WHERE thread_id = ? AND rights_level >= 0"
+	var count int
+	err := db.QueryRow(getCount, thread.ThreadID).Scan(&count)
+	if err != nil {
+		ErrorPrintf("Error getting the number of members in the thread: %v\n", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// IsAWebhookEvent checks if the string is a webhook event
+func IsAWebhookEvent(event string) bool {
+	for _, v := range WebhookEvents {
+		if string(v) == event {
+			return true
+		}
+	}
+	return false
+}
+
+// GetWebhookEventFromString returns the webhook event from the string
+// Returns an error if the event is not valid
+func GetWebhookEventFromString(event string) (WebhookEvent, error) {
+	for _, v := range WebhookEvents {
+		if string(v) == event {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("invalid webhook event: %s", event)
+}
+
+// GetWebhookEventsAsStrings returns the webhook events as a string
+func GetWebhookEventsAsStrings() []string {
+	var events []string
+	for _, v := range WebhookEvents {
+		events = append(events, string(v))
+	}
+	return events
+}
+
+// IsWebhookURLValid checks if the webhook url is valid
+// The url must be an absolute http or https url of at most 500 characters
+// Its host must resolve to public addresses only, see isWebhookIPAllowed (the address is checked again when the webhook is delivered)
+func IsWebhookURLValid(webhookURL string) bool {
+	if len(webhookURL) > 500 {
+		return false
+	}
+	parsedURL, err := url.Parse(webhookURL)
+	if err != nil {
+		return false
+	}
+	if (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Hostname() == "" {
+		return false
+	}
+	ips, err := net.LookupIP(parsedURL.Hostname())
+	if err != nil || len(ips) == 0 {
+		return false
+	}
+	for _, ip := range ips {
+		if !isWebhookIPAllowed(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// webhookEventsToString converts a slice of webhook events to the comma separated string stored in the database
+func webhookEventsToString(events []WebhookEvent) string {
+	strEvents := make([]string, len(events))
+	for i, event := range events {
+		strEvents[i] = string(event)
+	}
+	return strings.Join(strEvents, ",")
+}
+
+// stringToWebhookEvents converts the comma separated string stored in the database to a slice of webhook events
+// Unknown events are ignored
+func stringToWebhookEvents(strEvents string) []WebhookEvent {
+	var events []WebhookEvent
+	for _, strEvent := range strings.Split(strEvents, ",") {
+		event, err := GetWebhookEventFromString(strings.TrimSpace(strEvent))
+		if err == nil {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// HasEvent checks if the webhook is registered for the given event
+func (webhook ThreadWebhook) HasEvent(event WebhookEvent) bool {
+	for _, e := range webhook.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// AddThreadWebhook registers a new webhook on the thread for the given events
+// A random secret used to sign the deliveries is generated
+// Returns the id of the webhook, its secret and an error if there is one
+func AddThreadWebhook(thread ThreadGoForum, webhookURL string, events []WebhookEvent) (int, string, error) {
+	if len(events) == 0 {
+		return -1, "", fmt.Errorf("a webhook needs at least one event")
+	}
+	randomBytes := make([]byte, 32)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		ErrorPrintf("Error generating the webhook secret: %v\n", err)
+		return -1, "", err
+	}
+	secret := hex.EncodeToString(randomBytes)
+	insertWebhook := "INSERT INTO ThreadWebhooks (thread_id, webhook_url, webhook_secret, events) VALUES (?, ?, ?, ?)"
+	res, err := db.Exec(insertWebhook, thread.ThreadID, webhookURL, secret, webhookEventsToString(events))
+	if err != nil {
+		ErrorPrintf("Error inserting the webhook into the database: %v\n", err)
+		return -1, "", err
+	}
+	webhookID, err := res.LastInsertId()
+	if err != nil {
+		ErrorPrintf("Error getting the last insert id: %v\n", err)
+		return -1, "", err
+	}
+	return int(webhookID), secret, nil
+}
+
+// RemoveThreadWebhook removes the webhook with the given id from the thread (as well as its deliveries)
+// Returns an error if there is one
+func RemoveThreadWebhook(thread ThreadGoForum, webhookID int) error {
+	removeWebhook := "DELETE FROM ThreadWebhooks WHERE webhook_id = ? AND thread_id = ?"
+	_, err := db.Exec(removeWebhook, webhookID, thread.ThreadID)
+	if err != nil {
+		ErrorPrintf("Error removing the webhook from the database: %v\n", err)
+		return err
+	}
+	removeDeliveries := "DELETE FROM ThreadWebhookDeliveries WHERE webhook_id = ?"
+	_, err = db.Exec(removeDeliveries, webhookID)
+	if err != nil {
+		ErrorPrintf("Error removing the webhook deliveries from the database: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// WebhookExistsInThread checks if the webhook with the given id is registered on the thread
+func WebhookExistsInThread(thread ThreadGoForum, webhookID int) bool {
+	getWebhook := "SELECT webhook_id FROM ThreadWebhooks WHERE thread_id = ? AND webhook_id = ?"
+	rows, err := db.Query(getWebhook, thread.ThreadID, webhookID)
+	if err != nil {
+		ErrorPrintf("Error checking if the webhook exists in the thread: %v\n", err)
+		return false
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	if rows.Next() {
+		return true
+	}
+	return false
+}
+
+// GetThreadWebhooks returns the webhooks registered on the thread
+// Returns a slice of ThreadWebhook and an error if there is one
+func GetThreadWebhooks(thread ThreadGoForum) ([]ThreadWebhook, error) {
+	getWebhooks := "SELECT webhook_id, thread_id, webhook_url, webhook_secret, events, creation_date FROM ThreadWebhooks WHERE thread_id = ?"
+	rows, err := db.Query(getWebhooks, thread.ThreadID)
+	if err != nil {
+		ErrorPrintf("Error getting the webhooks of the thread: %v\n", err)
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	var webhooks []ThreadWebhook
+	for rows.Next() {
+		var webhook ThreadWebhook
+		var strEvents string
+		err := rows.Scan(
+			&webhook.WebhookID,
+			&webhook.ThreadID,
+			&webhook.WebhookURL,
+			&webhook.WebhookSecret,
+			&strEvents,
+			&webhook.CreationDate)
+		if err != nil {
+			ErrorPrintf("Error scanning the rows in GetThreadWebhooks: %v\n", err)
+			return nil, err
+		}
+		webhook.Events = stringToWebhookEvents(strEvents)
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, nil
+}
+
+// AddWebhookDelivery logs a delivery attempt of a webhook
+// Returns an error if there is one
+func AddWebhookDelivery(delivery WebhookDelivery) error {
+	insertDelivery := "INSERT INTO ThreadWebhookDeliveries (webhook_id, delivery_uuid, event, attempt, response_status, error_message) VALUES (?, ?, ?, ?, ?, ?)"
+	_, err := db.Exec(insertDelivery,
+		delivery.WebhookID,
+		delivery.DeliveryUUID,
+		string(delivery.Event),
+		delivery.Attempt,
+		delivery.ResponseStatus,
+		delivery.ErrorMessage)
+	if err != nil {
+		ErrorPrintf("Error inserting the webhook delivery into the database: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// GetWebhookDeliveries returns the last delivery attempts of the webhook, the most recent first
+// Returns a slice of WebhookDelivery and an error if there is one
+func GetWebhookDeliveries(webhookID int, limit int) ([]WebhookDelivery, error) {
+	getDeliveries := `
+		SELECT delivery_id, webhook_id, delivery_uuid, event, attempt, response_status, error_message, delivery_date
+		FROM ThreadWebhookDeliveries
+		WHERE webhook_id = ?
+		ORDER BY delivery_id DESC LIMIT ?`
+	rows, err := db.Query(getDeliveries, webhookID, limit)
+	if err != nil {
+		ErrorPrintf("Error getting the webhook deliveries: %v\n", err)
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		var delivery WebhookDelivery
+		err := rows.Scan(
+			&delivery.DeliveryID,
+			&delivery.WebhookID,
+			&delivery.DeliveryUUID,
+			&delivery.Event,
+			&delivery.Attempt,
+			&delivery.ResponseStatus,
+			&delivery.ErrorMessage,
+			&delivery.DeliveryDate)
+		if err != nil {
+			ErrorPrintf("Error scanning the rows in GetWebhookDeliveries: %v\n", err)
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+// GetThreadFromMessageID returns the thread containing the message with the given id
+// Returns an empty ThreadGoForum if the message does not exist or if there is an error
+func GetThreadFromMessageID(messageID int) ThreadGoForum {
+	getThread := `
+		SELECT tg.thread_id, tg.thread_name, tg.owner_id, tg.creation_date
+		FROM ThreadMessages tm
+		JOIN ThreadGoForum tg ON tm.thread_id = tg.thread_id
+		WHERE tm.message_id = ?`
+	var thread ThreadGoForum
+	err := db.QueryRow(getThread, messageID).Scan(&thread.ThreadID, &thread.ThreadName, &thread.OwnerID, &thread.CreationDate)
+	if err != nil {
+		ErrorPrintf("Error getting the thread of the message %d: %v\n", messageID, err)
+		return ThreadGoForum{}
+	}
+	return thread
+}
+
+// GetUserFeedToken returns the token used by the user to read the feeds of the private threads
+// The token is created the first time it is requested
+// Returns an error if there is one
+func GetUserFeedToken(user User) (string, error) {
+	var token string
+	err := db.QueryRow("SELECT feed_token FROM UserFeedTokens WHERE user_id = ?", user.UserID).Scan(&token)
+	if err == nil {
+		return token, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		ErrorPrintf("Error getting the feed token of the user: %v\n", err)
+		return "", err
+	}
+	return ResetUserFeedToken(user)
+}
+
+// ResetUserFeedToken replaces the feed token of the user by a new one, the old private feed urls stop working
+// Returns the new token and an error if there is one
+func ResetUserFeedToken(user User) (string, error) {
+	randomBytes := make([]byte, 20)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		ErrorPrintf("Error generating the feed token: %v\n", err)
+		return "", err
+	}
+	token := hex.EncodeToString(randomBytes)
+	upsertToken := `
+		INSERT INTO UserFeedTokens (user_id, feed_token) VALUES (?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET feed_token = excluded.feed_token, creation_date = CURRENT_TIMESTAMP`
+	_, err = db.Exec(upsertToken, user.UserID, token)
+	if err != nil {
+		ErrorPrintf("Error saving the feed token of the user: %v\n", err)
+		return "", err
+	}
+	return token, nil
+}
+
+// GetUserFromFeedToken returns the user owning the given feed token
+// Returns an error if the token does not exist
+func GetUserFromFeedToken(token string) (User, error) {
+	getUser := `
+		SELECT u.user_id, u.email, u.username, u.firstname, u.lastname, u.password_hash, u.email_verified, u.oauth_provider, u.oauth_id, u.creation_date
+		FROM UserFeedTokens t
+		JOIN Users u ON t.user_id = u.user_id
+		WHERE t.feed_token = ?`
+	var user User
+	err := db.QueryRow(getUser, token).Scan(
+		&user.UserID,
+		&user.Email,
+		&user.Username,
+		&user.Firstname,
+		&user.Lastname,
+		&user.PasswordHash,
+		&user.EmailVerified,
+		&user.OAuthProvider,
+		&user.OAuthID,
+		&user.CreatedAt)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			ErrorPrintf("Error getting the user from the feed token: %v\n", err)
+		}
+		return User{}, err
+	}
+	return user, nil
+}
+
+// GetLatestPublicMessages returns the latest messages posted in the threads open to everyone (non-connected users and non-members)
+// The private threads are left out, their messages are only shown to the users who know the thread
+// If the given user is not empty, only his messages are returned
+// The messages waiting for approval, deleted or hidden by their reports are not returned
+// Returns a slice of FeedMessage (most recent first) and an error if there is one
+func GetLatestPublicMessages(user User, limit int) ([]FeedMessage, error) {
+	userFilter := ""
+	args := []interface{}{}
+	if user.UserID > 0 {
+		userFilter = "AND v.username = ?"
+		args = append(args, user.Username)
+	}
+	args = append(args, limit)
+	getMessages := fmt.Sprintf(`
+		SELECT
+			v.thread_name,
+			v.message_id,
+			v.message_title,
+			v.message_content,
+			v.was_edited,
+			v.creation_date,
+			v.username,
+			v.pfp_media_address,
+			v.upvotes,
+			v.downvotes,
+			v.comments_number
+		FROM ViewThreadMessagesWithVotes v
+		JOIN ThreadMessages tm ON v.message_id = tm.message_id
+		JOIN ThreadGoForumConfigs c ON tm.thread_id = c.thread_id
+		WHERE c.is_open_to_non_members = 1 AND c.is_open_to_non_connected_Users = 1 AND c.is_private = FALSE
+			AND tm.approval_state = '%s' AND tm.deletion_date IS NULL
+			AND NOT %s %s
+		ORDER BY v.creation_date DESC LIMIT ?`,
+		MessageApproved,
+		profileHiddenSQL("r.message_id = tm.message_id AND r.comment_id = 0"),
+		userFilter)
+	rows, err := db.Query(getMessages, args...)
+	if err != nil {
+		ErrorPrintf("Error getting the latest public messages: %v\n", err)
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	var messages []FeedMessage
+	for rows.Next() {
+		var message FeedMessage
+		err := rows.Scan(
+			&message.ThreadName,
+			&message.Message.MessageID,
+			&message.Message.MessageTitle,
+			&message.Message.MessageContent,
+			&message.Message.WasEdited,
+			&message.Message.CreationDate,
+			&message.Message.UserName,
+			&message.Message.UserPfpAddress,
+			&message.Message.Upvotes,
+			&message.Message.Downvotes,
+			&message.Message.NumberOfComments)
+		if err != nil {
+			ErrorPrintf("Error scanning the rows in GetLatestPublicMessages: %v\n", err)
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// GetMessageIDFromCommentID returns the id of the message the comment was posted on
+// Returns -1 if the comment does not exist or if there is an error
+func GetMessageIDFromCommentID(commentID int) int {
+	var messageID int
+	err := db.QueryRow("SELECT message_id FROM ThreadComments WHERE comment_id = ?", commentID).Scan(&messageID)
+	if err != nil {
+		ErrorPrintf("Error getting the message of the comment %d: %v\n", commentID, err)
+		return -1
+	}
+	return messageID
+}
+
+// accessibleThreadsSQL is a subquery returning the ids of the threads whose content the user can see
+// The user id must be given as argument, the user must not be banned and must be a member of the threads not open to non-members
+const accessibleThreadsSQL = `
+	SELECT t.thread_id
+	FROM ThreadGoForum t
+	JOIN ThreadGoForumConfigs c ON t.thread_id = c.thread_id
+	LEFT JOIN ThreadGoForumMembers m ON t.thread_id = m.thread_id AND m.user_id = ?
+	WHERE COALESCE(m.rights_level, 0) >= 0 AND (c.is_open_to_non_members = 1 OR m.user_id IS NOT NULL)`
+
+// SubscribeToThread subscribes the user to the thread, the messages already posted are considered as read
+// Subscribing again keeps the mute state of the subscription
+// Returns an error if there is one
+func SubscribeToThread(thread ThreadGoForum, user User) error {
+	_, err := db.Exec("INSERT OR IGNORE INTO ThreadSubscriptions (user_id, thread_id) VALUES (?, ?)", user.UserID, thread.ThreadID)
+	if err != nil {
+		ErrorPrintf("Error subscribing to the thread: %v\n", err)
+		return err
+	}
+	insertReadPosition := `
+		INSERT OR IGNORE INTO ThreadReadPositions (user_id, thread_id, last_read_message_id)
+		VALUES (?, ?, (SELECT COALESCE(MAX(message_id), 0) FROM ThreadMessages WHERE thread_id = ?))`
+	_, err = db.Exec(insertReadPosition, user.UserID, thread.ThreadID, thread.ThreadID)
+	if err != nil {
+		ErrorPrintf("Error initialising the read position of the thread: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// UnsubscribeFromThread removes the subscription of the user to the thread
+// Returns an error if there is one
+func UnsubscribeFromThread(thread ThreadGoForum, user User) error {
+	_, err := db.Exec("DELETE FROM ThreadSubscriptions WHERE user_id = ? AND thread_id = ?", user.UserID, thread.ThreadID)
+	if err != nil {
+		ErrorPrintf("Error unsubscribing from the thread: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// SetThreadSubscriptionMuted mutes or unmutes the subscription of the user to the thread
+// Muting a thread the user is not subscribed to subscribes him silently
+// Returns an error if there is one
+func SetThreadSubscriptionMuted(thread ThreadGoForum, user User, muted bool) error {
+	if !muted {
+		_, err := db.Exec("UPDATE ThreadSubscriptions SET is_muted = 0 WHERE user_id = ? AND thread_id = ?", user.UserID, thread.ThreadID)
+		if err != nil {
+			ErrorPrintf("Error unmuting the thread: %v\n", err)
+		}
+		return err
+	}
+	err := SubscribeToThread(thread, user)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("UPDATE ThreadSubscriptions SET is_muted = 1 WHERE user_id = ? AND thread_id = ?", user.UserID, thread.ThreadID)
+	if err != nil {
+		ErrorPrintf("Error muting the thread: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// GetThreadSubscription returns the state of the subscription of the user to the thread
+// Returns an error if there is one
+func GetThreadSubscription(thread ThreadGoForum, user User) (Subscription, error) {
+	var subscription Subscription
+	err := db.QueryRow("SELECT is_muted FROM ThreadSubscriptions WHERE user_id = ? AND thread_id = ?", user.UserID, thread.ThreadID).Scan(&subscription.IsMuted)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Subscription{}, nil
+		}
+		ErrorPrintf("Error getting the subscription to the thread: %v\n", err)
+		return Subscription{}, err
+	}
+	subscription.IsSubscribed = true
+	return subscription, nil
+}
+
+// SubscribeToMessage subscribes the user to the comments of the message, the comments already posted are considered as read
+// Subscribing again keeps the mute state of the subscription
+// Returns an error if there is one
+func SubscribeToMessage(messageID int, user User) error {
+	insertSubscription := `
+		INSERT OR IGNORE INTO MessageSubscriptions (user_id, message_id, last_read_comment_id)
+		VALUES (?, ?, (SELECT COALESCE(MAX(comment_id), 0) FROM ThreadComments WHERE message_id = ?))`
+	_, err := db.Exec(insertSubscription, user.UserID, messageID, messageID)
+	if err != nil {
+		ErrorPrintf("Error subscribing to the message: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// UnsubscribeFromMessage removes the subscription of the user to the message
+// Returns an error if there is one
+func UnsubscribeFromMessage(messageID int, user User) error {
+	_, err := db.Exec("DELETE FROM MessageSubscriptions WHERE user_id = ? AND message_id = ?", user.UserID, messageID)
+	if err != nil {
+		ErrorPrintf("Error unsubscribing from the message: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// SetMessageSubscriptionMuted mutes or unmutes the subscription of the user to the message
+// A muted message and its comments are hidden from the 'Following' feed, even if the user follows its thread
+// Returns an error if there is one
+func SetMessageSubscriptionMuted(messageID int, user User, muted bool) error {
+	if !muted {
+		_, err := db.Exec("UPDATE MessageSubscriptions SET is_muted = 0 WHERE user_id = ? AND message_id = ?", user.UserID, messageID)
+		if err != nil {
+			ErrorPrintf("Error unmuting the message: %v\n", err)
+		}
+		return err
+	}
+	err := SubscribeToMessage(messageID, user)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("UPDATE MessageSubscriptions SET is_muted = 1 WHERE user_id = ? AND message_id = ?", user.UserID, messageID)
+	if err != nil {
+		ErrorPrintf("Error muting the message: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// GetMessageSubscription returns the state of the subscription of the user to the message
+// Returns an error if there is one
+func GetMessageSubscription(messageID int, user User) (Subscription, error) {
+	var subscription Subscription
+	err := db.QueryRow("SELECT is_muted FROM MessageSubscriptions WHERE user_id = ? AND message_id = ?", user.UserID, messageID).Scan(&subscription.IsMuted)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Subscription{}, nil
+		}
+		ErrorPrintf("Error getting the subscription to the message: %v\n", err)
+		return Subscription{}, err
+	}
+	subscription.IsSubscribed = true
+	return subscription, nil
+}
+
+// GetThreadLastReadMessageID returns the id of the last message of the thread the user has seen
+// Returns 0 if the user never opened the thread or if there is an error
+func GetThreadLastReadMessageID(thread ThreadGoForum, user User) int {
+	var messageID int
+	err := db.QueryRow("SELECT last_read_message_id FROM ThreadReadPositions WHERE user_id = ? AND thread_id = ?", user.UserID, thread.ThreadID).Scan(&messageID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			ErrorPrintf("Error getting the read position of the thread: %v\n", err)
+		}
+		return 0
+	}
+	return messageID
+}
+
+// MarkThreadAsRead moves the read position of the user to the last message of the thread
+// Returns an error if there is one
+func MarkThreadAsRead(thread ThreadGoForum, user User) error {
+	upsertReadPosition := `
+		INSERT INTO ThreadReadPositions (user_id, thread_id, last_read_message_id)
+		VALUES (?, ?, (SELECT COALESCE(MAX(message_id), 0) FROM ThreadMessages WHERE thread_id = ?))
+		ON CONFLICT(user_id, thread_id) DO UPDATE SET last_read_message_id = excluded.last_read_message_id, last_read_date = CURRENT_TIMESTAMP`
+	_, err := db.Exec(upsertReadPosition, user.UserID, thread.ThreadID, thread.ThreadID)
+	if err != nil {
+		ErrorPrintf("Error marking the thread as read: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// MarkMessageAsRead marks the comments of the message as read, it does nothing if the user is not subscribed to the message
+// Returns an error if there is one
+func MarkMessageAsRead(messageID int, user User) error {
+	updateReadPosition := `
+		UPDATE MessageSubscriptions
+		SET last_read_comment_id = (SELECT COALESCE(MAX(comment_id), 0) FROM ThreadComments WHERE message_id = ?)
+		WHERE user_id = ? AND message_id = ?`
+	_, err := db.Exec(updateReadPosition, messageID, user.UserID, messageID)
+	if err != nil {
+		ErrorPrintf("Error marking the message as read: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// GetFollowedThreads returns the threads the user is subscribed to, with the number of unread messages of each one
+// The messages posted by the user himself are never unread
+// Returns an error if there is one
+func GetFollowedThreads(user User) ([]FollowedThread, error) {
+	getThreads := `
+		SELECT
+			tg.thread_name,
+			ts.is_muted,
+			(SELECT COUNT(*) FROM ThreadMessages tm
+			 WHERE tm.thread_id = ts.thread_id AND tm.user_id != ts.user_id AND tm.approval_state = 'approved' AND tm.deletion_date IS NULL
+			   AND tm.message_id > COALESCE(rp.last_read_message_id, 0))
+		FROM ThreadSubscriptions ts
+		JOIN ThreadGoForum tg ON ts.thread_id = tg.thread_id
+		LEFT JOIN ThreadReadPositions rp ON ts.user_id = rp.user_id AND ts.thread_id = rp.thread_id
+		WHERE ts.user_id = ? AND ts.thread_id IN (` + accessibleThreadsSQL + `)
+		ORDER BY tg.thread_name`
+	rows, err := db.Query(getThreads, user.UserID, user.UserID)
+	if err != nil {
+		ErrorPrintf("Error getting the followed threads: %v\n", err)
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	var threads []FollowedThread
+	for rows.Next() {
+		var thread FollowedThread
+		err := rows.Scan(&thread.ThreadName, &thread.IsMuted, &thread.UnreadMessages)
+		if err != nil {
+			ErrorPrintf("Error scanning the rows in GetFollowedThreads: %v\n", err)
+			return nil, err
+		}
+		threads = append(threads, thread)
+	}
+	return threads, nil
+}
+
+// GetFollowingFeed returns the latest activity of the content the user is subscribed to
+// It contains the new messages of the followed threads and the new comments of the followed messages
+// The muted subscriptions and the content posted by the user himself are left out, like the content hidden by its reports
+// and the comments of the messages that are not approved
+// Returns a slice of FollowingFeedItem (most recent first) and an error if there is one
+func GetFollowingFeed(user User, limit int) ([]FollowingFeedItem, error) {
+	getFeed := fmt.Sprintf(`
+		SELECT tg.thread_name, tm.message_id, tm.message_title, 0 AS comment_id, u.username, tm.creation_date AS creation_date,
+			tm.message_id > COALESCE(rp.last_read_message_id, 0) AS is_unread
+		FROM ThreadSubscriptions ts
+		JOIN ThreadGoForum tg ON ts.thread_id = tg.thread_id
+		JOIN ThreadGoForumConfigs c ON ts.thread_id = c.thread_id
+		JOIN ThreadMessages tm ON ts.thread_id = tm.thread_id
+		JOIN Users u ON tm.user_id = u.user_id
+		LEFT JOIN ThreadReadPositions rp ON ts.user_id = rp.user_id AND ts.thread_id = rp.thread_id
+		WHERE ts.user_id = ? AND ts.is_muted = 0 AND tm.user_id != ts.user_id AND tm.approval_state = '%[1]s' AND tm.deletion_date IS NULL
+			AND tm.message_id NOT IN (SELECT message_id FROM MessageSubscriptions WHERE user_id = ts.user_id AND is_muted = 1)
+			AND ts.thread_id IN (%[2]s)
+			AND NOT %[3]s
+		UNION ALL
+		SELECT tg.thread_name, tm.message_id, tm.message_title, tc.comment_id, u.username, tc.creation_date,
+			tc.comment_id > ms.last_read_comment_id AS is_unread
+		FROM MessageSubscriptions ms
+		JOIN ThreadMessages tm ON ms.message_id = tm.message_id
+		JOIN ThreadGoForum tg ON tm.thread_id = tg.thread_id
+		JOIN ThreadGoForumConfigs c ON tm.thread_id = c.thread_id
+		JOIN ThreadComments tc ON ms.message_id = tc.message_id
+		JOIN Users u ON tc.user_id = u.user_id
+		WHERE ms.user_id = ? AND ms.is_muted = 0 AND tc.user_id != ms.user_id AND tm.deletion_date IS NULL AND tc.deletion_date IS NULL
+			AND tm.approval_state = '%[1]s'
+			AND tm.thread_id IN (%[2]s)
+			AND NOT %[3]s AND NOT %[4]s
+		ORDER BY creation_date DESC LIMIT ?`,
+		MessageApproved,
+		accessibleThreadsSQL,
+		profileHiddenSQL("r.message_id = tm.message_id AND r.comment_id = 0"),
+		profileHiddenSQL("r.comment_id = tc.comment_id"))
+	rows, err := db.Query(getFeed, user.UserID, user.UserID, user.UserID, user.UserID, limit)
+	if err != nil {
+		ErrorPrintf("Error getting the following feed: %v\n", err)
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	var feed []FollowingFeedItem
+	for rows.Next() {
+		var item FollowingFeedItem
+		err := rows.Scan(
+			&item.ThreadName,
+			&item.MessageID,
+			&item.MessageTitle,
+			&item.CommentID,
+			&item.UserName,
+			&item.CreationDate,
+			&item.IsUnread)
+		if err != nil {
+			ErrorPrintf("Error scanning the rows in GetFollowingFeed: %v\n", err)
+			return nil, err
+		}
+		feed = append(feed, item)
+	}
+	return feed, nil
+}
+
+// profileVisibleThreadsSQL returns the subquery of the ids of the threads whose content the viewer can see on a user profile
+// It follows the rules of accessibleThreadsSQL (the viewer id must be given as argument) and the threads not open to
+// the non-connected users are left out for the guests
+func profileVisibleThreadsSQL(viewer User) string {
+	if viewer.UserID > 0 {
+		return accessibleThreadsSQL
+	}
+	return accessibleThreadsSQL + " AND c.is_open_to_non_connected_Users = 1"
+}
+
+// profileHiddenSQL returns the SQL condition telling if the content is hidden by its reports
+// It works like hiddenMessageSQL but uses the threshold of the thread of the content, c being its ThreadGoForumConfigs row
+func profileHiddenSQL(reportCondition string) string {
+	return fmt.Sprintf(`(c.auto_hide_report_threshold > 0 AND (
+		SELECT COUNT(DISTINCT r.username) FROM Reports r
+		WHERE %s AND r.report_state IN ('%s', '%s') AND r.username != '%s'
+	) >= c.auto_hide_report_threshold)`, reportCondition, ReportOpen, ReportClaimed, SystemReporterName)
+}
+
+// GetUserProfileMessages returns a page of the messages posted by the author, as seen by the viewer on the author profile
+// Only the approved, not deleted and not hidden messages of the threads the viewer can see are returned
+// Returns a slice of FeedMessage (most recent first), true if there is a next page and an error if there is one
+func GetUserProfileMessages(author User, viewer User, offset int) ([]FeedMessage, bool, error) {
+	getMessages := fmt.Sprintf(`
+		SELECT
+			v.thread_name,
+			v.message_id,
+			v.message_title,
+			v.message_content,
+			v.was_edited,
+			v.creation_date,
+			v.username,
+			v.pfp_media_address,
+			v.upvotes,
+			v.downvotes,
+			v.comments_number
+		FROM ViewThreadMessagesWithVotes v
+		JOIN ThreadMessages tm ON v.message_id = tm.message_id
+		JOIN ThreadGoForumConfigs c ON tm.thread_id = c.thread_id
+		WHERE tm.user_id = ? AND tm.approval_state = '%s' AND tm.deletion_date IS NULL
+			AND tm.thread_id IN (%s)
+			AND NOT %s
+		ORDER BY v.creation_date DESC, v.message_id DESC LIMIT ? OFFSET ?`,
+		MessageApproved,
+		profileVisibleThreadsSQL(viewer),
+		profileHiddenSQL("r.message_id = tm.message_id AND r.comment_id = 0"))
+	rows, err := db.Query(getMessages, author.UserID, viewer.UserID, UserProfileActivityPageSize+1, offset)
+	if err != nil {
+		ErrorPrintf("Error getting the profile messages: %v\n", err)
+		return nil, false, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	var messages []FeedMessage
+	for rows.Next() {
+		var message FeedMessage
+		err := rows.Scan(
+			&message.ThreadName,
+			&message.Message.MessageID,
+			&message.Message.MessageTitle,
+			&message.Message.MessageContent,
+			&message.Message.WasEdited,
+			&message.Message.CreationDate,
+			&message.Message.UserName,
+			&message.Message.UserPfpAddress,
+			&message.Message.Upvotes,
+			&message.Message.Downvotes,
+			&message.Message.NumberOfComments)
+		if err != nil {
+			ErrorPrintf("Error scanning the rows in GetUserProfileMessages: %v\n", err)
+			return nil, false, err
+		}
+		messages = append(messages, message)
+	}
+	// One more message than the page size is loaded to know if there is a next page
+	hasNextPage := len(messages) > UserProfileActivityPageSize
+	if hasNextPage {
+		messages = messages[:UserProfileActivityPageSize]
+	}
+	return messages, hasNextPage, nil
+}
+
+// GetUserProfileComments returns a page of the comments posted by the author, as seen by the viewer on the author profile
+// Only the not deleted and not hidden comments of the messages the viewer can see on the profile are returned
+// Returns a slice of ProfileComment (most recent first), true if there is a next page and an error if there is one
+func GetUserProfileComments(author User, viewer User, offset int) ([]ProfileComment, bool, error) {
+	getComments := fmt.Sprintf(`
+		SELECT
+			tg.thread_name,
+			tm.message_id,
+			tm.message_title,
+			v.comment_id,
+			v.comment_content,
+			v.was_edited,
+			v.creation_date,
+			v.username,
+			v.pfp_media_address,
+			v.upvotes,
+			v.downvotes
+		FROM ViewMessageCommentsWithVotes v
+		JOIN ThreadComments tc ON v.comment_id = tc.comment_id
+		JOIN ThreadMessages tm ON tc.message_id = tm.message_id
+		JOIN ThreadGoForum tg ON tm.thread_id = tg.thread_id
+		JOIN ThreadGoForumConfigs c ON tm.thread_id = c.thread_id
+		WHERE tc.user_id = ? AND tc.deletion_date IS NULL
+			AND tm.approval_state = '%s' AND tm.deletion_date IS NULL
+			AND tm.thread_id IN (%s)
+			AND NOT %s AND NOT %s
+		ORDER BY v.creation_date DESC, v.comment_id DESC LIMIT ? OFFSET ?`,
+		MessageApproved,
+		profileVisibleThreadsSQL(viewer),
+		profileHiddenSQL("r.message_id = tm.message_id AND r.comment_id = 0"),
+		profileHiddenSQL("r.comment_id = tc.comment_id"))
+	rows, err := db.Query(getComments, author.UserID, viewer.UserID, UserProfileActivityPageSize+1, offset)
+	if err != nil {
+		ErrorPrintf("Error getting the profile comments: %v\n", err)
+		return nil, false, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	var comments []ProfileComment
+	for rows.Next() {
+		var comment ProfileComment
+		err := rows.Scan(
+			&comment.ThreadName,
+			&comment.MessageID,
+			&comment.MessageTitle,
+			&comment.Comment.CommentID,
+			&comment.Comment.CommentContent,
+			&comment.Comment.WasEdited,
+			&comment.Comment.CreationDate,
+			&comment.Comment.UserName,
+			&comment.Comment.UserPfpAddress,
+			&comment.Comment.Upvotes,
+			&comment.Comment.Downvotes)
+		if err != nil {
+			ErrorPrintf("Error scanning the rows in GetUserProfileComments: %v\n", err)
+			return nil, false, err
+		}
+		comments = append(comments, comment)
+	}
+	// One more comment than the page size is loaded to know if there is a next page
+	hasNextPage := len(comments) > UserProfileActivityPageSize
+	if hasNextPage {
+		comments = comments[:UserProfileActivityPageSize]
+	}
+	return comments, hasNextPage, nil
+}
+
+// GetUserProfileStats returns the aggregate stats of the user shown on his profile
+// The karma and the counts cover all his content, the moderated threads only the ones the viewer can find (not private or accessible)
+// Returns the stats and an error if there is one
+func GetUserProfileStats(user User, viewer User) (UserProfileStats, error) {
+	var stats UserProfileStats
+	getCounts := `
+		SELECT
+			COALESCE((
+				SELECT SUM(CASE WHEN v.is_upvote = 1 THEN 1 ELSE -1 END)
+				FROM ThreadVotes v JOIN ThreadMessages tm ON v.message_id = tm.message_id
+				WHERE tm.user_id = ?
+			), 0) + COALESCE((
+				SELECT SUM(CASE WHEN v.is_upvote = 1 THEN 1 ELSE -1 END)
+				FROM ThreadVotes v JOIN ThreadComments tc ON v.comment_id = tc.comment_id
+				WHERE tc.user_id = ?
+			), 0),
+			(SELECT COUNT(*) FROM ThreadMessages WHERE user_id = ? AND approval_state = ? AND deletion_date IS NULL),
+			(SELECT COUNT(*) FROM ThreadComments WHERE user_id = ? AND deletion_date IS NULL)`
+	err := db.QueryRow(getCounts, user.UserID, user.UserID, user.UserID, MessageApproved, user.UserID).Scan(&stats.Karma, &stats.MessagesCount, &stats.CommentsCount)
+	if err != nil {
+		ErrorPrintf("Error getting the profile stats: %v\n", err)
+		return UserProfileStats{}, err
+	}
+	stats.Reputation = GetUserReputation(user)
+
+	// The user moderates the threads he owns and the ones where he has a moderation rank or a custom role
+	getModeratedThreads := `
+		SELECT t.thread_name
+		FROM ThreadGoForum t
+		JOIN ThreadGoForumConfigs c ON t.thread_id = c.thread_id
+		LEFT JOIN ThreadGoForumMembers m ON t.thread_id = m.thread_id AND m.user_id = ?
+		WHERE (t.owner_id = ? OR m.rights_level >= ? OR (m.rights_level >= 0 AND m.role_id IS NOT NULL))
+			AND (c.is_private = FALSE OR t.thread_id IN (` + accessibleThreadsSQL + `))
+		ORDER BY t.thread_name`
+	rows, err := db.Query(getModeratedThreads, user.UserID, user.UserID, ThreadRankModerator, viewer.UserID)
+	if err != nil {
+		ErrorPrintf("Error getting the moderated threads: %v\n", err)
+		return UserProfileStats{}, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	for rows.Next() {
+		var threadName string
+		err := rows.Scan(&threadName)
+		if err != nil {
+			ErrorPrintf("Error scanning the rows in GetUserProfileStats: %v\n", err)
+			return UserProfileStats{}, err
+		}
+		stats.ModeratedThreads = append(stats.ModeratedThreads, threadName)
+	}
+	return stats, nil
+}
+
+// IsSavedFolderValid checks if the name of a folder of saved items is valid (at most 30 characters on a single line)
+// An empty name means that the item is in no folder
+func IsSavedFolderValid(folder string) bool {
+	return utf8.RuneCountInString(folder) <= SavedFolderMaxLength && !strings.ContainsAny(folder, "\r\n")
+}
+
+// SaveContent saves the message or the comment (the other id being 0) for the user in the given folder
+// Saving an item again moves it to the given folder
+// Returns an error if there is one
+func SaveContent(user User, messageID int, commentID int, folder string) error {
+	saveItem := `
+		INSERT INTO SavedItems (user_id, message_id, comment_id, folder) VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id, message_id, comment_id) DO UPDATE SET folder = excluded.folder`
+	_, err := db.Exec(saveItem, user.UserID, messageID, commentID, strings.TrimSpace(folder))
+	if err != nil {
+		ErrorPrintf("Error saving the content: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// UnsaveContent removes the message or the comment (the other id being 0) from the saved items of the user
+// Returns an error if there is one
+func UnsaveContent(user User, messageID int, commentID int) error {
+	_, err := db.Exec("DELETE FROM SavedItems WHERE user_id = ? AND message_id = ? AND comment_id = ?", user.UserID, messageID, commentID)
+	if err != nil {
+		ErrorPrintf("Error removing the saved content: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// MoveSavedContent moves the saved message or comment (the other id being 0) of the user to the given folder
+// Returns sql.ErrNoRows if the item is not saved and an error if there is one
+func MoveSavedContent(user User, messageID int, commentID int, folder string) error {
+	res, err := db.Exec("UPDATE SavedItems SET folder = ? WHERE user_id = ? AND message_id = ? AND comment_id = ?", strings.TrimSpace(folder), user.UserID, messageID, commentID)
+	if err != nil {
+		ErrorPrintf("Error moving the saved content: %v\n", err)
+		return err
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		ErrorPrintf("Error getting the number of moved items: %v\n", err)
+		return err
+	}
+	if updated == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// IsContentSavedByUser checks if the user saved the message or the comment (the other id being 0)
+func IsContentSavedByUser(user User, messageID int, commentID int) bool {
+	var saved bool
+	err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM SavedItems WHERE user_id = ? AND message_id = ? AND comment_id = ?)", user.UserID, messageID, commentID).Scan(&saved)
+	if err != nil {
+		ErrorPrintf("Error checking if the content is saved: %v\n", err)
+		return false
+	}
+	return saved
+}
+
+// getUserSavedItems returns which of the given messages or comments the user saved
+// The column is either 'message_id' or 'comment_id', the contents the user has not saved are not in the map
+// Returns an error if there is one
+func getUserSavedItems(user User, column string, ids []int) (map[int]bool, error) {
+	saved := make(map[int]bool)
+	if user.UserID == 0 || len(ids) == 0 {
+		return saved, nil
+	}
+	placeholders, args := idListSQL(ids)
+	getSaved := fmt.Sprintf("SELECT %[1]s FROM SavedItems WHERE user_id = ? AND %[1]s IN (%[2]s)", column, placeholders)
+	rows, err := db.Query(getSaved, append([]interface{}{user.UserID}, args...)...)
+	if err != nil {
+		ErrorPrintf("Error getting the saved items of the user: %v\n", err)
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	for rows.Next() {
+		var id int
+		err := rows.Scan(&id)
+		if err != nil {
+			ErrorPrintf("Error scanning the rows in getUserSavedItems: %v\n", err)
+			return nil, err
+		}
+		saved[id] = true
+	}
+	return saved, nil
+}
+
+// GetUserSavedFolders returns the names of the folders of the saved items of the user, sorted by name
+// Returns an error if there is one
+func GetUserSavedFolders(user User) ([]string, error) {
+	rows, err := db.Query("SELECT DISTINCT folder FROM SavedItems WHERE user_id = ? AND folder != '' ORDER BY folder", user.UserID)
+	if err != nil {
+		ErrorPrintf("Error getting the saved folders: %v\n", err)
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	var folders []string
+	for rows.Next() {
+		var folder string
+		err := rows.Scan(&folder)
+		if err != nil {
+			ErrorPrintf("Error scanning the rows in GetUserSavedFolders: %v\n", err)
+			return nil, err
+		}
+		folders = append(folders, folder)
+	}
+	return folders, nil
+}
+
+// GetUserSavedItems returns a page of the items saved by the user, the most recently saved first
+// Only the items of the given folder are returned if it is not empty
+// The deleted items and the ones of the threads the user can not access anymore are left out, they come back if it changes
+// Returns a slice of SavedItem, true if there is a next page and an error if there is one
+func GetUserSavedItems(user User, folder string, offset int) ([]SavedItem, bool, error) {
+	folderFilter := ""
+	args := []interface{}{user.UserID}
+	if folder != "" {
+		folderFilter = "AND s.folder = ?"
+		args = append(args, folder)
+	}
+	args = append(args, user.UserID, SavedItemsPageSize+1, offset)
+	getItems := fmt.Sprintf(`
+		SELECT
+			tg.thread_name,
+			tm.message_id,
+			tm.message_title,
+			s.comment_id,
+			COALESCE(tc.comment_content, tm.message_content),
+			u.username,
+			tm.creation_date,
+			tc.creation_date,
+			s.folder,
+			s.save_date
+		FROM SavedItems s
+		LEFT JOIN ThreadComments tc ON s.comment_id > 0 AND s.comment_id = tc.comment_id
+		JOIN ThreadMessages tm ON tm.message_id = CASE WHEN s.comment_id > 0 THEN tc.message_id ELSE s.message_id END
+		JOIN ThreadGoForum tg ON tm.thread_id = tg.thread_id
+		JOIN Users u ON u.user_id = COALESCE(tc.user_id, tm.user_id)
+		WHERE s.user_id = ? %s
+			AND tm.approval_state = '%s' AND tm.deletion_date IS NULL AND tc.deletion_date IS NULL
+			AND tm.thread_id IN (%s)
+		ORDER BY s.save_date DESC, s.rowid DESC LIMIT ? OFFSET ?`, folderFilter, MessageApproved, accessibleThreadsSQL)
+	rows, err := db.Query(getItems, args...)
+	if err != nil {
+		ErrorPrintf("Error getting the saved items: %v\n", err)
+		return nil, false, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	var items []SavedItem
+	for rows.Next() {
+		var item SavedItem
+		var commentDate sql.NullTime
+		err := rows.Scan(
+			&item.ThreadName,
+			&item.MessageID,
+			&item.MessageTitle,
+			&item.CommentID,
+			&item.Content,
+			&item.UserName,
+			&item.CreationDate,
+			&commentDate,
+			&item.Folder,
+			&item.SaveDate)
+		if err != nil {
+			ErrorPrintf("Error scanning the rows in GetUserSavedItems: %v\n", err)
+			return nil, false, err
+		}
+		// The creation date of a saved comment is the one of the comment, not of its message
+		if commentDate.Valid {
+			item.CreationDate = commentDate.Time
+		}
+		items = append(items, item)
+	}
+	hasNextPage := len(items) > SavedItemsPageSize
+	if hasNextPage {
+		items = items[:SavedItemsPageSize]
+	}
+	return items, hasNextPage, nil
+}
+
+// IsBanReasonValid checks if the reason of a ban is valid (between 1 and 200 characters)
+func IsBanReasonValid(reason string) bool {
+	reason = strings.TrimSpace(reason)
+	return len(reason) > 0 && len(reason) <= 200
+}
+
+// UnbanUserFromThread lifts the ban of the user, he gets back the rank he had when he was banned
+// The ban record is kept with the moderator who lifted it
+// Returns an error if there is one
+func UnbanUserFromThread(thread ThreadGoForum, user User, moderator User) error {
+	tx, err := db.Begin()
+	if err != nil {
+		ErrorPrintf("Error starting the unban of the user: %v\n", err)
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	// The bans recorded before the ranks were kept give back the rank of a simple member
+	unbanUser := fmt.Sprintf(`
+		UPDATE ThreadGoForumMembers SET rights_level = COALESCE((%s), ?)
+		WHERE thread_id = ? AND user_id = ? AND rights_level = ?`, previousRankSQL(""))
+	_, err = tx.Exec(unbanUser, ThreadRankUser, thread.ThreadID, user.UserID, ThreadRankBanned)
+	if err != nil {
+		ErrorPrintf("Error unbanning the user from the thread: %v\n", err)
+		return err
+	}
+	liftBan := "UPDATE ThreadBans SET lifted_date = CURRENT_TIMESTAMP, lifted_by = ? WHERE thread_id = ? AND user_id = ? AND lifted_date IS NULL"
+	_, err = tx.Exec(liftBan, moderator.UserID, thread.ThreadID, user.UserID)
+	if err != nil {
+		ErrorPrintf("Error lifting the ban record of the user: %v\n", err)
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		ErrorPrintf("Error committing the unban of the user: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// previousRankSQL returns the SQL query giving the rank a member of ThreadGoForumMembers had before his last ban that is not lifted yet
+// The condition is added to the ones of the ban, e.g. to only keep the expired bans
+func previousRankSQL(condition string) string {
+	return fmt.Sprintf(`
+		SELECT b.previous_rank FROM ThreadBans b
+		WHERE b.thread_id = ThreadGoForumMembers.thread_id AND b.user_id = ThreadGoForumMembers.user_id
+		AND b.lifted_date IS NULL %s
+		ORDER BY b.ban_id DESC LIMIT 1`, condition)
+}
+
+// liftExpiredBansOf lifts the expired bans, if the user is not empty only his ban in the given thread is lifted
+// The members get back the rank they had when they were banned
+// The expired bans keep a NULL 'lifted_by' to tell them apart from the bans lifted by a moderator
+// Returns the number of lifted bans and an error if there is one
+func liftExpiredBansOf(thread ThreadGoForum, user User) (int64, error) {
+	filter := ""
+	var args []interface{}
+	if user.UserID > 0 {
+		filter = "AND b.thread_id = ? AND b.user_id = ?"
+		args = append(args, thread.ThreadID, user.UserID)
+	}
+	expiredBan := "AND b.expiration_date IS NOT NULL AND b.expiration_date <= CURRENT_TIMESTAMP " + filter
+	tx, err := db.Begin()
+	if err != nil {
+		ErrorPrintf("Error starting the lift of the expired bans: %v\n", err)
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	restoreMembers := fmt.Sprintf(`
+		UPDATE ThreadGoForumMembers SET rights_level = (%s)
+		WHERE rights_level = ? AND EXISTS (%s)`, previousRankSQL(expiredBan), previousRankSQL(expiredBan))
+	restoreArgs := append([]interface{}{}, args...)
+	restoreArgs = append(restoreArgs, ThreadRankBanned)
+	restoreArgs = append(restoreArgs, args...)
+	res, err := tx.Exec(restoreMembers, restoreArgs...)
+	if err != nil {
+		ErrorPrintf("Error restoring the members with an expired ban: %v\n", err)
+		return 0, err
+	}
+	liftBans := fmt.Sprintf(`
+		UPDATE ThreadBans SET lifted_date = CURRENT_TIMESTAMP
+		WHERE ban_id IN (
+			SELECT b.ban_id FROM ThreadBans b
+			WHERE b.lifted_date IS NULL %s
+		)`, expiredBan)
+	_, err = tx.Exec(liftBans, args...)
+	if err != nil {
+		ErrorPrintf("Error lifting the expired bans: %v\n", err)
+		return 0, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		ErrorPrintf("Error committing the lift of the expired bans: %v\n", err)
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// LiftExpiredBans lifts every ban whose expiration date is passed
+// Returns an error if there is one
+func LiftExpiredBans() error {
+	lifted, err := liftExpiredBansOf(ThreadGoForum{}, User{})
+	if err != nil {
+		return err
+	}
+	if lifted > 0 {
+		InfoPrintf("%d expired ban(s) lifted\n", lifted)
+	}
+	return nil
+}
+
+// AutoLiftExpiredBans lifts the expired bans periodically
+// To disable it, set the environment variable 'AUTO_LIFT_EXPIRED_BANS' to 'false'
+// To change the interval, set the environment variable 'AUTO_LIFT_EXPIRED_BANS_INTERVAL' to the desired interval in minutes
+// The bans are also lifted when the banned user comes back, so the interval only delays the cleanup of the member list
+func AutoLiftExpiredBans() {
+	if os.Getenv("AUTO_LIFT_EXPIRED_BANS") == "false" {
+		InfoPrintln("Auto lift of the expired bans was disabled")
+		return
+	}
+	interval := 1
+	var err error // We have to define it here so we can use it in the 'if' statement
+	if os.Getenv("AUTO_LIFT_EXPIRED_BANS_INTERVAL") != "" {
+		interval, err = strconv.Atoi(os.Getenv("AUTO_LIFT_EXPIRED_BANS_INTERVAL"))
+		if err != nil {
+			ErrorPrintf("Error parsing the interval AUTO_LIFT_EXPIRED_BANS_INTERVAL : %v\n", err)
+			interval = 1
+		}
+	}
+	InfoPrintf("Auto lift of the expired bans interval is set %d minute(s)\n", interval)
+	for {
+		err := LiftExpiredBans()
+		if err != nil {
+			ErrorPrintf("Error lifting the expired bans: %v\n", err)
+			return
+		}
+		time.Sleep(time.Duration(interval) * time.Minute)
+	}
+}
+
+// scanThreadBan scans a row made of the ban id, the banned username, the moderator name, the reason, the note, the ban date and the expiration date
+func scanThreadBan(row interface{ Scan(...interface{}) error }) (ThreadBan, error) {
+	var ban ThreadBan
+	var banDate, expirationDate sql.NullTime
+	err := row.Scan(&ban.BanID, &ban.Username, &ban.ModeratorName, &ban.BanReason, &ban.BanNote, &banDate, &expirationDate)
+	if err != nil {
+		return ThreadBan{}, err
+	}
+	ban.BanDate = banDate.Time
+	ban.ExpirationDate = expirationDate.Time
+	ban.IsPermanent = !expirationDate.Valid
+	return ban, nil
+}
+
+// GetActiveThreadBan returns the current ban of the user in the thread
+// Returns sql.ErrNoRows if the user is not banned or if his ban was made before the bans were recorded
+func GetActiveThreadBan(thread ThreadGoForum, user User) (ThreadBan, error) {
+	getBan := `
+		SELECT b.ban_id, u.username, COALESCE(mu.username, ''), b.ban_reason, b.ban_note, b.ban_date, b.expiration_date
+		FROM ThreadBans b
+		JOIN Users u ON b.user_id = u.user_id
+		LEFT JOIN Users mu ON b.moderator_id = mu.user_id
+		WHERE b.thread_id = ? AND b.user_id = ? AND b.lifted_date IS NULL
+		ORDER BY b.ban_date DESC LIMIT 1`
+	ban, err := scanThreadBan(db.QueryRow(getBan, thread.ThreadID, user.UserID))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		ErrorPrintf("Error getting the ban of the user: %v\n", err)
+	}
+	return ban, err
+}
+
+// GetThreadBans returns the users currently banned from the thread with their ban record
+// Returns a slice of ThreadBan (most recent first) and an error if there is one
+func GetThreadBans(thread ThreadGoForum) ([]ThreadBan, error) {
+	getBans := `
+		SELECT COALESCE(b.ban_id, 0), u.username, COALESCE(mu.username, ''), COALESCE(b.ban_reason, ''), COALESCE(b.ban_note, ''), b.ban_date, b.expiration_date
+		FROM ThreadGoForumMembers m
+		JOIN Users u ON m.user_id = u.user_id
+		LEFT JOIN ThreadBans b ON m.thread_id = b.thread_id AND m.user_id = b.user_id AND b.lifted_date IS NULL
+		LEFT JOIN Users mu ON b.moderator_id = mu.user_id
+		WHERE m.thread_id = ? AND m.rights_level = ?
+		ORDER BY b.ban_date DESC`
+	rows, err := db.Query(getBans, thread.ThreadID, ThreadRankBanned)
+	if err != nil {
+		ErrorPrintf("Error getting the bans of the thread: %v\n", err)
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	var bans []ThreadBan
+	for rows.Next() {
+		ban, err := scanThreadBan(rows)
+		if err != nil {
+			ErrorPrintf("Error scanning the rows in GetThreadBans: %v\n", err)
+			return nil, err
+		}
+		bans = append(bans, ban)
+	}
+	return bans, nil
+}
+
+// IsAModerationAction checks if the string is a moderation action
+func IsAModerationAction(action string) bool {
+	for _, v := range ModerationActions {
+		if string(v) == action {
+			return true
+		}
+	}
+	return false
+}
+
+// GetModerationActionFromString returns the moderation action from the string
+// Returns an error if the action is not valid
+func GetModerationActionFromString(action string) (ModerationAction, error) {
+	for _, v := range ModerationActions {
+		if string(v) == action {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("invalid moderation action: %s", action)
+}
+
+// GetModerationActionsAsStrings returns the moderation actions as a string
+func GetModerationActionsAsStrings() []string {
+	var actions []string
+	for _, v := range ModerationActions {
+		actions = append(actions, string(v))
+	}
+	return actions
+}
+
+// GetMessageAuthorID returns the id of the user who posted the message
+// Returns an error if there is one
+func GetMessageAuthorID(messageID int) (int, error) {
+	getAuthor := "SELECT user_id FROM ThreadMessages WHERE message_id = ?"
+	var authorID int
+	err := db.QueryRow(getAuthor, messageID).Scan(&authorID)
+	if err != nil {
+		ErrorPrintf("Error getting the author of the message: %v\n", err)
+		return 0, err
+	}
+	return authorID, nil
+}
+
+// GetCommentAuthorID returns the id of the user who posted the comment
+// Returns an error if there is one
+func GetCommentAuthorID(commentID int) (int, error) {
+	getAuthor := "SELECT user_id FROM ThreadComments WHERE comment_id = ?"
+	var authorID int
+	err := db.QueryRow(getAuthor, commentID).Scan(&authorID)
+	if err != nil {
+		ErrorPrintf("Error getting the author of the comment: %v\n", err)
+		return 0, err
+	}
+	return authorID, nil
+}
+
+// moderationLogDetailsMaxLength is the maximum number of characters kept in the details of a moderation log entry
+const moderationLogDetailsMaxLength = 200
+
+// LogModerationAction appends the action to the moderation log of the thread
+// The targetUserID and the targetID are 0 when the action has no target user or no target content
+// The details are cut to moderationLogDetailsMaxLength characters
+// A failure is only printed, it must never cancel the action that was already made
+func LogModerationAction(thread ThreadGoForum, moderator User, action ModerationAction, targetUserID int, targetID int, details string) {
+	if runes := []rune(details); len(runes) > moderationLogDetailsMaxLength {
+		details = string(runes[:moderationLogDetailsMaxLength]) + "..."
+	}
+	addLog := `
+		INSERT INTO ModerationLog (thread_id, moderator_id, action, target_user_id, target_id, details)
+		VALUES (?, ?, ?, NULLIF(?, 0), NULLIF(?, 0), ?)`
+	_, err := db.Exec(addLog, thread.ThreadID, moderator.UserID, action, targetUserID, targetID, details)
+	if err != nil {
+		ErrorPrintf("Error adding the action %s to the moderation log: %v\n", action, err)
+	}
+}
+
+// GetModerationLog returns the moderation log of the thread matching the filter
+// Returns a slice of ModerationLogEntry (most recent first) and an error if there is one
+func GetModerationLog(thread ThreadGoForum, filter ModerationLogFilter) ([]ModerationLogEntry, error) {
+	getLog := `
+		SELECT l.log_id, COALESCE(mu.username, ''), l.action, COALESCE(tu.username, ''), COALESCE(l.target_id, 0), l.details, l.log_date
+		FROM ModerationLog l
+		LEFT JOIN Users mu ON l.moderator_id = mu.user_id
+		LEFT JOIN Users tu ON l.target_user_id = tu.user_id
+		WHERE l.thread_id = ?`
+	args := []interface{}{thread.ThreadID}
+	if filter.Action != "" {
+		getLog += " AND l.action = ?"
+		args = append(args, filter.Action)
+	}
+	if filter.ModeratorName != "" {
+		getLog += " AND mu.username = ?"
+		args = append(args, filter.ModeratorName)
+	}
+	if filter.TargetName != "" {
+		getLog += " AND tu.username = ?"
+		args = append(args, filter.TargetName)
+	}
+	if !filter.From.IsZero() {
+		getLog += " AND l.log_date >= ?"
+		args = append(args, filter.From.UTC().Format("2006-01-02 15:04:05"))
+	}
+	if !filter.To.IsZero() {
+		getLog += " AND l.log_date < ?"
+		args = append(args, filter.To.UTC().Format("2006-01-02 15:04:05"))
+	}
+	getLog += " ORDER BY l.log_date DESC, l.log_id DESC"
+	rows, err := db.Query(getLog, args...)
+	if err != nil {
+		ErrorPrintf("Error getting the moderation log of the thread: %v\n", err)
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	var entries []ModerationLogEntry
+	for rows.Next() {
+		var entry ModerationLogEntry
+		err := rows.Scan(&entry.LogID, &entry.ModeratorName, &entry.Action, &entry.TargetName, &entry.TargetID, &entry.Details, &entry.LogDate)
+		if err != nil {
+			ErrorPrintf("Error scanning the rows in GetModerationLog: %v\n", err)
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// IsAReportState checks if the string is a report state
+func IsAReportState(state string) bool {
+	for _, v := range ReportStates {
+		if string(v) == state {
+			return true
+		}
+	}
+	return false
+}
+
+// GetReportStateFromString returns the report state from the string
+// Returns an error if the state is not valid
+func GetReportStateFromString(state string) (ReportState, error) {
+	for _, v := range ReportStates {
+		if string(v) == state {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("invalid report state: %s", state)
+}
+
+// GetReportStatesAsStrings returns the report states as a string
+func GetReportStatesAsStrings() []string {
+	var states []string
+	for _, v := range ReportStates {
+		states = append(states, string(v))
+	}
+	return states
+}
+
+// IsResolutionNoteValid checks if the note left when closing a report is valid (500 characters at most, it can be empty)
+func IsResolutionNoteValid(note string) bool {
+	return len(note) <= 500
+}
+
+// reportContentCondition is the condition matching every report made about the same content as the report
+// Its arguments are given by reportContentArgs
+const reportContentCondition = "thread_id = ? AND message_id = ? AND comment_id = ?"
+
+// reportContentArgs returns the arguments of reportContentCondition for the content of the report
+func reportContentArgs(thread ThreadGoForum, report ReportedContent) []interface{} {
+	commentID := 0
+	if !report.IsAPostAndNotAComment {
+		commentID = report.ReportedContentID
+	}
+	return []interface{}{thread.ThreadID, report.PostID, commentID}
+}
+
+// reportsSelectSQL selects the reports along with their assignee, the author of the reported content and a preview of it
+// The preview is empty when the content was deleted
+const reportsSelectSQL = `
+	SELECT r.report_id, r.username, r.message_id, r.comment_id, r.report_type, r.report_content, r.report_state,
+	       COALESCE(au.username, ''), r.resolution_note, r.report_date, COALESCE(ru.username, ''),
+	       COALESCE(CASE WHEN r.comment_id != 0 THEN tc.comment_content ELSE tm.message_title END, '')
+	FROM Reports r
+	LEFT JOIN Users au ON r.assignee_id = au.user_id
+	LEFT JOIN Users ru ON r.reported_user_id = ru.user_id
+	LEFT JOIN ThreadMessages tm ON r.message_id = tm.message_id AND tm.deletion_date IS NULL
+	LEFT JOIN ThreadComments tc ON r.comment_id != 0 AND r.comment_id = tc.comment_id AND tc.deletion_date IS NULL`
+
+// scanReport scans a row selected with reportsSelectSQL
+// Returns the report and the preview of the reported content
+func scanReport(row interface{ Scan(...any) error }) (ReportedContent, string, error) {
+	var report ReportedContent
+	var messageID, commentID int
+	var reportDate sql.NullTime
+	var preview string
+	err := row.Scan(
+		&report.ReportID,
+		&report.UserName,
+		&messageID,
+		&commentID,
+		&report.ReportType,
+		&report.ReportContent,
+		&report.State,
+		&report.AssigneeName,
+		&report.ResolutionNote,
+		&reportDate,
+		&report.ReportedUserName,
+		&preview)
+	if err != nil {
+		return ReportedContent{}, "", err
+	}
+	if commentID != 0 {
+		report.ReportedContentID = commentID
+		report.IsAPostAndNotAComment = false
+	} else {
+		report.ReportedContentID = messageID
+		report.IsAPostAndNotAComment = true
+	}
+	report.PostID = messageID
+	report.ReportDate = reportDate.Time
+	return report, preview, nil
+}
+
+// GetReportInThread returns the report of the thread with the given id
+// Returns sql.ErrNoRows if the report does not exist in the thread
+func GetReportInThread(thread ThreadGoForum, reportID int) (ReportedContent, error) {
+	report, _, err := scanReport(db.QueryRow(reportsSelectSQL+" WHERE r.thread_id = ? AND r.report_id = ?", thread.ThreadID, reportID))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		ErrorPrintf("Error getting the report: %v\n", err)
+	}
+	return report, err
+}
+
+// GetReportGroupsInThread returns the reports of the thread matching the filter, grouped by reported content and state
+// Returns a slice of ReportGroup (most recently reported first) and an error if there is one
+func GetReportGroupsInThread(thread ThreadGoForum, filter ReportFilter) ([]ReportGroup, error) {
+	getReports := reportsSelectSQL + " WHERE r.thread_id = ?"
+	args := []interface{}{thread.ThreadID}
+	if len(filter.States) > 0 {
+		getReports += " AND r.report_state IN (?" + strings.Repeat(", ?", len(filter.States)-1) + ")"
+		for _, state := range filter.States {
+			args = append(args, state)
+		}
+	}
+	if filter.Type != "" {
+		getReports += " AND r.report_type = ?"
+		args = append(args, filter.Type)
+	}
+	getReports += " ORDER BY r.report_id DESC"
+	rows, err := db.Query(getReports, args...)
+	if err != nil {
+		ErrorPrintf("Error getting the reports of the thread: %v\n", err)
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+
+	// The reports closed together share the same state, assignee and note
+	type groupKey struct {
+		postID, contentID int
+		isAPost           bool
+		state             ReportState
+		assignee, note    string
+	}
+	var groups []ReportGroup
+	groupIndex := make(map[groupKey]int)
+	for rows.Next() {
+		report, preview, err := scanReport(rows)
+		if err != nil {
+			ErrorPrintf("Error scanning the rows in GetReportGroupsInThread: %v\n", err)
+			return nil, err
+		}
+		key := groupKey{report.PostID, report.ReportedContentID, report.IsAPostAndNotAComment, report.State, report.AssigneeName, report.ResolutionNote}
+		index, ok := groupIndex[key]
+		if !ok {
+			index = len(groups)
+			groupIndex[key] = index
+			groups = append(groups, ReportGroup{
+				PostID:                report.PostID,
+				ReportedContentID:     report.ReportedContentID,
+				IsAPostAndNotAComment: report.IsAPostAndNotAComment,
+				AuthorName:            report.ReportedUserName,
+				ContentPreview:        preview,
+				State:                 report.State,
+				AssigneeName:          report.AssigneeName,
+				ResolutionNote:        report.ResolutionNote,
+			})
+		}
+		groups[index].Reports = append(groups[index].Reports, report)
+	}
+	return groups, nil
+}
+
+// ClaimReportGroup assigns the moderator to every active report made about the same content as the report
+// Returns an error if there is one
+func ClaimReportGroup(thread ThreadGoForum, report ReportedContent, moderator User) error {
+	claimReports := "UPDATE Reports SET report_state = ?, assignee_id = ? WHERE " + reportContentCondition + " AND report_state IN (?, ?)"
+	args := append([]interface{}{ReportClaimed, moderator.UserID}, reportContentArgs(thread, report)...)
+	_, err := db.Exec(claimReports, append(args, ReportOpen, ReportClaimed)...)
+	if err != nil {
+		ErrorPrintf("Error claiming the reports: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// CloseReportGroup closes every active report made about the same content as the report
+// The state must be ReportActioned or ReportDismissed, the moderator becomes the assignee of the reports
+// Returns an error if there is one
+func CloseReportGroup(thread ThreadGoForum, report ReportedContent, moderator User, state ReportState, note string) error {
+	if state != ReportActioned && state != ReportDismissed {
+		return fmt.Errorf("a report cannot be closed with the state %s", state)
+	}
+	closeReports := `
+		UPDATE Reports SET report_state = ?, assignee_id = ?, resolution_note = ?, is_resolved = 1, closed_date = CURRENT_TIMESTAMP
+		WHERE ` + reportContentCondition + " AND report_state IN (?, ?)"
+	args := append([]interface{}{state, moderator.UserID, strings.TrimSpace(note)}, reportContentArgs(thread, report)...)
+	_, err := db.Exec(closeReports, append(args, ReportOpen, ReportClaimed)...)
+	if err != nil {
+		ErrorPrintf("Error closing the reports: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// addColumnIfMissing adds the column to the table of an existing database
+// Returns true if the column was added and an error if there is one
+func addColumnIfMissing(table string, column string, definition string) (bool, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, column).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	if err != nil {
+		return false, err
+	}
+	InfoPrintf("Column %s added to the table %s\n", column, table)
+	return true, nil
+}
+
+// IsAWordFilterAction checks if the string is a word filter action
+func IsAWordFilterAction(action string) bool {
+	for _, v := range WordFilterActions {
+		if string(v) == action {
+			return true
+		}
+	}
+	return false
+}
+
+// GetWordFilterActionFromString returns the word filter action from the string
+// Returns an error if the action is not valid
+func GetWordFilterActionFromString(action string) (WordFilterAction, error) {
+	for _, v := range WordFilterActions {
+		if string(v) == action {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("invalid word filter action: %s", action)
+}
+
+// GetWordFilterActionsAsStrings returns the word filter actions as a string
+func GetWordFilterActionsAsStrings() []string {
+	var actions []string
+	for _, v := range WordFilterActions {
+		actions = append(actions, string(v))
+	}
+	return actions
+}
+
+// IsWordFilterValid checks if the filtered word is valid
+// It must be 2 to 50 characters long and only contain letters, numbers, spaces, underscores, hyphens and apostrophes
+func IsWordFilterValid(word string) bool {
+	wordRegex := regexp.MustCompile(`^[a-zA-Z0-9 _\-'éèêëôçàâäïîùûü]{2,50}$`)
+	return wordRegex.MatchString(word) && strings.TrimSpace(word) == word
+}
+
+// AddThreadWordFilter adds a filtered word to the thread
+// The word is stored in lower case since the filters are case-insensitive
+// Returns the id of the filter and an error if there is one
+func AddThreadWordFilter(thread ThreadGoForum, word string, action WordFilterAction) (int, error) {
+	addFilter := "INSERT INTO ThreadWordFilters (thread_id, word, filter_action) VALUES (?, ?, ?)"
+	result, err := db.Exec(addFilter, thread.ThreadID, strings.ToLower(word), string(action))
+	if err != nil {
+		ErrorPrintf("Error adding the word filter: %v\n", err)
+		return 0, err
+	}
+	filterID, err := result.LastInsertId()
+	if err != nil {
+		ErrorPrintf("Error getting the id of the word filter: %v\n", err)
+		return 0, err
+	}
+	return int(filterID), nil
+}
+
+// RemoveThreadWordFilter removes the filtered word from the thread
+// Returns an error if there is one or if the filter does not belong to the thread
+func RemoveThreadWordFilter(thread ThreadGoForum, filterID int) error {
+	removeFilter := "DELETE FROM ThreadWordFilters WHERE filter_id = ? AND thread_id = ?"
+	result, err := db.Exec(removeFilter, filterID, thread.ThreadID)
+	if err != nil {
+		ErrorPrintf("Error removing the word filter: %v\n", err)
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		ErrorPrintf("Error getting the removed word filters: %v\n", err)
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("word filter %d does not exist in the thread %s", filterID, thread.ThreadName)
+	}
+	return nil
+}
+
+// GetThreadWordFilters returns the filtered words of the thread (without the global list)
+// Returns an error if there is one
+func GetThreadWordFilters(thread ThreadGoForum) ([]WordFilter, error) {
+	getFilters := "SELECT filter_id, thread_id, word, filter_action, creation_date FROM ThreadWordFilters WHERE thread_id = ? ORDER BY word"
+	rows, err := db.Query(getFilters, thread.ThreadID)
+	if err != nil {
+		ErrorPrintf("Error getting the word filters: %v\n", err)
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	var filters []WordFilter
+	for rows.Next() {
+		var filter WordFilter
+		err := rows.Scan(
+			&filter.FilterID,
+			&filter.ThreadID,
+			&filter.Word,
+			&filter.Action,
+			&filter.CreationDate)
+		if err != nil {
+			ErrorPrintf("Error scanning the rows in GetThreadWordFilters: %v\n", err)
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+	return filters, nil
+}
+
+// CountUserPostsWithContent returns the number of messages and comments of the user with the given content
+// Only the posts sent during the last 'minutes' minutes are counted
+// Returns an error if there is one
+func CountUserPostsWithContent(user User, content string, minutes int) (int, error) {
+	countPosts := `
+		SELECT
+			(SELECT COUNT(*) FROM ThreadMessages WHERE user_id = ? AND message_content = ? AND creation_date >= datetime('now', ?)) +
+			(SELECT COUNT(*) FROM ThreadComments WHERE user_id = ? AND comment_content = ? AND creation_date >= datetime('now', ?))`
+	since := fmt.Sprintf("-%d minutes", minutes)
+	var count int
+	err := db.QueryRow(countPosts, user.UserID, content, since, user.UserID, content, since).Scan(&count)
+	if err != nil {
+		ErrorPrintf("Error counting the posts of the user with the same content: %v\n", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// CountUserPostsSince returns the number of messages and comments the user sent during the last 'minutes' minutes
+// Returns an error if there is one
+func CountUserPostsSince(user User, minutes int) (int, error) {
+	countPosts := `
+		SELECT
+			(SELECT COUNT(*) FROM ThreadMessages WHERE user_id = ? AND creation_date >= datetime('now', ?)) +
+			(SELECT COUNT(*) FROM ThreadComments WHERE user_id = ? AND creation_date >= datetime('now', ?))`
+	since := fmt.Sprintf("-%d minutes", minutes)
+	var count int
+	err := db.QueryRow(countPosts, user.UserID, since, user.UserID, since).Scan(&count)
+	if err != nil {
+		ErrorPrintf("Error counting the recent posts of the user: %v\n", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// messageApprovalSQL is the approval state of a message of ViewThreadMessagesWithVotes
+const messageApprovalSQL = `approval_state`
+
+// IsApprovalSettingValid checks if the approval settings of a thread are valid
+// The number of posts must be between 0 and 1000 and the number of days between 0 and 365, 0 disables the setting
+func IsApprovalSettingValid(minPosts int, minDays int) bool {
+	return minPosts >= 0 && minPosts <= 1000 && minDays >= 0 && minDays <= 365
+}
+
+// NeedsPostApproval checks if the messages of the user go through the approval queue of the thread
+// It is the case when the member has less approved messages than the thread asks for or joined it too recently
+// The moderation team never needs an approval
+func NeedsPostApproval(thread ThreadGoForum, user User) bool {
+	threadConfig := GetThreadConfigFromThread(thread)
+	if threadConfig.ApprovalMinPosts <= 0 && threadConfig.ApprovalMinDays <= 0 {
+		return false
+	}
+	if CanUserSeeHiddenContent(thread, user) {
+		return false
+	}
+	getMemberHistory := `
+		SELECT
+			(SELECT COUNT(*) FROM ThreadMessages WHERE thread_id = ? AND user_id = ? AND approval_state = ?),
+			COALESCE((SELECT julianday('now') - julianday(creation_date) FROM ThreadGoForumMembers WHERE thread_id = ? AND user_id = ?), 0)`
+	var approvedMessages int
+	var membershipDays float64
+	err := db.QueryRow(getMemberHistory, thread.ThreadID, user.UserID, string(MessageApproved), thread.ThreadID, user.UserID).Scan(&approvedMessages, &membershipDays)
+	if err != nil {
+		ErrorPrintf("Error getting the history of the member in the thread: %v\n", err)
+		return true
+	}
+	return approvedMessages < threadConfig.ApprovalMinPosts || membershipDays < float64(threadConfig.ApprovalMinDays)
+}
+
+// CanUserSeeMessage checks if the message is visible to the user
+// The messages waiting for approval are only visible to their author and the moderation team, the rejected ones to their author
+// The messages hidden by their reports are only visible to the moderation team
+func CanUserSeeMessage(thread ThreadGoForum, user User, message FormattedThreadMessage) bool {
+	isModerator := CanUserSeeHiddenContent(thread, user)
+	if message.IsHidden && !isModerator {
+		return false
+	}
+	isAuthor := user.UserID != 0 && message.UserName == user.Username
+	switch message.ApprovalState {
+	case MessagePending:
+		return isAuthor || isModerator
+	case MessageRejected:
+		return isAuthor
+	}
+	return true
+}
+
+// CanUserSeeComment checks if the comment is visible to the user
+// The comments hidden by their reports are only visible to their author and the moderation team
+func CanUserSeeComment(thread ThreadGoForum, user User, comment FormattedMessageComment) bool {
+	if !comment.IsHidden {
+		return true
+	}
+	isAuthor := user.UserID != 0 && comment.UserName == user.Username
+	return isAuthor || CanUserSeeHiddenContent(thread, user)
+}
+
+// GetPendingMessagesInThread returns the messages waiting in the approval queue of the thread, the oldest first
+// Returns an error if there is one
+func GetPendingMessagesInThread(thread ThreadGoForum) ([]FormattedThreadMessage, error) {
+	getMessages := fmt.Sprintf(`
+		SELECT
+			message_id,
+			message_title,
+			message_content,
+			creation_date,
+			username,
+			pfp_media_address
+		FROM ViewThreadMessagesWithVotes WHERE thread_id = ? AND %s = ? AND %s = '' ORDER BY creation_date ASC`, messageApprovalSQL, messageDeletionSQL)
+	rows, err := db.Query(getMessages, thread.ThreadID, string(MessagePending))
+	if err != nil {
+		ErrorPrintf("Error getting the pending messages: %v\n", err)
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	var messages []FormattedThreadMessage
+	for rows.Next() {
+		message := FormattedThreadMessage{ApprovalState: MessagePending}
+		err := rows.Scan(
+			&message.MessageID,
+			&message.MessageTitle,
+			&message.MessageContent,
+			&message.CreationDate,
+			&message.UserName,
+			&message.UserPfpAddress)
+		if err != nil {
+			ErrorPrintf("Error scanning the rows in GetPendingMessagesInThread: %v\n", err)
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// setPendingMessageState changes the approval state of a message waiting in the approval queue of the thread
+// Returns an error if there is one or if the message is not pending
+func setPendingMessageState(thread ThreadGoForum, messageID int, state ApprovalState) error {
+	updateMessage := "UPDATE ThreadMessages SET approval_state = ? WHERE message_id = ? AND thread_id = ? AND approval_state = ?"
+	result, err := db.Exec(updateMessage, string(state), messageID, thread.ThreadID, string(MessagePending))
+	if err != nil {
+		ErrorPrintf("Error updating the approval state of the message: %v\n", err)
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		ErrorPrintf("Error getting the updated messages: %v\n", err)
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("message %d is not waiting for approval in the thread %s", messageID, thread.ThreadName)
+	}
+	return nil
+}
+
+// ApproveMessage approves a message of the approval queue, it becomes visible to everyone
+// The webhooks and the subscribers of the thread are told about it as if it was just sent
+// Returns an error if there is one or if the message is not pending
+func ApproveMessage(thread ThreadGoForum, messageID int) error {
+	err := setPendingMessageState(thread, messageID, MessageApproved)
+	if err != nil {
+		return err
+	}
+	message, err := GetMessageByID(messageID)
+	if err == nil {
+		announceNewMessage(thread, messageID, message.UserName)
+	}
+	return nil
+}
+
+// RejectMessage rejects a message of the approval queue, only its author still sees it
+// Returns an error if there is one or if the message is not pending
+func RejectMessage(thread ThreadGoForum, messageID int) error {
+	return setPendingMessageState(thread, messageID, MessageRejected)
+}
+
+// GetMessageRevisions returns the previous versions of the message, from the oldest to the newest
+// The current version is not included, see BuildRevisionHistory
+// Returns an error if there is one
+func GetMessageRevisions(messageID int) ([]ContentRevision, error) {
+	getRevisions := "SELECT message_title, message_content, replaced_date FROM ThreadMessageRevisions WHERE message_id = ? ORDER BY revision_id ASC"
+	rows, err := db.Query(getRevisions, messageID)
+	if err != nil {
+		ErrorPrintf("Error getting the revisions of the message: %v\n", err)
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	var revisions []ContentRevision
+	for rows.Next() {
+		var revision ContentRevision
+		err := rows.Scan(&revision.Title, &revision.Content, &revision.replacedDate)
+		if err != nil {
+			ErrorPrintf("Error scanning the rows in GetMessageRevisions: %v\n", err)
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, nil
+}
+
+// GetCommentRevisions returns the previous versions of the comment, from the oldest to the newest
+// The current version is not included, see BuildRevisionHistory
+// Returns an error if there is one
+func GetCommentRevisions(commentID int) ([]ContentRevision, error) {
+	getRevisions := "SELECT comment_content, replaced_date FROM ThreadCommentRevisions WHERE comment_id = ? ORDER BY revision_id ASC"
+	rows, err := db.Query(getRevisions, commentID)
+	if err != nil {
+		ErrorPrintf("Error getting the revisions of the comment: %v\n", err)
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	var revisions []ContentRevision
+	for rows.Next() {
+		var revision ContentRevision
+		err := rows.Scan(&revision.Content, &revision.replacedDate)
+		if err != nil {
+			ErrorPrintf("Error scanning the rows in GetCommentRevisions: %v\n", err)
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, nil
+}
+
+// CanUserSeeRevisions checks if the user can see the revisions of a message or a comment written by the author
+// Only the author and the moderation team can see them
+func CanUserSeeRevisions(thread ThreadGoForum, user User, authorID int) bool {
+	if user.UserID == 0 {
+		return false
+	}
+	return user.UserID == authorID || CanUserSeeHiddenContent(thread, user)
+}
+
+// messageDeletionSQL is the SQL expression giving the DeletionState of a message of ViewThreadMessagesWithVotes
+// The message was removed by the moderation team when it was deleted by someone else than its author
+const messageDeletionSQL = `(CASE WHEN deletion_date IS NULL THEN '' WHEN deleted_by = user_id THEN 'deleted' ELSE 'removed' END)`
+
+// messageDeletionDateSQL is the SQL expression giving the deletion date of a message of ViewThreadMessagesWithVotes
+const messageDeletionDateSQL = `deletion_date`
+
+// commentDeletionSQL is the SQL expression giving the DeletionState of a comment of ViewMessageCommentsWithVotes
+// It works the same way as messageDeletionSQL
+const commentDeletionSQL = `(CASE WHEN deletion_date IS NULL THEN '' WHEN deleted_by = user_id THEN 'deleted' ELSE 'removed' END)`
+
+// commentDeletionDateSQL is the SQL expression giving the deletion date of a comment of ViewMessageCommentsWithVotes
+const commentDeletionDateSQL = `deletion_date`
+
+// GetTombstone returns the text shown instead of a deleted message or comment
+func GetTombstone(state DeletionState) string {
+	if state == RemovedByModerator {
+		return "[removed by moderator]"
+	}
+	return "[deleted]"
+}
+
+// GetRestoreWindow returns the number of hours during which a deleted message or comment can be restored
+// It is set by the environment variable 'DELETED_CONTENT_RESTORE_WINDOW' (72 by default), the content is purged after it
+func GetRestoreWindow() int {
+	window, err := strconv.Atoi(os.Getenv("DELETED_CONTENT_RESTORE_WINDOW"))
+	if err != nil || window < 1 {
+		return 72
+	}
+	return window
+}
+
+// isInRestoreWindow checks if a content deleted at the given date can still be restored
+func isInRestoreWindow(deletionDate sql.NullTime) bool {
+	return deletionDate.Valid && time.Since(deletionDate.Time) < time.Duration(GetRestoreWindow())*time.Hour
+}
+
+// canUserRestore checks if the user can restore a content of the thread deleted at the given date
+// The moderation team can restore every deleted content, the authors can only restore what they deleted themselves
+func canUserRestore(thread ThreadGoForum, user User, authorName string, state DeletionState, deletionDate sql.NullTime) bool {
+	if user.UserID == 0 || state == NotDeleted || !isInRestoreWindow(deletionDate) {
+		return false
+	}
+	if CanUserSeeHiddenContent(thread, user) {
+		return true
+	}
+	return state == DeletedByAuthor && user.Username == authorName
+}
+
+// applyMessageTombstone replaces the title and the content of a deleted message by its tombstone
+// It also tells if the user can restore the message
+func applyMessageTombstone(thread ThreadGoForum, user User, message *FormattedThreadMessage, deletionDate sql.NullTime) {
+	message.CanRestore = canUserRestore(thread, user, message.UserName, message.DeletionState, deletionDate)
+	message.MessageTitle = GetTombstone(message.DeletionState)
+	message.MessageContent = GetTombstone(message.DeletionState)
+	message.MediaLinks = nil
+	message.MessageTags = nil
+}
+
+// applyCommentTombstone replaces the content of a deleted comment by its tombstone
+// It also tells if the user can restore the comment
+func applyCommentTombstone(thread ThreadGoForum, user User, comment *FormattedMessageComment, deletionDate sql.NullTime) {
+	comment.CanRestore = canUserRestore(thread, user, comment.UserName, comment.DeletionState, deletionDate)
+	comment.CommentContent = GetTombstone(comment.DeletionState)
+}
+
+// CanUserRestoreMessage checks if the user can restore the deleted message of the thread
+// The message must still be in the restore window, see canUserRestore for the rights
+func CanUserRestoreMessage(thread ThreadGoForum, user User, messageID int) bool {
+	getDeletion := `
+		SELECT u.username, CASE WHEN tm.deleted_by = tm.user_id THEN 'deleted' ELSE 'removed' END, tm.deletion_date
+		FROM ThreadMessages tm JOIN Users u ON tm.user_id = u.user_id
+		WHERE tm.thread_id = ? AND tm.message_id = ? AND tm.deletion_date IS NOT NULL`
+	var authorName string
+	var state DeletionState
+	var deletionDate sql.NullTime
+	err := db.QueryRow(getDeletion, thread.ThreadID, messageID).Scan(&authorName, &state, &deletionDate)
+	if err != nil {
+		return false
+	}
+	return canUserRestore(thread, user, authorName, state, deletionDate)
+}
+
+// CanUserRestoreComment checks if the user can restore the deleted comment of the thread
+// The comment must still be in the restore window, see canUserRestore for the rights
+func CanUserRestoreComment(thread ThreadGoForum, user User, commentID int) bool {
+	getDeletion := `
+		SELECT u.username, CASE WHEN tc.deleted_by = tc.user_id THEN 'deleted' ELSE 'removed' END, tc.deletion_date
+		FROM ThreadComments tc
+		JOIN ThreadMessages tm ON tc.message_id = tm.message_id
+		JOIN Users u ON tc.user_id = u.user_id
+		WHERE tm.thread_id = ? AND tc.comment_id = ? AND tc.deletion_date IS NOT NULL`
+	var authorName string
+	var state DeletionState
+	var deletionDate sql.NullTime
+	err := db.QueryRow(getDeletion, thread.ThreadID, commentID).Scan(&authorName, &state, &deletionDate)
+	if err != nil {
+		return false
+	}
+	return canUserRestore(thread, user, authorName, state, deletionDate)
+}
+
+// RestoreMessage restores a deleted message of the thread
+// Returns an error if there is one or if the message is not deleted
+func RestoreMessage(thread ThreadGoForum, messageID int) error {
+	restoreMessage := "UPDATE ThreadMessages SET deleted_by = NULL, deletion_date = NULL WHERE thread_id = ? AND message_id = ? AND deletion_date IS NOT NULL"
+	result, err := db.Exec(restoreMessage, thread.ThreadID, messageID)
+	if err != nil {
+		ErrorPrintf("Error restoring the message: %v\n", err)
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		ErrorPrintf("Error getting the restored messages: %v\n", err)
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("message %d is not deleted", messageID)
+	}
+	return nil
+}
+
+// RestoreComment restores a deleted comment
+// Returns an error if there is one or if the comment is not deleted
+func RestoreComment(commentID int) error {
+	restoreComment := "UPDATE ThreadComments SET deleted_by = NULL, deletion_date = NULL WHERE comment_id = ? AND deletion_date IS NOT NULL"
+	result, err := db.Exec(restoreComment, commentID)
+	if err != nil {
+		ErrorPrintf("Error restoring the comment: %v\n", err)
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		ErrorPrintf("Error getting the restored comments: %v\n", err)
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("comment %d is not deleted", commentID)
+	}
+	return nil
+}
+
+// IsMessageDeleted checks if the message is deleted, a deleted message cannot be voted nor commented until it is restored
+// Returns false if the message does not exist
+func IsMessageDeleted(messageID int) bool {
+	var isDeleted bool
+	err := db.QueryRow("SELECT deletion_date IS NOT NULL FROM ThreadMessages WHERE message_id = ?", messageID).Scan(&isDeleted)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			ErrorPrintf("Error checking if the message is deleted: %v\n", err)
+		}
+		return false
+	}
+	return isDeleted
+}
+
+// IsCommentDeleted checks if the comment is deleted, a deleted comment cannot be voted until it is restored
+// Returns false if the comment does not exist
+func IsCommentDeleted(commentID int) bool {
+	var isDeleted bool
+	err := db.QueryRow("SELECT deletion_date IS NOT NULL FROM ThreadComments WHERE comment_id = ?", commentID).Scan(&isDeleted)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			ErrorPrintf("Error checking if the comment is deleted: %v\n", err)
+		}
+		return false
+	}
+	return isDeleted
+}
+
+// purgedContentDependents are the rows removed along with the purged messages and comments, in the order they are removed
+// The foreign keys are not enforced on the connection, so every row depending on a purged message or comment is listed here
+// '%[1]s' is the query of the ids of the purged messages and '%[2]s' the one of the purged comments
+// The media files are not removed here, they are removed with their links by AutoDeleteUselessMediaLinks once they are not used anymore
+var purgedContentDependents = []struct {
+	table     string
+	condition string
+}{
+	{"ThreadMessagePollVotes", "option_id IN (SELECT o.option_id FROM ThreadMessagePollOptions o JOIN ThreadMessagePolls p ON o.poll_id = p.poll_id WHERE p.message_id IN (%[1]s))"},
+	{"ThreadMessagePollBallots", "poll_id IN (SELECT poll_id FROM ThreadMessagePolls WHERE message_id IN (%[1]s))"},
+	{"ThreadMessagePollOptions", "poll_id IN (SELECT poll_id FROM ThreadMessagePolls WHERE message_id IN (%[1]s))"},
+	{"ThreadMessagePolls", "message_id IN (%[1]s)"},
+	{"ThreadVotes", "message_id IN (%[1]s) OR comment_id IN (%[2]s)"},
+	{"ReputationEvents", "message_id IN (%[1]s) OR comment_id IN (%[2]s)"},
+	{"SavedItems", "message_id IN (%[1]s) OR comment_id IN (%[2]s)"},
+	{"Reports", "message_id IN (%[1]s) OR comment_id IN (%[2]s)"},
+	{"ThreadMessageRevisions", "message_id IN (%[1]s)"},
+	{"ThreadCommentRevisions", "comment_id IN (%[2]s)"},
+	{"ThreadMessageMediaLinks", "message_id IN (%[1]s)"},
+	{"ThreadMessageTags", "message_id IN (%[1]s)"},
+	{"MessageSubscriptions", "message_id IN (%[1]s)"},
+	{"ThreadComments", "comment_id IN (%[2]s)"},
+	{"ThreadMessages", "message_id IN (%[1]s)"},
+}
+
+// PurgeDeletedContent removes for good the messages and comments deleted before the restore window
+// The comments of a purged message are purged with it, even if they were not deleted
+// Everything depending on the purged content is removed in the same transaction, see purgedContentDependents
+// Returns an error if there is one
+func PurgeDeletedContent() error {
+	// The limit is computed once, so every statement removes the same content
+	var cutoff string
+	err := db.QueryRow("SELECT datetime('now', ?)", fmt.Sprintf("-%d hours", GetRestoreWindow())).Scan(&cutoff)
+	if err != nil {
+		ErrorPrintf("Error computing the end of the restore window: %v\n", err)
+		return err
+	}
+	purgedMessages := "SELECT message_id FROM ThreadMessages WHERE deletion_date IS NOT NULL AND deletion_date < @cutoff"
+	purgedComments := fmt.Sprintf(`
+		SELECT comment_id FROM ThreadComments
+		WHERE (deletion_date IS NOT NULL AND deletion_date < @cutoff) OR message_id IN (%s)`, purgedMessages)
+
+	tx, err := db.Begin()
+	if err != nil {
+		ErrorPrintf("Error starting the purge of the deleted content: %v\n", err)
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	for _, dependent := range purgedContentDependents {
+		condition := fmt.Sprintf(dependent.condition, purgedMessages, purgedComments)
+		_, err = tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s", dependent.table, condition), sql.Named("cutoff", cutoff))
+		if err != nil {
+			ErrorPrintf("Error purging the %s of the deleted content: %v\n", dependent.table, err)
+			return err
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		ErrorPrintf("Error committing the purge of the deleted content: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// AutoPurgeDeletedContent purges the deleted messages and comments periodically
+// To disable it, set the environment variable 'AUTO_PURGE_DELETED_CONTENT' to 'false'
+// To change the interval, set the environment variable 'AUTO_PURGE_DELETED_CONTENT_INTERVAL' to the desired interval in minutes
+func AutoPurgeDeletedContent() {
+	if os.Getenv("AUTO_PURGE_DELETED_CONTENT") == "false" {
+		InfoPrintln("Auto purge of the deleted content was disabled")
+		return
+	}
+	interval := 60
+	var err error // We have to define it here so we can use it in the 'if' statement
+	if os.Getenv("AUTO_PURGE_DELETED_CONTENT_INTERVAL") != "" {
+		interval, err = strconv.Atoi(os.Getenv("AUTO_PURGE_DELETED_CONTENT_INTERVAL"))
+		if err != nil {
+			ErrorPrintf("Error parsing the interval AUTO_PURGE_DELETED_CONTENT_INTERVAL : %v\n", err)
+			interval = 60
+		}
+	}
+	InfoPrintf("Auto purge of the deleted content interval is set %d minute(s)\n", interval)
+	for {
+		err := PurgeDeletedContent()
+		if err != nil {
+			ErrorPrintf("Error purging the deleted content: %v\n", err)
+			return
+		}
+		DebugPrintln("Deleted content purged")
+		time.Sleep(time.Duration(interval) * time.Minute)
+	}
+}
+
+// messagePinnedSQL tells if a message of ViewThreadMessagesWithVotes is pinned
+const messagePinnedSQL = `is_pinned`
+
+// messageDateKeySQL is the creation date of a message of ViewThreadMessagesWithVotes in seconds, used as sort key by the date orders
+const messageDateKeySQL = `CAST(strftime('%s', creation_date) AS INTEGER)`
+
+// messageStoredScoreKeySQL is the score stored in a message of ViewThreadMessagesWithVotes, see updateMessageScores
+// It is used as sort key by the popularity and the top orders
+const messageStoredScoreKeySQL = `(upvotes_count - downvotes_count)`
+
+// messageHotKeySQL is the hot score stored in a message of ViewThreadMessagesWithVotes, see updateMessageScores
+const messageHotKeySQL = `hot_score`
+
+// messageControversyKeySQL is the controversy of a message of ViewThreadMessagesWithVotes, its number of votes weighted by the ratio between its downvotes and upvotes
+// The messages with only upvotes or only downvotes are not controversial
+const messageControversyKeySQL = `(
+	CASE WHEN upvotes_count = 0 OR downvotes_count = 0 THEN 0
+	ELSE (upvotes_count + downvotes_count) * MIN(upvotes_count, downvotes_count) * 1000 / MAX(upvotes_count, downvotes_count) END)`
+
+// commentScoreKeySQL is the vote score of a comment of ViewMessageCommentsWithVotes, the comments are always sorted by it
+const commentScoreKeySQL = `(upvotes - downvotes)`
+
+// commentsOrder is the order stored in the cursors of the comments, as they only have one order
+const commentsOrder = "popular"
+
+// messageLockedSQL tells if a message of ViewThreadMessagesWithVotes is locked
+const messageLockedSQL = `is_locked`
+
+// setMessageFlag sets the pin or the lock state of a message of the thread
+// The deleted messages cannot be pinned nor locked
+// Returns an error if there is one or if the message does not exist
+func setMessageFlag(thread ThreadGoForum, messageID int, column string, value bool) error {
+	updateMessage := fmt.Sprintf("UPDATE ThreadMessages SET %s = ? WHERE message_id = ? AND thread_id = ? AND deletion_date IS NULL", column)
+	result, err := db.Exec(updateMessage, value, messageID, thread.ThreadID)
+	if err != nil {
+		ErrorPrintf("Error updating the %s column of the message: %v\n", column, err)
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		ErrorPrintf("Error getting the updated messages: %v\n", err)
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("message %d does not exist in the thread %s", messageID, thread.ThreadName)
+	}
+	return nil
+}
+
+// SetMessagePinned pins or unpins a message of the thread, the pinned messages are shown first whatever the ordering
+// Returns an error if there is one
+func SetMessagePinned(thread ThreadGoForum, messageID int, pinned bool) error {
+	return setMessageFlag(thread, messageID, "is_pinned", pinned)
+}
+
+// SetMessageLocked locks or unlocks a message of the thread, a locked message cannot be commented nor voted anymore
+// Returns an error if there is one
+func SetMessageLocked(thread ThreadGoForum, messageID int, locked bool) error {
+	return setMessageFlag(thread, messageID, "is_locked", locked)
+}
+
+// IsMessageLocked checks if the message is locked
+// Returns false if the message does not exist
+func IsMessageLocked(messageID int) bool {
+	var locked bool
+	err := db.QueryRow("SELECT is_locked FROM ThreadMessages WHERE message_id = ?", messageID).Scan(&locked)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			ErrorPrintf("Error checking if the message is locked: %v\n", err)
+		}
+		return false
+	}
+	return locked
+}
+
+// Limits of the polls attached to the messages
+const (
+	PollMinOptions      = 2
+	PollMaxOptions      = 10
+	PollMaxOptionLength = 100
+)
+
+// IsNewPollValid checks if a poll can be attached to a new message
+// It needs between PollMinOptions and PollMaxOptions distinct and non-empty options, a known results visibility and a closing date in the future
+// Returns the reason why the poll is not valid, an empty string if it is
+func IsNewPollValid(poll NewPoll) string {
+	if len(poll.Options) < PollMinOptions || len(poll.Options) > PollMaxOptions {
+		return fmt.Sprintf("A poll must have between %d and %d options", PollMinOptions, PollMaxOptions)
+	}
+	var seen []string
+	for _, option := range poll.Options {
+		option = strings.TrimSpace(option)
+		if option == "" || utf8.RuneCountInString(option) > PollMaxOptionLength {
+			return fmt.Sprintf("The options of a poll must have between 1 and %d characters", PollMaxOptionLength)
+		}
+		if slices.Contains(seen, option) {
+			return "The options of a poll must be different"
+		}
+		seen = append(seen, option)
+	}
+	if !slices.Contains(PollResultsVisibilities, poll.ResultsVisibility) {
+		return "Unknown visibility of the results"
+	}
+	if poll.ClosingDate != nil && !poll.ClosingDate.After(time.Now()) {
+		return "The closing date of a poll must be in the future"
+	}
+	return ""
+}
+
+// addPollToMessage attaches a poll to the message in the transaction adding it, see AddMessageInThread
+// Returns an error if there is one
+func addPollToMessage(tx *sql.Tx, messageID int, poll NewPoll) error {
+	var closingDate sql.NullTime
+	if poll.ClosingDate != nil {
+		closingDate = sql.NullTime{Time: poll.ClosingDate.UTC(), Valid: true}
+	}
+	insertPoll := "INSERT INTO ThreadMessagePolls (message_id, is_multiple_choice, results_visibility, closing_date) VALUES (?, ?, ?, ?)"
+	result, err := tx.Exec(insertPoll, messageID, poll.IsMultipleChoice, string(poll.ResultsVisibility), closingDate)
+	if err != nil {
+		ErrorPrintf("Error inserting the poll: %v\n", err)
+		return err
+	}
+	pollID, err := result.LastInsertId()
+	if err != nil {
+		ErrorPrintf("Error getting the id of the poll: %v\n", err)
+		return err
+	}
+	insertOption := "INSERT INTO ThreadMessagePollOptions (poll_id, option_text, option_position) VALUES (?, ?, ?)"
+	for position, option := range poll.Options {
+		_, err = tx.Exec(insertOption, pollID, strings.TrimSpace(option), position)
+		if err != nil {
+			ErrorPrintf("Error inserting the option of the poll: %v\n", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// GetMessagePollWithPOV returns the poll attached to the message viewed from the point of view of the user
+// Returns nil if the message has no poll, and an error if there is one
+func GetMessagePollWithPOV(messageID int, user User) (*MessagePoll, error) {
+	polls, err := getMessagesPollsWithPOV([]int{messageID}, user)
+	if err != nil {
+		return nil, err
+	}
+	return polls[messageID], nil
+}
+
+// getMessagesPollsWithPOV returns the polls of the given messages viewed from the point of view of the user, indexed by message id
+// The messages without a poll are not in the map
+// Returns an error if there is one
+func getMessagesPollsWithPOV(messageIDs []int, user User) (map[int]*MessagePoll, error) {
+	polls := make(map[int]*MessagePoll)
+	if len(messageIDs) == 0 {
+		return polls, nil
+	}
+	placeholders, args := idListSQL(messageIDs)
+	getPolls := fmt.Sprintf(`
+		SELECT
+			p.message_id,
+			p.poll_id,
+			p.is_multiple_choice,
+			p.results_visibility,
+			p.closing_date,
+			(SELECT COUNT(*) FROM ThreadMessagePollBallots b WHERE b.poll_id = p.poll_id),
+			EXISTS (SELECT 1 FROM ThreadMessagePollBallots b WHERE b.poll_id = p.poll_id AND b.user_id = ?)
+		FROM ThreadMessagePolls p
+		WHERE p.message_id IN (%s)`, placeholders)
+	rows, err := db.Query(getPolls, append([]interface{}{user.UserID}, args...)...)
+	if err != nil {
+		ErrorPrintf("Error getting the polls of the messages: %v\n", err)
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(rows)
+	pollsByID := make(map[int]*MessagePoll)
+	var pollIDs []int
+	for rows.Next() {
+		var messageID int
+		var poll MessagePoll
+		var closingDate sql.NullTime
+		err := rows.Scan(&messageID, &poll.PollID, &poll.IsMultipleChoice, &poll.ResultsVisibility, &closingDate, &poll.NumberOfVoters, &poll.HasVoted)
+		if err != nil {
+			ErrorPrintf("Error scanning the rows in getMessagesPollsWithPOV: %v\n", err)
+			return nil, err
+		}
+		if closingDate.Valid {
+			poll.ClosingDate = &closingDate.Time
+			poll.IsClosed = !closingDate.Time.After(time.Now())
+		}
+		poll.HasVoted = poll.HasVoted && user.UserID != 0
+		poll.VotedOptions = []int{}
+		polls[messageID] = &poll
+		pollsByID[poll.PollID] = &poll
+		pollIDs = append(pollIDs, poll.PollID)
+	}
+	if len(pollIDs) == 0 {
+		return polls, nil
+	}
+
+	// Get the options of the polls with their number of votes
+	placeholders, args = idListSQL(pollIDs)
+	getOptions := fmt.Sprintf(`
+		SELECT o.poll_id, o.option_id, o.option_text, COUNT(v.user_id), COALESCE(MAX(v.user_id = ?), 0)
+		FROM ThreadMessagePollOptions o
+		LEFT JOIN ThreadMessagePollVotes v ON o.option_id = v.option_id
+		WHERE o.poll_id IN (%s)
+		GROUP BY o.option_id
+		ORDER BY o.option_position ASC`, placeholders)
+	optionRows, err := db.Query(getOptions, append([]interface{}{user.UserID}, args...)...)
+	if err != nil {
+		ErrorPrintf("Error getting the options of the polls: %v\n", err)
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the rows: %v\n", err)
+		}
+	}(optionRows)
+	for optionRows.Next() {
+		var pollID int
+		var option PollOption
+		var votedByUser bool
+		err := optionRows.Scan(&pollID, &option.OptionID, &option.OptionText, &option.Votes, &votedByUser)
+		if err != nil {
+			ErrorPrintf("Error scanning the rows in getMessagesPollsWithPOV: %v\n", err)
+			return nil, err
+		}
+		poll := pollsByID[pollID]
+		if votedByUser && user.UserID != 0 {
+			poll.VotedOptions = append(poll.VotedOptions, option.OptionID)
+		}
+		poll.Options = append(poll.Options, option)
+	}
+
+	// The results are hidden until the user votes if the poll asks for it
+	for _, poll := range pollsByID {
+		poll.ShowResults = poll.ResultsVisibility == PollResultsAlways || poll.HasVoted || poll.IsClosed
+		if !poll.ShowResults {
+			poll.NumberOfVoters = 0
+			for i := range poll.Options {
+				poll.Options[i].Votes = 0
+			}
+		}
 	}
-	return reports, nil
+	return polls, nil
 }
 
-// RemoveReportedContent removes the reported content from the database
-// Returns an error if there is one
-func RemoveReportedContent(reportID int) error {
-	removeReport := "DELETE FROM Reports WHERE report_id = ?"
-	_, err := db.Exec(removeReport, reportID)
+// VoteInPoll casts the ballot of the user in the poll, a user can only vote once in a poll
+// The options must belong to the poll, see GetMessagePollWithPOV
+// Returns an error if there is one or if the user already voted
+func VoteInPoll(pollID int, user User, optionIDs []int) error {
+	// The ballot and its votes are inserted together, a failed vote does not leave a ballot without its votes
+	tx, err := db.Begin()
 	if err != nil {
-		ErrorPrintf("Error removing the reported content from the database: %v\n", err)
+		ErrorPrintf("Error starting the vote in the poll: %v\n", err)
 		return err
 	}
-	return nil
-}
-
-func ReportExistsInThread(thread ThreadGoForum, reportID int) bool {
-	getReport := "SELECT report_id FROM Reports WHERE thread_id = ? AND report_id = ?"
-	rows, err := db.Query(getReport, thread.ThreadID, reportID)
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	// The ballot is unique for each user and poll, it prevents voting twice
+	_, err = tx.Exec("INSERT INTO ThreadMessagePollBallots (poll_id, user_id) VALUES (?, ?)", pollID, user.UserID)
 	if err != nil {
-		ErrorPrintf("Error checking if the report exists in the thread: %v\n", err)
-		return false
+		ErrorPrintf("Error inserting the ballot of the poll: %v\n", err)
+		return err
 	}
-	defer func(rows *sql.Rows) {
-		err := rows.Close()
+	insertVote := "INSERT INTO ThreadMessagePollVotes (option_id, user_id) VALUES (?, ?)"
+	for _, optionID := range optionIDs {
+		_, err = tx.Exec(insertVote, optionID, user.UserID)
 		if err != nil {
-			ErrorPrintf("Error closing the rows: %v\n", err)
+			ErrorPrintf("Error inserting the vote of the poll: %v\n", err)
+			return err
 		}
-	}(rows)
-	if rows.Next() {
-		return true
 	}
-	return false
+	err = tx.Commit()
+	if err != nil {
+		ErrorPrintf("Error committing the vote in the poll: %v\n", err)
+		return err
+	}
+	return nil
 }
 
 // InitDatabase initialises the database.
@@ -3034,6 +8305,10 @@ func InitDatabase() {
 			lang TEXT DEFAULT '%s' NOT NULL,
 			theme TEXT DEFAULT '%s' NOT NULL,
 			pfp_id INTEGER DEFAULT 1 NOT NULL,
+			display_name TEXT DEFAULT '' NOT NULL,
+			bio TEXT DEFAULT '' NOT NULL,
+			website TEXT DEFAULT '' NOT NULL,
+			location TEXT DEFAULT '' NOT NULL,
 			FOREIGN KEY (user_id) REFERENCES Users(id) ON DELETE CASCADE
 		);
 		`, string(DefaultLang),
@@ -3043,6 +8318,27 @@ func InitDatabase() {
 		ErrorPrintf("Error creating Users or UserConfigs table: %v\n", err)
 		return
 	}
+	// The 'display_name', 'bio', 'website' and 'location' columns were added after the creation of the 'UserConfigs' table
+	_, err = addColumnIfMissing("UserConfigs", "display_name", "TEXT DEFAULT '' NOT NULL")
+	if err != nil {
+		ErrorPrintf("Error adding the display_name column to the UserConfigs table: %v\n", err)
+		return
+	}
+	_, err = addColumnIfMissing("UserConfigs", "bio", "TEXT DEFAULT '' NOT NULL")
+	if err != nil {
+		ErrorPrintf("Error adding the bio column to the UserConfigs table: %v\n", err)
+		return
+	}
+	_, err = addColumnIfMissing("UserConfigs", "website", "TEXT DEFAULT '' NOT NULL")
+	if err != nil {
+		ErrorPrintf("Error adding the website column to the UserConfigs table: %v\n", err)
+		return
+	}
+	_, err = addColumnIfMissing("UserConfigs", "location", "TEXT DEFAULT '' NOT NULL")
+	if err != nil {
+		ErrorPrintf("Error adding the location column to the UserConfigs table: %v\n", err)
+		return
+	}
 
 	// the 'EmailIdentificationTable' table only contains the id of a user and the id of a link from an email
 	// the 'email_id' column is used to determine the email id (it's a unique identifier, it's a 64 characters long hexadecimal string)
@@ -3087,6 +8383,15 @@ func InitDatabase() {
 		    allow_images BOOLEAN DEFAULT TRUE NOT NULL,
 		    allow_links BOOLEAN DEFAULT TRUE NOT NULL,
 		    allow_text_formatting BOOLEAN DEFAULT TRUE NOT NULL,
+		    auto_hide_report_threshold INTEGER DEFAULT 0 NOT NULL,
+		    approval_min_posts INTEGER DEFAULT 0 NOT NULL,
+		    approval_min_days INTEGER DEFAULT 0 NOT NULL,
+		    thread_category TEXT DEFAULT 'general' NOT NULL,
+		    join_policy TEXT DEFAULT 'open' NOT NULL,
+		    is_private BOOLEAN DEFAULT FALSE NOT NULL,
+		    min_reputation_images INTEGER DEFAULT 0 NOT NULL,
+		    min_reputation_tags INTEGER DEFAULT 0 NOT NULL,
+		    min_reputation_reports INTEGER DEFAULT 0 NOT NULL,
 			FOREIGN KEY (thread_id) REFERENCES ThreadGoForum(thread_id) ON DELETE CASCADE,
 		    FOREIGN KEY (thread_icon_id) REFERENCES MediaLink(media_id) ON DELETE CASCADE,
 		    FOREIGN KEY (thread_banner_id) REFERENCES MediaLink(media_id) ON DELETE CASCADE
@@ -3097,6 +8402,48 @@ func InitDatabase() {
 		ErrorPrintf("Error creating ThreadGoForum or ThreadGoForumConfigs table: %v\n", err)
 		return
 	}
+	// The 'auto_hide_report_threshold' column was added after the creation of the 'ThreadGoForumConfigs' table
+	_, err = addColumnIfMissing("ThreadGoForumConfigs", "auto_hide_report_threshold", "INTEGER DEFAULT 0 NOT NULL")
+	if err != nil {
+		ErrorPrintf("Error adding the auto_hide_report_threshold column to the ThreadGoForumConfigs table: %v\n", err)
+		return
+	}
+	// The 'approval_min_posts' and 'approval_min_days' columns were added after the creation of the 'ThreadGoForumConfigs' table
+	_, err = addColumnIfMissing("ThreadGoForumConfigs", "approval_min_posts", "INTEGER DEFAULT 0 NOT NULL")
+	if err != nil {
+		ErrorPrintf("Error adding the approval_min_posts column to the ThreadGoForumConfigs table: %v\n", err)
+		return
+	}
+	_, err = addColumnIfMissing("ThreadGoForumConfigs", "approval_min_days", "INTEGER DEFAULT 0 NOT NULL")
+	if err != nil {
+		ErrorPrintf("Error adding the approval_min_days column to the ThreadGoForumConfigs table: %v\n", err)
+		return
+	}
+	// The 'thread_category' column was added after the creation of the 'ThreadGoForumConfigs' table, the existing threads are in the default category
+	_, err = addColumnIfMissing("ThreadGoForumConfigs", "thread_category", "TEXT DEFAULT 'general' NOT NULL")
+	if err != nil {
+		ErrorPrintf("Error adding the thread_category column to the ThreadGoForumConfigs table: %v\n", err)
+		return
+	}
+	// The 'join_policy' (one of the ThreadJoinPolicies) and 'is_private' columns were added after the creation of the 'ThreadGoForumConfigs' table
+	_, err = addColumnIfMissing("ThreadGoForumConfigs", "join_policy", "TEXT DEFAULT 'open' NOT NULL")
+	if err != nil {
+		ErrorPrintf("Error adding the join_policy column to the ThreadGoForumConfigs table: %v\n", err)
+		return
+	}
+	_, err = addColumnIfMissing("ThreadGoForumConfigs", "is_private", "BOOLEAN DEFAULT FALSE NOT NULL")
+	if err != nil {
+		ErrorPrintf("Error adding the is_private column to the ThreadGoForumConfigs table: %v\n", err)
+		return
+	}
+	// The 'min_reputation_images', 'min_reputation_tags' and 'min_reputation_reports' columns were added after the creation of the 'ThreadGoForumConfigs' table
+	for _, column := range []string{"min_reputation_images", "min_reputation_tags", "min_reputation_reports"} {
+		_, err = addColumnIfMissing("ThreadGoForumConfigs", column, "INTEGER DEFAULT 0 NOT NULL")
+		if err != nil {
+			ErrorPrintf("Error adding the %s column to the ThreadGoForumConfigs table: %v\n", column, err)
+			return
+		}
+	}
 
 	// The 'ThreadGoForumTags' table represents the tags of a thread
 	// the tag_color column is used to determine the color of the tag (it's a hexadecimal color code, e.g. #FF0000)
@@ -3121,11 +8468,13 @@ func InitDatabase() {
 	// if the 'rights_level' is 1, the user is a moderator
 	// if the 'rights_level' is 2, the user is an admin
 	// if the 'rights_level' is 3, the user is the owner of the thread
+	// the 'role_id' is the custom role of the member (see ThreadRoles), NULL if he has none
 	ThreadGoForumMembersTableSQL := `
 		CREATE TABLE IF NOT EXISTS ThreadGoForumMembers (
 			user_id INTEGER NOT NULL,
 			thread_id INTEGER NOT NULL,
 			rights_level INTEGER DEFAULT 0 NOT NULL,
+			role_id INTEGER DEFAULT NULL,
 			creation_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			PRIMARY KEY (user_id, thread_id),
 		    FOREIGN KEY (user_id) REFERENCES Users(user_id) ON DELETE CASCADE
@@ -3136,6 +8485,12 @@ func InitDatabase() {
 		ErrorPrintf("Error creating ThreadGoForumMembers table: %v\n", err)
 		return
 	}
+	// The 'role_id' column was added after the creation of the 'ThreadGoForumMembers' table
+	_, err = addColumnIfMissing("ThreadGoForumMembers", "role_id", "INTEGER DEFAULT NULL")
+	if err != nil {
+		ErrorPrintf("Error adding the role_id column to the ThreadGoForumMembers table: %v\n", err)
+		return
+	}
 
 	// The 'MediaLink' table represents the media links (images, videos, etc.) that are shared in the threads
 	// For now, we only will do images as stated in the project instructions
@@ -3157,6 +8512,7 @@ func InitDatabase() {
 	// The 'ThreadMessageMediaLinks' table represents the media links that are shared in the messages
 	// The 'ThreadVotes' table represents the votes that are sent in the messages
 	// The 'ThreadMessageTags' table represents the tags that are sent in the messages
+	// The 'approval_state' column is one of the ApprovalStates, the pending messages wait in the approval queue of their thread
 	ThreadMessagesTableSQL := `
 		CREATE TABLE IF NOT EXISTS ThreadMessages (
 			message_id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -3165,6 +8521,14 @@ func InitDatabase() {
 			message_title TEXT NOT NULL,
 			message_content TEXT NOT NULL,
 			was_edited BOOLEAN DEFAULT FALSE NOT NULL,
+			approval_state TEXT DEFAULT 'approved' NOT NULL,
+			is_pinned BOOLEAN DEFAULT FALSE NOT NULL,
+			is_locked BOOLEAN DEFAULT FALSE NOT NULL,
+			upvotes_count INTEGER DEFAULT 0 NOT NULL,
+			downvotes_count INTEGER DEFAULT 0 NOT NULL,
+			hot_score REAL DEFAULT 0 NOT NULL,
+			deleted_by INTEGER DEFAULT NULL,
+			deletion_date TIMESTAMP DEFAULT NULL,
 			creation_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		    FOREIGN KEY (user_id) REFERENCES Users(user_id) ON DELETE CASCADE, 
 		    FOREIGN KEY (thread_id) REFERENCES ThreadGoForum(thread_id) ON DELETE CASCADE
@@ -3175,10 +8539,13 @@ func InitDatabase() {
 			user_id INTEGER NOT NULL,
 			comment_content TEXT NOT NULL,
 			was_edited BOOLEAN DEFAULT FALSE NOT NULL,
+			deleted_by INTEGER DEFAULT NULL,
+			deletion_date TIMESTAMP DEFAULT NULL,
 			creation_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (message_id) REFERENCES ThreadMessages(message_id) ON DELETE CASCADE,
 			FOREIGN KEY (user_id) REFERENCES Users(user_id) ON DELETE CASCADE
 		);
+		CREATE INDEX IF NOT EXISTS idx_thread_comments_message ON ThreadComments (message_id);
 		CREATE TABLE IF NOT EXISTS ThreadMessageMediaLinks (
 		    message_id INTEGER NOT NULL,
 		    media_id INTEGER NOT NULL,
@@ -3191,11 +8558,13 @@ func InitDatabase() {
 		    comment_id INTEGER,
 		    user_id INTEGER NOT NULL,
 		    is_upvote BOOLEAN NOT NULL,
+		    vote_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (message_id) REFERENCES ThreadMessages(message_id) ON DELETE CASCADE,
 		    FOREIGN KEY (comment_id) REFERENCES ThreadComments(comment_id) ON DELETE CASCADE,
 		    FOREIGN KEY (user_id) REFERENCES Users(user_id) ON DELETE CASCADE,
 		    PRIMARY KEY (message_id, comment_id, user_id)
 		);
+		CREATE INDEX IF NOT EXISTS idx_thread_votes_comment ON ThreadVotes (comment_id, user_id);
 		CREATE TABLE IF NOT EXISTS ThreadMessageTags (
 		    message_id INTEGER NOT NULL,
 		    tag_id INTEGER NOT NULL,
@@ -3209,10 +8578,152 @@ func InitDatabase() {
 		ErrorPrintf("Error creating the ThreadMessage tables (ThreadMessages / ThreadMessageMediaLinks / ThreadVotes / ThreadMessageTags): %v\n", err)
 		return
 	}
+	// The 'approval_state' column was added after the creation of the 'ThreadMessages' table, the existing messages are approved
+	_, err = addColumnIfMissing("ThreadMessages", "approval_state", "TEXT DEFAULT 'approved' NOT NULL")
+	if err != nil {
+		ErrorPrintf("Error adding the approval_state column to the ThreadMessages table: %v\n", err)
+		return
+	}
+	// The 'is_pinned' and 'is_locked' columns were added after the creation of the 'ThreadMessages' table
+	for _, column := range []string{"is_pinned", "is_locked"} {
+		_, err = addColumnIfMissing("ThreadMessages", column, "BOOLEAN DEFAULT FALSE NOT NULL")
+		if err != nil {
+			ErrorPrintf("Error adding the %s column to the ThreadMessages table: %v\n", column, err)
+			return
+		}
+	}
+	// The 'upvotes_count', 'downvotes_count' and 'hot_score' columns were added after the creation of the 'ThreadMessages' table
+	// They are computed from the votes of the existing messages
+	scoresAdded := false
+	for _, column := range []string{"upvotes_count INTEGER", "downvotes_count INTEGER", "hot_score REAL"} {
+		name, columnType, _ := strings.Cut(column, " ")
+		added, err := addColumnIfMissing("ThreadMessages", name, columnType+" DEFAULT 0 NOT NULL")
+		if err != nil {
+			ErrorPrintf("Error adding the %s column to the ThreadMessages table: %v\n", name, err)
+			return
+		}
+		scoresAdded = scoresAdded || added
+	}
+	if scoresAdded {
+		err = UpdateAllMessageScores()
+		if err != nil {
+			return
+		}
+	}
+	// The 'deleted_by' and 'deletion_date' columns were added after the creation of the 'ThreadMessages' and 'ThreadComments' tables
+	// A message or a comment is deleted when 'deletion_date' is set, it is purged once the restore window is over
+	for _, table := range []string{"ThreadMessages", "ThreadComments"} {
+		_, err = addColumnIfMissing(table, "deleted_by", "INTEGER DEFAULT NULL")
+		if err != nil {
+			ErrorPrintf("Error adding the deleted_by column to the %s table: %v\n", table, err)
+			return
+		}
+		_, err = addColumnIfMissing(table, "deletion_date", "TIMESTAMP DEFAULT NULL")
+		if err != nil {
+			ErrorPrintf("Error adding the deletion_date column to the %s table: %v\n", table, err)
+			return
+		}
+	}
+	// The messages of a thread are loaded by their thread, pinned first and sorted on the date in both directions, the hot score or the score
+	// The sort keys are the same expressions as messageDateKeySQL and messageStoredScoreKeySQL, otherwise SQLite does not use the indexes to sort
+	// The ascending orders need their own index as the pinned messages still come first
+	messageIndexes := map[string]string{
+		"idx_thread_messages_thread":     "thread_id, is_pinned, " + messageDateKeySQL + ", message_id",
+		"idx_thread_messages_thread_asc": "thread_id, is_pinned DESC, " + messageDateKeySQL + ", message_id",
+		"idx_thread_messages_hot":        "thread_id, is_pinned, " + messageHotKeySQL + ", message_id",
+		"idx_thread_messages_score":      "thread_id, is_pinned, " + messageStoredScoreKeySQL + ", message_id",
+		"idx_thread_messages_score_asc":  "thread_id, is_pinned DESC, " + messageStoredScoreKeySQL + ", message_id",
+	}
+	for name, columns := range messageIndexes {
+		_, err = db.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON ThreadMessages (%s)", name, columns))
+		if err != nil {
+			ErrorPrintf("Error creating the %s index: %v\n", name, err)
+			return
+		}
+	}
+
+	// The 'ReputationEvents' table holds the reputation points given by each vote to the author of the voted content
+	// The 'message_id' and 'comment_id' columns are the voted content (0 when not used), the 'user_id' column is its author
+	// The 'points' column is the weight of the vote, the upvotes being capped by ReputationDailyCap for each author and day
+	// The reputation of a user in a thread is the sum of his points in this thread
+	ReputationEventsTableSQL := `
+		CREATE TABLE IF NOT EXISTS ReputationEvents (
+		    voter_id INTEGER NOT NULL,
+		    message_id INTEGER DEFAULT 0 NOT NULL,
+		    comment_id INTEGER DEFAULT 0 NOT NULL,
+		    user_id INTEGER NOT NULL,
+		    thread_id INTEGER NOT NULL,
+		    points INTEGER NOT NULL,
+		    event_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		    FOREIGN KEY (voter_id) REFERENCES Users(user_id) ON DELETE CASCADE,
+		    FOREIGN KEY (user_id) REFERENCES Users(user_id) ON DELETE CASCADE,
+		    FOREIGN KEY (thread_id) REFERENCES ThreadGoForum(thread_id) ON DELETE CASCADE,
+		    PRIMARY KEY (voter_id, message_id, comment_id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_reputation_events_user ON ReputationEvents (user_id, thread_id);
+		`
+	_, err = db.Exec(ReputationEventsTableSQL)
+	if err != nil {
+		ErrorPrintf("Error creating the ReputationEvents table: %v\n", err)
+		return
+	}
+	// The 'vote_date' column was added after the creation of the 'ThreadVotes' table
+	// The reputation is computed from the existing votes, their date is the creation date of the voted content
+	voteDateAdded, err := addColumnIfMissing("ThreadVotes", "vote_date", "TIMESTAMP DEFAULT NULL")
+	if err != nil {
+		ErrorPrintf("Error adding the vote_date column to the ThreadVotes table: %v\n", err)
+		return
+	}
+	if voteDateAdded {
+		err = RecomputeAllReputation()
+		if err != nil {
+			return
+		}
+	}
+
+	// The 'UserBadges' table holds the badges awarded to the users
+	// The 'badge_id' column is the id of a Badge, the badges are defined by the configuration (see InitBadges)
+	// The awarded badges are kept when their definition is removed, they are just not displayed anymore
+	UserBadgesTableSQL := `
+		CREATE TABLE IF NOT EXISTS UserBadges (
+		    user_id INTEGER NOT NULL,
+		    badge_id TEXT NOT NULL,
+		    award_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		    FOREIGN KEY (user_id) REFERENCES Users(user_id) ON DELETE CASCADE,
+		    PRIMARY KEY (user_id, badge_id)
+		);
+		`
+	_, err = db.Exec(UserBadgesTableSQL)
+	if err != nil {
+		ErrorPrintf("Error creating the UserBadges table: %v\n", err)
+		return
+	}
+
+	// The 'SavedItems' table holds the messages and the comments saved by the users to read them later
+	// The 'message_id' and 'comment_id' columns are the saved content (0 when not used), the 'folder' column is empty when the item is in no folder
+	SavedItemsTableSQL := `
+		CREATE TABLE IF NOT EXISTS SavedItems (
+		    user_id INTEGER NOT NULL,
+		    message_id INTEGER DEFAULT 0 NOT NULL,
+		    comment_id INTEGER DEFAULT 0 NOT NULL,
+		    folder TEXT DEFAULT '' NOT NULL,
+		    save_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		    FOREIGN KEY (user_id) REFERENCES Users(user_id) ON DELETE CASCADE,
+		    PRIMARY KEY (user_id, message_id, comment_id)
+		);
+		`
+	_, err = db.Exec(SavedItemsTableSQL)
+	if err != nil {
+		ErrorPrintf("Error creating the SavedItems table: %v\n", err)
+		return
+	}
 
 	// The 'Reports' table represents the reports about a messages or a comment
 	// The 'report_type' column is used to determine the type of the report (e.g. spam, harassment, etc...)
 	// The 'report_content' column is used to determine the additional content given by the report owner (e.g. information about the report)
+	// The 'report_state' column is one of the ReportStates, 'is_resolved' is kept for the closed states (actioned or dismissed)
+	// The 'assignee_id' column is the member of the moderation team who claimed or closed the report, the 'resolution_note' is left when closing it
+	// The 'reported_user_id' column is the author of the reported content, it is kept when the content is deleted
 	ReportsTableSQL := `
 		CREATE TABLE IF NOT EXISTS Reports (
 		    report_id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -3223,48 +8734,397 @@ func InitDatabase() {
 		    report_type TEXT NOT NULL,
 		    report_content TEXT NOT NULL,
 		    is_resolved BOOLEAN DEFAULT FALSE NOT NULL,
+		    report_state TEXT DEFAULT 'open' NOT NULL,
+		    assignee_id INTEGER DEFAULT NULL,
+		    resolution_note TEXT DEFAULT '' NOT NULL,
+		    reported_user_id INTEGER DEFAULT NULL,
+		    report_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		    closed_date TIMESTAMP DEFAULT NULL,
 		    FOREIGN KEY (username) REFERENCES Users(username) ON DELETE CASCADE,
 		    FOREIGN KEY (message_id) REFERENCES ThreadMessages(message_id) ON DELETE CASCADE,
 		    FOREIGN KEY (comment_id) REFERENCES ThreadComments(comment_id) ON DELETE CASCADE,
-		    FOREIGN KEY (thread_id) REFERENCES ThreadGoForum(thread_id) ON DELETE CASCADE
-		);`
+		    FOREIGN KEY (thread_id) REFERENCES ThreadGoForum(thread_id) ON DELETE CASCADE,
+		    FOREIGN KEY (assignee_id) REFERENCES Users(user_id) ON DELETE SET NULL,
+		    FOREIGN KEY (reported_user_id) REFERENCES Users(user_id) ON DELETE SET NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_reports_message ON Reports (message_id, comment_id);`
 	_, err = db.Exec(ReportsTableSQL)
 	if err != nil {
 		ErrorPrintf("Error creating the Reports table: %v\n", err)
 		return
 	}
+	// The databases made before the report workflow get its columns, their resolved reports are considered actioned
+	// (ALTER TABLE cannot add a column with CURRENT_TIMESTAMP as default value, the old reports have no date)
+	for _, column := range [][2]string{
+		{"report_state", "TEXT DEFAULT 'open' NOT NULL"},
+		{"assignee_id", "INTEGER DEFAULT NULL"},
+		{"resolution_note", "TEXT DEFAULT '' NOT NULL"},
+		{"reported_user_id", "INTEGER DEFAULT NULL"},
+		{"report_date", "TIMESTAMP DEFAULT NULL"},
+		{"closed_date", "TIMESTAMP DEFAULT NULL"},
+	} {
+		added, err := addColumnIfMissing("Reports", column[0], column[1])
+		if err != nil {
+			ErrorPrintf("Error adding the %s column to the Reports table: %v\n", column[0], err)
+			return
+		}
+		if added && column[0] == "report_state" {
+			_, err = db.Exec("UPDATE Reports SET report_state = ? WHERE is_resolved = 1", ReportActioned)
+		}
+		if added && column[0] == "reported_user_id" {
+			_, err = db.Exec(`
+				UPDATE Reports SET reported_user_id = CASE
+				    WHEN comment_id != 0 THEN (SELECT user_id FROM ThreadComments WHERE comment_id = Reports.comment_id)
+				    ELSE (SELECT user_id FROM ThreadMessages WHERE message_id = Reports.message_id)
+				END`)
+		}
+		if err != nil {
+			ErrorPrintf("Error filling the %s column of the Reports table: %v\n", column[0], err)
+			return
+		}
+	}
+
+	// The 'UserApiTokens' table represents the personal access tokens used to authenticate on the public api
+	// The 'token_hash' column is the sha256 hash of the token, the token itself is never stored
+	// The 'token_prefix' column contains the first characters of the token to help the user recognise it
+	// The 'scopes' column is a comma separated list of scopes (e.g. 'read,post')
+	UserApiTokensTableSQL := `
+		CREATE TABLE IF NOT EXISTS UserApiTokens (
+		    token_id INTEGER PRIMARY KEY AUTOINCREMENT,
+		    user_id INTEGER NOT NULL,
+		    token_name TEXT NOT NULL,
+		    token_hash TEXT NOT NULL UNIQUE,
+		    token_prefix TEXT NOT NULL,
+		    scopes TEXT NOT NULL,
+		    creation_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		    last_used_date TIMESTAMP,
+		    FOREIGN KEY (user_id) REFERENCES Users(user_id) ON DELETE CASCADE
+		);`
+	_, err = db.Exec(UserApiTokensTableSQL)
+	if err != nil {
+		ErrorPrintf("Error creating the UserApiTokens table: %v\n", err)
+		return
+	}
+
+	// The 'UserFeedTokens' table contains the token of each user used in the urls of the private thread feeds
+	// Feed readers can't log in, so the token identifies the user reading the feed
+	UserFeedTokensTableSQL := `
+		CREATE TABLE IF NOT EXISTS UserFeedTokens (
+		    user_id INTEGER PRIMARY KEY,
+		    feed_token TEXT NOT NULL UNIQUE,
+		    creation_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		    FOREIGN KEY (user_id) REFERENCES Users(user_id) ON DELETE CASCADE
+		);`
+	_, err = db.Exec(UserFeedTokensTableSQL)
+	if err != nil {
+		ErrorPrintf("Error creating the UserFeedTokens table: %v\n", err)
+		return
+	}
+
+	// The 'ThreadWebhooks' table represents the webhooks registered by the thread owner
+	// The 'webhook_secret' column is the secret used to sign the deliveries (HMAC-SHA256)
+	// The 'events' column is a comma separated list of events (e.g. 'message.created,member.joined')
+	// The 'ThreadWebhookDeliveries' table logs every delivery attempt with its response status (0 if no response was received)
+	ThreadWebhooksTableSQL := `
+		CREATE TABLE IF NOT EXISTS ThreadWebhooks (
+		    webhook_id INTEGER PRIMARY KEY AUTOINCREMENT,
+		    thread_id INTEGER NOT NULL,
+		    webhook_url TEXT NOT NULL,
+		    webhook_secret TEXT NOT NULL,
+		    events TEXT NOT NULL,
+		    creation_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		    FOREIGN KEY (thread_id) REFERENCES ThreadGoForum(thread_id) ON DELETE CASCADE
+		);
+		CREATE TABLE IF NOT EXISTS ThreadWebhookDeliveries (
+		    delivery_id INTEGER PRIMARY KEY AUTOINCREMENT,
+		    webhook_id INTEGER NOT NULL,
+		    delivery_uuid TEXT NOT NULL,
+		    event TEXT NOT NULL,
+		    attempt INTEGER NOT NULL,
+		    response_status INTEGER DEFAULT 0 NOT NULL,
+		    error_message TEXT DEFAULT '' NOT NULL,
+		    delivery_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		    FOREIGN KEY (webhook_id) REFERENCES ThreadWebhooks(webhook_id) ON DELETE CASCADE
+		);`
+	_, err = db.Exec(ThreadWebhooksTableSQL)
+	if err != nil {
+		ErrorPrintf("Error creating the ThreadWebhooks or ThreadWebhookDeliveries table: %v\n", err)
+		return
+	}
+
+	// The 'ThreadSubscriptions' and 'MessageSubscriptions' tables represent what the users follow, independently of the thread membership
+	// A muted subscription ('is_muted' = 1) is kept but hidden from the 'Following' feed
+	// The 'last_read_comment_id' column is the last comment of the message the user has seen
+	// The 'ThreadReadPositions' table keeps the last message of each thread the user has seen
+	ThreadSubscriptionsTableSQL := `
+		CREATE TABLE IF NOT EXISTS ThreadSubscriptions (
+		    user_id INTEGER NOT NULL,
+		    thread_id INTEGER NOT NULL,
+		    is_muted BOOLEAN DEFAULT 0 NOT NULL,
+		    creation_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		    PRIMARY KEY (user_id, thread_id),
+		    FOREIGN KEY (user_id) REFERENCES Users(user_id) ON DELETE CASCADE,
+		    FOREIGN KEY (thread_id) REFERENCES ThreadGoForum(thread_id) ON DELETE CASCADE
+		);
+		CREATE TABLE IF NOT EXISTS MessageSubscriptions (
+		    user_id INTEGER NOT NULL,
+		    message_id INTEGER NOT NULL,
+		    is_muted BOOLEAN DEFAULT 0 NOT NULL,
+		    last_read_comment_id INTEGER DEFAULT 0 NOT NULL,
+		    creation_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		    PRIMARY KEY (user_id, message_id),
+		    FOREIGN KEY (user_id) REFERENCES Users(user_id) ON DELETE CASCADE,
+		    FOREIGN KEY (message_id) REFERENCES ThreadMessages(message_id) ON DELETE CASCADE
+		);
+		CREATE TABLE IF NOT EXISTS ThreadReadPositions (
+		    user_id INTEGER NOT NULL,
+		    thread_id INTEGER NOT NULL,
+		    last_read_message_id INTEGER DEFAULT 0 NOT NULL,
+		    last_read_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		    PRIMARY KEY (user_id, thread_id),
+		    FOREIGN KEY (user_id) REFERENCES Users(user_id) ON DELETE CASCADE,
+		    FOREIGN KEY (thread_id) REFERENCES ThreadGoForum(thread_id) ON DELETE CASCADE
+		);`
+	_, err = db.Exec(ThreadSubscriptionsTableSQL)
+	if err != nil {
+		ErrorPrintf("Error creating the ThreadSubscriptions, MessageSubscriptions or ThreadReadPositions table: %v\n", err)
+		return
+	}
+
+	// The 'ThreadBans' table keeps a record of every ban made in a thread
+	// The 'expiration_date' column is NULL for a permanent ban
+	// The 'lifted_date' column is set when the ban ends, 'lifted_by' is the moderator who lifted it (NULL if it expired)
+	ThreadBansTableSQL := `
+		CREATE TABLE IF NOT EXISTS ThreadBans (
+		    ban_id INTEGER PRIMARY KEY AUTOINCREMENT,
+		    thread_id INTEGER NOT NULL,
+		    user_id INTEGER NOT NULL,
+		    moderator_id INTEGER NOT NULL,
+		    ban_reason TEXT NOT NULL,
+		    ban_note TEXT DEFAULT '' NOT NULL,
+		    ban_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		    expiration_date TIMESTAMP DEFAULT NULL,
+		    lifted_date TIMESTAMP DEFAULT NULL,
+		    lifted_by INTEGER DEFAULT NULL,
+		    FOREIGN KEY (thread_id) REFERENCES ThreadGoForum(thread_id) ON DELETE CASCADE,
+		    FOREIGN KEY (user_id) REFERENCES Users(user_id) ON DELETE CASCADE,
+		    FOREIGN KEY (moderator_id) REFERENCES Users(user_id) ON DELETE CASCADE,
+		    FOREIGN KEY (lifted_by) REFERENCES Users(user_id) ON DELETE SET NULL
+		);`
+	_, err = db.Exec(ThreadBansTableSQL)
+	if err != nil {
+		ErrorPrintf("Error creating the ThreadBans table: %v\n", err)
+		return
+	}
+	// The 'previous_rank' column was added after the creation of the 'ThreadBans' table, it is the rank given back when the ban ends
+	_, err = addColumnIfMissing("ThreadBans", "previous_rank", fmt.Sprintf("INTEGER DEFAULT %d NOT NULL", ThreadRankUser))
+	if err != nil {
+		ErrorPrintf("Error adding the previous_rank column to the ThreadBans table: %v\n", err)
+		return
+	}
+
+	// The 'ModerationLog' table keeps a trace of the actions made by the moderation team of a thread
+	// It is append-only, the triggers prevent any entry from being edited or removed
+	// The 'target_id' column is the id of the message, comment, tag or report concerned by the action (NULL if none)
+	ModerationLogTableSQL := `
+		CREATE TABLE IF NOT EXISTS ModerationLog (
+		    log_id INTEGER PRIMARY KEY AUTOINCREMENT,
+		    thread_id INTEGER NOT NULL,
+		    moderator_id INTEGER NOT NULL,
+		    action TEXT NOT NULL,
+		    target_user_id INTEGER DEFAULT NULL,
+		    target_id INTEGER DEFAULT NULL,
+		    details TEXT DEFAULT '' NOT NULL,
+		    log_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		    FOREIGN KEY (thread_id) REFERENCES ThreadGoForum(thread_id),
+		    FOREIGN KEY (moderator_id) REFERENCES Users(user_id),
+		    FOREIGN KEY (target_user_id) REFERENCES Users(user_id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_moderation_log_thread ON ModerationLog (thread_id, log_date);
+		CREATE TRIGGER IF NOT EXISTS moderation_log_no_update
+		BEFORE UPDATE ON ModerationLog
+		BEGIN
+		    SELECT RAISE(ABORT, 'the moderation log is append-only');
+		END;
+		CREATE TRIGGER IF NOT EXISTS moderation_log_no_delete
+		BEFORE DELETE ON ModerationLog
+		BEGIN
+		    SELECT RAISE(ABORT, 'the moderation log is append-only');
+		END;`
+	_, err = db.Exec(ModerationLogTableSQL)
+	if err != nil {
+		ErrorPrintf("Error creating the ModerationLog table: %v\n", err)
+		return
+	}
+
+	// The 'ThreadWordFilters' table keeps the words filtered in each thread, the global list is read from a file (see ContentFilterFuncs.go)
+	// The words are stored in lower case, the 'filter_action' column is one of the WordFilterActions
+	ThreadWordFiltersTableSQL := `
+		CREATE TABLE IF NOT EXISTS ThreadWordFilters (
+		    filter_id INTEGER PRIMARY KEY AUTOINCREMENT,
+		    thread_id INTEGER NOT NULL,
+		    word TEXT NOT NULL,
+		    filter_action TEXT NOT NULL,
+		    creation_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		    UNIQUE (thread_id, word),
+		    FOREIGN KEY (thread_id) REFERENCES ThreadGoForum(thread_id) ON DELETE CASCADE
+		);`
+	_, err = db.Exec(ThreadWordFiltersTableSQL)
+	if err != nil {
+		ErrorPrintf("Error creating the ThreadWordFilters table: %v\n", err)
+		return
+	}
+
+	// The 'ThreadJoinRequests' table keeps the pending requests to join the threads with the 'request' join policy
+	// The 'ThreadInvites' table keeps the invite links of the threads, 'max_uses' is 0 and 'expiration_date' is NULL when there is no limit
+	ThreadAccessTablesSQL := `
+		CREATE TABLE IF NOT EXISTS ThreadJoinRequests (
+		    request_id INTEGER PRIMARY KEY AUTOINCREMENT,
+		    thread_id INTEGER NOT NULL,
+		    user_id INTEGER NOT NULL,
+		    creation_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		    UNIQUE (thread_id, user_id),
+		    FOREIGN KEY (thread_id) REFERENCES ThreadGoForum(thread_id) ON DELETE CASCADE,
+		    FOREIGN KEY (user_id) REFERENCES Users(user_id) ON DELETE CASCADE
+		);
+		CREATE TABLE IF NOT EXISTS ThreadInvites (
+		    invite_id INTEGER PRIMARY KEY AUTOINCREMENT,
+		    thread_id INTEGER NOT NULL,
+		    creator_id INTEGER NOT NULL,
+		    invite_code TEXT NOT NULL UNIQUE,
+		    max_uses INTEGER DEFAULT 0 NOT NULL,
+		    uses_count INTEGER DEFAULT 0 NOT NULL,
+		    expiration_date TIMESTAMP,
+		    creation_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		    FOREIGN KEY (thread_id) REFERENCES ThreadGoForum(thread_id) ON DELETE CASCADE,
+		    FOREIGN KEY (creator_id) REFERENCES Users(user_id) ON DELETE CASCADE
+		);`
+	_, err = db.Exec(ThreadAccessTablesSQL)
+	if err != nil {
+		ErrorPrintf("Error creating the ThreadJoinRequests or ThreadInvites table: %v\n", err)
+		return
+	}
+
+	// The 'ThreadRoles' table represents the custom roles of the threads
+	// The 'permissions' are the comma separated AssignableThreadPermissions given to the members having the role
+	ThreadRolesTableSQL := `
+		CREATE TABLE IF NOT EXISTS ThreadRoles (
+		    role_id INTEGER PRIMARY KEY AUTOINCREMENT,
+		    thread_id INTEGER NOT NULL,
+		    role_name TEXT NOT NULL,
+		    permissions TEXT DEFAULT '' NOT NULL,
+		    creation_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		    UNIQUE (thread_id, role_name),
+		    FOREIGN KEY (thread_id) REFERENCES ThreadGoForum(thread_id) ON DELETE CASCADE
+		);`
+	_, err = db.Exec(ThreadRolesTableSQL)
+	if err != nil {
+		ErrorPrintf("Error creating the ThreadRoles table: %v\n", err)
+		return
+	}
+
+	// The 'ThreadMessageRevisions' and 'ThreadCommentRevisions' tables keep the previous versions of the edited messages and comments
+	// A row is added each time a message or a comment is edited, 'replaced_date' is the date of the edit
+	RevisionsTableSQL := `
+		CREATE TABLE IF NOT EXISTS ThreadMessageRevisions (
+		    revision_id INTEGER PRIMARY KEY AUTOINCREMENT,
+		    message_id INTEGER NOT NULL,
+		    message_title TEXT NOT NULL,
+		    message_content TEXT NOT NULL,
+		    replaced_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		    FOREIGN KEY (message_id) REFERENCES ThreadMessages(message_id) ON DELETE CASCADE
+		);
+		CREATE TABLE IF NOT EXISTS ThreadCommentRevisions (
+		    revision_id INTEGER PRIMARY KEY AUTOINCREMENT,
+		    comment_id INTEGER NOT NULL,
+		    comment_content TEXT NOT NULL,
+		    replaced_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		    FOREIGN KEY (comment_id) REFERENCES ThreadComments(comment_id) ON DELETE CASCADE
+		);`
+	_, err = db.Exec(RevisionsTableSQL)
+	if err != nil {
+		ErrorPrintf("Error creating the revisions tables: %v\n", err)
+		return
+	}
+
+	// The 'ThreadMessagePolls' table holds the polls attached to the messages, the 'ThreadMessagePollBallots' table makes sure each user votes once
+	PollsTableSQL := `
+		CREATE TABLE IF NOT EXISTS ThreadMessagePolls (
+		    poll_id INTEGER PRIMARY KEY AUTOINCREMENT,
+		    message_id INTEGER NOT NULL UNIQUE,
+		    is_multiple_choice BOOLEAN DEFAULT FALSE NOT NULL,
+		    results_visibility TEXT DEFAULT 'always' NOT NULL,
+		    closing_date TIMESTAMP DEFAULT NULL,
+		    FOREIGN KEY (message_id) REFERENCES ThreadMessages(message_id) ON DELETE CASCADE
+		);
+		CREATE TABLE IF NOT EXISTS ThreadMessagePollOptions (
+		    option_id INTEGER PRIMARY KEY AUTOINCREMENT,
+		    poll_id INTEGER NOT NULL,
+		    option_text TEXT NOT NULL,
+		    option_position INTEGER NOT NULL,
+		    FOREIGN KEY (poll_id) REFERENCES ThreadMessagePolls(poll_id) ON DELETE CASCADE
+		);
+		CREATE TABLE IF NOT EXISTS ThreadMessagePollBallots (
+		    poll_id INTEGER NOT NULL,
+		    user_id INTEGER NOT NULL,
+		    vote_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		    PRIMARY KEY (poll_id, user_id),
+		    FOREIGN KEY (poll_id) REFERENCES ThreadMessagePolls(poll_id) ON DELETE CASCADE,
+		    FOREIGN KEY (user_id) REFERENCES Users(user_id) ON DELETE CASCADE
+		);
+		CREATE TABLE IF NOT EXISTS ThreadMessagePollVotes (
+		    option_id INTEGER NOT NULL,
+		    user_id INTEGER NOT NULL,
+		    PRIMARY KEY (option_id, user_id),
+		    FOREIGN KEY (option_id) REFERENCES ThreadMessagePollOptions(option_id) ON DELETE CASCADE,
+		    FOREIGN KEY (user_id) REFERENCES Users(user_id) ON DELETE CASCADE
+		);`
+	_, err = db.Exec(PollsTableSQL)
+	if err != nil {
+		ErrorPrintf("Error creating the polls tables: %v\n", err)
+		return
+	}
 
+	// The views are created again at each start, so the databases created before a change of the views get the new columns
+	// The vote counts of the messages are the ones stored by updateMessageScores, the votes are not counted at each page load
+	_, err = db.Exec("DROP VIEW IF EXISTS ViewThreadMessagesWithVotes; DROP VIEW IF EXISTS ViewMessageCommentsWithVotes")
+	if err != nil {
+		ErrorPrintf("Error dropping the views: %v\n", err)
+		return
+	}
 	ViewThreadMessageWithLikesTableSQL := `
-		CREATE VIEW IF NOT EXISTS ViewThreadMessagesWithVotes AS
-		SELECT 
+		CREATE VIEW ViewThreadMessagesWithVotes AS
+		SELECT
 			tm.message_id,
+			tm.thread_id,
 			tg.thread_name,
 			tm.message_title,
 			tm.message_content,
 			tm.was_edited,
 			tm.creation_date,
+			tm.user_id,
 			u.username,
 			ml.media_address AS pfp_media_address,
-			COALESCE(v.upvotes, 0) AS upvotes,
-			COALESCE(v.downvotes, 0) AS downvotes,
-			COALESCE((
+			tm.upvotes_count AS upvotes,
+			tm.downvotes_count AS downvotes,
+			(
 				SELECT COUNT(*)
 				FROM ThreadComments tc
 				WHERE tc.message_id = tm.message_id
-			), 0) AS comments_number
+			) AS comments_number,
+			tm.upvotes_count,
+			tm.downvotes_count,
+			tm.hot_score,
+			tm.is_pinned,
+			tm.is_locked,
+			tm.approval_state,
+			tm.deleted_by,
+			tm.deletion_date
 		FROM ThreadMessages tm
 		JOIN ThreadGoForum tg ON tm.thread_id = tg.thread_id
 		JOIN Users u ON tm.user_id = u.user_id
 		LEFT JOIN UserConfigs uc ON u.user_id = uc.user_id
-		LEFT JOIN MediaLink ml ON uc.pfp_id = ml.media_id
-		LEFT JOIN (
-			SELECT 
-				message_id,
-				SUM(CASE WHEN is_upvote = 1 THEN 1 ELSE 0 END) AS upvotes,
-				SUM(CASE WHEN is_upvote = 0 THEN 1 ELSE 0 END) AS downvotes
-			FROM ThreadVotes
-			GROUP BY message_id
-		) v ON tm.message_id = v.message_id;
+		LEFT JOIN MediaLink ml ON uc.pfp_id = ml.media_id;
 		`
 	_, err = db.Exec(ViewThreadMessageWithLikesTableSQL)
 	if err != nil {
@@ -3272,30 +9132,26 @@ func InitDatabase() {
 		return
 	}
 
+	// The votes of a comment are counted with the idx_thread_votes_comment index, only for the loaded comments
 	ViewMessageCommentWithLikesTableSQL := `
-		CREATE VIEW IF NOT EXISTS ViewMessageCommentsWithVotes AS
+		CREATE VIEW ViewMessageCommentsWithVotes AS
 		SELECT
 			tc.comment_id,
 			tc.message_id,
 			tc.comment_content,
 			tc.was_edited,
 			tc.creation_date,
+			tc.user_id,
 			u.username,
 			ml.media_address AS pfp_media_address,
-			COALESCE(v.upvotes, 0) AS upvotes,
-			COALESCE(v.downvotes, 0) AS downvotes
+			(SELECT COUNT(*) FROM ThreadVotes v WHERE v.comment_id = tc.comment_id AND v.is_upvote = 1) AS upvotes,
+			(SELECT COUNT(*) FROM ThreadVotes v WHERE v.comment_id = tc.comment_id AND v.is_upvote = 0) AS downvotes,
+			tc.deleted_by,
+			tc.deletion_date
 		FROM ThreadComments tc
 		JOIN Users u ON tc.user_id = u.user_id
 		LEFT JOIN UserConfigs uc ON u.user_id = uc.user_id
-		LEFT JOIN MediaLink ml ON uc.pfp_id = ml.media_id
-		LEFT JOIN (
-			SELECT
-				comment_id,
-				SUM(CASE WHEN is_upvote = 1 THEN 1 ELSE 0 END) AS upvotes,
-				SUM(CASE WHEN is_upvote = 0 THEN 1 ELSE 0 END) AS downvotes
-			FROM ThreadVotes
-			GROUP BY comment_id
-		) v ON tc.comment_id = v.comment_id;
+		LEFT JOIN MediaLink ml ON uc.pfp_id = ml.media_id;
 		`
 	_, err = db.Exec(ViewMessageCommentWithLikesTableSQL)
 	if err != nil {
@@ -3367,6 +9223,12 @@ func InitDatabase() {
 	// Starting the auto delete of the useless media links
 	go AutoDeleteUselessMediaLinks()
 
+	// Starting the auto lift of the expired bans
+	go AutoLiftExpiredBans()
+
+	// Starting the auto purge of the deleted messages and comments
+	go AutoPurgeDeletedContent()
+
 	InfoPrintln("Database initialised")
 }
 
@@ -3419,7 +9281,7 @@ func FillDatabase() {
 	// TODO : fill the database with test data for development testing and demonstration purposes
 
 	// A test thread
-	err := AddThread(User{UserID: 1}, "TestThread", "This is a test thread ! :P  (o_o)")
+	err := AddThread(User{UserID: 1}, "TestThread", "This is a test thread ! :P  (o_o)", DefaultThreadCategory)
 	if err != nil {
 		ErrorPrintf("Error adding thread TestThread: %v\n", err)
 		return
@@ -3441,7 +9303,7 @@ func FillDatabase() {
 	}
 
 	// A test thread with must be connected
-	err = AddThread(User{UserID: 1}, "TestThread2", "This is an other test thread where you must be connected ! (►__◄)")
+	err = AddThread(User{UserID: 1}, "TestThread2", "This is an other test thread where you must be connected ! (►__◄)", "technology")
 	if err != nil {
 		ErrorPrintf("Error adding thread TestThread2: %v\n", err)
 		return
@@ -3455,7 +9317,7 @@ func FillDatabase() {
 	}
 
 	// A test thread with must be a member
-	err = AddThread(User{UserID: 1}, "TestThread3", "This is also an other test thread where you must be a member ! (◕‿-)")
+	err = AddThread(User{UserID: 1}, "TestThread3", "This is also an other test thread where you must be a member ! (◕‿-)", "gaming")
 	if err != nil {
 		ErrorPrintf("Error adding thread TestThread3: %v\n", err)
 		return
@@ -3521,7 +9383,8 @@ func FillDatabase() {
 			fmt.Sprintf("This is a test %d message ", i),
 			User{UserID: (i % 15) + 1},
 			mediaIDs,
-			tagIDs)
+			tagIDs,
+			nil)
 		if err != nil {
 			ErrorPrintf("Error adding fake message %d: %v\n", i, err)
 			return
@@ -0,0 +1,197 @@
+package functions
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestMain runs the tests from the root of the project, where the default media files copied by InitDatabase are
+// The uploads of the tests are stored in a temporary folder removed at the end of the tests
+func TestMain(m *testing.M) {
+	err := os.Chdir("..")
+	if err != nil {
+		fmt.Printf("Error moving to the root of the project: %v\n", err)
+		os.Exit(1)
+	}
+	uploads, err := os.MkdirTemp("", "goforum-uploads-")
+	if err != nil {
+		fmt.Printf("Error creating the uploads folder of the tests: %v\n", err)
+		os.Exit(1)
+	}
+	uploadFolder = uploads
+	InitUploadsDirectory()
+
+	code := m.Run()
+	_ = os.RemoveAll(uploads)
+	os.Exit(code)
+}
+
+// setupTestDatabase opens a new empty database for the test, it is closed at the end of the test
+func setupTestDatabase(tb testing.TB) {
+	tb.Helper()
+	tb.Setenv("DB_NAME", filepath.Join(tb.TempDir(), "test.db"))
+	InitDatabaseConnection()
+	if !databaseInitialised {
+		tb.Fatal("the test database could not be initialised")
+	}
+	tb.Cleanup(CloseDatabase)
+}
+
+// newTestUser adds a user with the given username to the test database
+func newTestUser(tb testing.TB, username string) User {
+	tb.Helper()
+	err := AddUser(username+"@example.com", username, "Test", "User", "Password123!")
+	if err != nil {
+		tb.Fatalf("adding the user %s: %v", username, err)
+	}
+	user, err := GetUserFromUsername(username)
+	if err != nil {
+		tb.Fatalf("getting the user %s: %v", username, err)
+	}
+	return user
+}
+
+// newTestThread adds a thread owned by the user to the test database
+func newTestThread(tb testing.TB, owner User, threadName string) ThreadGoForum {
+	tb.Helper()
+	err := AddThread(owner, threadName, "A thread used by the tests", DefaultThreadCategory)
+	if err != nil {
+		tb.Fatalf("adding the thread %s: %v", threadName, err)
+	}
+	return GetThreadFromName(threadName)
+}
+
+// countPurgedContentRows returns the number of rows of the table depending on the given messages and comments
+func countPurgedContentRows(t *testing.T, table string, messageIDs string, commentIDs string) int {
+	t.Helper()
+	var dependent struct {
+		table     string
+		condition string
+	}
+	for _, d := range purgedContentDependents {
+		if d.table == table {
+			dependent = d
+		}
+	}
+	var count int
+	condition := fmt.Sprintf(dependent.condition, messageIDs, commentIDs)
+	err := db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", table, condition)).Scan(&count)
+	if err != nil {
+		t.Fatalf("counting the rows of %s: %v", table, err)
+	}
+	return count
+}
+
+func TestPurgeDeletedContent(t *testing.T) {
+	setupTestDatabase(t)
+	owner := newTestUser(t, "owner")
+	voter := newTestUser(t, "voter")
+	thread := newTestThread(t, owner, "purge")
+	if err := JoinThread(thread, voter); err != nil {
+		t.Fatalf("joining the thread: %v", err)
+	}
+
+	// The purged message has a comment that was not deleted, both are removed with everything depending on them
+	poll := &NewPoll{Options: []string{"Yes", "No"}, ResultsVisibility: PollResultsAlways}
+	purgedMessage, err := AddMessageInThread(thread, "Purged", "A purged message", owner, nil, nil, poll)
+	if err != nil {
+		t.Fatalf("adding the message: %v", err)
+	}
+	purgedComment, err := AddCommentToPost(owner, purgedMessage, "A comment of the purged message")
+	if err != nil {
+		t.Fatalf("adding the comment: %v", err)
+	}
+	_ = ThreadMessageUpVote(purgedMessage, voter.UserID)
+	_ = MessageCommentUpVote(purgedComment, voter.UserID)
+	_ = SaveContent(voter, purgedMessage, 0, "")
+	_ = SaveContent(voter, 0, purgedComment, "")
+	_ = SubscribeToMessage(purgedMessage, voter)
+
+	// The kept message is not deleted, only its deleted comment is purged
+	keptMessage, err := AddMessageInThread(thread, "Kept", "A kept message", owner, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("adding the message: %v", err)
+	}
+	deletedComment, err := AddCommentToPost(owner, keptMessage, "A deleted comment")
+	if err != nil {
+		t.Fatalf("adding the comment: %v", err)
+	}
+	keptComment, err := AddCommentToPost(owner, keptMessage, "A kept comment")
+	if err != nil {
+		t.Fatalf("adding the comment: %v", err)
+	}
+	_ = MessageCommentUpVote(deletedComment, voter.UserID)
+	_ = MessageCommentUpVote(keptComment, voter.UserID)
+
+	if err = RemoveMessageFromThread(thread, purgedMessage, owner); err != nil {
+		t.Fatalf("removing the message: %v", err)
+	}
+	if err = RemoveCommentFromPost(deletedComment, owner); err != nil {
+		t.Fatalf("removing the comment: %v", err)
+	}
+	expired := fmt.Sprintf("-%d hours", GetRestoreWindow()+1)
+	_, err = db.Exec("UPDATE ThreadMessages SET deletion_date = datetime('now', ?) WHERE deletion_date IS NOT NULL", expired)
+	if err == nil {
+		_, err = db.Exec("UPDATE ThreadComments SET deletion_date = datetime('now', ?) WHERE deletion_date IS NOT NULL", expired)
+	}
+	if err != nil {
+		t.Fatalf("moving the deletions out of the restore window: %v", err)
+	}
+
+	if err = PurgeDeletedContent(); err != nil {
+		t.Fatalf("purging the deleted content: %v", err)
+	}
+
+	purgedMessages := fmt.Sprint(purgedMessage)
+	purgedComments := fmt.Sprintf("%d, %d", purgedComment, deletedComment)
+	for _, dependent := range purgedContentDependents {
+		if count := countPurgedContentRows(t, dependent.table, purgedMessages, purgedComments); count != 0 {
+			t.Errorf("%d rows of %s depend on the purged content, expected none", count, dependent.table)
+		}
+	}
+	keptComments := fmt.Sprint(keptComment)
+	if count := countPurgedContentRows(t, "ThreadMessages", fmt.Sprint(keptMessage), keptComments); count != 1 {
+		t.Errorf("%d kept messages are left, expected 1", count)
+	}
+	if count := countPurgedContentRows(t, "ThreadComments", fmt.Sprint(keptMessage), keptComments); count != 1 {
+		t.Errorf("%d kept comments are left, expected 1", count)
+	}
+	if count := countPurgedContentRows(t, "ThreadVotes", "0", keptComments); count != 1 {
+		t.Errorf("%d votes of the kept comment are left, expected 1", count)
+	}
+}
+
+func TestVoteInPollIsAtomic(t *testing.T) {
+	setupTestDatabase(t)
+	owner := newTestUser(t, "owner")
+	thread := newTestThread(t, owner, "poll")
+	poll := &NewPoll{Options: []string{"Yes", "No"}, IsMultipleChoice: true, ResultsVisibility: PollResultsAlways}
+	messageID, err := AddMessageInThread(thread, "Poll", "A message with a poll", owner, nil, nil, poll)
+	if err != nil {
+		t.Fatalf("adding the message: %v", err)
+	}
+	messagePoll, err := GetMessagePollWithPOV(messageID, owner)
+	if err != nil || messagePoll == nil {
+		t.Fatalf("getting the poll: %v", err)
+	}
+
+	// The second vote for the same option fails, the ballot inserted before it must not be kept
+	option := messagePoll.Options[0].OptionID
+	if err = VoteInPoll(messagePoll.PollID, owner, []int{option, option}); err == nil {
+		t.Fatalf("voting twice for the same option succeeded")
+	}
+	messagePoll, err = GetMessagePollWithPOV(messageID, owner)
+	if err != nil {
+		t.Fatalf("getting the poll: %v", err)
+	}
+	if messagePoll.HasVoted || messagePoll.NumberOfVoters != 0 {
+		t.Errorf("the failed vote was kept: voted %t, %d voters", messagePoll.HasVoted, messagePoll.NumberOfVoters)
+	}
+
+	// The user can still vote as the failed vote left nothing behind
+	if err = VoteInPoll(messagePoll.PollID, owner, []int{option}); err != nil {
+		t.Fatalf("voting after the failed vote: %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package functions
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// ThreadEventType is the type of the events sent to the pages opened on a thread
+type ThreadEventType string
+
+const (
+	ThreadEventMessageCreated ThreadEventType = "message.created" // A message was posted in the thread
+	ThreadEventMessageDeleted ThreadEventType = "message.deleted" // A message was removed from the thread
+	ThreadEventMessageVoted   ThreadEventType = "message.voted"   // The votes of a message changed
+	ThreadEventCommentCreated ThreadEventType = "comment.created" // A comment was posted on a message of the thread
+	ThreadEventCommentDeleted ThreadEventType = "comment.deleted" // A comment was removed from a message of the thread
+	ThreadEventCommentVoted   ThreadEventType = "comment.voted"   // The votes of a comment changed
+)
+
+// ThreadEvent is an event published to the subscribers of a thread
+// Data is the JSON encoded content of the event
+type ThreadEvent struct {
+	Type ThreadEventType
+	Data []byte
+}
+
+// ThreadEventMessageData is the data of the message events
+type ThreadEventMessageData struct {
+	MessageID int    `json:"message_id"`
+	UserName  string `json:"user_name,omitempty"`
+	Upvotes   int    `json:"up_votes"`
+	Downvotes int    `json:"down_votes"`
+}
+
+// ThreadEventCommentData is the data of the comment events
+type ThreadEventCommentData struct {
+	MessageID int    `json:"message_id"`
+	CommentID int    `json:"comment_id"`
+	UserName  string `json:"user_name,omitempty"`
+	Upvotes   int    `json:"up_votes"`
+	Downvotes int    `json:"down_votes"`
+}
+
+// threadEventSubscriberBuffer is the number of events kept for a slow subscriber before the next ones are dropped
+const threadEventSubscriberBuffer = 16
+
+// threadEventHub keeps the subscribers of each thread (by thread id)
+var threadEventHub = struct {
+	sync.RWMutex
+	subscribers map[int]map[chan ThreadEvent]struct{}
+}{subscribers: make(map[int]map[chan ThreadEvent]struct{})}
+
+// SubscribeToThreadEvents subscribes to the events of the thread
+// Returns the channel receiving the events and the function to call to unsubscribe (it closes the channel)
+func SubscribeToThreadEvents(thread ThreadGoForum) (chan ThreadEvent, func()) {
+	events := make(chan ThreadEvent, threadEventSubscriberBuffer)
+	threadEventHub.Lock()
+	if threadEventHub.subscribers[thread.ThreadID] == nil {
+		threadEventHub.subscribers[thread.ThreadID] = make(map[chan ThreadEvent]struct{})
+	}
+	threadEventHub.subscribers[thread.ThreadID][events] = struct{}{}
+	threadEventHub.Unlock()
+
+	unsubscribe := func() {
+		threadEventHub.Lock()
+		delete(threadEventHub.subscribers[thread.ThreadID], events)
+		if len(threadEventHub.subscribers[thread.ThreadID]) == 0 {
+			delete(threadEventHub.subscribers, thread.ThreadID)
+		}
+		threadEventHub.Unlock()
+		close(events)
+	}
+	return events, unsubscribe
+}
+
+// PublishThreadEvent sends the event to every subscriber of the thread
+// It never blocks, the event is dropped for the subscribers that are too slow to read it
+func PublishThreadEvent(thread ThreadGoForum, eventType ThreadEventType, data interface{}) {
+	if thread.ThreadID <= 0 {
+		return
+	}
+	threadEventHub.RLock()
+	defer threadEventHub.RUnlock()
+	if len(threadEventHub.subscribers[thread.ThreadID]) == 0 {
+		return
+	}
+	content, err := json.Marshal(data)
+	if err != nil {
+		ErrorPrintf("Error encoding the thread event: %v\n", err)
+		return
+	}
+	event := ThreadEvent{Type: eventType, Data: content}
+	for subscriber := range threadEventHub.subscribers[thread.ThreadID] {
+		select {
+		case subscriber <- event:
+		default:
+			DebugPrintf("Thread event %s dropped for a slow subscriber of %s\n", eventType, thread.ThreadName)
+		}
+	}
+}
+
+// publishMessageVotes publishes the new vote count of the message
+func publishMessageVotes(messageID int) {
+	message, err := GetMessageByID(messageID)
+	if err != nil {
+		return
+	}
+	PublishThreadEvent(GetThreadFromMessageID(messageID), ThreadEventMessageVoted, ThreadEventMessageData{
+		MessageID: messageID,
+		Upvotes:   message.Upvotes,
+		Downvotes: message.Downvotes,
+	})
+}
+
+// publishCommentVotes publishes the new vote count of the comment
+func publishCommentVotes(commentID int) {
+	comment, err := GetCommentByIDWithPOV(commentID, User{})
+	if err != nil {
+		return
+	}
+	messageID := GetMessageIDFromCommentID(commentID)
+	PublishThreadEvent(GetThreadFromMessageID(messageID), ThreadEventCommentVoted, ThreadEventCommentData{
+		MessageID: messageID,
+		CommentID: commentID,
+		Upvotes:   comment.Upvotes,
+		Downvotes: comment.Downvotes,
+	})
+}
@@ -0,0 +1,210 @@
+package functions
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// ErrWebhookAddressForbidden is returned when a webhook is delivered to an address of the internal network
+var ErrWebhookAddressForbidden = errors.New("the webhook address is not a public address")
+
+// isWebhookIPAllowed checks if the webhooks can be delivered to the ip
+// The loopback, private, link-local, multicast and unspecified addresses are refused so the webhooks cannot reach the internal network
+// It is a variable so the tests can deliver the webhooks to a local server
+var isWebhookIPAllowed = func(ip net.IP) bool {
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	// 0.0.0.0/8 reaches the local host on most systems
+	if ip4 := ip.To4(); ip4 != nil && ip4[0] == 0 {
+		return false
+	}
+	return true
+}
+
+// webhookDialer is the dialer used to deliver the webhooks
+// The address is checked once resolved, so a hostname cannot lead a delivery (or one of its redirections) to the internal network
+var webhookDialer = &net.Dialer{
+	Timeout: 5 * time.Second,
+	Control: func(network string, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		if !isWebhookIPAllowed(net.ParseIP(host)) {
+			return ErrWebhookAddressForbidden
+		}
+		return nil
+	},
+}
+
+// webhookClient is the http client used to deliver the webhooks
+// The timeout prevents a slow receiver from keeping the delivery goroutine alive forever
+// The transport does not use the proxy of the environment, so the address checked by webhookDialer is the one of the receiver
+var webhookClient = &http.Client{
+	Timeout:   10 * time.Second,
+	Transport: &http.Transport{DialContext: webhookDialer.DialContext},
+}
+
+// WebhookPayload is the body sent to the webhooks
+type WebhookPayload struct {
+	Event     WebhookEvent `json:"event"`
+	Thread    string       `json:"thread"`
+	Timestamp time.Time    `json:"timestamp"`
+	Data      interface{}  `json:"data"`
+}
+
+// WebhookMemberData is the data sent with the 'member.joined' and 'member.banned' events
+type WebhookMemberData struct {
+	Username string `json:"username"`
+}
+
+// WebhookCommentData is the data sent with the 'comment.created' event
+type WebhookCommentData struct {
+	MessageID int                     `json:"message_id"`
+	Comment   FormattedMessageComment `json:"comment"`
+}
+
+// WebhookReportData is the data sent with the 'report.created' event
+// The reporter is not sent, CommentID is 0 when a message was reported
+type WebhookReportData struct {
+	ReportType ReportType `json:"report_type"`
+	MessageID  int        `json:"message_id"`
+	CommentID  int        `json:"comment_id,omitempty"`
+}
+
+// getWebhookMaxAttempts returns the maximum number of delivery attempts of a webhook (WEBHOOK_MAX_ATTEMPTS, 5 by default)
+func getWebhookMaxAttempts() int {
+	maxAttempts, err := strconv.Atoi(os.Getenv("WEBHOOK_MAX_ATTEMPTS"))
+	if err != nil || maxAttempts < 1 {
+		return 5
+	}
+	return maxAttempts
+}
+
+// getWebhookRetryDelay returns the delay before the first retry of a webhook delivery (WEBHOOK_RETRY_DELAY in seconds, 10 by default)
+// The delay is doubled after each failed attempt
+func getWebhookRetryDelay() time.Duration {
+	delay, err := strconv.Atoi(os.Getenv("WEBHOOK_RETRY_DELAY"))
+	if err != nil || delay < 1 {
+		return 10 * time.Second
+	}
+	return time.Duration(delay) * time.Second
+}
+
+// SignWebhookPayload returns the HMAC-SHA256 signature of the body with the secret of the webhook
+// The receiver can check the 'X-GoForum-Signature' header by computing the same signature
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// TriggerThreadWebhooks sends the event to every webhook of the thread registered for it
+// The deliveries are made in the background, so this function never blocks the caller
+func TriggerThreadWebhooks(thread ThreadGoForum, event WebhookEvent, data interface{}) {
+	if thread.ThreadID <= 0 {
+		return
+	}
+	webhooks, err := GetThreadWebhooks(thread)
+	if err != nil {
+		ErrorPrintf("Error getting the webhooks of the thread %s: %v\n", thread.ThreadName, err)
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+	body, err := json.Marshal(WebhookPayload{
+		Event:     event,
+		Thread:    thread.ThreadName,
+		Timestamp: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		ErrorPrintf("Error encoding the webhook payload: %v\n", err)
+		return
+	}
+	for _, webhook := range webhooks {
+		if webhook.HasEvent(event) {
+			go deliverWebhook(webhook, event, body)
+		}
+	}
+}
+
+// deliverWebhook sends the body to the webhook until it answers with a 2xx status code
+// Every attempt is logged in the database, the delay between two attempts grows exponentially
+func deliverWebhook(webhook ThreadWebhook, event WebhookEvent, body []byte) {
+	deliveryUUID := uuid.New().String()
+	signature := SignWebhookPayload(webhook.WebhookSecret, body)
+	maxAttempts := getWebhookMaxAttempts()
+	delay := getWebhookRetryDelay()
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		status, err := sendWebhookRequest(webhook.WebhookURL, event, deliveryUUID, signature, body)
+		delivery := WebhookDelivery{
+			WebhookID:      webhook.WebhookID,
+			DeliveryUUID:   deliveryUUID,
+			Event:          event,
+			Attempt:        attempt,
+			ResponseStatus: status,
+		}
+		if err != nil {
+			delivery.ErrorMessage = err.Error()
+		}
+		if dbErr := AddWebhookDelivery(delivery); dbErr != nil {
+			// The webhook may have been removed in the meantime
+			return
+		}
+		if err == nil {
+			DebugPrintf("Webhook %d delivered the event %s (attempt %d)\n", webhook.WebhookID, event, attempt)
+			return
+		}
+		DebugPrintf("Webhook %d failed to deliver the event %s (attempt %d): %v\n", webhook.WebhookID, event, attempt, err)
+		if attempt < maxAttempts {
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+	InfoPrintf("Webhook %d gave up delivering the event %s after %d attempts\n", webhook.WebhookID, event, maxAttempts)
+}
+
+// sendWebhookRequest makes a single delivery attempt
+// Returns the response status code (0 if there was no response) and an error if the delivery failed
+func sendWebhookRequest(webhookURL string, event WebhookEvent, deliveryUUID string, signature string, body []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "GoForum-Webhook")
+	req.Header.Set("X-GoForum-Event", string(event))
+	req.Header.Set("X-GoForum-Delivery", deliveryUUID)
+	req.Header.Set("X-GoForum-Signature", signature)
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		err := resp.Body.Close()
+		if err != nil {
+			ErrorPrintf("Error closing the webhook response body: %v\n", err)
+		}
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,192 @@
+package functions
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// webhookReceiver is a local server receiving the webhook deliveries
+// It answers with the given status codes in order, the last one being repeated
+type webhookReceiver struct {
+	server   *httptest.Server
+	mutex    sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+// newWebhookReceiver starts a webhookReceiver, it is closed at the end of the test
+func newWebhookReceiver(t *testing.T, statuses ...int) *webhookReceiver {
+	t.Helper()
+	receiver := &webhookReceiver{statuses: statuses}
+	receiver.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receiver.mutex.Lock()
+		defer receiver.mutex.Unlock()
+		receiver.requests = append(receiver.requests, r)
+		receiver.bodies = append(receiver.bodies, body)
+		status := receiver.statuses[min(len(receiver.requests), len(receiver.statuses))-1]
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(receiver.server.Close)
+	return receiver
+}
+
+// received returns the number of requests received
+func (receiver *webhookReceiver) received() int {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	return len(receiver.requests)
+}
+
+// allowLocalWebhooks lets the webhooks be delivered to the local webhookReceiver during the test
+func allowLocalWebhooks(t *testing.T) {
+	t.Helper()
+	isAllowed := isWebhookIPAllowed
+	isWebhookIPAllowed = func(ip net.IP) bool { return true }
+	t.Cleanup(func() { isWebhookIPAllowed = isAllowed })
+}
+
+// newTestWebhook registers a webhook to the receiver on a new thread
+func newTestWebhook(t *testing.T, receiver *webhookReceiver) ThreadWebhook {
+	t.Helper()
+	owner := newTestUser(t, "owner")
+	thread := newTestThread(t, owner, "webhooks")
+	_, _, err := AddThreadWebhook(thread, receiver.server.URL, []WebhookEvent{WebhookMessageCreated})
+	if err != nil {
+		t.Fatalf("adding the webhook: %v", err)
+	}
+	webhooks, err := GetThreadWebhooks(thread)
+	if err != nil || len(webhooks) != 1 {
+		t.Fatalf("getting the webhook: %v", err)
+	}
+	return webhooks[0]
+}
+
+// getTestDeliveries returns the delivery attempts of the webhook, the oldest first
+func getTestDeliveries(t *testing.T, webhook ThreadWebhook) []WebhookDelivery {
+	t.Helper()
+	deliveries, err := GetWebhookDeliveries(webhook.WebhookID, 100)
+	if err != nil {
+		t.Fatalf("getting the deliveries: %v", err)
+	}
+	for i, j := 0, len(deliveries)-1; i < j; i, j = i+1, j-1 {
+		deliveries[i], deliveries[j] = deliveries[j], deliveries[i]
+	}
+	return deliveries
+}
+
+func TestIsWebhookURLValid(t *testing.T) {
+	tests := map[string]bool{
+		"https://93.184.215.14/hook":         true,
+		"http://93.184.215.14:8080/hook":     true,
+		"ftp://93.184.215.14/hook":           false,
+		"/hook":                              false,
+		"http://localhost/hook":              false,
+		"http://127.0.0.1/hook":              false,
+		"http://[::1]/hook":                  false,
+		"http://10.1.2.3/hook":               false,
+		"http://192.168.1.1/hook":            false,
+		"http://172.16.0.1/hook":             false,
+		"http://169.254.169.254/latest":      false,
+		"http://0.0.0.0/hook":                false,
+		"http://[fe80::1]/hook":              false,
+		"http://[::ffff:127.0.0.1]/hook":     false,
+		"http://" + strings.Repeat("a", 500): false,
+	}
+	for webhookURL, expected := range tests {
+		if IsWebhookURLValid(webhookURL) != expected {
+			t.Errorf("IsWebhookURLValid(%q) = %t, expected %t", webhookURL, !expected, expected)
+		}
+	}
+}
+
+func TestDeliverWebhookSignature(t *testing.T) {
+	setupTestDatabase(t)
+	allowLocalWebhooks(t)
+	receiver := newWebhookReceiver(t, http.StatusOK)
+	webhook := newTestWebhook(t, receiver)
+
+	body := []byte(`{"event":"message.created"}`)
+	deliverWebhook(webhook, WebhookMessageCreated, body)
+
+	if receiver.received() != 1 {
+		t.Fatalf("received %d requests, expected 1", receiver.received())
+	}
+	request := receiver.requests[0]
+	if string(receiver.bodies[0]) != string(body) {
+		t.Errorf("received the body %s, expected %s", receiver.bodies[0], body)
+	}
+	if signature := request.Header.Get("X-GoForum-Signature"); signature != SignWebhookPayload(webhook.WebhookSecret, body) {
+		t.Errorf("received the signature %q, expected %q", signature, SignWebhookPayload(webhook.WebhookSecret, body))
+	}
+	if event := request.Header.Get("X-GoForum-Event"); event != string(WebhookMessageCreated) {
+		t.Errorf("received the event %q, expected %q", event, WebhookMessageCreated)
+	}
+	deliveries := getTestDeliveries(t, webhook)
+	if len(deliveries) != 1 || deliveries[0].ResponseStatus != http.StatusOK {
+		t.Errorf("logged the deliveries %+v, expected a single successful one", deliveries)
+	}
+}
+
+func TestDeliverWebhookRetry(t *testing.T) {
+	setupTestDatabase(t)
+	allowLocalWebhooks(t)
+	t.Setenv("WEBHOOK_RETRY_DELAY", "1")
+	receiver := newWebhookReceiver(t, http.StatusInternalServerError, http.StatusOK)
+	webhook := newTestWebhook(t, receiver)
+
+	deliverWebhook(webhook, WebhookMessageCreated, []byte(`{}`))
+
+	deliveries := getTestDeliveries(t, webhook)
+	if len(deliveries) != 2 {
+		t.Fatalf("logged %d deliveries, expected 2", len(deliveries))
+	}
+	if deliveries[0].Attempt != 1 || deliveries[0].ResponseStatus != http.StatusInternalServerError || deliveries[0].ErrorMessage == "" {
+		t.Errorf("first delivery is %+v, expected a failed first attempt", deliveries[0])
+	}
+	if deliveries[1].Attempt != 2 || deliveries[1].ResponseStatus != http.StatusOK || deliveries[1].ErrorMessage != "" {
+		t.Errorf("second delivery is %+v, expected a successful second attempt", deliveries[1])
+	}
+	if deliveries[0].DeliveryUUID != deliveries[1].DeliveryUUID {
+		t.Errorf("the attempts have different delivery ids: %s and %s", deliveries[0].DeliveryUUID, deliveries[1].DeliveryUUID)
+	}
+}
+
+func TestDeliverWebhookMaxAttempts(t *testing.T) {
+	setupTestDatabase(t)
+	allowLocalWebhooks(t)
+	t.Setenv("WEBHOOK_RETRY_DELAY", "1")
+	t.Setenv("WEBHOOK_MAX_ATTEMPTS", "2")
+	receiver := newWebhookReceiver(t, http.StatusInternalServerError)
+	webhook := newTestWebhook(t, receiver)
+
+	deliverWebhook(webhook, WebhookMessageCreated, []byte(`{}`))
+
+	if receiver.received() != 2 {
+		t.Errorf("received %d requests, expected 2", receiver.received())
+	}
+	if deliveries := getTestDeliveries(t, webhook); len(deliveries) != 2 {
+		t.Errorf("logged %d deliveries, expected 2", len(deliveries))
+	}
+}
+
+func TestDeliverWebhookToInternalAddress(t *testing.T) {
+	setupTestDatabase(t)
+	receiver := newWebhookReceiver(t, http.StatusOK)
+	webhook := newTestWebhook(t, receiver)
+
+	status, err := sendWebhookRequest(webhook.WebhookURL, WebhookMessageCreated, "uuid", "signature", []byte(`{}`))
+	if !errors.Is(err, ErrWebhookAddressForbidden) || status != 0 {
+		t.Errorf("delivery to %s returned (%d, %v), expected ErrWebhookAddressForbidden", webhook.WebhookURL, status, err)
+	}
+	if receiver.received() != 0 {
+		t.Errorf("received %d requests, expected none", receiver.received())
+	}
+}
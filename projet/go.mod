@@ -11,6 +11,7 @@ require (
 	github.com/markbates/goth v1.81.0
 	github.com/mattn/go-sqlite3 v1.14.27
 	golang.org/x/crypto v0.37.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -20,6 +20,36 @@ document.addEventListener("DOMContentLoaded", function () {
     const rankUpdatePromoteButton = document.getElementById('promote-button');
     const rankUpdateDemoteButton = document.getElementById('demote-button');
 
+    const webhookUrlInput = document.getElementById('webhook-url');
+    const webhookCreateButton = document.getElementById('create-webhook-button');
+    const webhookSecretBox = document.getElementById('webhook-secret-box');
+    const webhookSecret = document.getElementById('webhook-secret');
+
+    const autoHideThresholdInput = document.getElementById('auto-hide-threshold');
+    const autoHideButton = document.getElementById('auto-hide-button');
+    const approvalMinPostsInput = document.getElementById('approval-min-posts');
+    const approvalMinDaysInput = document.getElementById('approval-min-days');
+    const approvalButton = document.getElementById('approval-button');
+    const reputationMinImagesInput = document.getElementById('reputation-min-images');
+    const reputationMinTagsInput = document.getElementById('reputation-min-tags');
+    const reputationMinReportsInput = document.getElementById('reputation-min-reports');
+    const reputationButton = document.getElementById('reputation-button');
+    const categorySelect = document.getElementById('thread-category');
+    const joinPolicySelect = document.getElementById('join-policy');
+    const isPrivateCheckbox = document.getElementById('is-private');
+    const accessButton = document.getElementById('access-button');
+    const categoryButton = document.getElementById('category-button');
+
+    const wordFilterWordInput = document.getElementById('word-filter-word');
+    const wordFilterActionSelect = document.getElementById('word-filter-action');
+    const wordFilterAddButton = document.getElementById('add-word-filter-button');
+
+    const roleNameInput = document.getElementById('role-name');
+    const roleCreateButton = document.getElementById('create-role-button');
+    const roleMemberInput = document.getElementById('role-member');
+    const roleSelect = document.getElementById('role-select');
+    const roleAssignButton = document.getElementById('assign-role-button');
+
     function renderTags() {
         tagList.innerHTML = '';
         editTagList.innerHTML = '';
@@ -167,6 +197,11 @@ document.addEventListener("DOMContentLoaded", function () {
             errorDiv.textContent = err.message;
         });
 
+    // The other settings are only shown to the users allowed to manage the thread
+    if (!threadIconInput) {
+        return;
+    }
+
     threadIconInput.addEventListener('change', function () {
         const file = this.files[0];
         if (file) {
@@ -278,4 +313,203 @@ document.addEventListener("DOMContentLoaded", function () {
                 });
         }
     });
-});
\ No newline at end of file
+
+    webhookCreateButton.addEventListener('click', function () {
+        const url = webhookUrlInput.value.trim();
+        const events = Array.from(document.querySelectorAll('.webhook-event:checked')).map(input => input.value);
+        if (!url) {
+            return;
+        }
+        if (events.length === 0) {
+            alert(getI18nText("webhook_no_event_message"));
+            return;
+        }
+        createWebhook(threadName, url, events)
+            .then(response => {
+                if (!response.ok) throw new Error(getI18nText("webhook_create_failed_message"));
+                return response.json();
+            })
+            .then(data => {
+                // The secret is only given once, so we show it until the page is reloaded
+                webhookUrlInput.value = '';
+                webhookSecret.textContent = data.secret;
+                webhookSecretBox.classList.remove('hidden');
+            })
+            .catch(err => {
+                console.error('Failed to create webhook:', err);
+                alert(err.message);
+            });
+    });
+
+    document.querySelectorAll('.webhook-delete-button').forEach(btn => {
+        btn.addEventListener('click', function () {
+            const webhookId = parseInt(this.dataset.webhookId);
+            deleteWebhook(threadName, webhookId)
+                .then(response => {
+                    if (!response.ok) throw new Error(getI18nText("webhook_delete_failed_message"));
+                    document.querySelector(`.webhook[data-webhook-id="${webhookId}"]`).remove();
+                })
+                .catch(err => {
+                    console.error('Failed to delete webhook:', err);
+                    alert(err.message);
+                });
+        });
+    });
+
+    // The custom roles are only shown to the users allowed to manage them
+    if (roleCreateButton) {
+        roleCreateButton.addEventListener('click', function () {
+            const roleName = roleNameInput.value.trim();
+            const permissions = Array.from(document.querySelectorAll('.role-permission:checked')).map(input => input.value);
+            if (!roleName) {
+                return;
+            }
+            createRole(threadName, roleName, permissions)
+                .then(response => {
+                    if (!response.ok) throw new Error(getI18nText("role_create_failed_message"));
+                    window.location.reload();
+                })
+                .catch(err => {
+                    console.error('Failed to create role:', err);
+                    alert(err.message);
+                });
+        });
+
+        document.querySelectorAll('.role-delete-button').forEach(btn => {
+            btn.addEventListener('click', function () {
+                const roleId = parseInt(this.dataset.roleId);
+                deleteRole(threadName, roleId)
+                    .then(response => {
+                        if (!response.ok) throw new Error(getI18nText("role_delete_failed_message"));
+                        window.location.reload();
+                    })
+                    .catch(err => {
+                        console.error('Failed to delete role:', err);
+                        alert(err.message);
+                    });
+            });
+        });
+
+        roleAssignButton.addEventListener('click', function () {
+            const username = roleMemberInput.value.trim();
+            if (!username) {
+                return;
+            }
+            setMemberRole(threadName, username, parseInt(roleSelect.value))
+                .then(response => {
+                    if (!response.ok) throw new Error(getI18nText("role_assign_failed_message"));
+                    alert(getI18nText("role_assign_success_message"));
+                    window.location.reload();
+                })
+                .catch(err => {
+                    console.error('Failed to set the role of the member:', err);
+                    alert(err.message);
+                });
+        });
+    }
+
+    autoHideButton.addEventListener('click', function () {
+        const threshold = parseInt(autoHideThresholdInput.value);
+        if (isNaN(threshold) || threshold < 0 || threshold > 100) {
+            alert(getI18nText("auto_hide_failed_message"));
+            return;
+        }
+        setAutoHideThreshold(threadName, threshold)
+            .then(response => {
+                if (!response.ok) throw new Error(getI18nText("auto_hide_failed_message"));
+                alert(getI18nText("auto_hide_success_message"));
+            })
+            .catch(err => {
+                console.error('Failed to set the auto hide threshold:', err);
+                alert(err.message);
+            });
+    });
+
+    approvalButton.addEventListener('click', function () {
+        const minPosts = parseInt(approvalMinPostsInput.value);
+        const minDays = parseInt(approvalMinDaysInput.value);
+        if (isNaN(minPosts) || minPosts < 0 || minPosts > 1000 || isNaN(minDays) || minDays < 0 || minDays > 365) {
+            alert(getI18nText("approval_failed_message"));
+            return;
+        }
+        setApprovalSettings(threadName, minPosts, minDays)
+            .then(response => {
+                if (!response.ok) throw new Error(getI18nText("approval_failed_message"));
+                alert(getI18nText("approval_success_message"));
+            })
+            .catch(err => {
+                console.error('Failed to set the approval settings:', err);
+                alert(err.message);
+            });
+    });
+
+    reputationButton.addEventListener('click', function () {
+        const thresholds = [reputationMinImagesInput, reputationMinTagsInput, reputationMinReportsInput].map(input => parseInt(input.value));
+        if (thresholds.some(threshold => isNaN(threshold) || threshold < 0 || threshold > 100000)) {
+            alert(getI18nText("reputation_failed_message"));
+            return;
+        }
+        setReputationSettings(threadName, thresholds[0], thresholds[1], thresholds[2])
+            .then(response => {
+                if (!response.ok) throw new Error(getI18nText("reputation_failed_message"));
+                alert(getI18nText("reputation_success_message"));
+            })
+            .catch(err => {
+                console.error('Failed to set the reputation settings:', err);
+                alert(err.message);
+            });
+    });
+
+    accessButton.addEventListener('click', function () {
+        setAccessSettings(threadName, joinPolicySelect.value, isPrivateCheckbox.checked)
+            .then(response => {
+                if (!response.ok) throw new Error(getI18nText("access_failed_message"));
+                alert(getI18nText("access_success_message"));
+            })
+            .catch(err => {
+                console.error('Failed to set the access settings:', err);
+                alert(err.message);
+            });
+    });
+
+    categoryButton.addEventListener('click', function () {
+        setThreadCategory(threadName, categorySelect.value)
+            .then(response => {
+                if (!response.ok) throw new Error(getI18nText("category_failed_message"));
+                alert(getI18nText("category_success_message"));
+            })
+            .catch(err => {
+                console.error('Failed to set the thread category:', err);
+                alert(err.message);
+            });
+    });
+
+    wordFilterAddButton.addEventListener('click', function () {
+        const word = wordFilterWordInput.value.trim();
+        if (word === '') return;
+        addWordFilter(threadName, word, wordFilterActionSelect.value)
+            .then(response => {
+                if (!response.ok) throw new Error(getI18nText("word_filter_add_failed_message"));
+                window.location.reload();
+            })
+            .catch(err => {
+                console.error('Failed to add the word filter:', err);
+                alert(err.message);
+            });
+    });
+
+    document.querySelectorAll('.word-filter-remove-button').forEach(btn => {
+        btn.addEventListener('click', function () {
+            const filterId = parseInt(this.dataset.filterId);
+            removeWordFilter(threadName, filterId)
+                .then(response => {
+                    if (!response.ok) throw new Error(getI18nText("word_filter_remove_failed_message"));
+                    document.querySelector(`.word-filter[data-filter-id="${filterId}"]`).remove();
+                })
+                .catch(err => {
+                    console.error('Failed to remove the word filter:', err);
+                    alert(err.message);
+                });
+        });
+    });
+});
@@ -12,14 +12,14 @@ document.addEventListener("DOMContentLoaded", function () {
     const threadName = getCurrentThreadName()
     let userIsAuthenticated = document.getElementById("isAuthenticated").textContent === "true";
     let userIsAMember = document.getElementById("isAMember").textContent === "true";
-    let userRank = parseInt(document.getElementById("userRank").textContent,10);
-    let userIsModerator = userRank >= 1;
-    let userIsAdmin = userRank >= 2;
-    let userIsThreadOwner = userRank >= 3;
-    let offset= 0;
+    let userPermissions = document.getElementById("userPermissions").textContent.trim().split(" ");
+    const lastReadMessageId = parseInt(document.getElementById("lastReadMessageId").textContent, 10);
+    let cursor = "";
     let hasReachedEnd = false;
     let orderSelect = document.getElementById("order")
     const postsContainer = document.getElementById("posts-container");
+    const newPostsBanner = document.getElementById("new-posts-banner");
+    let newPostsCount = 0;
     let selectedTags = [];
 
     const tagListContainer = document.getElementById('tagList');
@@ -33,6 +33,11 @@ document.addEventListener("DOMContentLoaded", function () {
     const newPostfileInput = document.getElementById("new-post-file-input")
     const newPostImagesPreview = document.getElementById("new-post-medias-container")
     const newPostTagsContainer = document.getElementById("new-post-tags-container");
+    const newPostPoll = document.getElementById("new-post-poll");
+    const newPostPollOptions = document.getElementById("new-post-poll-options");
+    const newPostPollMultiple = document.getElementById("new-post-poll-multiple");
+    const newPostPollVisibility = document.getElementById("new-post-poll-visibility");
+    const newPostPollClosingDate = document.getElementById("new-post-poll-closing-date");
     let MediaIDs = [];
     let titleValid = false;
     let contentValid = false;
@@ -64,6 +69,9 @@ document.addEventListener("DOMContentLoaded", function () {
     let editedPostID = null;
     const editMenuMediasPreview = document.getElementById("edit-post-medias-container");
 
+    // Ban menu (only on the page of the users allowed to ban)
+    const showBanMenu = setupBanMenu(threadName);
+
     /**
      * Show the report menu for a message.
      * @description This function displays the report menu and sets the message ID to report.
@@ -123,7 +131,7 @@ document.addEventListener("DOMContentLoaded", function () {
         if (hasReachedEnd) {
             return;
         }
-        let res = getMessage(threadName, offset, orderSelect.value, selectedTags);
+        let res = getMessage(threadName, cursor, orderSelect.value, selectedTags);
         res.then(async (response) => {
             if (response.ok) {
                 const data = await response.json();
@@ -139,7 +147,12 @@ document.addEventListener("DOMContentLoaded", function () {
                     const postElement = createNewPost(post);
                     postsContainer.appendChild(postElement);
                 }
-                offset += data.length;
+                cursor = response.headers.get("X-Next-Cursor") || "";
+                if (cursor === "") {
+                    hasReachedEnd = true;
+                    loadMorePostsButton.disabled = true;
+                    loadMorePostsButton.innerText = "No more posts";
+                }
             } else {
                 console.error(response);
             }
@@ -198,7 +211,7 @@ document.addEventListener("DOMContentLoaded", function () {
                         }
                         postsContainer.innerHTML = "";
                         hasReachedEnd = false;
-                        offset = 0;
+                        cursor = "";
                         // Reload more messages
                         loadMorePosts();
                     });
@@ -256,6 +269,26 @@ document.addEventListener("DOMContentLoaded", function () {
         let isPostOwner = data.user_name === document.getElementById("username").textContent;
 
         container.classList.add("post-box", "win95-border");
+        container.dataset.messageId = data.message_id;
+        // Highlight the messages posted since the last visit of the user
+        if (lastReadMessageId > 0 && data.message_id > lastReadMessageId && !isPostOwner) {
+            container.classList.add("post-unread");
+        }
+        // The messages hidden by their reports are only sent to the moderation team
+        if (data.is_hidden) {
+            container.classList.add("post-hidden");
+        }
+        // The messages out of the approval queue are only sent to their author and the moderation team
+        if (data.approval_state === "pending" || data.approval_state === "rejected") {
+            container.classList.add("post-pending");
+        }
+        // The deleted messages are shown as a tombstone until they are purged
+        if (data.deletion_state) {
+            container.classList.add("post-deleted");
+        }
+        if (data.is_pinned) {
+            container.classList.add("post-pinned");
+        }
 
         postHeader.classList.add("post-header", "win95-header");
         container.appendChild(postHeader);
@@ -281,11 +314,37 @@ document.addEventListener("DOMContentLoaded", function () {
             window.location.href = `/profile/${data.user_name}`
         }
         authorAndTime.appendChild(author);
+        authorAndTime.appendChild(createUserBadges(data.user_badges));
 
         time.classList.add("time-ago");
         time.innerText = ` - ${timeAgo(data.creation_date)}`;
         authorAndTime.appendChild(time);
 
+        if (data.is_hidden) {
+            const hiddenLabel = document.createElement("span");
+            hiddenLabel.classList.add("hidden-content-label");
+            hiddenLabel.innerText = getI18nText("hidden-content-label");
+            authorAndTime.appendChild(hiddenLabel);
+        }
+        if (data.approval_state === "pending" || data.approval_state === "rejected") {
+            const approvalLabel = document.createElement("span");
+            approvalLabel.classList.add("pending-content-label");
+            approvalLabel.innerText = getI18nText(`${data.approval_state}-content-label`);
+            authorAndTime.appendChild(approvalLabel);
+        }
+        if (data.is_pinned) {
+            const pinnedLabel = document.createElement("span");
+            pinnedLabel.classList.add("pinned-content-label");
+            pinnedLabel.innerText = getI18nText("pinned-content-label");
+            authorAndTime.appendChild(pinnedLabel);
+        }
+        if (data.is_locked) {
+            const lockedLabel = document.createElement("span");
+            lockedLabel.classList.add("locked-content-label");
+            lockedLabel.innerText = getI18nText("locked-content-label");
+            authorAndTime.appendChild(lockedLabel);
+        }
+
         option.classList.add();
         postHeader.appendChild(option)
 
@@ -322,13 +381,50 @@ document.addEventListener("DOMContentLoaded", function () {
                 <img src="/img/ban.png" alt="" class="win95-minor-logo unselectable" draggable="false">
                 <span>${getI18nText("option-menu-ban-button-text")}</span>
             </li>`
+
+        let optionMenuRestoreButtonHTML = `
+            <li class="win95-menu-button message-restore menu-button" id="post-restore-button-p${data.message_id}">
+                <img src="/img/edit.png" alt="" class="win95-minor-logo unselectable" draggable="false">
+                <span>${getI18nText("option-menu-restore-button-text")}</span>
+            </li>`
+
+        let optionMenuSaveButtonHTML = `
+            <li class="win95-menu-button message-save menu-button" id="post-save-button-p${data.message_id}">
+                <img src="/img/edit.png" alt="" class="win95-minor-logo unselectable" draggable="false">
+                <span>${getI18nText(data.saved ? "option-menu-unsave-button-text" : "option-menu-save-button-text")}</span>
+            </li>`
+
+        const pinAction = data.is_pinned ? "unpinMessage" : "pinMessage";
+        const lockAction = data.is_locked ? "unlockMessage" : "lockMessage";
+        let optionMenuPinButtonHTML = `
+            <li class="win95-menu-button message-pin menu-button" id="post-pin-button-p${data.message_id}">
+                <img src="/img/edit.png" alt="" class="win95-minor-logo unselectable" draggable="false">
+                <span>${getI18nText(`option-menu-${pinAction}-button-text`)}</span>
+            </li>`
+
+        let optionMenuLockButtonHTML = `
+            <li class="win95-menu-button message-lock menu-button" id="post-lock-button-p${data.message_id}">
+                <img src="/img/edit.png" alt="" class="win95-minor-logo unselectable" draggable="false">
+                <span>${getI18nText(`option-menu-${lockAction}-button-text`)}</span>
+            </li>`
         let additionalButtonsHTML = "";
         let showReportButton = false;
         let showEditButton = false;
         let showDeleteButton = false;
         let showBanButton = false;
-
-        if (userIsAuthenticated) { // If the user is authenticated he can see the option menu
+        let showRestoreButton = false;
+        let showPinAndLockButtons = false;
+        let showSaveButton = false;
+
+        if (data.deletion_state) { // A deleted message can only be restored
+            if (data.can_restore) {
+                additionalButtonsHTML += optionMenuRestoreButtonHTML;
+                showRestoreButton = true;
+            }
+        } else if (userIsAuthenticated) { // If the user is authenticated he can see the option menu
+            // Every authenticated user can save a post for later
+            additionalButtonsHTML += optionMenuSaveButtonHTML;
+            showSaveButton = true;
             if (!isPostOwner) { // If the user is authenticated he can report a post (exept his posts)
                 additionalButtonsHTML += optionMenuReportButtonHTML;
                 showReportButton = true;
@@ -337,14 +433,18 @@ document.addEventListener("DOMContentLoaded", function () {
                 additionalButtonsHTML += optionMenuEditButtonHTML;
                 showEditButton = true;
             }
-            if (isPostOwner || userIsModerator || userIsAdmin || userIsAdmin) { // If the user is the owner of the post or his rank is moderator or higher he can delete it
+            if (isPostOwner || userPermissions.includes("messages.delete")) { // If the user is the owner of the post or is allowed to delete the others ones he can delete it
                 additionalButtonsHTML += optionMenuDeleteButtonHTML;
                 showDeleteButton = true;
             }
-            if ((userIsThreadOwner || userIsAdmin) && !isPostOwner) { // If the user rank is admin or higher he can ban the user (exept himself)
+            if (userPermissions.includes("users.ban") && !isPostOwner) { // If the user is allowed to ban he can ban the user (exept himself)
                 additionalButtonsHTML += optionMenuBanButtonHTML;
                 showBanButton = true;
             }
+            if (userPermissions.includes("messages.pin")) { // If the user is allowed to pin he can pin and lock the posts
+                additionalButtonsHTML += optionMenuPinButtonHTML + optionMenuLockButtonHTML;
+                showPinAndLockButtons = true;
+            }
         }
 
         // All user can report a post
@@ -365,6 +465,23 @@ document.addEventListener("DOMContentLoaded", function () {
             });
         }
 
+        // Add the event listener to the save button, the text follows the saved state
+        if (showSaveButton) {
+            const saveButton = optionMenu.querySelector(`#post-save-button-p${data.message_id}`);
+            saveButton.addEventListener("click", function() {
+                updateSavedMessage(threadName, data.message_id, !data.saved).then((response) => {
+                    if (response.ok) {
+                        data.saved = !data.saved;
+                        saveButton.querySelector("span").innerText = getI18nText(data.saved ? "option-menu-unsave-button-text" : "option-menu-save-button-text");
+                        optionMenu.classList.remove("active");
+                    } else {
+                        alert("Error while saving post : " + response.statusText);
+                        console.error(response);
+                    }
+                });
+            });
+        }
+
         // Add the event listener to the delete button
         if (showDeleteButton) {
             const deleteButton = optionMenu.querySelector(`#post-delete-button-p${data.message_id}`);
@@ -383,6 +500,39 @@ document.addEventListener("DOMContentLoaded", function () {
             });
         }
 
+        // Add the event listeners to the pin and lock buttons
+        if (showPinAndLockButtons) {
+            const pinButton = optionMenu.querySelector(`#post-pin-button-p${data.message_id}`);
+            const lockButton = optionMenu.querySelector(`#post-lock-button-p${data.message_id}`);
+            [[pinButton, pinAction], [lockButton, lockAction]].forEach(([button, action]) => {
+                button.addEventListener("click", function() {
+                    pinOrLockMessage(threadName, action, data.message_id).then((response) => {
+                        if (response.ok) {
+                            window.location.reload();
+                        } else {
+                            alert("Error while updating post : " + response.statusText);
+                            console.error(response);
+                        }
+                    });
+                });
+            });
+        }
+
+        // Add the event listener to the restore button
+        if (showRestoreButton) {
+            const restoreButton = optionMenu.querySelector(`#post-restore-button-p${data.message_id}`);
+            restoreButton.addEventListener("click", function() {
+                restoreMessage(threadName, data.message_id).then((response) => {
+                    if (response.ok) {
+                        window.location.reload();
+                    } else {
+                        alert("Error while restoring post : " + response.statusText);
+                        console.error(response);
+                    }
+                });
+            });
+        }
+
         // Add the event listener to the report button
         if (showReportButton) {
             const reportButton = optionMenu.querySelector(`#post-report-button-p${data.message_id}`);
@@ -397,16 +547,7 @@ document.addEventListener("DOMContentLoaded", function () {
             const banButton = optionMenu.querySelector(`#post-ban-button-p${data.message_id}`);
             banButton.addEventListener("click", function() {
                 console.log(`Ban button clicked for post ${data.message_id}`);
-                const result = banUser(threadName, data.user_name);
-                result.then(async (response) => {
-                    if (response.ok) {
-                        alert("User banned successfully");
-                        console.log("User banned successfully");
-                    } else {
-                        alert("Error while banning user : " + response.statusText);
-                        console.error(response);
-                    }
-                });
+                showBanMenu(data.user_name);
             });
         }
 
@@ -449,6 +590,10 @@ document.addEventListener("DOMContentLoaded", function () {
         postDescription.innerText = data.message_content;
         container.appendChild(postDescription);
 
+        if (data.poll) {
+            container.appendChild(createPollElement(threadName, data.message_id, data.poll, userIsAuthenticated && !data.is_locked));
+        }
+
         tags.classList.add("tag-container");
         container.appendChild(tags);
 
@@ -546,7 +691,7 @@ document.addEventListener("DOMContentLoaded", function () {
 
         upvoteButton.type = "button";
         upvoteButton.classList.add("win95-button", "post-vote-button");
-        if (userIsAuthenticated) {
+        if (userIsAuthenticated && !data.is_locked) { // The locked posts cannot be voted
             upvoteButton.addEventListener("click", function () {
                 upvoteMessage(threadName, data.message_id)
                     .then(r => {
@@ -584,7 +729,7 @@ document.addEventListener("DOMContentLoaded", function () {
 
         downvoteButton.type = "button";
         downvoteButton.classList.add("win95-button", "post-vote-button");
-        if (userIsAuthenticated) {
+        if (userIsAuthenticated && !data.is_locked) { // The locked posts cannot be voted
             downvoteButton.addEventListener("click", function () {
                 downvoteMessage(threadName, data.message_id)
                     .then(r => {
@@ -628,6 +773,12 @@ document.addEventListener("DOMContentLoaded", function () {
         container.appendChild(messageID);
         container.appendChild(br);
 
+        // Used by the live updates to show the votes of the other users
+        container.updateVotes = function (upVotes, downVotes) {
+            currentVoteCount = upVotes - downVotes;
+            vote.innerText = currentVoteCount;
+        };
+
         return container;
     }
 
@@ -637,7 +788,10 @@ document.addEventListener("DOMContentLoaded", function () {
             const result = leaveThread(threadName);
             result.then(async (response) => {
                 if (response.ok) {
-                    joinButton.classList.remove("hidden");
+                    // An invite only thread can not be joined again without an invite
+                    if (joinButton.dataset.joinPolicy !== "invite") {
+                        joinButton.classList.remove("hidden");
+                    }
                     leaveButton.classList.add("hidden");
                     newPostContainer.classList.add("hidden");
                     console.log("You have left the thread");
@@ -653,6 +807,13 @@ document.addEventListener("DOMContentLoaded", function () {
             const result = joinThread(threadName)
             result.then(async (response) => {
                 if (response.ok) {
+                    // The threads with the 'request' join policy are only joined once the request is approved
+                    const data = await response.json();
+                    if (data.status === "requested") {
+                        joinButton.innerText = getI18nText("join-requested");
+                        joinButton.disabled = true;
+                        return;
+                    }
                     joinButton.classList.add("hidden");
                     leaveButton.classList.remove("hidden");
                     newPostContainer.classList.remove("hidden");
@@ -673,6 +834,12 @@ document.addEventListener("DOMContentLoaded", function () {
             window.location = `/t/${threadName}/reports`;
         });
     }
+    bindSubscriptionButtons({
+        subscribe: document.getElementById("SubscribeThreadButton"),
+        unsubscribe: document.getElementById("UnsubscribeThreadButton"),
+        mute: document.getElementById("MuteThreadButton"),
+        unmute: document.getElementById("UnmuteThreadButton"),
+    }, (action) => updateThreadSubscription(threadName, `${action}Thread`));
 
 
 
@@ -684,11 +851,63 @@ document.addEventListener("DOMContentLoaded", function () {
        // Empty the posts container
         postsContainer.innerHTML = "";
         hasReachedEnd = false;
-        offset = 0;
+        cursor = "";
         // Reload more messages
         loadMorePosts();
     });
 
+    /**
+     * Show the number of posts sent since the posts were loaded.
+     * @description The banner reloads the posts when clicked.
+     * @returns {void}
+     */
+    function updateNewPostsBanner() {
+        if (newPostsCount === 0) {
+            newPostsBanner.classList.add("hidden");
+            return;
+        }
+        newPostsBanner.innerText = newPostsCount === 1 ? getI18nText("new-post-banner") : getI18nText("new-posts-banner", newPostsCount);
+        newPostsBanner.classList.remove("hidden");
+    }
+
+    newPostsBanner.addEventListener('click', function() {
+        newPostsCount = 0;
+        updateNewPostsBanner();
+        postsContainer.innerHTML = "";
+        hasReachedEnd = false;
+        loadMorePostsButton.disabled = false;
+        cursor = "";
+        loadMorePosts();
+    });
+
+    // Live updates of the thread, only when its content is shown
+    if (document.getElementById("showContent").textContent === "true") {
+        const threadEvents = subscribeToThreadEvents(threadName);
+        threadEvents.addEventListener("message.created", function (e) {
+            const data = JSON.parse(e.data);
+            // The posts of the user are already reloaded when he sends them
+            if (data.user_name === document.getElementById("username").textContent) {
+                return;
+            }
+            newPostsCount++;
+            updateNewPostsBanner();
+        });
+        threadEvents.addEventListener("message.deleted", function (e) {
+            const data = JSON.parse(e.data);
+            const post = postsContainer.querySelector(`.post-box[data-message-id="${data.message_id}"]`);
+            if (post) {
+                post.remove();
+            }
+        });
+        threadEvents.addEventListener("message.voted", function (e) {
+            const data = JSON.parse(e.data);
+            const post = postsContainer.querySelector(`.post-box[data-message-id="${data.message_id}"]`);
+            if (post) {
+                post.updateVotes(data.up_votes, data.down_votes);
+            }
+        });
+    }
+
     /**
      * Load tags from the server.
      * @description This function sends a request to get the tags for the current thread.
@@ -773,6 +992,35 @@ document.addEventListener("DOMContentLoaded", function () {
         }
     });
 
+    /**
+     * Get the poll to attach to the new post.
+     * @description The poll is only attached when its section is open and has options (one per line).
+     * @returns {{options: string[], multipleChoice: boolean, resultsVisibility: string, closingDate: string}|null} - The poll or null.
+     */
+    function getNewPostPoll() {
+        const options = newPostPollOptions.value.split("\n").map(option => option.trim()).filter(option => option !== "");
+        if (!newPostPoll.open || options.length === 0) {
+            return null;
+        }
+        return {
+            options: options,
+            multipleChoice: newPostPollMultiple.checked,
+            resultsVisibility: newPostPollVisibility.value,
+            closingDate: newPostPollClosingDate.value ? new Date(newPostPollClosingDate.value).toISOString() : ""
+        };
+    }
+
+    /**
+     * Clear the poll fields of the new post.
+     */
+    function clearNewPostPoll() {
+        newPostPoll.open = false;
+        newPostPollOptions.value = "";
+        newPostPollMultiple.checked = false;
+        newPostPollVisibility.value = "always";
+        newPostPollClosingDate.value = "";
+    }
+
     newPostButton.addEventListener("click", function() {
         if (!userIsAuthenticated) {
             alert("You must be logged in to create a post.");
@@ -782,12 +1030,13 @@ document.addEventListener("DOMContentLoaded", function () {
             alert("You must be a member of the thread to create a post.");
             return;
         }
-        sendMessage(threadName, newPostTitle.value, newPostContent.value, MediaIDs, newPostTags)
+        sendMessage(threadName, newPostTitle.value, newPostContent.value, MediaIDs, newPostTags, getNewPostPoll())
             .then(r => {
                 if (r.ok) {
                     return r.json();
                 } else {
-                    throw new Error("Error while sending message");
+                    // The server explains why the message was refused (content filters, invalid poll...)
+                    return r.text().then(text => { throw new Error(text || "Error while sending message"); });
                 }
             })
             .then(
@@ -804,6 +1053,7 @@ document.addEventListener("DOMContentLoaded", function () {
                             tag.classList.toggle('selected');
                         }
                     }
+                    clearNewPostPoll();
                     MediaIDs = [];
                     while (newPostImagesPreview.firstChild) {
                         newPostImagesPreview.removeChild(newPostImagesPreview.firstChild);
@@ -812,12 +1062,12 @@ document.addEventListener("DOMContentLoaded", function () {
                     newPostImagesPreview.innerHTML = "";
                     postsContainer.innerHTML = "";
                     hasReachedEnd = false;
-                    offset = 0;
+                    cursor = "";
                     // Reload more messages
                     loadMorePosts();
                 }
             ).catch(error => {
-                alert("An error occurred while sending message");
+                alert(error.message);
                 console.error("Error:", error);
         });
     });
@@ -871,7 +1121,7 @@ document.addEventListener("DOMContentLoaded", function () {
                     hideEditMenu();
                     postsContainer.innerHTML = "";
                     hasReachedEnd = false;
-                    offset = 0;
+                    cursor = "";
                     // Reload more messages
                     loadMorePosts();
                 } else {
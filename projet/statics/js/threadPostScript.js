@@ -19,13 +19,13 @@ document.addEventListener("DOMContentLoaded", function (){
 
     const threadName = getCurrentThreadName();
     const messageId = document.getElementById("data_postID").textContent;
+    const showBanMenu = setupBanMenu(threadName);
     let userIsAuthenticated = document.getElementById("isAuthenticated").textContent === "true";
     let userIsAMember = document.getElementById("isAMember").textContent === "true";
-    let userRank = parseInt(document.getElementById("userRank").textContent,10);
-    let userIsModerator = userRank >= 1;
-    let userIsAdmin = userRank >= 2;
-    let userIsThreadOwner = userRank >= 3;
-    let offset= 0;
+    let userPermissions = document.getElementById("userPermissions").textContent.trim().split(" ");
+    // The locked posts cannot be commented nor voted anymore
+    let postIsLocked = document.getElementById("isLocked").textContent === "true";
+    let cursor = "";
     let hasReachedEnd = false;
     const commentsContainer = document.getElementById("comments-container");
 
@@ -56,6 +56,12 @@ document.addEventListener("DOMContentLoaded", function (){
     const editCommentMenuSendButton = document.getElementById("edit-comment-send-button");
     let editedCommentID = null;
 
+    // Revisions menu elements
+    const revisionsMenu = document.getElementById("revisions-menu");
+    const revisionsMenuBackground = revisionsMenu.getElementsByClassName("full-screens-menu-background")[0];
+    const revisionsMenuCloseButton = document.getElementById("close-revisions-menu");
+    const revisionsList = document.getElementById("revisions-list");
+
     /**
      * Show the report menu for a message.
      * @description This function displays the report menu and sets the message ID to report.
@@ -108,6 +114,69 @@ document.addEventListener("DOMContentLoaded", function (){
         editCommentMenuSendButton.disabled = true;
     }
 
+    /**
+     * Fill an element with the parts of a diff.
+     * @description The added parts are shown in <ins> and the removed parts in <del>.
+     * @param element {HTMLElement} - The element to fill.
+     * @param parts {{type: string, text: string}[]} - The parts of the diff.
+     */
+    function renderDiff(element, parts) {
+        for (const part of parts) {
+            const partElement = document.createElement(part.type === "added" ? "ins" : part.type === "removed" ? "del" : "span");
+            partElement.textContent = part.text;
+            element.appendChild(partElement);
+        }
+    }
+
+    /**
+     * Show the revisions menu with the edit history of a message or a comment.
+     * @description The versions are shown from the newest to the oldest, each one with the changes since the previous one.
+     * @param request {Promise<Response>} - The request getting the versions (see getMessageRevisions and getCommentRevisions).
+     */
+    function showRevisionsMenu(request) {
+        revisionsMenu.classList.remove("hidden");
+        scrollbar.classList.add("hidden");
+        revisionsList.innerHTML = "";
+        request.then(response => {
+            if (!response.ok) throw new Error(response.statusText);
+            return response.json();
+        }).then(revisions => {
+            for (const revision of revisions.reverse()) {
+                const revisionBox = document.createElement("div");
+                const revisionHeader = document.createElement("p");
+                const revisionTitle = document.createElement("p");
+                const revisionContent = document.createElement("p");
+                revisionBox.classList.add("revision", "win95-border");
+
+                let label = `${getI18nText("revisions-revision")} ${revision.revision_number}`;
+                if (revision.revision_number === 1) label += ` (${getI18nText("revisions-original")})`;
+                if (revision.is_current) label += ` (${getI18nText("revisions-current")})`;
+                revisionHeader.classList.add("revision-header");
+                revisionHeader.textContent = `${label} - ${timeAgo(revision.revision_date)}`;
+                revisionBox.appendChild(revisionHeader);
+
+                if (revision.title_diff || revision.title) {
+                    revisionTitle.classList.add("revision-title");
+                    revision.title_diff ? renderDiff(revisionTitle, revision.title_diff) : revisionTitle.textContent = revision.title;
+                    revisionBox.appendChild(revisionTitle);
+                }
+                revisionContent.classList.add("revision-content");
+                revision.content_diff ? renderDiff(revisionContent, revision.content_diff) : revisionContent.textContent = revision.content;
+                revisionBox.appendChild(revisionContent);
+                revisionsList.appendChild(revisionBox);
+            }
+        }).catch(error => {
+            console.error("Error:", error);
+            revisionsList.textContent = getI18nText("revisions-load-error");
+        });
+    }
+
+    function hideRevisionsMenu() {
+        revisionsMenu.classList.add("hidden");
+        scrollbar.classList.remove("hidden");
+        revisionsList.innerHTML = "";
+    }
+
     if (userIsAuthenticated && userIsAMember) {
         newCommentButton = document.getElementById("new-comment-send-button");
         newCommentContent = document.getElementById("new-comment-content");
@@ -126,11 +195,18 @@ document.addEventListener("DOMContentLoaded", function (){
         postVoteDownImage.src = `/img/downvote_empty.png`;
     }
 
-    if (!userIsAuthenticated) {
+    if (!userIsAuthenticated || postIsLocked) {
         postVoteUpButton.disabled = true;
         postVoteDownButton.disabled = true;
     }
 
+    // Show the poll of the post
+    const postPoll = JSON.parse(document.getElementById("data_postPoll").textContent);
+    if (postPoll) {
+        const pollContainer = document.getElementById("t-post-poll");
+        pollContainer.appendChild(createPollElement(threadName, parseInt(messageId, 10), postPoll, userIsAuthenticated && !postIsLocked));
+    }
+
     mediaContainer.classList.add("post-media-container");
     mediaContainer.id = "t-post-media-container";
     postContent.appendChild(mediaContainer);
@@ -224,7 +300,7 @@ document.addEventListener("DOMContentLoaded", function (){
         if (hasReachedEnd) {
             return;
         }
-        let res = getComment(threadName, offset, messageId);
+        let res = getComment(threadName, cursor, messageId);
         res.then(async (response) => {
             if (response.ok) {
                 const data = await response.json();
@@ -239,7 +315,12 @@ document.addEventListener("DOMContentLoaded", function (){
                     const postElement = createNewComment(comment);
                     commentsContainer.appendChild(postElement);
                 }
-                offset += data.length;
+                cursor = response.headers.get("X-Next-Cursor") || "";
+                if (cursor === "") {
+                    hasReachedEnd = true;
+                    loadMoreCommentsButton.disabled = true;
+                    loadMoreCommentsButton.innerText = "No more comments";
+                }
             } else {
                 console.error(response);
             }
@@ -282,6 +363,15 @@ document.addEventListener("DOMContentLoaded", function (){
         console.log(document.getElementById("username").textContent);
 
         container.classList.add("comment-box", "win95-border");
+        container.dataset.commentId = data.comment_id;
+        // The comments hidden by their reports are only sent to the moderation team
+        if (data.is_hidden) {
+            container.classList.add("comment-hidden");
+        }
+        // The deleted comments are shown as a tombstone until they are purged
+        if (data.deletion_state) {
+            container.classList.add("comment-deleted");
+        }
 
         commentHeader.classList.add("comment-header", "win95-header");
         container.appendChild(commentHeader);
@@ -307,6 +397,14 @@ document.addEventListener("DOMContentLoaded", function (){
             window.location.href = `/profile/${data.user_name}`
         }
         authorAndTime.appendChild(author);
+        authorAndTime.appendChild(createUserBadges(data.user_badges));
+
+        if (data.is_hidden) {
+            const hiddenLabel = document.createElement("span");
+            hiddenLabel.classList.add("hidden-content-label");
+            hiddenLabel.innerText = getI18nText("hidden-content-label");
+            authorAndTime.appendChild(hiddenLabel);
+        }
 
         option.classList.add();
         commentHeader.appendChild(option);
@@ -341,13 +439,42 @@ document.addEventListener("DOMContentLoaded", function (){
                 <img src="/img/ban.png" alt="" class="win95-minor-logo unselectable" draggable="false">
                 <span>${getI18nText("option-menu-ban-button-text")}</span>
             </li>`
+
+        let optionMenuHistoryButtonHTML = `
+            <li class="win95-menu-button message-history menu-button" id="comment-history-button-p${data.comment_id}">
+                <img src="/img/edit.png" alt="" class="win95-minor-logo unselectable" draggable="false">
+                <span>${getI18nText("revisions-menu-button-text")}</span>
+            </li>`
+
+        let optionMenuSaveButtonHTML = `
+            <li class="win95-menu-button message-save menu-button" id="comment-save-button-p${data.comment_id}">
+                <img src="/img/edit.png" alt="" class="win95-minor-logo unselectable" draggable="false">
+                <span>${getI18nText(data.saved ? "option-menu-unsave-button-text" : "option-menu-save-button-text")}</span>
+            </li>`
+
+        let optionMenuRestoreButtonHTML = `
+            <li class="win95-menu-button message-restore menu-button" id="comment-restore-button-p${data.comment_id}">
+                <img src="/img/edit.png" alt="" class="win95-minor-logo unselectable" draggable="false">
+                <span>${getI18nText("option-menu-restore-button-text")}</span>
+            </li>`
         let additionalButtonsHTML = "";
         let showReportButton = false;
         let showEditButton = false;
         let showDeleteButton = false;
         let showBanButton = false;
-
-        if (userIsAuthenticated) { // If the user is authenticated he can see the option menu
+        let showHistoryButton = false;
+        let showRestoreButton = false;
+        let showSaveButton = false;
+
+        if (data.deletion_state) { // A deleted comment can only be restored
+            if (data.can_restore) {
+                additionalButtonsHTML += optionMenuRestoreButtonHTML;
+                showRestoreButton = true;
+            }
+        } else if (userIsAuthenticated) { // If the user is authenticated he can see the option menu
+            // Every authenticated user can save a comment for later
+            additionalButtonsHTML += optionMenuSaveButtonHTML;
+            showSaveButton = true;
             if (!isCommentOwner) { // If the user is authenticated he can report a post (exept his posts)
                 additionalButtonsHTML += optionMenuReportButtonHTML;
                 showReportButton = true;
@@ -356,14 +483,18 @@ document.addEventListener("DOMContentLoaded", function (){
                 additionalButtonsHTML += optionMenuEditButtonHTML;
                 showEditButton = true;
             }
-            if (isCommentOwner || userIsModerator || userIsAdmin || userIsAdmin) { // If the user is the owner of the post or his rank is moderator or higher he can delete it
+            if (isCommentOwner || userPermissions.includes("comments.delete")) { // If the user is the owner of the post or is allowed to delete the others ones he can delete it
                 additionalButtonsHTML += optionMenuDeleteButtonHTML;
                 showDeleteButton = true;
             }
-            if ((userIsThreadOwner || userIsAdmin) && !isCommentOwner) { // If the user rank is admin or higher he can ban the user (exept himself)
+            if (userPermissions.includes("users.ban") && !isCommentOwner) { // If the user is allowed to ban he can ban the user (exept himself)
                 additionalButtonsHTML += optionMenuBanButtonHTML;
                 showBanButton = true;
             }
+            if (data.was_edited && (isCommentOwner || userPermissions.includes("content.see_hidden"))) { // The author and the moderation team can see the edit history
+                additionalButtonsHTML += optionMenuHistoryButtonHTML;
+                showHistoryButton = true;
+            }
         }
 
         // All user can report a post
@@ -384,6 +515,31 @@ document.addEventListener("DOMContentLoaded", function (){
             });
         }
 
+        // Add the event listener to the save button, the text follows the saved state
+        if (showSaveButton) {
+            const saveButton = optionMenu.querySelector(`#comment-save-button-p${data.comment_id}`);
+            saveButton.addEventListener("click", function() {
+                updateSavedComment(threadName, messageId, data.comment_id, !data.saved).then((response) => {
+                    if (response.ok) {
+                        data.saved = !data.saved;
+                        saveButton.querySelector("span").innerText = getI18nText(data.saved ? "option-menu-unsave-button-text" : "option-menu-save-button-text");
+                        optionMenu.classList.remove("active");
+                    } else {
+                        alert("Error while saving comment : " + response.statusText);
+                        console.error(response);
+                    }
+                });
+            });
+        }
+
+        // Add the event listener to the history button
+        if (showHistoryButton) {
+            const historyButton = optionMenu.querySelector(`#comment-history-button-p${data.comment_id}`);
+            historyButton.addEventListener("click", function() {
+                showRevisionsMenu(getCommentRevisions(data.comment_id));
+            });
+        }
+
         // Add the event listener to the delete button
         if (showDeleteButton) {
             const deleteButton = optionMenu.querySelector(`#comment-delete-button-p${data.comment_id}`);
@@ -402,6 +558,21 @@ document.addEventListener("DOMContentLoaded", function (){
             });
         }
 
+        // Add the event listener to the restore button
+        if (showRestoreButton) {
+            const restoreButton = optionMenu.querySelector(`#comment-restore-button-p${data.comment_id}`);
+            restoreButton.addEventListener("click", function() {
+                restoreComment(threadName, messageId, data.comment_id).then((response) => {
+                    if (response.ok) {
+                        window.location.reload();
+                    } else {
+                        alert("Error while restoring comment : " + response.statusText);
+                        console.error(response);
+                    }
+                });
+            });
+        }
+
         // Add the event listener to the report button
         if (showReportButton) {
             const reportButton = optionMenu.querySelector(`#comment-report-button-p${data.comment_id}`);
@@ -416,16 +587,7 @@ document.addEventListener("DOMContentLoaded", function (){
             const banButton = optionMenu.querySelector(`#comment-ban-button-p${data.comment_id}`);
             banButton.addEventListener("click", function() {
                 console.log(`Ban button clicked for post ${data.comment_id}`);
-                const result = banUser(threadName, data.user_name);
-                result.then(async (response) => {
-                    if (response.ok) {
-                        alert("User banned successfully");
-                        console.log("User banned successfully");
-                    } else {
-                        alert("Error while banning user : " + response.statusText);
-                        console.error(response);
-                    }
-                });
+                showBanMenu(data.user_name);
             });
         }
 
@@ -465,7 +627,7 @@ document.addEventListener("DOMContentLoaded", function (){
 
         upvoteButton.type = "button";
         upvoteButton.classList.add("win95-button", "comment-vote-button");
-        if (userIsAuthenticated) {
+        if (userIsAuthenticated && !postIsLocked) {
             upvoteButton.addEventListener("click", function () {
                 upvoteComment(threadName, messageId, data.comment_id)
                     .then(r => {
@@ -503,7 +665,7 @@ document.addEventListener("DOMContentLoaded", function (){
 
         downvoteButton.type = "button";
         downvoteButton.classList.add("win95-button", "comment-vote-button");
-        if (userIsAuthenticated) {
+        if (userIsAuthenticated && !postIsLocked) {
             downvoteButton.addEventListener("click", function () {
                 downvoteComment(threadName, messageId, data.comment_id)
                     .then(r => {
@@ -551,6 +713,12 @@ document.addEventListener("DOMContentLoaded", function (){
         } else {
             isEditedSpan.innerText = "";
         }
+
+        // Used by the live updates to show the votes of the other users
+        container.updateVotes = function (upVotes, downVotes) {
+            currentVoteCount = upVotes - downVotes;
+            vote.innerText = currentVoteCount;
+        };
         return container;
     }
 
@@ -558,7 +726,7 @@ document.addEventListener("DOMContentLoaded", function (){
         loadMoreComments();
     })
 
-    if (userIsAuthenticated && userIsAMember) {
+    if (userIsAuthenticated && userIsAMember && !postIsLocked) {
         postVoteUpButton.addEventListener('click', function() {
             postId = parseInt(messageId, 10);
             upvoteMessage(threadName, postId)
@@ -621,7 +789,8 @@ document.addEventListener("DOMContentLoaded", function (){
                     if (r.ok) {
                         return r.json();
                     } else {
-                        throw new Error("Error while creating comment");
+                        // The content filters explain why the comment was refused
+                        return r.text().then(text => { throw new Error(text || "Error while creating comment"); });
                     }
                 })
                 .then(data => {
@@ -632,7 +801,7 @@ document.addEventListener("DOMContentLoaded", function (){
                     newCommentContentCharCountValue.innerText = "0";
 
                     // reload the comments
-                    offset = 0;
+                    cursor = "";
                     hasReachedEnd = false;
                     loadMoreCommentsButton.disabled = false;
                     loadMoreCommentsButton.innerText = "Load more comments";
@@ -641,6 +810,7 @@ document.addEventListener("DOMContentLoaded", function (){
                 })
                 .catch(error => {
                     console.error("Error:", error);
+                    alert(error.message);
                 });
         });
     }
@@ -679,6 +849,31 @@ document.addEventListener("DOMContentLoaded", function (){
         reportMenuSendButton.disabled = (charCount < 20 || charCount > 500);
     });
 
+    // Close the revisions menu when the background or the close button is clicked
+    revisionsMenuBackground.addEventListener('click', hideRevisionsMenu);
+    revisionsMenuCloseButton.addEventListener('click', hideRevisionsMenu);
+    // Restore the post if it was deleted
+    const postRestoreButton = document.getElementById("t-post-restore-button");
+    if (postRestoreButton) {
+        postRestoreButton.addEventListener('click', function () {
+            restoreMessage(threadName, parseInt(messageId)).then((response) => {
+                if (response.ok) {
+                    window.location.reload();
+                } else {
+                    alert("Error while restoring post : " + response.statusText);
+                    console.error(response);
+                }
+            });
+        });
+    }
+    // Show the edit history of the post
+    const postRevisionsButton = document.getElementById("t-post-revisions-button");
+    if (postRevisionsButton) {
+        postRevisionsButton.addEventListener('click', function () {
+            showRevisionsMenu(getMessageRevisions(messageId));
+        });
+    }
+
     // Close the edit menu when the close button is clicked
     editCommentMenuBackground.addEventListener('click', hideEditMenu);
     // Close the edit menu when the close button is clicked
@@ -694,7 +889,7 @@ document.addEventListener("DOMContentLoaded", function (){
                     hideEditMenu();
                     commentsContainer.innerHTML = "";
                     hasReachedEnd = false;
-                    offset = 0;
+                    cursor = "";
                     // Reload the comments
                     loadMoreComments();
                 } else {
@@ -713,6 +908,69 @@ document.addEventListener("DOMContentLoaded", function (){
         editCommentMenuSendButton.disabled = (charCount < 5 || charCount > 500);
     });
 
+    // Live updates of the post
+    const threadEvents = subscribeToThreadEvents(threadName);
+    threadEvents.addEventListener("comment.created", function (e) {
+        const data = JSON.parse(e.data);
+        // The comments of the user are already reloaded when he sends them
+        if (data.message_id.toString() !== messageId || data.user_name === document.getElementById("username").textContent) {
+            return;
+        }
+        // If every comment was loaded we load the new one, otherwise it will come with the next ones
+        if (hasReachedEnd) {
+            hasReachedEnd = false;
+            loadMoreCommentsButton.disabled = false;
+            loadMoreComments();
+        }
+    });
+    threadEvents.addEventListener("comment.deleted", function (e) {
+        const data = JSON.parse(e.data);
+        const comment = commentsContainer.querySelector(`.comment-box[data-comment-id="${data.comment_id}"]`);
+        if (comment) {
+            comment.remove();
+        }
+    });
+    threadEvents.addEventListener("comment.voted", function (e) {
+        const data = JSON.parse(e.data);
+        const comment = commentsContainer.querySelector(`.comment-box[data-comment-id="${data.comment_id}"]`);
+        if (comment) {
+            comment.updateVotes(data.up_votes, data.down_votes);
+        }
+    });
+    threadEvents.addEventListener("message.voted", function (e) {
+        const data = JSON.parse(e.data);
+        if (data.message_id.toString() !== messageId) {
+            return;
+        }
+        postVoteCount = data.up_votes - data.down_votes;
+        postVoteCountSpan.innerText = `${postVoteCount}`;
+    });
+
+    const saveButton = document.getElementById("SaveMessageButton");
+    const unsaveButton = document.getElementById("UnsaveMessageButton");
+    [[saveButton, true], [unsaveButton, false]].forEach(([button, save]) => {
+        if (!button) {
+            return;
+        }
+        button.addEventListener("click", function () {
+            updateSavedMessage(threadName, parseInt(messageId, 10), save).then((response) => {
+                if (response.ok) {
+                    saveButton.classList.toggle("hidden", save);
+                    unsaveButton.classList.toggle("hidden", !save);
+                } else {
+                    console.error(response);
+                }
+            });
+        });
+    });
+
+    bindSubscriptionButtons({
+        subscribe: document.getElementById("SubscribeMessageButton"),
+        unsubscribe: document.getElementById("UnsubscribeMessageButton"),
+        mute: document.getElementById("MuteMessageButton"),
+        unmute: document.getElementById("UnmuteMessageButton"),
+    }, (action) => updateMessageSubscription(threadName, parseInt(messageId, 10), `${action}Message`));
+
     loadMoreComments()
     // Update the vote count
     postVoteCountSpan.innerText = `${postVoteCount}`;
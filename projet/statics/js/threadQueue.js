@@ -0,0 +1,63 @@
+function ReviewMessage(threadName, action, messageId) {
+    reviewMessage(threadName, action, messageId)
+        .then(async r => {
+            if (r.ok) {
+                // The message left the queue, remove it from the list
+                document.getElementById(`pending-${messageId}`).remove();
+            } else {
+                alert('Error reviewing message: ' + await r.text());
+            }
+        }).catch(error => {
+            alert('Error reviewing message: ' + error);
+            console.error("Error:", error);
+        });
+}
+
+function ReviewJoinRequest(threadName, action, requestId) {
+    reviewJoinRequest(threadName, action, requestId)
+        .then(async r => {
+            if (r.ok) {
+                // The request was reviewed, remove it from the list
+                document.getElementById(`join-request-${requestId}`).remove();
+            } else {
+                alert('Error reviewing join request: ' + await r.text());
+            }
+        }).catch(error => {
+            alert('Error reviewing join request: ' + error);
+            console.error("Error:", error);
+        });
+}
+
+function CreateInvite(threadName) {
+    const maxUses = parseInt(document.getElementById("invite-max-uses").value);
+    const expiresInHours = parseInt(document.getElementById("invite-expires-in").value);
+    if (isNaN(maxUses) || maxUses < 0 || maxUses > 1000 || isNaN(expiresInHours) || expiresInHours < 0 || expiresInHours > 8760) {
+        alert('Error creating invite: invalid limits');
+        return;
+    }
+    createThreadInvite(threadName, maxUses, expiresInHours)
+        .then(async r => {
+            if (r.ok) {
+                window.location.reload();
+            } else {
+                alert('Error creating invite: ' + await r.text());
+            }
+        }).catch(error => {
+            alert('Error creating invite: ' + error);
+            console.error("Error:", error);
+        });
+}
+
+function DeleteInvite(threadName, inviteId) {
+    deleteThreadInvite(threadName, inviteId)
+        .then(async r => {
+            if (r.ok) {
+                document.getElementById(`invite-${inviteId}`).remove();
+            } else {
+                alert('Error deleting invite: ' + await r.text());
+            }
+        }).catch(error => {
+            alert('Error deleting invite: ' + error);
+            console.error("Error:", error);
+        });
+}
@@ -1,14 +1,30 @@
-function ResolveReport(threadName, reportId) {
-    setReportToResolved(threadName, reportId)
+function ReportAction(threadName, action, reportId) {
+    const note = document.getElementById(`report-note-${reportId}`).value;
+    const durationSelect = document.getElementById(`report-ban-duration-${reportId}`);
+    const duration = durationSelect ? parseInt(durationSelect.value) : 0;
+    handleReport(threadName, action, reportId, note, duration)
+        .then(async r => {
+            if (r.ok) {
+                // The state of the reports changed, reload the page to show it
+                window.location.reload();
+            } else {
+                alert('Error handling report: ' + await r.text());
+            }
+        }).catch(error => {
+            alert('Error handling report: ' + error);
+            console.error("Error:", error);
+        });
+}
+function UnbanUser(threadName, username) {
+    unbanUser(threadName, username)
         .then(r => {
             if (r.ok) {
-                // If the report was resolved successfully, remove the report from the list
-                document.getElementById(`report-${reportId}`).remove();
-                // Show a success message
-                alert('Report ' + reportId + ' has been resolved.');
+                // If the ban was lifted successfully, remove the ban from the list
+                document.getElementById(`ban-${username}`).remove();
+                alert(username + ' has been unbanned.');
             }
         }).catch(error => {
-            alert('Error resolving report: ' + error);
+            alert('Error unbanning user: ' + error);
             console.error("Error:", error);
         });
-}
\ No newline at end of file
+}
@@ -18,9 +18,10 @@ function getCurrentThreadName() {
  * @param messageContent {string} - The content of the message.
  * @param messageMedias {string[]} - The media files to attach to the message.
  * @param messageTags {int[]} - The tags of the message.
+ * @param messagePoll {{options: string[], multipleChoice: boolean, resultsVisibility: string, closingDate: string}|null} - The poll to attach to the message (optional).
  * @returns {Promise<Response>} - The response from the server.
  */
-function sendMessage(threadName, messageTitle, messageContent, messageMedias, messageTags) {
+function sendMessage(threadName, messageTitle, messageContent, messageMedias, messageTags, messagePoll = null) {
     return fetch(`/api/thread/${threadName}/sendMessage`, {
         method: "POST",
         headers: {
@@ -30,11 +31,143 @@ function sendMessage(threadName, messageTitle, messageContent, messageMedias, me
             title: messageTitle,
             content: messageContent,
             medias: messageMedias,
-            tags: messageTags
+            tags: messageTags,
+            poll: messagePoll
         })
     });
 }
 
+/**
+ * Vote in the poll of the message with the given id in the given thread.
+ * @description This function sends the ballot of the user. It does not handle the response.
+ * @description A success response contains the poll with its results, each user can only vote once.
+ * @param threadName {string} - The name of the thread of the message.
+ * @param messageId {number} - The ID of the message of the poll.
+ * @param optionIds {number[]} - The IDs of the chosen options.
+ * @returns {Promise<Response>} - The response from the server.
+ */
+function votePoll(threadName, messageId, optionIds) {
+    return fetch(`/api/thread/${threadName}/votePoll`, {
+        method: "POST",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        body: JSON.stringify({
+            messageId: messageId,
+            optionIds: optionIds
+        })
+    });
+}
+
+/**
+ * Create the element showing the poll of a message.
+ * @description The user can vote until the poll is closed, the results are shown depending on the poll settings.
+ * @description The element is rebuilt with the results once the user voted.
+ * @param threadName {string} - The name of the thread of the message.
+ * @param messageId {number} - The ID of the message of the poll.
+ * @param poll {Object} - The poll of the message (see FormattedThreadMessage.poll).
+ * @param canVote {boolean} - Whether the user is allowed to vote (authenticated and the message is not locked).
+ * @returns {HTMLElement} - The element showing the poll.
+ */
+function createPollElement(threadName, messageId, poll, canVote) {
+    const pollElement = document.createElement("div");
+    pollElement.classList.add("post-poll", "win95-border-indent");
+    const isOpen = canVote && !poll.has_voted && !poll.is_closed;
+    const inputType = poll.is_multiple_choice ? "checkbox" : "radio";
+    const totalVotes = poll.options.reduce((total, option) => total + option.votes, 0);
+
+    for (const option of poll.options) {
+        const optionElement = document.createElement("label");
+        optionElement.classList.add("post-poll-option");
+        if (poll.voted_options.includes(option.option_id)) {
+            optionElement.classList.add("voted");
+        }
+        if (isOpen) {
+            const input = document.createElement("input");
+            input.type = inputType;
+            input.name = `poll-${poll.poll_id}`;
+            input.value = option.option_id;
+            optionElement.appendChild(input);
+        }
+        const text = document.createElement("span");
+        text.innerText = option.option_text;
+        optionElement.appendChild(text);
+        if (poll.show_results) {
+            const percentage = totalVotes > 0 ? Math.round(option.votes * 100 / totalVotes) : 0;
+            const result = document.createElement("span");
+            result.classList.add("post-poll-result");
+            result.innerText = `${option.votes} (${percentage}%)`;
+            optionElement.appendChild(result);
+            optionElement.style.setProperty("--poll-percentage", `${percentage}%`);
+        }
+        pollElement.appendChild(optionElement);
+    }
+
+    const footer = document.createElement("div");
+    footer.classList.add("post-poll-footer");
+    if (poll.show_results) {
+        footer.innerText = getI18nText("poll-voters").replace("%d", poll.number_of_voters);
+    } else {
+        footer.innerText = getI18nText("poll-results-hidden");
+    }
+    if (poll.is_closed) {
+        footer.innerText += ` - ${getI18nText("poll-closed")}`;
+    } else if (poll.closing_date) {
+        footer.innerText += ` - ${getI18nText("poll-closes-on")} ${new Date(poll.closing_date).toLocaleString()}`;
+    }
+    pollElement.appendChild(footer);
+
+    if (isOpen) {
+        const voteButton = document.createElement("button");
+        voteButton.type = "button";
+        voteButton.classList.add("win95-button");
+        voteButton.innerText = getI18nText("poll-vote-button");
+        voteButton.addEventListener("click", function () {
+            const optionIds = Array.from(pollElement.querySelectorAll("input:checked")).map(input => parseInt(input.value, 10));
+            if (optionIds.length === 0) {
+                return;
+            }
+            votePoll(threadName, messageId, optionIds)
+                .then(r => {
+                    if (r.ok) {
+                        return r.json();
+                    } else {
+                        throw new Error("Error while voting in the poll");
+                    }
+                })
+                .then(updatedPoll => {
+                    pollElement.replaceWith(createPollElement(threadName, messageId, updatedPoll, canVote));
+                })
+                .catch(error => {
+                    alert(error.message);
+                    console.error("Error:", error);
+                });
+        });
+        pollElement.appendChild(voteButton);
+    }
+    return pollElement;
+}
+
+/**
+ * Create the element showing the badges of the author of a message or a comment.
+ * @description The name of each badge is shown in the language of the page, the english one being used when it is missing.
+ * @param badges {Array<Object>|null} - The badges of the author (see FormattedThreadMessage.user_badges).
+ * @returns {HTMLElement} - The element showing the badges, empty if the author has none.
+ */
+function createUserBadges(badges) {
+    const badgesElement = document.createElement("span");
+    badgesElement.classList.add("user-badges");
+    const lang = document.documentElement.lang;
+    for (const badge of badges || []) {
+        const badgeElement = document.createElement("span");
+        badgeElement.classList.add("user-badge");
+        badgeElement.innerText = badge.icon;
+        badgeElement.title = badge.names[lang] || badge.names.en;
+        badgesElement.appendChild(badgeElement);
+    }
+    return badgesElement;
+}
+
 /**
  * Delete a message from the current thread.
  * @description This function sends a request to delete a message from the current thread. It does not handle the response.
@@ -55,6 +188,26 @@ function deleteMessage(threadName, messageId) {
     });
 }
 
+/**
+ * Restore a deleted message of the current thread.
+ * @description This function sends a request to restore a deleted message of the current thread. It does not handle the response.
+ * @description But a success response means that the message is visible again.
+ * @param threadName {string} - The name of the thread of the message.
+ * @param messageId {number} - The ID of the message to restore.
+ * @returns {Promise<Response>} - The response from the server.
+ */
+function restoreMessage(threadName, messageId) {
+    return fetch( `/api/thread/${threadName}/restoreMessage`, {
+        method: "POST",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        body: JSON.stringify({
+            messageId: messageId
+        })
+    });
+}
+
 /**
  * Remove a media from the current thread.
  * @description This function sends a request to remove a media from the current message. It does not handle the response.
@@ -203,22 +356,23 @@ function leaveThread(threadName) {
  * Get the messages from the current thread.
  * @description This function sends a request to get the messages from the current thread. It does not handle the response.
  * @description But a success response means that the messages have been retrieved.
+ * @description The cursor of the next page is given in the 'X-Next-Cursor' header of the response, it is empty when there is nothing left to load.
  * @param threadName {string} - The name of the thread to get the messages from.
- * @param offset {number} - The offset to start getting the messages from.
+ * @param cursor {string} - The cursor to start getting the messages from (empty to get the first page).
  * @param order {string} - The order to get the messages in.
  * @param tags {string[]} - The tags to filter the messages by.
  * @returns {Promise<Response>} - The response from the server.
  */
-function getMessage(threadName, offset, order, tags = []) {
+function getMessage(threadName, cursor, order, tags = []) {
     if (tags.length > 0) {
-        return fetch( `/api/messages?thread=${threadName}&offset=${offset}&order=${order}&tags=${encodeURIComponent(JSON.stringify(tags))}`, {
+        return fetch( `/api/messages?thread=${threadName}&cursor=${encodeURIComponent(cursor)}&order=${order}&tags=${encodeURIComponent(JSON.stringify(tags))}`, {
             method: "GET",
             headers: {
                 "Content-Type": "application/json",
             }
         });
     }
-    return fetch( `/api/messages?thread=${threadName}&offset=${offset}&order=${order}`, {
+    return fetch( `/api/messages?thread=${threadName}&cursor=${encodeURIComponent(cursor)}&order=${order}`, {
         method: "GET",
         headers: {
             "Content-Type": "application/json",
@@ -268,6 +422,27 @@ function deleteComment(threadName, messageId, commentId) {
     });
 }
 
+/**
+ * Restore a deleted comment of the message with the given id in the given thread.
+ * @description This function sends a request to restore a deleted comment. It does not handle the response.
+ * @param threadName {string} - The name of the thread of the comment.
+ * @param messageId {string} - The ID of the message of the comment.
+ * @param commentId {number} - The ID of the comment to restore.
+ * @returns {Promise<Response>} - The response from the server.
+ */
+function restoreComment(threadName, messageId, commentId) {
+    return fetch( `/api/thread/${threadName}/restoreComment`, {
+        method: "POST",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        body: JSON.stringify({
+            commentId: commentId,
+            messageId: messageId
+        })
+    });
+}
+
 /**
  * Edit the comment with the given id in the given thread.
  * @description This function sends a request to edit a comment in the current thread. It does not handle the response.
@@ -366,10 +541,36 @@ function downvoteComment(threadName, messageId, commentId) {
  * @description If you're not a dev looking at this code, you won't be able to use this function, the server double checks if the user has the right to do so. (●'◡'●)
  * @param threadName {string} - The name of the thread to ban the user from.
  * @param username {string} - The username of the user to ban.
+ * @param reason {string} - The reason of the ban, shown to the banned user.
+ * @param duration {number} - The duration of the ban in hours, 0 for a permanent ban.
+ * @param note {string} - An optional note for the moderation team.
  * @returns {Promise<Response>} - The response from the server.
  */
-function banUser(threadName, username) {
+function banUser(threadName, username, reason, duration, note = "") {
     return fetch( `/api/thread/${threadName}/banUser`, {
+        method: "POST",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        body: JSON.stringify({
+            username: username,
+            reason: reason,
+            duration: duration,
+            note: note
+        })
+    });
+}
+
+/**
+ * Lift the ban of the user with the given username in the given thread.
+ * @description This function sends a request to unban a user from the current thread. It does not handle the response.
+ * @description But a success response means that the user has been unbanned.
+ * @param threadName {string} - The name of the thread to unban the user from.
+ * @param username {string} - The username of the user to unban.
+ * @returns {Promise<Response>} - The response from the server.
+ */
+function unbanUser(threadName, username) {
+    return fetch( `/api/thread/${threadName}/unbanUser`, {
         method: "POST",
         headers: {
             "Content-Type": "application/json",
@@ -380,6 +581,65 @@ function banUser(threadName, username) {
     });
 }
 
+/**
+ * Bind the ban menu of the page.
+ * @description The menu asks for the reason, the duration and the note of the ban before sending it.
+ * @param threadName {string} - The name of the current thread.
+ * @returns {function(string)|null} - The function showing the menu for a username, null if the page has no ban menu.
+ */
+function setupBanMenu(threadName) {
+    const banMenu = document.getElementById("ban-button-menu");
+    if (!banMenu) {
+        return null;
+    }
+    const scrollbar = document.getElementsByClassName("custom-scrollbar")[0];
+    const banMenuBackground = banMenu.getElementsByClassName("full-screens-menu-background")[0];
+    const banUsername = document.getElementById("ban-username");
+    const banReason = document.getElementById("ban-reason");
+    const banDuration = document.getElementById("ban-duration");
+    const banNote = document.getElementById("ban-note");
+    const sendBanButton = document.getElementById("send-ban-button");
+    const banSuccessMessage = document.getElementById("ban-success-message");
+    const banErrorMessage = document.getElementById("ban-error-message");
+
+    function hideBanMenu() {
+        banMenu.classList.add("hidden");
+        scrollbar.classList.remove("hidden");
+    }
+
+    banReason.addEventListener("input", function () {
+        sendBanButton.disabled = banReason.value.trim().length === 0;
+    });
+    banMenuBackground.addEventListener("click", hideBanMenu);
+    document.getElementById("close-ban-menu").addEventListener("click", hideBanMenu);
+    sendBanButton.addEventListener("click", function () {
+        sendBanButton.disabled = true;
+        banUser(threadName, banUsername.textContent, banReason.value.trim(), parseInt(banDuration.value, 10), banNote.value.trim())
+            .then((response) => {
+                if (response.ok) {
+                    banSuccessMessage.classList.remove("hidden");
+                    banErrorMessage.classList.add("hidden");
+                } else {
+                    banErrorMessage.classList.remove("hidden");
+                    sendBanButton.disabled = false;
+                    console.error(response);
+                }
+            });
+    });
+
+    return function showBanMenu(username) {
+        banUsername.textContent = username;
+        banReason.value = "";
+        banNote.value = "";
+        banDuration.selectedIndex = 0;
+        sendBanButton.disabled = true;
+        banSuccessMessage.classList.add("hidden");
+        banErrorMessage.classList.add("hidden");
+        banMenu.classList.remove("hidden");
+        scrollbar.classList.add("hidden");
+    };
+}
+
 /**
  * Set a report to resolved.
  * @description This function sends a request to set a report to resolved. It does not handle the response.
@@ -400,17 +660,172 @@ function setReportToResolved(threadName, reportId) {
     });
 }
 
+/**
+ * Handle the reports made about the same content as the report with the given id.
+ * @description This function sends a request to claim, dismiss or act on the reports. It does not handle the response.
+ * @param threadName {string} - The name of the thread of the report.
+ * @param action {string} - The action to make ("claimReport", "dismissReport", "deleteReportedContent" or "banReportedAuthor").
+ * @param reportId {string} - The ID of one of the reports.
+ * @param note {string} - The resolution note, visible to the moderation team.
+ * @param duration {number} - The duration of the ban in hours (0 for a permanent ban), only used by "banReportedAuthor".
+ * @returns {Promise<Response>} - The response from the server.
+ */
+function handleReport(threadName, action, reportId, note, duration) {
+    return fetch( `/api/thread/${threadName}/${action}`, {
+        method: "POST",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        body: JSON.stringify({
+            reportId: reportId,
+            note: note,
+            duration: duration
+        })
+    });
+}
+
+/**
+ * Pin, unpin, lock or unlock the message with the given id in the given thread.
+ * @description This function sends a request to change the pin or the lock state of a message. It does not handle the response.
+ * @description Pinned messages are shown first and locked messages cannot be commented nor voted anymore.
+ * @param threadName {string} - The name of the thread of the message.
+ * @param action {string} - The action to make ("pinMessage", "unpinMessage", "lockMessage" or "unlockMessage").
+ * @param messageId {string} - The ID of the message.
+ * @returns {Promise<Response>} - The response from the server.
+ */
+function pinOrLockMessage(threadName, action, messageId) {
+    return fetch( `/api/thread/${threadName}/${action}`, {
+        method: "POST",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        body: JSON.stringify({
+            messageId: parseInt(messageId)
+        })
+    });
+}
+
+/**
+ * Approve or reject the message with the given id waiting in the approval queue of the given thread.
+ * @description This function sends a request to review a pending message. It does not handle the response.
+ * @description But a success response means that the message left the approval queue.
+ * @param threadName {string} - The name of the thread of the message.
+ * @param action {string} - The action to make ("approveMessage" or "rejectMessage").
+ * @param messageId {string} - The ID of the message to review.
+ * @returns {Promise<Response>} - The response from the server.
+ */
+function reviewMessage(threadName, action, messageId) {
+    return fetch( `/api/thread/${threadName}/${action}`, {
+        method: "POST",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        body: JSON.stringify({
+            messageId: parseInt(messageId)
+        })
+    });
+}
+
+/**
+ * Approve or deny the join request with the given id of the given thread.
+ * @description This function sends a request to review a join request. It does not handle the response.
+ * @description But a success response means that the request was removed and, if approved, that its user joined the thread.
+ * @param threadName {string} - The name of the thread of the request.
+ * @param action {string} - The action to make ("approveJoinRequest" or "denyJoinRequest").
+ * @param requestId {string} - The ID of the join request to review.
+ * @returns {Promise<Response>} - The response from the server.
+ */
+function reviewJoinRequest(threadName, action, requestId) {
+    return fetch( `/api/thread/${threadName}/${action}`, {
+        method: "POST",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        body: JSON.stringify({
+            requestId: parseInt(requestId)
+        })
+    });
+}
+
+/**
+ * Create an invite link of the given thread.
+ * @description This function sends a request to create an invite link. It does not handle the response.
+ * @description But a success response contains the id and the code of the new invite.
+ * @param threadName {string} - The name of the thread.
+ * @param maxUses {number} - The number of times the invite can be used, between 0 and 1000 (0 disables the limit).
+ * @param expiresInHours {number} - The number of hours the invite can be used for, between 0 and 8760 (0 disables the limit).
+ * @returns {Promise<Response>} - The response from the server.
+ */
+function createThreadInvite(threadName, maxUses, expiresInHours) {
+    return fetch( `/api/thread/${threadName}/createInvite`, {
+        method: "POST",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        body: JSON.stringify({
+            maxUses: maxUses,
+            expiresInHours: expiresInHours
+        })
+    });
+}
+
+/**
+ * Delete the invite link with the given id of the given thread.
+ * @description This function sends a request to delete an invite link. It does not handle the response.
+ * @param threadName {string} - The name of the thread.
+ * @param inviteId {string} - The ID of the invite to delete.
+ * @returns {Promise<Response>} - The response from the server.
+ */
+function deleteThreadInvite(threadName, inviteId) {
+    return fetch( `/api/thread/${threadName}/deleteInvite`, {
+        method: "POST",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        body: JSON.stringify({
+            inviteId: parseInt(inviteId)
+        })
+    });
+}
+
+/**
+ * Get every version of the message with the given id.
+ * @description This function sends a request to get the edit history of a message. It does not handle the response.
+ * @description But a success response contains the versions, from the original to the current one, with the changes since the previous version.
+ * @param messageId {string} - The ID of the message to get the versions of.
+ * @returns {Promise<Response>} - The response from the server.
+ */
+function getMessageRevisions(messageId) {
+    return fetch(`/api/messages/${messageId}/revisions`, {
+        method: "GET"
+    });
+}
+
+/**
+ * Get every version of the comment with the given id.
+ * @description This function sends a request to get the edit history of a comment. It does not handle the response.
+ * @description But a success response contains the versions, from the original to the current one, with the changes since the previous version.
+ * @param commentId {string} - The ID of the comment to get the versions of.
+ * @returns {Promise<Response>} - The response from the server.
+ */
+function getCommentRevisions(commentId) {
+    return fetch(`/api/comments/${commentId}/revisions`, {
+        method: "GET"
+    });
+}
+
 /**
  * Get the comments from the message with the given id in the given thread.
  * @description This function sends a request to get the comments from a message in the current thread. It does not handle the response.
  * @description But a success response means that the comments have been retrieved.
+ * @description The cursor of the next page is given in the 'X-Next-Cursor' header of the response, it is empty when there is nothing left to load.
  * @param threadName {string} - The name of the thread to get the comments from.
- * @param offset {number} - The offset to start getting the comments from.
+ * @param cursor {string} - The cursor to start getting the comments from (empty to get the first page).
  * @param messageId {string} - The ID of the message to get the comments from.
  * @returns {Promise<Response>} - The response from the server.
  */
-function getComment(threadName, offset, messageId) {
-    return fetch( `/api/comments?thread=${threadName}&offset=${offset}&message=${messageId}`, {
+function getComment(threadName, cursor, messageId) {
+    return fetch( `/api/comments?thread=${threadName}&cursor=${encodeURIComponent(cursor)}&message=${messageId}`, {
         method: "GET",
         headers: {
             "Content-Type": "application/json",
@@ -485,6 +900,270 @@ function deleteThreadTag(threadName, tagId) {
     });
 }
 
+/**
+ * Create a custom role in the given thread.
+ * @description This function sends a request to create a custom role. It does not handle the response.
+ * @description But a success response means that the role has been created, the response contains its id.
+ * @param threadName {string} - The name of the thread to create the role in.
+ * @param roleName {string} - The name of the role (between 1 and 30 characters).
+ * @param permissions {string[]} - The permissions given by the role.
+ * @returns {Promise<Response>} - The response from the server.
+ */
+function createRole(threadName, roleName, permissions) {
+    return fetch( `/api/thread/${threadName}/createRole`, {
+        method: "POST",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        body: JSON.stringify({
+            roleName: roleName,
+            permissions: permissions
+        })
+    });
+}
+
+/**
+ * Delete the custom role with the given id from the given thread.
+ * @description This function sends a request to delete a custom role. It does not handle the response.
+ * @description But a success response means that the role has been deleted, its members keep their rank.
+ * @param threadName {string} - The name of the thread to delete the role from.
+ * @param roleId {number} - The ID of the role to delete.
+ * @returns {Promise<Response>} - The response from the server.
+ */
+function deleteRole(threadName, roleId) {
+    return fetch( `/api/thread/${threadName}/deleteRole`, {
+        method: "POST",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        body: JSON.stringify({
+            roleId: roleId
+        })
+    });
+}
+
+/**
+ * Give a custom role to a member of the given thread.
+ * @description This function sends a request to change the custom role of a member. It does not handle the response.
+ * @param threadName {string} - The name of the thread.
+ * @param username {string} - The username of the member.
+ * @param roleId {number} - The ID of the role to give, 0 removes the role of the member.
+ * @returns {Promise<Response>} - The response from the server.
+ */
+function setMemberRole(threadName, username, roleId) {
+    return fetch( `/api/thread/${threadName}/setMemberRole`, {
+        method: "POST",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        body: JSON.stringify({
+            username: username,
+            roleId: roleId
+        })
+    });
+}
+
+/**
+ * Register a new webhook in the given thread.
+ * @description This function sends a request to create a webhook in the current thread. It does not handle the response.
+ * @description But a success response means that the webhook has been created, the response contains its id and secret.
+ * @param threadName {string} - The name of the thread to create the webhook in.
+ * @param url {string} - The url the events will be sent to.
+ * @param events {string[]} - The events the webhook is registered for.
+ * @returns {Promise<Response>} - The response from the server.
+ */
+function createWebhook(threadName, url, events) {
+    return fetch( `/api/thread/${threadName}/createWebhook`, {
+        method: "POST",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        body: JSON.stringify({
+            url: url,
+            events: events
+        })
+    });
+}
+
+/**
+ * Delete the webhook with the given id from the given thread.
+ * @description This function sends a request to delete a webhook in the current thread. It does not handle the response.
+ * @description But a success response means that the webhook has been deleted.
+ * @param threadName {string} - The name of the thread to delete the webhook from.
+ * @param webhookId {number} - The ID of the webhook to delete.
+ * @returns {Promise<Response>} - The response from the server.
+ */
+function deleteWebhook(threadName, webhookId) {
+    return fetch( `/api/thread/${threadName}/deleteWebhook`, {
+        method: "POST",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        body: JSON.stringify({
+            webhookId: webhookId
+        })
+    });
+}
+
+/**
+ * Set the number of distinct reporters hiding a message or a comment in the given thread.
+ * @description This function sends a request to change the auto hide threshold of the thread. It does not handle the response.
+ * @description But a success response means that the threshold has been saved, 0 disables the auto hiding.
+ * @param threadName {string} - The name of the thread to change the threshold of.
+ * @param threshold {number} - The new threshold, between 0 and 100.
+ * @returns {Promise<Response>} - The response from the server.
+ */
+function setAutoHideThreshold(threadName, threshold) {
+    return fetch( `/api/thread/${threadName}/setAutoHideThreshold`, {
+        method: "POST",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        body: JSON.stringify({
+            threshold: threshold
+        })
+    });
+}
+
+/**
+ * Set the approval settings of the given thread.
+ * @description This function sends a request to change the approval settings of the thread. It does not handle the response.
+ * @description But a success response means that the settings have been saved, the posts of the members below them wait for a moderator.
+ * @param threadName {string} - The name of the thread to change the settings of.
+ * @param minPosts {number} - The number of approved posts a member needs to post freely, between 0 and 1000 (0 disables it).
+ * @param minDays {number} - The number of days a member needs to have joined since to post freely, between 0 and 365 (0 disables it).
+ * @returns {Promise<Response>} - The response from the server.
+ */
+function setApprovalSettings(threadName, minPosts, minDays) {
+    return fetch( `/api/thread/${threadName}/setApprovalSettings`, {
+        method: "POST",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        body: JSON.stringify({
+            minPosts: minPosts,
+            minDays: minDays
+        })
+    });
+}
+
+/**
+ * Set the reputation thresholds of the given thread.
+ * @description This function sends a request to change the reputation thresholds of the thread. It does not handle the response.
+ * @description But a success response means that the thresholds have been saved, the members below them cannot use the matching capability.
+ * @param threadName {string} - The name of the thread to change the settings of.
+ * @param minImages {number} - The reputation a member needs to post images, between 0 and 100000 (0 disables it).
+ * @param minTags {number} - The reputation a member needs to create tags, between 0 and 100000 (0 disables it).
+ * @param minReports {number} - The reputation a member needs to report content, between 0 and 100000 (0 disables it).
+ * @returns {Promise<Response>} - The response from the server.
+ */
+function setReputationSettings(threadName, minImages, minTags, minReports) {
+    return fetch( `/api/thread/${threadName}/setReputationSettings`, {
+        method: "POST",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        body: JSON.stringify({
+            minImages: minImages,
+            minTags: minTags,
+            minReports: minReports
+        })
+    });
+}
+
+/**
+ * Set who can see and join the given thread.
+ * @description This function sends a request to change the access settings of the thread. It does not handle the response.
+ * @param threadName {string} - The name of the thread.
+ * @param joinPolicy {string} - How the users join the thread ("open", "request" or "invite").
+ * @param isPrivate {boolean} - If the thread is only listed on the home page for its members.
+ * @returns {Promise<Response>} - The response from the server.
+ */
+function setAccessSettings(threadName, joinPolicy, isPrivate) {
+    return fetch( `/api/thread/${threadName}/setAccessSettings`, {
+        method: "POST",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        body: JSON.stringify({
+            joinPolicy: joinPolicy,
+            isPrivate: isPrivate
+        })
+    });
+}
+
+/**
+ * Set the category of the given thread.
+ * @description This function sends a request to set the category of the current thread. It does not handle the response.
+ * @param threadName {string} - The name of the thread.
+ * @param category {string} - The category of the thread.
+ * @returns {Promise<Response>}
+ */
+function setThreadCategory(threadName, category) {
+    return fetch( `/api/thread/${threadName}/setThreadCategory`, {
+        method: "POST",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        body: JSON.stringify({
+            category: category
+        })
+    });
+}
+
+/**
+ * Add a filtered word to the given thread.
+ * @description This function sends a request to add a word filter in the current thread. It does not handle the response.
+ * @description But a success response means that the filter has been added, the response contains its id.
+ * @param threadName {string} - The name of the thread to add the word filter to.
+ * @param word {string} - The word to filter.
+ * @param action {string} - What happens to the content containing the word ('block', 'mask' or 'flag').
+ * @returns {Promise<Response>} - The response from the server.
+ */
+function addWordFilter(threadName, word, action) {
+    return fetch( `/api/thread/${threadName}/addWordFilter`, {
+        method: "POST",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        body: JSON.stringify({
+            word: word,
+            action: action
+        })
+    });
+}
+
+/**
+ * Remove the filtered word with the given id from the given thread.
+ * @description This function sends a request to remove a word filter from the current thread. It does not handle the response.
+ * @description But a success response means that the filter has been removed.
+ * @param threadName {string} - The name of the thread to remove the word filter from.
+ * @param filterId {number} - The ID of the word filter to remove.
+ * @returns {Promise<Response>} - The response from the server.
+ */
+function removeWordFilter(threadName, filterId) {
+    return fetch( `/api/thread/${threadName}/removeWordFilter`, {
+        method: "POST",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        body: JSON.stringify({
+            filterId: filterId
+        })
+    });
+}
+
+/**
+ * Subscribe to the live events of the given thread.
+ * @description This function opens a Server-Sent Events stream, the browser reconnects by itself if the stream is cut.
+ * @description The events are 'message.created', 'message.deleted', 'message.voted', 'comment.created', 'comment.deleted' and 'comment.voted'.
+ * @param threadName {string} - The name of the thread to get the events from.
+ * @returns {EventSource} - The event source, use addEventListener with the event names to handle them.
+ */
+function subscribeToThreadEvents(threadName) {
+    return new EventSource(`/t/${threadName}/events`);
+}
+
 /**
  * Get the tags from the given thread.
  * @description This function sends a request to get the tags from the current thread. It does not handle the response.
@@ -650,4 +1329,125 @@ function updateVoteState(currentVoteState, currentVoteCount, IsUpvoting, upvoteI
     console.log("Current Vote State: ", state);
     console.log("Current Vote Count: ", count);
     return {state, count};
-}
\ No newline at end of file
+}
+/**
+ * Change the subscription of the user to the given thread.
+ * @description This function sends a request to change the subscription of the user to a thread. It does not handle the response.
+ * @description But a success response means that the subscription has been changed.
+ * @param threadName {string} - The name of the thread.
+ * @param action {string} - The action to make, "subscribeThread", "unsubscribeThread", "muteThread" or "unmuteThread".
+ * @returns {Promise<Response>} - The response from the server.
+ */
+function updateThreadSubscription(threadName, action) {
+    return fetch( `/api/thread/${threadName}/${action}`, {
+        method: "POST",
+        headers: {
+            "Content-Type": "application/json",
+        }
+    });
+}
+
+/**
+ * Change the subscription of the user to the message with the given id in the given thread.
+ * @description This function sends a request to change the subscription of the user to a message. It does not handle the response.
+ * @description But a success response means that the subscription has been changed.
+ * @param threadName {string} - The name of the thread of the message.
+ * @param messageId {number} - The ID of the message.
+ * @param action {string} - The action to make, "subscribeMessage", "unsubscribeMessage", "muteMessage" or "unmuteMessage".
+ * @returns {Promise<Response>} - The response from the server.
+ */
+function updateMessageSubscription(threadName, messageId, action) {
+    return fetch( `/api/thread/${threadName}/${action}`, {
+        method: "POST",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        body: JSON.stringify({
+            messageId: messageId
+        })
+    });
+}
+
+/**
+ * Save or unsave the message with the given id in the given thread.
+ * @description This function sends a request to add or remove a message from the saved items of the user. It does not handle the response.
+ * @description The saved items are listed on the /profile/saved page, where they can be sorted in folders.
+ * @param threadName {string} - The name of the thread of the message.
+ * @param messageId {number} - The ID of the message.
+ * @param save {boolean} - Whether the message is saved or unsaved.
+ * @param folder {string} - The folder to save the message in, empty for no folder.
+ * @returns {Promise<Response>} - The response from the server.
+ */
+function updateSavedMessage(threadName, messageId, save, folder = "") {
+    return fetch( `/api/thread/${threadName}/${save ? "saveMessage" : "unsaveMessage"}`, {
+        method: "POST",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        body: JSON.stringify({
+            messageId: messageId,
+            folder: folder
+        })
+    });
+}
+
+/**
+ * Save or unsave the comment with the given id in the given thread.
+ * @description This function sends a request to add or remove a comment from the saved items of the user. It does not handle the response.
+ * @description The saved items are listed on the /profile/saved page, where they can be sorted in folders.
+ * @param threadName {string} - The name of the thread of the comment.
+ * @param messageId {string} - The ID of the message of the comment.
+ * @param commentId {number} - The ID of the comment.
+ * @param save {boolean} - Whether the comment is saved or unsaved.
+ * @param folder {string} - The folder to save the comment in, empty for no folder.
+ * @returns {Promise<Response>} - The response from the server.
+ */
+function updateSavedComment(threadName, messageId, commentId, save, folder = "") {
+    return fetch( `/api/thread/${threadName}/${save ? "saveComment" : "unsaveComment"}`, {
+        method: "POST",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        body: JSON.stringify({
+            commentId: commentId,
+            messageId: messageId,
+            folder: folder
+        })
+    });
+}
+
+/**
+ * Bind the subscription buttons of a thread or a message.
+ * @description Each button makes its action and the visible buttons are updated to the new subscription state.
+ * @description Muting subscribes silently and unsubscribing removes the mute.
+ * @param buttons {{subscribe: HTMLElement, unsubscribe: HTMLElement, mute: HTMLElement, unmute: HTMLElement}} - The subscription buttons.
+ * @param sendAction {function(string): Promise<Response>} - The function sending the action ("subscribe", "unsubscribe", "mute" or "unmute").
+ */
+function bindSubscriptionButtons(buttons, sendAction) {
+    if (!buttons.subscribe || !buttons.unsubscribe || !buttons.mute || !buttons.unmute) {
+        return;
+    }
+    const setState = (isSubscribed, isMuted) => {
+        buttons.subscribe.classList.toggle("hidden", isSubscribed);
+        buttons.unsubscribe.classList.toggle("hidden", !isSubscribed);
+        buttons.mute.classList.toggle("hidden", isMuted);
+        buttons.unmute.classList.toggle("hidden", !isMuted);
+    };
+    const isMuted = () => !buttons.unmute.classList.contains("hidden");
+    const bind = (button, action, nextState) => {
+        button.addEventListener("click", function () {
+            sendAction(action).then((response) => {
+                if (response.ok) {
+                    const state = nextState();
+                    setState(state.isSubscribed, state.isMuted);
+                } else {
+                    console.error(response);
+                }
+            });
+        });
+    };
+    bind(buttons.subscribe, "subscribe", () => ({isSubscribed: true, isMuted: isMuted()}));
+    bind(buttons.unsubscribe, "unsubscribe", () => ({isSubscribed: false, isMuted: false}));
+    bind(buttons.mute, "mute", () => ({isSubscribed: true, isMuted: true}));
+    bind(buttons.unmute, "unmute", () => ({isSubscribed: true, isMuted: false}));
+}
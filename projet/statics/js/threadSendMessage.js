@@ -21,7 +21,8 @@ document.addEventListener("DOMContentLoaded", function () {
                 if (r.ok) {
                     return r.json();
                 } else {
-                    throw new Error("Error while sending message");
+                    // The content filters explain why the message was refused
+                    return r.text().then(text => { throw new Error(text || "Error while sending message"); });
                 }
             })
             .then(data => {
@@ -36,6 +37,10 @@ document.addEventListener("DOMContentLoaded", function () {
                 messageThreadContainer.textContent = threadName;
                 messageIDContainer.textContent = data.messageId;
                 afterMessageSendOptionContainer.classList.remove("hidden");
+                // The messages of the new members may wait for a moderator before being visible
+                if (data.isPending) {
+                    alert(getI18nText("pending-message-notice"));
+                }
                 MediaIDs = []; // Clear the MediaIDs array after sending the message
                 while (imagePreviewContainer.firstChild) { // Remove all images from the preview
                     imagePreviewContainer.removeChild(imagePreviewContainer.firstChild);
@@ -45,6 +50,7 @@ document.addEventListener("DOMContentLoaded", function () {
             })
             .catch(error => {
                     console.error("Error:", error);
+                    alert(error.message);
                 });
     });
 